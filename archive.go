@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// savedBasis is one entry of the manifest of a basis archive: a basis file
+// written by --save-bases together with how it was obtained.
+type savedBasis struct {
+	ID        string          `json:"id"`               // e.g. "basis_0003"
+	File      string          `json:"file"`             // basis in fplll format, relative to the archive
+	Vector    string          `json:"vector,omitempty"` // shortest vector found on the basis, if any
+	Lab       string          `json:"lab"`
+	N         int             `json:"n"`
+	Q         string          `json:"q,omitempty"`
+	Trial     int             `json:"trial"`
+	Reduction []reductionStep `json:"reduction"`        // every step applied since the basis was generated
+	Parent    string          `json:"parent,omitempty"` // entry this basis was continued from
+	Seed      uint64          `json:"seed"`
+	Created   time.Time       `json:"created"`
+}
+
+// basisArchive is a directory of saved bases with a JSON Lines manifest,
+// manifest.jsonl, listing one savedBasis per line, so that expensive
+// reductions can be picked up again by the continue command.
+type basisArchive struct {
+	dir     string
+	mu      sync.Mutex // guards entries and the manifest; labs save concurrently with --jobs
+	entries []savedBasis
+}
+
+// archive, if set, receives every reduced basis and shortest vector computed
+// by the labs (--save-bases).
+var archive *basisArchive
+
+// manifestName is the name of the manifest file inside an archive.
+const manifestName = "manifest.jsonl"
+
+// openBasisArchive opens the archive in dir, creating the directory if
+// needed and loading the entries of an existing manifest. In a dry run
+// nothing is created.
+func openBasisArchive(dir string) (*basisArchive, error) {
+	a := &basisArchive{dir: dir}
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	// Ignore a partial last line, left by a crash in the middle of a write.
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var entry savedBasis
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", filepath.Join(dir, manifestName), i+1, err)
+		}
+		a.entries = append(a.entries, entry)
+	}
+	if dryRun != nil {
+		fmt.Fprintf(dryRun, "[dry-run] save reduced bases to %s\n", dir)
+		return a, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// save writes basis, and vector if it is not nil, into the archive and
+// appends entry to the manifest. The ID and file names are assigned here and
+// returned in the completed entry.
+func (a *basisArchive) save(entry savedBasis, basis Basis, vector []*big.Int) (savedBasis, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry.ID = fmt.Sprintf("basis_%04d", len(a.entries)+1)
+	entry.File = entry.ID + ".txt"
+	if vector != nil {
+		entry.Vector = entry.ID + "_svp.txt"
+	}
+	entry.Created = time.Now()
+	if dryRun != nil {
+		fmt.Fprintf(dryRun, "[dry-run] save %dx%d basis to %s\n", len(basis), len(basis[0]), filepath.Join(a.dir, entry.File))
+		a.entries = append(a.entries, entry)
+		return entry, nil
+	}
+
+	if err := writeBasisToFile(basis, filepath.Join(a.dir, entry.File)); err != nil {
+		return entry, err
+	}
+	if vector != nil {
+		if err := os.WriteFile(filepath.Join(a.dir, entry.Vector), []byte(formatVector(vector)), 0o644); err != nil {
+			return entry, err
+		}
+	}
+
+	// The manifest line is written last, so that it never names missing files.
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+	f, err := os.OpenFile(filepath.Join(a.dir, manifestName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return entry, err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return entry, err
+	}
+	if err := f.Close(); err != nil {
+		return entry, err
+	}
+	a.entries = append(a.entries, entry)
+	return entry, nil
+}
+
+// lookup returns the entry with the given ID.
+func (a *basisArchive) lookup(id string) (savedBasis, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, entry := range a.entries {
+		if entry.ID == id {
+			return entry, true
+		}
+	}
+	return savedBasis{}, false
+}
+
+// archiveBasis saves a basis computed by a lab if --save-bases is set. A
+// failure to save is logged but does not stop the lab.
+func archiveBasis(entry savedBasis, basis Basis, vector []*big.Int) {
+	if archive == nil || basis == nil {
+		return
+	}
+	entry.Seed = runInfo.Seed
+	saved, err := archive.save(entry, basis, vector)
+	if err != nil {
+		slog.Error("saving basis", "dir", archive.dir, "err", err)
+		return
+	}
+	slog.Info("saved basis", "id", saved.ID, "lab", saved.Lab, "n", saved.N, "trial", saved.Trial)
+}
+
+// continueConfig holds the arguments of the continue command.
+type continueConfig struct {
+	Source    string // ID of an archived basis, or a basis file in any supported format
+	Reduction []reductionStep
+}
+
+// parseContinueFlags builds a continueConfig from the continue command line.
+func parseContinueFlags(args []string) (continueConfig, error) {
+	fs := flag.NewFlagSet("continue", flag.ContinueOnError)
+	algo := fs.String("a", "bkz", "reduction algorithm: "+reductionAlgos)
+	beta := fs.Int("b", 30, "block size of BKZ and slide reduction")
+	bkz := bkzFlags(fs)
+	lll := lllFlags(fs)
+	bestOf := bestOfFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return continueConfig{}, err
+	}
+	if fs.NArg() != 1 {
+		return continueConfig{}, fmt.Errorf("usage: lattice-labs [--save-bases dir] continue [-a lll|bkz|pbkz|hkz|sld] [-b beta] [-bkz... options] [-delta d] [-eta e] [-best-of k] ID|file")
+	}
+	step := reductionStep{Algo: strings.ToLower(*algo), BestOf: *bestOf, bkzOptions: *bkz, lllOptions: *lll}
+	if step.blockwise() {
+		step.Beta = *beta
+	}
+	if step.algo() == "enum" {
+		return continueConfig{}, fmt.Errorf("an enum step leaves the basis as it is; use the svp command")
+	}
+	if err := step.validate(); err != nil {
+		return continueConfig{}, err
+	}
+	return continueConfig{Source: fs.Arg(0), Reduction: []reductionStep{step}}, nil
+}
+
+// loadContinueSource loads the basis to continue from: an entry of the
+// archive if Source is one of its IDs, otherwise a basis file.
+func loadContinueSource(source string) (savedBasis, Basis, error) {
+	if archive != nil {
+		if entry, ok := archive.lookup(source); ok {
+			f, err := os.Open(filepath.Join(archive.dir, entry.File))
+			if err != nil {
+				return entry, nil, err
+			}
+			defer f.Close()
+			basis, err := readBasis(f, "fplll")
+			if err != nil {
+				return entry, nil, fmt.Errorf("%s: %w", entry.ID, err)
+			}
+			return entry, basis, nil
+		}
+	}
+	basis, err := loadPipeBasis(source, "auto")
+	if err != nil {
+		return savedBasis{}, nil, err
+	}
+	return savedBasis{Lab: "file " + source, N: len(basis)}, basis, nil
+}
+
+// runContinue applies further reduction to a saved basis, prints its new
+// profile like Lab 2 and saves the result as a new archive entry whose
+// reduction history extends the parent's. The result is returned as a Lab 2
+// result with the full reduction history; it has no profile if ctx was
+// cancelled.
+func runContinue(ctx context.Context, w io.Writer, cfg continueConfig) (lab2Result, error) {
+	start := time.Now()
+	parent, basis, err := loadContinueSource(cfg.Source)
+	if err != nil {
+		return lab2Result{}, err
+	}
+	history := append(append([]reductionStep(nil), parent.Reduction...), cfg.Reduction...)
+	result := lab2Result{Rank: len(basis), Q: parent.Q, Reduction: history}
+
+	fmt.Fprintf(w, "--- Continuing reduction of %s ---\n", cfg.Source)
+	fmt.Fprintf(w, "Rank %d, reduced so far with %s.\n", len(basis), describeReduction(parent.Reduction))
+	fmt.Fprintf(w, "Running %s...\n", describeReduction(cfg.Reduction))
+
+	ev := progressEvent{Lab: "Continue", N: len(basis), Beta: finalBlockSize(cfg.Reduction), Q: parent.Q, Trial: 1, Trials: 1, Total: 1,
+		Backend: reductionCommands(cfg.Reduction)}
+	reportProgress(ev)
+	reductionStart := time.Now()
+	var tours tourLog
+	state, err := reductionPipeline{Steps: cfg.Reduction, MaxTime: backend.MaxTime, CheckVolume: true}.run(withTourLog(ctx, &tours), basis)
+	reduced := state.Basis
+	result.ReductionSeconds = time.Since(reductionStart).Seconds()
+	if ctx.Err() != nil {
+		fmt.Fprintln(w, "\nContinue interrupted.")
+		return result, nil
+	}
+	if errors.Is(err, errTimeout) {
+		result.Status = statusTimeout
+		fmt.Fprintf(w, "\n%s timed out after %s.\n", reductionCommands(cfg.Reduction), backend.Timeout)
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("%s: %w", reductionCommands(cfg.Reduction), err)
+	}
+	if state.Stopped {
+		result.Stopped = true
+		fmt.Fprintf(w, "Reduction stopped at the time budget of %s; the basis is the best reached.\n", backend.MaxTime)
+	}
+	result.Profile = reduced.Profile()
+	result.Tours, result.TourProfiles = tours.Tours(), tours.Profiles()
+	ev.Backend, ev.Done, ev.Profile, ev.Seconds = "", 1, result.Profile, result.ReductionSeconds
+	reportProgress(ev)
+
+	writeTours(w, result.Tours)
+	writeTourProfiles(w, result.TourProfiles)
+	fmt.Fprintln(w, "Basis Profile (log2 of Gram-Schmidt norms):")
+	fmt.Fprint(w, "[")
+	for i, val := range result.Profile {
+		if i > 0 {
+			fmt.Fprint(w, ", ")
+		}
+		fmt.Fprintf(w, "%.2f", val)
+	}
+	fmt.Fprintln(w, "]")
+	if profileBounds {
+		eps := certifyProfile(reduced, result.Profile)
+		writeProfileBounds(w, result.Profile, eps)
+		result.ProfileError = jsonFloats(eps)
+	}
+	fmt.Fprintln(w)
+	writeASCIIProfilePlot(w, result.Profile)
+
+	if archive != nil {
+		entry := savedBasis{Lab: parent.Lab, N: len(reduced), Q: parent.Q, Trial: parent.Trial, Reduction: history, Parent: parent.ID, Seed: parent.Seed}
+		saved, err := archive.save(entry, reduced, nil)
+		if err != nil {
+			return result, fmt.Errorf("saving basis: %w", err)
+		}
+		fmt.Fprintf(w, "\nSaved the reduced basis as %s (%s).\n", saved.ID, filepath.Join(archive.dir, saved.File))
+	} else {
+		fmt.Fprintln(w, "\nThe reduced basis was not saved; use --save-bases to keep it.")
+	}
+
+	result.Seconds = time.Since(start).Seconds()
+	return result, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// asciiPlotHeight is the number of text rows used for the plot area.
+const asciiPlotHeight = 12
+
+// writeASCIIProfilePlot draws a Lab 2 profile in plain text so that its
+// linearity can be judged on a remote terminal without exporting anything.
+// Each index gets two columns; profile points are drawn as '*' and the
+// fitted GSA line as '-'.
+func writeASCIIProfilePlot(w io.Writer, profile Profile) {
+	if len(profile) < 2 {
+		return
+	}
+	slope, intercept, r2 := profile.Fit()
+	fit := func(i int) float64 { return slope*float64(i) + intercept }
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i, v := range profile {
+		lo = math.Min(lo, math.Min(v, fit(i)))
+		hi = math.Max(hi, math.Max(v, fit(i)))
+	}
+	if hi-lo < 1e-9 {
+		lo, hi = lo-1, hi+1
+	}
+	row := func(v float64) int {
+		return int(math.Round((hi - v) / (hi - lo) * float64(asciiPlotHeight-1)))
+	}
+
+	width := 2 * len(profile)
+	grid := make([][]byte, asciiPlotHeight)
+	for r := range grid {
+		grid[r] = []byte(strings.Repeat(" ", width))
+	}
+	for i, v := range profile {
+		grid[row(fit(i))][2*i] = '-'
+		grid[row(v)][2*i] = '*'
+	}
+
+	for r, line := range grid {
+		label := ""
+		switch r {
+		case 0:
+			label = fmt.Sprintf("%.2f", hi)
+		case asciiPlotHeight / 2:
+			label = fmt.Sprintf("%.2f", hi-(hi-lo)*float64(r)/float64(asciiPlotHeight-1))
+		case asciiPlotHeight - 1:
+			label = fmt.Sprintf("%.2f", lo)
+		}
+		fmt.Fprintf(w, "%8s |%s\n", label, strings.TrimRight(string(line), " "))
+	}
+	fmt.Fprintf(w, "%8s +%s\n", "", strings.Repeat("-", width))
+
+	// Index ticks every ten positions
+	ticks := []byte(strings.Repeat(" ", width+4))
+	for i := 0; i < len(profile); i += 10 {
+		copy(ticks[2*i:], fmt.Sprint(i))
+	}
+	fmt.Fprintf(w, "%8s  %s\n", "", strings.TrimRight(string(ticks), " "))
+	fmt.Fprintf(w, "* profile   - fitted GSA line (slope %.4f, R^2 %.3f)\n", slope, r2)
+}
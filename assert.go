@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// thresholds are the verification limits checked by --assert.
+type thresholds struct {
+	// MaxGHError is the largest relative error between λ1 and the GH
+	// prediction, in percent, allowed for any Lab 1 instance.
+	MaxGHError float64
+	// MinR2 is the smallest coefficient of determination of the fitted GSA
+	// line allowed for any Lab 2 profile.
+	MinR2 float64
+}
+
+// assertionError reports the checks failed by a run, e.g. violated
+// thresholds or regressions found by compare. main exits with exitAssertion
+// when it sees one.
+type assertionError struct {
+	Kind       string // what the violations are, e.g. "verification threshold(s) violated"
+	Violations []string
+}
+
+// Error implements error.
+func (e *assertionError) Error() string {
+	return fmt.Sprintf("%d %s:\n  %s", len(e.Violations), e.Kind, strings.Join(e.Violations, "\n  "))
+}
+
+// checkAssertions checks every result set against t and returns an
+// *assertionError listing all violations, or nil if there are none. A failed
+// or timed-out oracle call or reduction counts as a violation.
+func checkAssertions(t thresholds, results []*runResults) error {
+	var violations []string
+	for _, res := range results {
+		for _, lab := range res.Lab1 {
+			for _, row := range lab.Rows {
+				relErr := float64(row.RelativeError)
+				if row.Status != "" {
+					violations = append(violations, fmt.Sprintf("Lab 1 (q = %s) n=%d trial %d: oracle %s",
+						lab.Q, row.N, row.Trial, statusText(row.Status)))
+					continue
+				}
+				if math.IsNaN(relErr) || math.IsInf(relErr, 0) || relErr > t.MaxGHError {
+					violations = append(violations, fmt.Sprintf("Lab 1 (q = %s) n=%d trial %d: relative GH error %.2f%% exceeds %.2f%%",
+						lab.Q, row.N, row.Trial, relErr, t.MaxGHError))
+				}
+			}
+		}
+		for _, lab := range res.Lab2 {
+			if lab.Status != "" {
+				violations = append(violations, fmt.Sprintf("Lab 2 (rank %d, q = %s, %s): reduction %s",
+					lab.Rank, lab.Q, describeReduction(lab.Reduction), statusText(lab.Status)))
+				continue
+			}
+			if _, _, r2 := lab.Profile.Fit(); r2 < t.MinR2 {
+				violations = append(violations, fmt.Sprintf("Lab 2 (rank %d, q = %s, %s): GSA fit R^2 %.4f below %.4f",
+					lab.Rank, lab.Q, describeReduction(lab.Reduction), r2, t.MinR2))
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return &assertionError{Kind: "verification threshold(s) violated", Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	mrand "math/rand/v2"
+)
+
+// nearestPlane is Babai's nearest plane algorithm on one basis, in exact
+// rational arithmetic: the Gram-Schmidt vectors are computed once, as
+// rationals, and every coefficient is rounded exactly, so the decoding
+// depends on the basis and the target only, never on floating point. It is
+// the deterministic decoder the CVP-flavoured labs build on.
+type nearestPlane struct {
+	basis Basis
+	star  [][]*big.Rat // b*ᵢ
+	r     []*big.Rat   // ‖b*ᵢ‖²
+}
+
+// newNearestPlane prepares decoding with basis, whose rows must be
+// linearly independent.
+func newNearestPlane(basis Basis) (*nearestPlane, error) {
+	gso, err := newExactGSO(basis)
+	if err != nil {
+		return nil, err
+	}
+	np := &nearestPlane{basis: basis, star: make([][]*big.Rat, len(basis)), r: make([]*big.Rat, len(basis))}
+	var t big.Rat
+	for i, row := range basis {
+		np.star[i] = ratVector(row)
+		for j := range i {
+			mu := gso.mu(i, j)
+			for k, v := range np.star[j] {
+				np.star[i][k].Sub(np.star[i][k], t.Mul(mu, v))
+			}
+		}
+		np.r[i] = gso.r(i)
+	}
+	return np, nil
+}
+
+// Decode returns the lattice point Babai's nearest plane algorithm finds for
+// target and the error vector target - point. From the last basis vector
+// to the first, it subtracts from the target the multiple of bᵢ that
+// leaves the coefficient along b*ᵢ in [-1/2, 1/2), so the error lies in
+// that box around the origin spanned by the b*ᵢ (plus the part of target
+// outside the span of the basis). It is the closest point if the target
+// is within half the shortest ‖b*ᵢ‖ of the lattice.
+func (np *nearestPlane) Decode(target []*big.Rat) (point []*big.Int, errVec []*big.Rat, err error) {
+	if _, cols := np.basis.Dims(); len(target) != cols {
+		return nil, nil, fmt.Errorf("target has %d entries, the basis vectors %d", len(target), cols)
+	}
+	errVec = make([]*big.Rat, len(target))
+	for k, v := range target {
+		errVec[k] = new(big.Rat).Set(v)
+	}
+	coeffs := make([]*big.Int, len(np.basis))
+	var c, t, z big.Rat
+	for i := len(np.basis) - 1; i >= 0; i-- {
+		c.SetInt64(0)
+		for k, v := range np.star[i] {
+			if v.Sign() != 0 {
+				c.Add(&c, t.Mul(errVec[k], v))
+			}
+		}
+		coeffs[i] = roundRat(c.Quo(&c, np.r[i]))
+		if coeffs[i].Sign() == 0 {
+			continue
+		}
+		z.SetInt(coeffs[i])
+		for k, v := range np.basis[i] {
+			errVec[k].Sub(errVec[k], t.Mul(&z, t.SetInt(v)))
+		}
+	}
+	return np.point(coeffs), errVec, nil
+}
+
+// point returns the lattice point with coefficients coeffs.
+func (np *nearestPlane) point(coeffs []*big.Int) []*big.Int {
+	_, cols := np.basis.Dims()
+	point := make([]*big.Int, cols)
+	for k := range point {
+		point[k] = new(big.Int)
+	}
+	var term big.Int
+	for i, row := range np.basis {
+		if coeffs[i].Sign() == 0 {
+			continue
+		}
+		for k, v := range row {
+			point[k].Add(point[k], term.Mul(coeffs[i], v))
+		}
+	}
+	return point
+}
+
+// randomizedDecoding are the parameters of nearestPlane.DecodeRandomized.
+type randomizedDecoding struct {
+	// Candidates is how many of the nearest planes are candidates at
+	// every level; 1 is Babai's algorithm.
+	Candidates int
+	// Sigma is the expected width of the error: a candidate z for the
+	// coefficient c of bᵢ is drawn with probability proportional to
+	// exp(-(z-c)²‖b*ᵢ‖²/(2σ²)), the likelihood of the error it leaves.
+	Sigma float64
+	// Tries is how many decodings are run; the closest point wins.
+	Tries int
+}
+
+// DecodeRandomized decodes target like Decode, but at every level draws the
+// coefficient among the opts.Candidates integers nearest to its exact
+// value, weighted by the likelihood of the error each leaves under a
+// Gaussian error of width opts.Sigma, and keeps the closest of opts.Tries
+// such points, with their error vector. This is the randomized counterpart
+// of Lindner and Peikert's nearest planes: where Babai's one wrong rounding
+// at a level with a short b*ᵢ is final, some tries take the right plane,
+// which extends the decoding radius at the cost of the tries.
+func (np *nearestPlane) DecodeRandomized(rng *mrand.Rand, target []*big.Rat, opts randomizedDecoding) (point []*big.Int, errVec []*big.Rat, err error) {
+	if opts.Candidates < 1 || !(opts.Sigma > 0) {
+		return nil, nil, fmt.Errorf("want at least 1 candidate and a positive σ, got %d and %g", opts.Candidates, opts.Sigma)
+	}
+	if _, cols := np.basis.Dims(); len(target) != cols {
+		return nil, nil, fmt.Errorf("target has %d entries, the basis vectors %d", len(target), cols)
+	}
+	var best *big.Rat
+	var bestCoeffs []*big.Int
+	weights := make([]float64, opts.Candidates)
+	candidates := make([]*big.Int, opts.Candidates)
+	for range max(opts.Tries, 1) {
+		e := make([]*big.Rat, len(target))
+		for k, v := range target {
+			e[k] = new(big.Rat).Set(v)
+		}
+		coeffs := make([]*big.Int, len(np.basis))
+		var c, t, d big.Rat
+		for i := len(np.basis) - 1; i >= 0; i-- {
+			c.SetInt64(0)
+			for k, v := range np.star[i] {
+				if v.Sign() != 0 {
+					c.Add(&c, t.Mul(e[k], v))
+				}
+			}
+			c.Quo(&c, np.r[i])
+			// The candidates alternate around the nearest integer, nearest
+			// first: z, then the integers beyond c on either side.
+			z := roundRat(&c)
+			total := 0.0
+			for j := range candidates {
+				step := int64((j + 1) / 2)
+				if (j%2 == 1) == (d.Sub(&c, t.SetInt(z)).Sign() < 0) {
+					step = -step
+				}
+				candidates[j] = new(big.Int).Add(z, big.NewInt(step))
+				d.Sub(t.SetInt(candidates[j]), &c)
+				d.Mul(&d, &d)
+				dist, _ := d.Mul(&d, np.r[i]).Float64()
+				weights[j] = math.Exp(-dist / (2 * opts.Sigma * opts.Sigma))
+				total += weights[j]
+			}
+			pick := 0
+			if total > 0 {
+				for u := rng.Float64() * total; pick < len(weights)-1 && u >= weights[pick]; pick++ {
+					u -= weights[pick]
+				}
+			}
+			coeffs[i] = candidates[pick]
+			if coeffs[i].Sign() == 0 {
+				continue
+			}
+			d.SetInt(coeffs[i])
+			for k, v := range np.basis[i] {
+				e[k].Sub(e[k], t.Mul(&d, t.SetInt(v)))
+			}
+		}
+		if norm := ratNormSquared(e); best == nil || norm.Cmp(best) < 0 {
+			best, bestCoeffs, errVec = norm, coeffs, e
+		}
+	}
+	return np.point(bestCoeffs), errVec, nil
+}
+
+// NearestPlane decodes target with Babai's nearest plane algorithm on b
+// and returns the lattice point and the error vector target - point; see
+// nearestPlane. Decoding several targets with one newNearestPlane saves
+// the Gram-Schmidt computation.
+func (b Basis) NearestPlane(target []*big.Rat) (point []*big.Int, errVec []*big.Rat, err error) {
+	np, err := newNearestPlane(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return np.Decode(target)
+}
+
+// ratVector returns v with rational entries.
+func ratVector(v []*big.Int) []*big.Rat {
+	r := make([]*big.Rat, len(v))
+	for i, x := range v {
+		r[i] = new(big.Rat).SetInt(x)
+	}
+	return r
+}
+
+// roundRat returns the integer nearest to x, rounding halves up: ⌊x + 1/2⌋.
+func roundRat(x *big.Rat) *big.Int {
+	// ⌊(2·num + den) / (2·den)⌋, Div rounding towards -∞ for the positive
+	// denominator.
+	num := new(big.Int).Lsh(x.Num(), 1)
+	num.Add(num, x.Denom())
+	return num.Div(num, new(big.Int).Lsh(x.Denom(), 1))
+}
+
+// ratNormSquared returns the squared Euclidean norm of v.
+func ratNormSquared(v []*big.Rat) *big.Rat {
+	sum, sq := new(big.Rat), new(big.Rat)
+	for _, x := range v {
+		sum.Add(sum, sq.Mul(x, x))
+	}
+	return sum
+}
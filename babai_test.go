@@ -0,0 +1,70 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+// TestNearestPlane decodes targets that are lattice vectors plus an error
+// well within half the shortest Gram-Schmidt vector, which Babai's
+// algorithm must undo exactly.
+func TestNearestPlane(t *testing.T) {
+	basis := basisOf([]int64{5, 1, 0}, []int64{-1, 5, 1}, []int64{0, -1, 5})
+	np, err := newNearestPlane(basis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		coeffs []int64
+		err    []*big.Rat
+	}{
+		{[]int64{0, 0, 0}, []*big.Rat{big.NewRat(1, 3), big.NewRat(-1, 2), big.NewRat(0, 1)}},
+		{[]int64{3, -2, 7}, []*big.Rat{big.NewRat(1, 1), big.NewRat(-1, 1), big.NewRat(1, 1)}},
+		{[]int64{-40, 11, 9}, []*big.Rat{big.NewRat(-7, 5), big.NewRat(2, 7), big.NewRat(9, 10)}},
+		{[]int64{1, 1, 1}, []*big.Rat{big.NewRat(0, 1), big.NewRat(0, 1), big.NewRat(0, 1)}},
+	} {
+		v := make([]*big.Int, 3)
+		target := make([]*big.Rat, 3)
+		for j := range v {
+			v[j] = new(big.Int)
+			for i, c := range tc.coeffs {
+				v[j].Add(v[j], new(big.Int).Mul(big.NewInt(c), basis[i][j]))
+			}
+			target[j] = new(big.Rat).Add(new(big.Rat).SetInt(v[j]), tc.err[j])
+		}
+		point, errVec, err := np.Decode(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := range v {
+			if point[j].Cmp(v[j]) != 0 || errVec[j].Cmp(tc.err[j]) != 0 {
+				t.Errorf("coefficients %v: decoded %v with error %v, want %v with error %v", tc.coeffs, point, errVec, v, tc.err)
+				break
+			}
+		}
+	}
+	if _, _, err := np.Decode(ratVector(intVector(1, 2))); err == nil {
+		t.Error("Decode accepted a target of the wrong length")
+	}
+	if _, err := newNearestPlane(basisOf([]int64{1, 2}, []int64{2, 4})); err == nil {
+		t.Error("newNearestPlane accepted linearly dependent rows")
+	}
+}
+
+func TestRoundRat(t *testing.T) {
+	for _, tc := range []struct {
+		x    *big.Rat
+		want int64
+	}{
+		{big.NewRat(7, 3), 2},
+		{big.NewRat(5, 2), 3},
+		{big.NewRat(-5, 2), -2},
+		{big.NewRat(-7, 3), -2},
+		{big.NewRat(-8, 3), -3},
+		{big.NewRat(4, 1), 4},
+	} {
+		if got := roundRat(tc.x); got.Cmp(big.NewInt(tc.want)) != 0 {
+			t.Errorf("roundRat(%s) = %s, want %d", tc.x.RatString(), got, tc.want)
+		}
+	}
+}
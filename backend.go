@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"runtime/trace"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backendConfig selects and tunes the external solver. run fills it in from
+// the flags, the environment and the config file before any lab starts.
+type backendConfig struct {
+	// Name is the solver in use; only "fplll" is supported.
+	Name string
+	// Binary is the fplll executable, looked up in PATH unless it contains
+	// a path separator.
+	Binary string
+	// Flatter is the flatter executable; after startup (see useFlatter) its
+	// absolute path, or "" if it isn't used.
+	Flatter string
+	// Library is the C interface to libfplll that fplll calls go through
+	// in process, "" to search for it or "off"; after startup (see
+	// useLibfplll) its path, or "" if the calls run Binary.
+	Library string
+	// Sage is the SageMath executable, run to cross-validate fplll's
+	// results (-backend sage, bench -backends sage).
+	Sage string
+	// TempDir is the directory receiving the temporary files of fplll
+	// calls, such as the GSO dumps of --tour-profiles. Every call gets a
+	// file of its own, so concurrent runs sharing the directory don't
+	// interfere.
+	TempDir string
+	// Precision is the floating-point precision in bits used by fplll (with
+	// its MPFR backend), or 0 for fplll's own choice.
+	Precision int
+	// FloatType is the floating-point type of fplll (-f), one of
+	// fplllFloatTypes, or "" for fplll's own choice, or mpfr if Precision is
+	// set.
+	FloatType string
+	// Strategy, if set, is the pruning strategy file BKZ and slide
+	// reduction run with (fplll -s), and StrategyDigest its SHA-256, both
+	// set by useStrategy at startup.
+	Strategy       string
+	StrategyDigest string
+	// TourStats runs BKZ and slide reduction verbosely (fplll -v) so that
+	// the statistics of their tours are recorded (--tour-stats).
+	TourStats bool
+	// TourProfiles has BKZ and slide reduction dump the profile of the
+	// basis after every tour (fplll -bkzdumpgso) so that it is recorded
+	// (--tour-profiles).
+	TourProfiles bool
+	// Jobs is the number of fplll calls run concurrently by the labs.
+	Jobs int
+	// Timeout limits every fplll call; 0 means no limit. A call that runs
+	// longer is killed and its instance recorded as timed out.
+	Timeout time.Duration
+	// MaxTime is the time budget of every reduction pipeline; 0 means no
+	// limit. Unlike Timeout it keeps the basis reduced so far (see
+	// reductionPipeline.MaxTime).
+	MaxTime time.Duration
+	// Retries is how many times a failed fplll call is repeated, waiting
+	// RetryBackoff before the first retry and doubling the wait each time.
+	Retries      int
+	RetryBackoff time.Duration
+	// Rerandomize gives every retry a random basis of the same lattice.
+	Rerandomize bool
+	// Version is the fplll version detected at startup; it selects how the
+	// output of fplll is parsed.
+	Version fplllVersion
+}
+
+// backend is the solver configuration of the current run.
+var backend = defaultBackend()
+
+// fplllExited, if set, is called with the state of every fplll process, or
+// process of another solver, that ran to completion; the backend benchmark
+// reads its memory use from it.
+var fplllExited func(state *os.ProcessState)
+
+// defaultBackend returns the solver configuration used without a config
+// file, environment variables or flags.
+func defaultBackend() backendConfig {
+	return backendConfig{Name: "fplll", Binary: "fplll", Flatter: defaultFlatter, Sage: defaultSage, TempDir: os.TempDir(), Jobs: 1, RetryBackoff: time.Second}
+}
+
+// fplllFloatTypes are the floating-point types fplll -f accepts, from the
+// fastest to the most precise.
+var fplllFloatTypes = []string{"double", "longdouble", "dpe", "dd", "qd", "mpfr"}
+
+// validate reports whether the settings name a supported, usable backend.
+func (b backendConfig) validate() error {
+	if !strings.EqualFold(b.Name, "fplll") {
+		return fmt.Errorf("unknown backend %q (only fplll; use reduce/svp -backend sage for SageMath and -a flatter for flatter)", b.Name)
+	}
+	if b.Binary == "" {
+		return fmt.Errorf("empty fplll path")
+	}
+	if b.Precision < 0 {
+		return fmt.Errorf("precision must not be negative, got %d", b.Precision)
+	}
+	if b.FloatType != "" && !slices.Contains(fplllFloatTypes, b.FloatType) {
+		return fmt.Errorf("unknown float type %q (want %s)", b.FloatType, strings.Join(fplllFloatTypes, ", "))
+	}
+	if b.Precision > 0 && b.FloatType != "" && b.FloatType != "mpfr" {
+		return fmt.Errorf("precision %d needs float type mpfr, got %s", b.Precision, b.FloatType)
+	}
+	if b.Jobs < 1 {
+		return fmt.Errorf("jobs must be at least 1, got %d", b.Jobs)
+	}
+	if b.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative, got %s", b.Timeout)
+	}
+	if b.MaxTime < 0 {
+		return fmt.Errorf("max time must not be negative, got %s", b.MaxTime)
+	}
+	if b.Retries < 0 {
+		return fmt.Errorf("retries must not be negative, got %d", b.Retries)
+	}
+	if b.RetryBackoff < 0 {
+		return fmt.Errorf("retry backoff must not be negative, got %s", b.RetryBackoff)
+	}
+	return nil
+}
+
+// fplllArgs returns the fplll command-line arguments running algo with the
+// extra arguments, adding the configured strategy file for BKZ and slide
+// reduction and the float type and precision.
+func (b backendConfig) fplllArgs(algo string, args ...string) []string {
+	cmdArgs := append([]string{"-a", algo}, args...)
+	if b.Strategy != "" && (algo == "bkz" || algo == "sld") {
+		cmdArgs = append(cmdArgs, "-s", b.Strategy)
+	}
+	if b.TourStats && (algo == "bkz" || algo == "sld") {
+		cmdArgs = append(cmdArgs, "-v")
+	}
+	if b.TourProfiles && (algo == "bkz" || algo == "sld") {
+		cmdArgs = append(cmdArgs, "-bkzdumpgso", gsoDumpFile)
+	}
+	floatType := b.FloatType
+	if b.Precision > 0 {
+		floatType = "mpfr"
+	}
+	if floatType != "" {
+		cmdArgs = append(cmdArgs, "-f", floatType)
+	}
+	if b.Precision > 0 {
+		cmdArgs = append(cmdArgs, "-p", strconv.Itoa(b.Precision))
+	}
+	return cmdArgs
+}
+
+// runExternal runs a solver other than fplll, binary named name in errors,
+// with args and basis on its standard input in fplll's format, and returns
+// its standard output. The call is limited to b.Timeout like fplll calls,
+// but runs locally and is neither cached nor retried.
+func runExternal(ctx context.Context, name, binary string, args []string, basis Basis) ([]byte, error) {
+	callCtx := ctx
+	if backend.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, backend.Timeout)
+		defer cancel()
+	}
+	var input, stdout, stderr bytes.Buffer
+	if err := writeBasis(&input, basis); err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(callCtx, binary, args...)
+	cmd.WaitDelay = time.Second
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = &input, &stdout, &stderr
+	slog.Debug("running "+name, "rank", len(basis))
+	start := time.Now()
+	err := cmd.Run()
+	if fplllExited != nil && cmd.ProcessState != nil {
+		fplllExited(cmd.ProcessState)
+	}
+	switch {
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case callCtx.Err() != nil:
+		return nil, fmt.Errorf("%s: %w after %s", name, errTimeout, backend.Timeout)
+	case err != nil:
+		return nil, fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	slog.Debug(name+" finished", "rank", len(basis), "seconds", time.Since(start).Seconds())
+	return stdout.Bytes(), nil
+}
+
+// errTimeout is returned for fplll calls stopped by the per-call timeout.
+var errTimeout = errors.New("fplll timed out")
+
+// Statuses of results whose backend call did not succeed; a successful
+// result has an empty status.
+const (
+	statusTimeout = "timeout" // stopped by the per-call timeout
+	statusFailed  = "failed"  // fplll failed or its output could not be parsed
+)
+
+// failureStatus returns the status of a result whose backend call returned
+// err.
+func failureStatus(err error) string {
+	if errors.Is(err, errTimeout) {
+		return statusTimeout
+	}
+	return statusFailed
+}
+
+// statusText describes a status in tables and logs.
+func statusText(status string) string {
+	if status == statusTimeout {
+		return "timed out"
+	}
+	return status
+}
+
+// runFplll runs fplll with args, writing basis to its standard input while
+// parse reads its standard output, so that no file is involved and large
+// bases are never held as text in memory. The call is limited to b.Timeout,
+// after which fplll is killed and an error wrapping errTimeout is returned;
+// if ctx is cancelled, ctx.Err() is returned. A failure of fplll itself is
+// returned as an *fplllError carrying its standard error, and takes
+// precedence over the error of parse. With remote workers (see workerPool)
+// the call is sent to one of them instead, and with libfplll loaded it is
+// made in process if it can be (see runFplllLibrary).
+func (b backendConfig) runFplll(ctx context.Context, basis Basis, args []string, parse func(io.Reader) error) error {
+	if workers != nil {
+		return b.runFplllRemote(ctx, basis, args, parse)
+	}
+	if handled, err := b.runFplllLibrary(ctx, basis, args, parse); handled {
+		return err
+	}
+	callCtx := ctx
+	if b.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, b.Timeout)
+		defer cancel()
+	}
+	cmdArgs, dumpPath, err := withGSODumpFile(args, b.TempDir)
+	if err != nil {
+		return err
+	}
+	if dumpPath != "" {
+		defer os.Remove(dumpPath)
+	}
+	cmd := exec.CommandContext(callCtx, b.Binary, cmdArgs...)
+	// Don't wait for children of a killed fplll holding on to its output.
+	cmd.WaitDelay = time.Second
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	slog.Debug("running fplll", "args", args, "rank", len(basis))
+	defer trace.StartRegion(ctx, "fplll").End()
+	trace.Logf(ctx, "fplll", "%s (rank %d)", strings.Join(args, " "), len(basis))
+	if err := cmd.Start(); err != nil {
+		return newFplllError(err, "")
+	}
+
+	written := make(chan error, 1)
+	go func() {
+		err := writeFplllInput(stdin, basis, args)
+		if closeErr := stdin.Close(); err == nil {
+			err = closeErr
+		}
+		written <- err
+	}()
+	parseErr := parse(stdout)
+	// Drain what parse left, so that fplll isn't blocked writing it.
+	io.Copy(io.Discard, stdout)
+	waitErr := cmd.Wait()
+	writeErr := <-written
+	if fplllExited != nil && cmd.ProcessState != nil {
+		fplllExited(cmd.ProcessState)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case callCtx.Err() != nil:
+		return fmt.Errorf("%w after %s", errTimeout, b.Timeout)
+	case waitErr != nil && (parseErr != nil || !stoppedAtBKZLimit(stderr.String())):
+		return newFplllError(waitErr, stderr.String())
+	case writeErr != nil:
+		return fmt.Errorf("writing basis to fplll: %w", writeErr)
+	case parseErr != nil:
+		return b.badOutputError(parseErr, stderr.String())
+	}
+	if l := tourLogOf(ctx); l != nil {
+		l.add(parseBKZTours(stderr.String()))
+		if dumpPath != "" {
+			l.addProfiles(readGSODump(dumpPath, len(basis)))
+		}
+	}
+	if waitErr != nil {
+		slog.Info("BKZ stopped at its tour or time limit", "args", args, "rank", len(basis))
+	}
+	if stderr.Len() > 0 {
+		slog.Debug("fplll diagnostics", "args", args, "stderr", summarizeStderr(stderr.String()))
+	}
+	return nil
+}
+
+// badOutputError returns the error of an fplll call whose output parse
+// rejected.
+func (b backendConfig) badOutputError(parseErr error, stderr string) *fplllError {
+	fe := newFplllError(fmt.Errorf("output of fplll %s does not match the %s format: %w",
+		b.Version, b.Version.dialect().Name, parseErr), stderr)
+	fe.Failure = failureBadOutput
+	return fe
+}
+
+// logFplllError logs a failed fplll call at a level matching its cause:
+// cancellation is expected, a timeout is recorded in the results, anything
+// else is an error.
+func logFplllError(algo string, rank int, err error) {
+	switch {
+	case errors.Is(err, context.Canceled):
+		slog.Debug("fplll cancelled", "algo", algo, "rank", rank)
+	case errors.Is(err, errTimeout):
+		slog.Warn("fplll timed out", "algo", algo, "rank", rank, "timeout", backend.Timeout)
+	default:
+		slog.Error("fplll failed", "algo", algo, "rank", rank, "err", err)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+)
+
+// Basis is a lattice basis, one row per basis vector. The generators,
+// readers, reductions and oracles all pass bases as a Basis; integer
+// matrices that aren't bases, Gram and transformation matrices, stay
+// [][]*big.Int, which a Basis converts to and from without copying.
+type Basis [][]*big.Int
+
+// Dims returns the number of basis vectors and their dimension.
+func (b Basis) Dims() (rows, cols int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	return len(b), len(b[0])
+}
+
+// Clone returns a copy of b that shares no entries with it, for callers that
+// modify a basis in place.
+func (b Basis) Clone() Basis {
+	out := make(Basis, len(b))
+	for i, row := range b {
+		out[i] = make([]*big.Int, len(row))
+		for j, v := range row {
+			out[i][j] = new(big.Int).Set(v)
+		}
+	}
+	return out
+}
+
+// exactVolumeMaxRank is the largest rank whose volume Volume and LogVolume
+// take from the exact det(B·Bᵀ). Its cost grows with the size of the
+// entries of the determinant as well as the rank, to seconds at rank 150,
+// so larger bases use bigFloatLogVolume, tens of times faster and accurate
+// to float64's precision.
+const exactVolumeMaxRank = 64
+
+// LogVolume returns log2 of the volume of the lattice, half of log2 of
+// the exact det(B·Bᵀ), which stays finite where the volume exceeds the
+// range of float64, from rank 50 or so. A basis of dependent vectors has
+// the volume 0 and the log volume -Inf. The volume of a structured basis
+// is taken from its diagonal (see structuredVolume), and that of a basis
+// of rank above exactVolumeMaxRank from bigFloatLogVolume.
+func (b Basis) LogVolume() float64 {
+	if v, ok := b.structuredVolume(); ok {
+		if v.Sign() == 0 {
+			return math.Inf(-1)
+		}
+		return log2Int(v)
+	}
+	if len(b) > exactVolumeMaxRank {
+		return bigFloatLogVolume(intMatrixOf(b))
+	}
+	return gramLogVolume(b.Gram())
+}
+
+// Volume returns the volume of the lattice as a big.Float, as it overflows
+// float64 for the ranks of the labs: the square root of the exact
+// det(B·Bᵀ), or the exact volume of a structured basis, rounded once to 53
+// bits. Above exactVolumeMaxRank it is 2 to the power LogVolume.
+func (b Basis) Volume() *big.Float {
+	if v, ok := b.structuredVolume(); ok {
+		return new(big.Float).SetPrec(53).SetInt(v)
+	}
+	if len(b) > exactVolumeMaxRank {
+		logVol := bigFloatLogVolume(intMatrixOf(b))
+		if math.IsInf(logVol, -1) {
+			return new(big.Float)
+		}
+		exp := math.Floor(logVol)
+		return new(big.Float).SetMantExp(big.NewFloat(math.Exp2(logVol-exp)), int(exp))
+	}
+	return gramVolume(b.Gram())
+}
+
+// SquaredVolume returns the square of the volume of the lattice exactly,
+// det(B·Bᵀ), which all bases of the lattice share.
+func (b Basis) SquaredVolume() *big.Int {
+	if v, ok := b.structuredVolume(); ok {
+		return v.Mul(v, v)
+	}
+	return determinant(b.Gram())
+}
+
+// structuredVolume returns the volume |det B| of a square basis that is
+// triangular up to the order of its columns, the product of its diagonal,
+// and reports whether b is one. The q-ary bases are: [[qI, 0], [A, I]], the
+// LWE embeddings, the NTRU lattice and the SIS kernel basis all have volume
+// q^m for their m rows q·eᵢ. The check peels off rows with a single
+// non-zero entry in the columns left, in O(n²) against the O(n³) of the
+// Gram determinant; a row left with none makes the volume 0.
+func (b Basis) structuredVolume() (*big.Int, bool) {
+	n, cols := b.Dims()
+	if n == 0 || n != cols {
+		return nil, false
+	}
+	// count[i] is the number of non-zero entries of row i in the columns
+	// not peeled yet.
+	count := make([]int, n)
+	var ready []int
+	for i, row := range b {
+		for _, v := range row {
+			if v.Sign() != 0 {
+				count[i]++
+			}
+		}
+		if count[i] <= 1 {
+			ready = append(ready, i)
+		}
+	}
+	peeled, colDone := make([]bool, n), make([]bool, n)
+	vol := big.NewInt(1)
+	for range n {
+		if len(ready) == 0 {
+			return nil, false
+		}
+		i := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		if count[i] == 0 {
+			return new(big.Int), true
+		}
+		c := 0
+		for colDone[c] || b[i][c].Sign() == 0 {
+			c++
+		}
+		vol.Mul(vol, b[i][c])
+		peeled[i], colDone[c] = true, true
+		for k := range b {
+			if !peeled[k] && b[k][c].Sign() != 0 {
+				if count[k]--; count[k] == 1 {
+					ready = append(ready, k)
+				}
+			}
+		}
+	}
+	return vol.Abs(vol), true
+}
+
+// Gram returns the Gram matrix B·Bᵀ, the inner products of the basis
+// vectors, computed exactly (in int64 while it fits). The volumes, the
+// exact Gram-Schmidt data and fplll's Gram input (-gram) all start from it.
+func (b Basis) Gram() [][]*big.Int {
+	return intMatrixOf(b).gram().bigRows()
+}
+
+// Profile returns the basis profile, log2 ‖b*ᵢ‖ for every Gram-Schmidt
+// vector, computed with the implementation --gso selects.
+func (b Basis) Profile() Profile {
+	return computeGramSchmidtProfile(b)
+}
+
+// WriteTo writes b to w in fplll's format, so that a Basis is an
+// io.WriterTo. WriteFormat writes the other formats.
+func (b Basis) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	err := writeBasis(cw, b)
+	return cw.n, err
+}
+
+// WriteFormat writes b to w in format, one of the keys of basisWriters.
+func (b Basis) WriteFormat(w io.Writer, format string) error {
+	write := basisWriters[format]
+	if write == nil {
+		return fmt.Errorf("unknown basis format %q (want %s)", format, basisFormatNames())
+	}
+	return write(w, b)
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
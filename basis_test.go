@@ -0,0 +1,52 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+// TestStructuredVolume compares the volume peeled off triangular and q-ary
+// bases with the determinant, and checks that other bases are left to the
+// Gram determinant.
+func TestStructuredVolume(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		basis Basis
+	}{
+		{"lower", basisOf([]int64{3, 0, 0}, []int64{-7, 2, 0}, []int64{5, 11, -4})},
+		{"upper", basisOf([]int64{2, 9, -1}, []int64{0, -5, 8}, []int64{0, 0, 7})},
+		{"permuted", basisOf([]int64{0, 6, 0}, []int64{4, 1, 0}, []int64{2, 3, 5})},
+		{"q-ary", qaryBasis(8, 4, 97, 1)},
+		{"q-ary large", qaryBasis(20, 10, 1<<20+7, 2)},
+		{"zero row", basisOf([]int64{1, 0, 0}, []int64{0, 0, 0}, []int64{4, 5, 6})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			vol, ok := tc.basis.structuredVolume()
+			if !ok {
+				t.Fatal("not recognized as structured")
+			}
+			want := determinant(tc.basis)
+			if want.Abs(want).Cmp(vol) != 0 {
+				t.Errorf("volume %s, want |det| = %s", vol, want)
+			}
+			if sq := tc.basis.SquaredVolume(); sq.Cmp(determinant(tc.basis.Gram())) != 0 {
+				t.Errorf("squared volume %s, want the Gram determinant %s", sq, determinant(tc.basis.Gram()))
+			}
+		})
+	}
+	for _, tc := range []struct {
+		name  string
+		basis Basis
+	}{
+		{"dense", basisOf([]int64{2, 1}, []int64{1, 3})},
+		{"rectangular", basisOf([]int64{1, 0, 0}, []int64{0, 1, 0})},
+		{"empty", Basis{}},
+	} {
+		if vol, ok := tc.basis.structuredVolume(); ok {
+			t.Errorf("%s basis recognized as structured with volume %s", tc.name, vol)
+		}
+	}
+	if got := qaryBasis(8, 4, 97, 1).Volume(); got.Cmp(new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(97), big.NewInt(4), nil))) != 0 {
+		t.Errorf("Volume() of a q-ary basis = %s, want 97^4", got)
+	}
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// basisReaders maps the names of the supported matrix formats to their
+// parsers. The formats are those used by common lattice software:
+//
+//	fplll  [[1 2]\n[3 4]]                             fplll, latgen, fpylll
+//	ntl    [[1 2]\n[3 4]\n]                           NTL mat_ZZ
+//	magma  Matrix(IntegerRing(), 2, 2, [1, 2, 3, 4]);  Magma
+//	sage   matrix(ZZ, [[1, 2], [3, 4]])               SageMath
+//	csv    1,2\n3,4                                   spreadsheets, numpy
+//	json   [[1, 2], [3, 4]]                           scripts, Python lists
+var basisReaders = map[string]func(data []byte) (Basis, error){
+	"fplll": readBracketBasis,
+	"ntl":   readBracketBasis,
+	"magma": readMagmaBasis,
+	"sage":  readSageBasis,
+	"csv":   readCSVBasis,
+	"json":  readJSONBasis,
+}
+
+// basisWriters maps the names of the supported matrix formats to their
+// writers; see basisReaders.
+var basisWriters = map[string]func(w io.Writer, basis Basis) error{
+	"fplll": writeBasis,
+	"ntl":   writeNTLBasis,
+	"magma": writeMagmaBasis,
+	"sage":  writeSageBasis,
+	"csv":   writeCSVBasis,
+	"json":  writeJSONBasis,
+}
+
+// basisFormatNames returns the names of the supported matrix formats for
+// usage messages.
+func basisFormatNames() string {
+	names := make([]string, 0, len(basisWriters))
+	for name := range basisWriters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// detectBasisFormat guesses the format of a matrix from its content: the
+// Magma and Sage constructors, bracketed rows with commas (JSON) or without
+// (fplll, which also reads NTL), and anything else as CSV.
+func detectBasisFormat(data []byte) string {
+	text := strings.TrimSpace(string(data))
+	switch {
+	case strings.HasPrefix(text, "Matrix("):
+		return "magma"
+	case strings.HasPrefix(text, "matrix("):
+		return "sage"
+	case strings.HasPrefix(text, "["):
+		if strings.Contains(text, ",") {
+			return "json"
+		}
+		return "fplll"
+	default:
+		return "csv"
+	}
+}
+
+// readBasis parses a basis in the given format, detecting it from the
+// content if format is "" or "auto", and checks that it is a non-empty
+// matrix of integers.
+func readBasis(r io.Reader, format string) (Basis, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if format == "" || format == "auto" {
+		format = detectBasisFormat(data)
+	}
+	read := basisReaders[format]
+	if read == nil {
+		return nil, fmt.Errorf("unknown basis format %q (want %s)", format, basisFormatNames())
+	}
+	basis, err := read(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s format: %w", format, err)
+	}
+	if len(basis) == 0 {
+		return nil, fmt.Errorf("%s format: no basis vectors found", format)
+	}
+	for i, row := range basis {
+		if len(row) != len(basis[0]) {
+			return nil, fmt.Errorf("%s format: row %d has %d entries, expected %d", format, i+1, len(row), len(basis[0]))
+		}
+	}
+	return basis, nil
+}
+
+// parseIntegers parses each field as a decimal integer.
+func parseIntegers(fields []string) ([]*big.Int, error) {
+	values := make([]*big.Int, len(fields))
+	for i, field := range fields {
+		v, ok := parseEntry([]byte(field))
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", field)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// readBracketBasis reads a matrix written as bracketed rows, e.g. by fplll or
+// NTL. Rows may be spread over lines or all on one line, and entries may be
+// separated by spaces or commas.
+func readBracketBasis(data []byte) (Basis, error) {
+	return scanBracketRows(bytes.NewReader(data))
+}
+
+// scanBracketRows reads the rows of a bracketed matrix, the innermost
+// bracketed lists of entries, from r as it arrives, so that fplll's output
+// is parsed while fplll is still writing it. Empty rows and anything outside
+// brackets are skipped; a single bracketed vector yields one row.
+func scanBracketRows(r io.Reader) (Basis, error) {
+	scan, err := scanBrackets(r)
+	return scan.rows, err
+}
+
+// bracketScan is what scanBrackets found in bracketed text, for parsers that
+// check its shape.
+type bracketScan struct {
+	rows     Basis
+	depths   []int  // nesting depth of each row: 1 for a bare vector, 2 in a matrix
+	stray    string // first word found outside the rows, if any
+	balanced bool   // every bracket was closed and none was closed twice
+}
+
+// scanBrackets reads bracketed rows from r as it arrives and records the
+// shape of the text around them.
+func scanBrackets(r io.Reader) (bracketScan, error) {
+	var scan bracketScan
+	err := walkBrackets(r, &scan, func(row []*big.Int, depth int) error {
+		scan.rows = append(scan.rows, row)
+		scan.depths = append(scan.depths, depth)
+		return nil
+	})
+	return scan, err
+}
+
+// walkBrackets reads bracketed rows from r as it arrives and passes each one
+// to onRow with its nesting depth, without keeping them, so that a basis
+// can be processed row by row. It records the stray text and the balance of
+// the brackets in scan; an error of onRow stops the walk.
+func walkBrackets(r io.Reader, scan *bracketScan, onRow func(row []*big.Int, depth int) error) error {
+	br := bufio.NewReader(r)
+	var (
+		rows  int
+		row   []*big.Int
+		field []byte
+		depth int
+		inRow bool
+		// inStray is set while the first stray word is read.
+		inStray bool
+	)
+	flush := func() error {
+		if len(field) == 0 {
+			return nil
+		}
+		v, ok := parseEntry(field)
+		if !ok {
+			return fmt.Errorf("row %d: invalid integer %q", rows+1, field)
+		}
+		row = append(row, v)
+		field = field[:0]
+		return nil
+	}
+	scan.balanced = true
+	for {
+		c, err := br.ReadByte()
+		if inStray && (err != nil || c == '[' || c == ']' || isEntrySeparator(c)) {
+			inStray = false
+		}
+		if err == io.EOF {
+			scan.balanced = scan.balanced && depth == 0
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch {
+		case c == '[':
+			depth++
+			inRow, row, field = true, nil, field[:0]
+		case c == ']':
+			if inRow {
+				if err := flush(); err != nil {
+					return err
+				}
+				if len(row) > 0 {
+					rows++
+					if err := onRow(row, depth); err != nil {
+						return err
+					}
+				}
+				inRow = false
+			}
+			if depth--; depth < 0 {
+				scan.balanced, depth = false, 0
+			}
+		case isEntrySeparator(c):
+			if err := flush(); err != nil {
+				return err
+			}
+		case !inRow:
+			if scan.stray == "" {
+				inStray = true
+			}
+			if inStray {
+				scan.stray += string(c)
+			}
+		default:
+			field = append(field, c)
+		}
+	}
+}
+
+// isEntrySeparator reports whether c separates the entries of a bracketed
+// row.
+func isEntrySeparator(c byte) bool {
+	return c == ',' || c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// sageMatrix matches a Sage integer matrix constructor.
+var sageMatrix = regexp.MustCompile(`(?s)^matrix\(\s*(?:ZZ|Integers\(\))\s*,\s*(.*)\)$`)
+
+// readSageBasis reads matrix(ZZ, [[...], ...]) as printed by Sage's
+// sage_input or written by hand.
+func readSageBasis(data []byte) (Basis, error) {
+	m := sageMatrix.FindStringSubmatch(strings.TrimSpace(string(data)))
+	if m == nil {
+		return nil, fmt.Errorf("expected matrix(ZZ, [[...], ...])")
+	}
+	return readBracketBasis([]byte(m[1]))
+}
+
+// magmaMatrix matches a Magma integer matrix constructor, with the
+// dimensions and a flat list of entries or with a list of rows.
+var magmaMatrix = regexp.MustCompile(`(?s)^Matrix\(\s*(?:IntegerRing\(\)|Integers\(\))\s*,\s*(?:(\d+)\s*,\s*(\d+)\s*,\s*)?(.*)\)\s*;?$`)
+
+// readMagmaBasis reads Matrix(IntegerRing(), r, c, [...]) or
+// Matrix(IntegerRing(), [[...], ...]).
+func readMagmaBasis(data []byte) (Basis, error) {
+	m := magmaMatrix.FindStringSubmatch(strings.TrimSpace(string(data)))
+	if m == nil {
+		return nil, fmt.Errorf("expected Matrix(IntegerRing(), r, c, [...])")
+	}
+	if m[1] == "" {
+		return readBracketBasis([]byte(m[3]))
+	}
+
+	rows, _ := strconv.Atoi(m[1])
+	cols, _ := strconv.Atoi(m[2])
+	list := strings.TrimSpace(m[3])
+	if !strings.HasPrefix(list, "[") || !strings.HasSuffix(list, "]") {
+		return nil, fmt.Errorf("expected a list of entries")
+	}
+	entries, err := parseIntegers(strings.Fields(strings.ReplaceAll(list[1:len(list)-1], ",", " ")))
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != rows*cols {
+		return nil, fmt.Errorf("%d entries for a %dx%d matrix", len(entries), rows, cols)
+	}
+	basis := make(Basis, rows)
+	for i := range basis {
+		basis[i] = entries[i*cols : (i+1)*cols]
+	}
+	return basis, nil
+}
+
+// readCSVBasis reads one row per line with comma-separated entries.
+func readCSVBasis(data []byte) (Basis, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var basis Basis
+	for _, record := range records {
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		row, err := parseIntegers(record)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", len(basis)+1, err)
+		}
+		basis = append(basis, row)
+	}
+	return basis, nil
+}
+
+// readJSONBasis reads an array of rows of integers. Entries may also be
+// strings, for tools that cannot write integers beyond float64 precision.
+func readJSONBasis(data []byte) (Basis, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var rows [][]any
+	if err := dec.Decode(&rows); err != nil {
+		return nil, err
+	}
+	basis := make(Basis, len(rows))
+	for i, row := range rows {
+		fields := make([]string, len(row))
+		for j, v := range row {
+			switch v := v.(type) {
+			case json.Number:
+				fields[j] = v.String()
+			case string:
+				fields[j] = v
+			default:
+				return nil, fmt.Errorf("row %d: invalid entry %v", i+1, v)
+			}
+		}
+		var err error
+		if basis[i], err = parseIntegers(fields); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+	}
+	return basis, nil
+}
+
+// joinRow formats the entries of a row separated by sep.
+func joinRow(row []*big.Int, sep string) string {
+	parts := make([]string, len(row))
+	for i, v := range row {
+		parts[i] = v.String()
+	}
+	return strings.Join(parts, sep)
+}
+
+// writeNTLBasis writes a basis as NTL prints a mat_ZZ.
+func writeNTLBasis(w io.Writer, basis Basis) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, "[")
+	for _, row := range basis {
+		fmt.Fprintf(bw, "[%s]\n", joinRow(row, " "))
+	}
+	fmt.Fprintln(bw, "]")
+	return bw.Flush()
+}
+
+// writeMagmaBasis writes a basis as a Magma Matrix constructor, one row of
+// the flat entry list per line.
+func writeMagmaBasis(w io.Writer, basis Basis) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Matrix(IntegerRing(), %d, %d, [\n", len(basis), len(basis[0]))
+	for i, row := range basis {
+		sep := ","
+		if i == len(basis)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(bw, "%s%s\n", joinRow(row, ", "), sep)
+	}
+	fmt.Fprintln(bw, "]);")
+	return bw.Flush()
+}
+
+// writeSageBasis writes a basis as a Sage matrix(ZZ, ...) expression.
+func writeSageBasis(w io.Writer, basis Basis) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "matrix(ZZ, [")
+	for i, row := range basis {
+		sep := ","
+		if i == len(basis)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(bw, "[%s]%s\n", joinRow(row, ", "), sep)
+	}
+	fmt.Fprintln(bw, "])")
+	return bw.Flush()
+}
+
+// writeCSVBasis writes one row per line with comma-separated entries.
+func writeCSVBasis(w io.Writer, basis Basis) error {
+	bw := bufio.NewWriter(w)
+	for _, row := range basis {
+		fmt.Fprintln(bw, joinRow(row, ","))
+	}
+	return bw.Flush()
+}
+
+// writeJSONBasis writes an array of rows, one row per line. Entries are
+// JSON numbers of arbitrary size.
+func writeJSONBasis(w io.Writer, basis Basis) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "[")
+	for i, row := range basis {
+		sep := ","
+		if i == len(basis)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(bw, "  [%s]%s\n", joinRow(row, ", "), sep)
+	}
+	fmt.Fprintln(bw, "]")
+	return bw.Flush()
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// benchConfig is the configuration of the bench command.
+type benchConfig struct {
+	Dims    []int
+	Workers []int
+	Q       int64
+	Repeat  int
+	// Exact compares every profile with the exact rational one.
+	Exact bool
+	// Backends, if set, makes the command compare these backends on BKZ
+	// with block size Beta instead of timing the profiles.
+	Backends []string
+	Beta     int
+}
+
+// parseBenchFlags builds a benchConfig from the bench command line.
+func parseBenchFlags(args []string) (benchConfig, error) {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	dims := fs.String("n", "200,400,800", "comma-separated ranks of the random bases")
+	workers := fs.String("workers", defaultBenchWorkers(), "comma-separated goroutine counts of the block-parallel Gram-Schmidt")
+	q := fs.Int64("q", 100003, "coefficient bound of the random bases")
+	repeat := fs.Int("repeat", 3, "timings per measurement; the fastest is reported")
+	exact := fs.Bool("exact", false, "report the largest deviation of every profile from the exact rational one")
+	backends := fs.String("backends", "", "compare these backends on BKZ instead: all or a comma-separated list of "+strings.Join(benchBackendNames(), ", "))
+	beta := fs.Int("beta", 20, "BKZ block size of the backend comparison")
+	if err := fs.Parse(args); err != nil {
+		return benchConfig{}, err
+	}
+	if *backends != "" && !flagSet(fs, "n") {
+		// The profile ranks would take BKZ far too long.
+		*dims = "40,60,80"
+	}
+
+	cfg := benchConfig{Q: *q, Repeat: *repeat, Exact: *exact, Beta: *beta}
+	if *backends != "" {
+		var err error
+		if cfg.Backends, err = parseBackendList(*backends); err != nil {
+			return cfg, fmt.Errorf("-backends: %w", err)
+		}
+		if err := bkzStep(cfg.Beta).validate(); err != nil {
+			return cfg, fmt.Errorf("-beta: %w", err)
+		}
+	}
+	if cfg.Q < 2 {
+		return cfg, fmt.Errorf("-q must be at least 2")
+	}
+	if cfg.Repeat < 1 {
+		return cfg, fmt.Errorf("-repeat must be at least 1")
+	}
+	dimList, err := parseIntList(*dims)
+	if err != nil {
+		return cfg, fmt.Errorf("-n: %w", err)
+	}
+	workerList, err := parseIntList(*workers)
+	if err != nil {
+		return cfg, fmt.Errorf("-workers: %w", err)
+	}
+	for _, n := range dimList {
+		if n < 1 {
+			return cfg, fmt.Errorf("-n: rank must be positive, got %d", n)
+		}
+		cfg.Dims = append(cfg.Dims, int(n))
+	}
+	for _, w := range workerList {
+		if w < 1 {
+			return cfg, fmt.Errorf("-workers: count must be positive, got %d", w)
+		}
+		cfg.Workers = append(cfg.Workers, int(w))
+	}
+	return cfg, nil
+}
+
+// flagSet reports whether the flag name was given on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
+}
+
+// defaultBenchWorkers returns the powers of two below GOMAXPROCS followed
+// by GOMAXPROCS itself, e.g. "1,2,4,6".
+func defaultBenchWorkers() string {
+	procs := runtime.GOMAXPROCS(0)
+	list := ""
+	for w := 1; w < procs; w *= 2 {
+		list += strconv.Itoa(w) + ","
+	}
+	return list + strconv.Itoa(procs)
+}
+
+// runBench times the Gram-Schmidt profile implementations on random bases of
+// every rank in cfg.Dims: the classical loop, the Cholesky factorization,
+// Householder QR, the streaming Gram-Schmidt and the block-parallel
+// Gram-Schmidt with every worker count, the latter with its speedup over the
+// first worker count. With cfg.Exact every profile is also compared with the
+// exact rational one. The bases depend on the seed only. With cfg.Backends
+// it compares the backends instead; see runBackendBench.
+func runBench(ctx context.Context, w io.Writer, cfg benchConfig) error {
+	if len(cfg.Backends) > 0 {
+		return runBackendBench(ctx, w, cfg)
+	}
+	fmt.Fprintf(w, "Gram-Schmidt profile timings (GOMAXPROCS %d, q %d, best of %d)\n\n", runtime.GOMAXPROCS(0), cfg.Q, cfg.Repeat)
+	fmt.Fprintf(w, "%-6s | %-11s | %-7s | %-12s | %-7s | %s\n", "n", "Method", "Workers", "Time", "Speedup", "Max error")
+	fmt.Fprintln(w, "-------------------------------------------------------------------")
+	q := big.NewInt(cfg.Q)
+	for _, n := range cfg.Dims {
+		m := intMatrixOf(genRandomBasisFrom(trialSource("bench", int64(n), cfg.Q), n, q))
+		var exact []float64
+		if cfg.Exact {
+			exact = rationalProfile(m)
+		}
+		row := func(method string, workers int, d time.Duration, profile []float64, speedup string) {
+			maxErr := ""
+			if exact != nil {
+				worst, _ := profileDeviation(profile, exact)
+				maxErr = fmt.Sprintf("%.1e", worst)
+			}
+			fmt.Fprintf(w, "%-6d | %-11s | %-7d | %-12s | %-7s | %s\n", n, method, workers, d.Round(time.Microsecond), speedup, maxErr)
+		}
+
+		d, profile := bestTime(cfg.Repeat, func() []float64 { p, _ := classicalProfile(m); return p })
+		row("classical", 1, d, profile, "")
+		d, profile = bestTime(cfg.Repeat, func() []float64 { p, _ := choleskyProfile(m); return p })
+		row("cholesky", 1, d, profile, "")
+		d, profile = bestTime(cfg.Repeat, func() []float64 { p, _ := householderProfile(m); return p })
+		row("householder", 1, d, profile, "")
+		d, profile = bestTime(cfg.Repeat, func() []float64 { p, _ := streamProfile(m); return p })
+		row("stream", runtime.GOMAXPROCS(0), d, profile, "")
+		var single time.Duration
+		for _, workers := range cfg.Workers {
+			if ctx.Err() != nil {
+				return nil
+			}
+			d, profile := bestTime(cfg.Repeat, func() []float64 { p, _ := blockProfile(m, workers); return p })
+			if single == 0 {
+				single = d
+			}
+			row("block", workers, d, profile, fmt.Sprintf("%.2fx", single.Seconds()/d.Seconds()))
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+	return nil
+}
+
+// bestTime returns the shortest of repeat timings of f and the profile it
+// computed.
+func bestTime(repeat int, f func() []float64) (time.Duration, []float64) {
+	var best time.Duration
+	var profile []float64
+	for i := 0; i < repeat; i++ {
+		start := time.Now()
+		profile = f()
+		if d := time.Since(start); i == 0 || d < best {
+			best = d
+		}
+	}
+	return best, profile
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// benchBackend is a solver the backend benchmark runs instances through.
+type benchBackend struct {
+	// Unavailable says why the backend can't run in this build or on this
+	// machine, or returns "".
+	Unavailable func() string
+	// Reduce BKZ-reduces basis with block size beta.
+	Reduce func(ctx context.Context, basis Basis, beta int) (Basis, error)
+}
+
+// benchBackends maps the names of the backends the benchmark knows to them.
+// The fplll executable and SageMath, whose BKZ uses fplll's library, are
+// run as processes, dlopen calls libfplll in process through its C
+// interface loaded at run time (see useLibfplll), and native runs the BKZ
+// tours in Go with fplll as the SVP oracle (parallelBKZ); the cgo bindings
+// to libfplll are listed so that their row shows why they are missing.
+var benchBackends = map[string]benchBackend{
+	"fplll": {
+		Unavailable: func() string {
+			if !BackendAvailable() {
+				return fmt.Sprintf("%s not found or not answering --version", backend.Binary)
+			}
+			return ""
+		},
+		Reduce: func(ctx context.Context, basis Basis, beta int) (Basis, error) {
+			defer func(l *fplllLibrary) { libfplll = l }(libfplll)
+			libfplll = nil
+			return bkzReduce(ctx, basis, beta, bkzOptions{})
+		},
+	},
+	"sage": {
+		Unavailable: sageAvailable,
+		Reduce: func(ctx context.Context, basis Basis, beta int) (Basis, error) {
+			return sageReduce(ctx, basis, bkzStep(beta))
+		},
+	},
+	"cgo": {
+		Unavailable: func() string { return "not built: this binary has no cgo bindings to libfplll" },
+	},
+	"dlopen": {
+		Unavailable: func() string {
+			switch {
+			case libfplll != nil:
+				return ""
+			case findSharedLibrary("fplll") != "":
+				return fmt.Sprintf("found %s, but not its C interface lib%s (see libfplll/)", findSharedLibrary("fplll"), libfplllInterface)
+			}
+			return "no libfplll shared library found"
+		},
+		Reduce: func(ctx context.Context, basis Basis, beta int) (Basis, error) {
+			return bkzReduce(ctx, basis, beta, bkzOptions{})
+		},
+	},
+	"native": {
+		Unavailable: func() string {
+			if !BackendAvailable() {
+				return fmt.Sprintf("its SVP oracle %s not found or not answering --version", backend.Binary)
+			}
+			return ""
+		},
+		Reduce: func(ctx context.Context, basis Basis, beta int) (Basis, error) {
+			return parallelBKZ(ctx, basis, beta, bkzOptions{})
+		},
+	},
+}
+
+// benchBackendNames returns the names of benchBackends, fplll first, for
+// usage messages and -backends all.
+func benchBackendNames() []string {
+	names := make([]string, 0, len(benchBackends))
+	for name := range benchBackends {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] == "fplll" || names[j] != "fplll" && names[i] < names[j] })
+	return names
+}
+
+// parseBackendList parses the -backends flag: "all" or a comma-separated
+// list of benchBackends names.
+func parseBackendList(s string) ([]string, error) {
+	if s == "all" {
+		return benchBackendNames(), nil
+	}
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := benchBackends[name]; !ok {
+			return nil, fmt.Errorf("unknown backend %q (want all or %s)", name, strings.Join(benchBackendNames(), ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// backendUsage is what a benchmarked reduction cost: the peak resident
+// memory of the solver processes, if known, and the bytes the Go side
+// allocated.
+type backendUsage struct {
+	SolverRSS uint64
+	GoAlloc   uint64
+}
+
+// measureBackend runs reduce once and returns its reduced basis and usage.
+// The peak memory of fplll is taken from the process state of every call.
+func measureBackend(reduce func() (Basis, error)) (Basis, backendUsage, error) {
+	var usage backendUsage
+	fplllExited = func(state *os.ProcessState) {
+		if rss, ok := peakRSS(state); ok {
+			usage.SolverRSS = max(usage.SolverRSS, rss)
+		}
+	}
+	defer func() { fplllExited = nil }()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	reduced, err := reduce()
+	runtime.ReadMemStats(&after)
+	usage.GoAlloc = after.TotalAlloc - before.TotalAlloc
+	return reduced, usage, err
+}
+
+// runBackendBench runs BKZ-cfg.Beta on the same random bases of every rank
+// in cfg.Dims through every backend in cfg.Backends and reports side by
+// side the time, the memory, and the quality of the reduced bases: log2 of
+// the first vector, the root Hermite factor it reaches, the slope of the
+// profile and the largest profile difference from the first backend.
+// Backends that can't run are listed with the reason.
+func runBackendBench(ctx context.Context, w io.Writer, cfg benchConfig) error {
+	fmt.Fprintf(w, "Backend timings (BKZ-%d, q %d, best of %d)\n\n", cfg.Beta, cfg.Q, cfg.Repeat)
+	fmt.Fprintf(w, "%-6s | %-7s | %-12s | %-10s | %-10s | %-9s | %-6s | %-7s | %s\n",
+		"n", "Backend", "Time", "Solver RSS", "Go alloc", "log2 ‖b1‖", "δ", "Slope", "Max Δ profile")
+	fmt.Fprintln(w, "------------------------------------------------------------------------------------------------------")
+	// Timings of cached results would be meaningless.
+	defer func(c *fplllCache) { resultCache = c }(resultCache)
+	resultCache = nil
+	unavailable := map[string]string{}
+	for _, name := range cfg.Backends {
+		unavailable[name] = benchBackends[name].Unavailable()
+	}
+	q := big.NewInt(cfg.Q)
+	for _, n := range cfg.Dims {
+		basis := genRandomBasisFrom(trialSource("bench", int64(n), cfg.Q), n, q)
+		var first []float64
+		for _, name := range cfg.Backends {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if reason := unavailable[name]; reason != "" {
+				fmt.Fprintf(w, "%-6d | %-7s | unavailable: %s\n", n, name, reason)
+				continue
+			}
+			var best time.Duration
+			var reduced Basis
+			var usage backendUsage
+			for i := 0; i < cfg.Repeat; i++ {
+				start := time.Now()
+				r, u, err := measureBackend(func() (Basis, error) {
+					return benchBackends[name].Reduce(ctx, basis, cfg.Beta)
+				})
+				d := time.Since(start)
+				if ctx.Err() != nil {
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("%s backend, n %d: %w", name, n, err)
+				}
+				if i == 0 || d < best {
+					best, reduced, usage = d, r, u
+				}
+			}
+
+			profile := reduced.Profile()
+			diff := ""
+			if first == nil {
+				first = profile
+			} else {
+				worst, _ := profileDeviation(profile, first)
+				diff = fmt.Sprintf("%.1e", worst)
+			}
+			rss := "n/a"
+			if usage.SolverRSS > 0 {
+				rss = formatBytes(usage.SolverRSS)
+			}
+			fmt.Fprintf(w, "%-6d | %-7s | %-12s | %-10s | %-10s | %-9.3f | %.4f | %-7.4f | %s\n",
+				n, name, best.Round(time.Microsecond), rss, formatBytes(usage.GoAlloc),
+				profile[0], profile.RHF(), profile.Slope(), diff)
+		}
+	}
+	return nil
+}
+
+// formatBytes formats a byte count with a binary unit, e.g. "12.3 MiB".
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+)
+
+// BenchmarkBackends runs BKZ-20 on the same random basis of rank 60, the
+// middle instance of bench -backends, through every backend of
+// benchBackends, skipping those that can't run here with the reason:
+//
+//	go test -run '^$' -bench Backends
+func BenchmarkBackends(b *testing.B) {
+	useLibfplll()
+	const n, beta, q = 60, 20, 100003
+	basis := genRandomBasisFrom(trialSource("bench", n, q), n, big.NewInt(q))
+	ctx := context.Background()
+	for _, name := range benchBackendNames() {
+		b.Run(name, func(b *testing.B) {
+			solver := benchBackends[name]
+			if reason := solver.Unavailable(); reason != "" {
+				b.Skip(reason)
+			}
+			for range b.N {
+				if _, err := solver.Reduce(ctx, basis, beta); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math/big"
+	"sync"
+)
+
+// bestOfReduce runs the reduction step on step.BestOf copies of basis, the
+// basis itself and rerandomizations of it (see rerandomizeBasis), and
+// returns the reduced basis with the shortest first vector. Reductions of
+// the same lattice that end in different local optima differ mostly in
+// ‖b_1‖; since the volume is the same for all copies, the root Hermite
+// factor δ0 ranks them the same way. Up to backend.Jobs copies are reduced
+// at once. The copies are seeded by the basis, so a seeded run picks the
+// same ones every time. If every copy fails, the error of the first is
+// returned; failures of the others are only logged.
+func bestOfReduce(ctx context.Context, basis Basis, step reductionStep) (Basis, error) {
+	single := step
+	single.BestOf = 0
+	fingerprint := basisFingerprint(basis)
+	reduced := make([]Basis, step.BestOf)
+	errs := make([]error, step.BestOf)
+	slots := make(chan struct{}, max(backend.Jobs, 1))
+	var wg sync.WaitGroup
+	for i := range step.BestOf {
+		input := basis
+		if i > 0 {
+			input = rerandomizeBasis(basis, trialRand("best-of", fingerprint, int64(i)))
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			reduced[i], _, errs[i] = runStep(ctx, input, single)
+		}()
+	}
+	wg.Wait()
+
+	best := -1
+	var bestNorm *big.Int
+	for i, b := range reduced {
+		if errs[i] != nil {
+			if ctx.Err() == nil {
+				slog.Warn("reduction of a rerandomized copy failed", "step", single.String(), "copy", i+1, "copies", step.BestOf, "err", errs[i])
+			}
+			continue
+		}
+		if norm := normSquared(b[0]); best < 0 || norm.Cmp(bestNorm) < 0 {
+			best, bestNorm = i, norm
+		}
+	}
+	if best < 0 {
+		return nil, errs[0]
+	}
+	slog.Debug("best of rerandomized reductions", "step", single.String(), "copies", step.BestOf, "best", best+1)
+	return reduced[best], nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// BetaForRHF returns the least block size β in [2, maxBeta] with which BKZ
+// is expected to reach the root Hermite factor delta, deltas.At(β) ≤ delta,
+// found by bisection since the δ0 table doesn't grow with β. It reports
+// false if even maxBeta doesn't get there.
+func BetaForRHF(delta float64, maxBeta int) (int, bool) {
+	if maxBeta < 2 || deltas.At(maxBeta) > delta {
+		return 0, false
+	}
+	return 2 + sort.Search(maxBeta-1, func(i int) bool { return deltas.At(2+i) <= delta }), true
+}
+
+// BetaForLength returns the least block size β in [2, maxBeta] with which
+// BKZ is expected to make the first vector of a basis of rank n and log2
+// volume logVol at most length long: ‖b1‖ = δ0ⁿ·vol^(1/n), the root Hermite
+// factor Profile.RHF measures, so the δ0 required is
+// (length/vol^(1/n))^(1/n). It reports false if even maxBeta doesn't get
+// there.
+func BetaForLength(n int, logVol, length float64, maxBeta int) (int, bool) {
+	if n < 1 || !(length > 0) {
+		return 0, false
+	}
+	return BetaForRHF(math.Exp2((math.Log2(length)-logVol/float64(n))/float64(n)), maxBeta)
+}
+
+// BetaForUniqueSVP returns the least block size β in [2, min(d, maxBeta)]
+// with which uniqueSVPSucceeds for a vector of norm length in a lattice of
+// rank d and log2 volume logVol, the block size an attack that looks for an
+// unusually short vector, as the key of NTRU, needs. It reports false if
+// none does.
+func BetaForUniqueSVP(d int, logVol, length float64, maxBeta int) (int, bool) {
+	if !(length > 0) {
+		return 0, false
+	}
+	for beta := 2; beta <= min(d, maxBeta); beta++ {
+		if uniqueSVPSucceeds(d, logVol, math.Log2(length), beta) {
+			return beta, true
+		}
+	}
+	return 0, false
+}
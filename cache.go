@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+)
+
+// resultCache, if set, is the on-disk cache of fplll results of the run
+// (--cache).
+var resultCache *fplllCache
+
+// fplllCache keeps the output of successful fplll calls on disk, keyed by a
+// hash of everything the output depends on: the fplll version, the
+// command-line arguments (algorithm, block size, precision), the content of
+// the strategy file and the exact input basis. Rerunning an experiment whose
+// instances overlap with an earlier run, e.g. after changing only the
+// analysis, replays the stored outputs instead of calling fplll again.
+// Entries are written atomically, so concurrent calls and runs can share a
+// cache directory.
+type fplllCache struct {
+	dir          string
+	hits, misses atomic.Int64
+}
+
+// openFplllCache opens the cache in dir, creating the directory if needed.
+func openFplllCache(dir string) (*fplllCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &fplllCache{dir: dir}, nil
+}
+
+// key returns the cache key of an fplll call: the hex SHA-256 of the
+// version line, the arguments, the digest of the strategy file if the call
+// names one, and the basis as written to fplll.
+func (c *fplllCache) key(b backendConfig, args []string, basis Basis) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%q\n", b.Version.Line, args)
+	if b.StrategyDigest != "" && slices.Contains(args, "-s") {
+		fmt.Fprintf(h, "strategy %s\n", b.StrategyDigest)
+	}
+	writeBasis(h, basis)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path returns the file of the entry with the given key, in a subdirectory
+// named by its first two digits so that no directory grows too large.
+func (c *fplllCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".txt")
+}
+
+// get returns the stored output for key, if there is one.
+func (c *fplllCache) get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("reading result cache", "err", err)
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+// put stores the output for key.
+func (c *fplllCache) put(key string, data []byte) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic writes data to path through a temporary file in the same
+// directory that is then renamed, so that readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// runFplllCached runs fplll like runFplllWithRetries, but answers the call
+// from resultCache if it holds the output for the same version, arguments
+// and basis, and stores the output of a successful call there. A stored
+// output that parse rejects is ignored and fplll runs after all.
+func (b backendConfig) runFplllCached(ctx context.Context, basis Basis, args []string, parse func(io.Reader) error) error {
+	c := resultCache
+	if c == nil {
+		return b.runFplllWithRetries(ctx, basis, args, parse)
+	}
+	key := c.key(b, args, basis)
+	if data, ok := c.get(key); ok {
+		err := parse(bytes.NewReader(data))
+		if err == nil {
+			c.hits.Add(1)
+			slog.Debug("fplll result from cache", "args", args, "rank", len(basis), "key", key[:12])
+			return nil
+		}
+		slog.Warn("ignoring unreadable result cache entry", "path", c.path(key), "err", err)
+	}
+	c.misses.Add(1)
+	var out bytes.Buffer
+	err := b.runFplllWithRetries(ctx, basis, args, func(r io.Reader) error {
+		out.Reset() // a retry starts over
+		return parse(io.TeeReader(r, &out))
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.put(key, out.Bytes()); err != nil {
+		slog.Warn("writing result cache", "err", err)
+	}
+	return nil
+}
+
+// logStats logs how many fplll calls the cache answered.
+func (c *fplllCache) logStats() {
+	slog.Info("result cache", "dir", c.dir, "hits", c.hits.Load(), "misses", c.misses.Load())
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// checkpointRecord is one line of a sweep checkpoint file: a completed row
+// together with the number of trials it averages, so that a resumed sweep
+// with a different trial count recomputes the combination.
+type checkpointRecord struct {
+	sweepRow
+	Trials int `json:"trials"`
+}
+
+// sweepKey identifies one combination of a sweep.
+type sweepKey struct {
+	N, Beta, Trials int
+	Q               int64
+}
+
+// sweepCheckpoint appends every completed combination of a sweep to a JSON
+// Lines file as soon as it is done, so that an interrupted sweep can skip
+// the finished combinations when it is resumed.
+type sweepCheckpoint struct {
+	f    *os.File
+	enc  *json.Encoder
+	done map[sweepKey]sweepRow
+}
+
+// openCheckpoint opens the checkpoint file at path. With resume the rows
+// already in the file are loaded and new rows are appended; otherwise the
+// file is started afresh. A missing file is not an error when resuming.
+func openCheckpoint(path string, resume bool) (*sweepCheckpoint, error) {
+	cp := &sweepCheckpoint{done: make(map[sweepKey]sweepRow)}
+	if !resume {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		cp.f, cp.enc = f, json.NewEncoder(f)
+		return cp, nil
+	}
+
+	valid, err := cp.load(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	// Drop a partial last line so that new rows start on a line of their own.
+	if err := f.Truncate(valid); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	cp.f, cp.enc = f, json.NewEncoder(f)
+	return cp, nil
+}
+
+// load reads the completed rows of an existing checkpoint file and returns
+// the length of its complete lines. A truncated last line, left by a crash
+// in the middle of a write, is ignored.
+func (cp *sweepCheckpoint) load(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	valid := bytes.LastIndexByte(data, '\n') + 1
+	for i, line := range bytes.Split(data[:valid], []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var rec checkpointRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return 0, fmt.Errorf("%s:%d: %w", path, i+1, err)
+		}
+		cp.done[sweepKey{N: rec.N, Beta: rec.Beta, Trials: rec.Trials, Q: rec.Q}] = rec.sweepRow
+	}
+	return int64(valid), nil
+}
+
+// lookup returns the checkpointed row of a combination, if any.
+func (cp *sweepCheckpoint) lookup(n, beta int, q int64, trials int) (sweepRow, bool) {
+	row, ok := cp.done[sweepKey{N: n, Beta: beta, Trials: trials, Q: q}]
+	return row, ok
+}
+
+// record appends a completed row to the checkpoint file.
+func (cp *sweepCheckpoint) record(row sweepRow, trials int) error {
+	return cp.enc.Encode(checkpointRecord{sweepRow: row, Trials: trials})
+}
+
+// Close closes the checkpoint file.
+func (cp *sweepCheckpoint) Close() error {
+	return cp.f.Close()
+}
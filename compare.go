@@ -0,0 +1,306 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// compareConfig holds the arguments of the compare command.
+type compareConfig struct {
+	Base, New string  // JSON result files, or run ids when DB is set
+	DB        string  // SQLite database written by --db
+	Alpha     float64 // significance level of the regression test
+}
+
+// parseCompareFlags builds a compareConfig from the compare command line.
+func parseCompareFlags(args []string) (compareConfig, error) {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	db := fs.String("db", "", "read the result sets from this SQLite database; BASE and NEW are run ids")
+	alpha := fs.Float64("alpha", 0.05, "significance level below which a change is flagged")
+	if err := fs.Parse(args); err != nil {
+		return compareConfig{}, err
+	}
+	if fs.NArg() != 2 {
+		return compareConfig{}, fmt.Errorf("usage: lattice-labs compare [-db results.db] [-alpha 0.05] BASE NEW")
+	}
+	if *alpha <= 0 || *alpha >= 1 {
+		return compareConfig{}, fmt.Errorf("-alpha must be between 0 and 1")
+	}
+	return compareConfig{Base: fs.Arg(0), New: fs.Arg(1), DB: *db, Alpha: *alpha}, nil
+}
+
+// loadResultsJSON reads a result set written with --output json. Files with
+// several documents, e.g. from an experiment suite, are merged.
+func loadResultsJSON(path string) (*runResults, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	merged := &runResults{}
+	dec := json.NewDecoder(f)
+	for {
+		var res runResults
+		if err := dec.Decode(&res); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if merged.Metadata == nil {
+			merged.Metadata = res.Metadata
+		}
+		merged.Lab1 = append(merged.Lab1, res.Lab1...)
+		merged.Lab2 = append(merged.Lab2, res.Lab2...)
+		merged.Sweep = append(merged.Sweep, res.Sweep...)
+	}
+	return merged, nil
+}
+
+// loadResultsSQLite reads the result set of run id from a database written
+// with --db.
+func loadResultsSQLite(db *sql.DB, id string) (*runResults, error) {
+	runID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid run id %q", id)
+	}
+
+	meta := &runMetadata{}
+	var seed, started, finished string
+	err = db.QueryRow(`SELECT seed, fplll_version, go_version, hostname, started, finished, wall_seconds FROM runs WHERE id = ?`, runID).
+		Scan(&seed, &meta.FplllVersion, &meta.GoVersion, &meta.Hostname, &started, &finished, &meta.WallSeconds)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no run with id %d", runID)
+	} else if err != nil {
+		return nil, err
+	}
+	meta.Seed, _ = strconv.ParseUint(seed, 10, 64)
+	meta.Started, _ = time.Parse(time.RFC3339Nano, started)
+	meta.Finished, _ = time.Parse(time.RFC3339Nano, finished)
+	res := &runResults{Metadata: meta}
+
+	// Lab 1 rows, regrouped into one lab1Result per lab run.
+	rows, err := db.Query(`SELECT lab_run, q, n, trial, volume, gh, lambda1, relative_error_percent, oracle_seconds
+		FROM lab1 WHERE run_id = ? ORDER BY lab_run, rowid`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var labRun int
+		var q string
+		var row lab1Row
+		var volume, gh, lambda1, relErr sql.NullFloat64
+		if err := rows.Scan(&labRun, &q, &row.N, &row.Trial, &volume, &gh, &lambda1, &relErr, &row.OracleSeconds); err != nil {
+			return nil, err
+		}
+		row.Volume, row.GH = jsonFloat(nullToNaN(volume)), jsonFloat(nullToNaN(gh))
+		row.Lambda1, row.RelativeError = jsonFloat(nullToNaN(lambda1)), jsonFloat(nullToNaN(relErr))
+		for len(res.Lab1) <= labRun {
+			res.Lab1 = append(res.Lab1, lab1Result{Q: q})
+		}
+		res.Lab1[labRun].Rows = append(res.Lab1[labRun].Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	labs, err := db.Query(`SELECT rank, q, reduction, profile, reduction_seconds, seconds FROM lab2 WHERE run_id = ? ORDER BY lab_run`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer labs.Close()
+	for labs.Next() {
+		var lab lab2Result
+		var reduction string
+		var profile []byte
+		if err := labs.Scan(&lab.Rank, &lab.Q, &reduction, &profile, &lab.ReductionSeconds, &lab.Seconds); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(reduction), &lab.Reduction); err != nil {
+			return nil, err
+		}
+		lab.Profile = profileFromBlob(profile)
+		res.Lab2 = append(res.Lab2, lab)
+	}
+	if err := labs.Err(); err != nil {
+		return nil, err
+	}
+
+	sweep, err := db.Query(`SELECT n, beta, q, gh, b1, delta0, slope, seconds FROM sweep WHERE run_id = ? ORDER BY rowid`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer sweep.Close()
+	for sweep.Next() {
+		var row sweepRow
+		var gh, b1, delta, slope sql.NullFloat64
+		if err := sweep.Scan(&row.N, &row.Beta, &row.Q, &gh, &b1, &delta, &slope, &row.Seconds); err != nil {
+			return nil, err
+		}
+		row.GH, row.B1, row.Delta, row.Slope = nullToNaN(gh), nullToNaN(b1), nullToNaN(delta), nullToNaN(slope)
+		res.Sweep = append(res.Sweep, row)
+	}
+	return res, sweep.Err()
+}
+
+// nullToNaN maps a NULL stored by sqlFloat back to NaN.
+func nullToNaN(v sql.NullFloat64) float64 {
+	if !v.Valid {
+		return math.NaN()
+	}
+	return v.Float64
+}
+
+// profileFromBlob decodes a profile stored by profileBlob.
+func profileFromBlob(b []byte) []float64 {
+	profile := make([]float64, len(b)/8)
+	for i := range profile {
+		profile[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[8*i:]))
+	}
+	return profile
+}
+
+// resultMetrics extracts the compared samples from a result set, one slice
+// per metric in the order of compareMetricNames.
+func resultMetrics(res *runResults) [][]float64 {
+	samples := make([][]float64, len(compareMetricNames))
+	add := func(i int, v float64) {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			samples[i] = append(samples[i], v)
+		}
+	}
+	for _, lab := range res.Lab1 {
+		for _, row := range lab.Rows {
+			add(0, float64(row.RelativeError))
+			add(4, row.OracleSeconds)
+		}
+	}
+	for _, lab := range res.Lab2 {
+		if len(lab.Profile) < 2 {
+			continue
+		}
+		add(1, lab.Profile.RHF())
+		add(2, lab.Profile.Slope())
+		add(3, lab.ReductionSeconds)
+	}
+	for _, row := range res.Sweep {
+		add(1, row.Delta)
+		add(2, row.Slope)
+		add(3, row.Seconds)
+	}
+	return samples
+}
+
+// compareMetricNames are the metrics compared, in the order produced by
+// resultMetrics; a steeper (more negative) slope means a weaker reduction.
+var compareMetricNames = []struct {
+	name           string
+	higherIsBetter bool
+}{
+	{"GH relative error (%)", false},
+	{"delta0", false},
+	{"profile slope", true},
+	{"reduction time (s)", false},
+	{"oracle time (s)", false},
+}
+
+// welchTest returns the two-sided p-value of Welch's t-test for a difference
+// in the means of a and b, or NaN if either sample has fewer than two values.
+func welchTest(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return math.NaN()
+	}
+	meanA, varA := stat.MeanVariance(a, nil)
+	meanB, varB := stat.MeanVariance(b, nil)
+	na, nb := float64(len(a)), float64(len(b))
+	se2 := varA/na + varB/nb
+	if se2 == 0 {
+		if meanA == meanB {
+			return 1
+		}
+		return 0
+	}
+	t := (meanB - meanA) / math.Sqrt(se2)
+	df := se2 * se2 / (varA*varA/(na*na*(na-1)) + varB*varB/(nb*nb*(nb-1)))
+	return 2 * distuv.StudentsT{Mu: 0, Sigma: 1, Nu: df}.Survival(math.Abs(t))
+}
+
+// runCompare prints the comparison of two result sets to w. Changes for the
+// worse with a p-value below the significance level are flagged as
+// regressions and returned as an *assertionError.
+func runCompare(w io.Writer, cfg compareConfig) error {
+	var base, next *runResults
+	var err error
+	if cfg.DB != "" {
+		db, err := sql.Open("sqlite", cfg.DB)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if base, err = loadResultsSQLite(db, cfg.Base); err != nil {
+			return fmt.Errorf("run %s: %w", cfg.Base, err)
+		}
+		if next, err = loadResultsSQLite(db, cfg.New); err != nil {
+			return fmt.Errorf("run %s: %w", cfg.New, err)
+		}
+	} else {
+		if base, err = loadResultsJSON(cfg.Base); err != nil {
+			return err
+		}
+		if next, err = loadResultsJSON(cfg.New); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(w, "--- Comparing %s (base) with %s (new) ---\n", cfg.Base, cfg.New)
+	fmt.Fprintf(w, "Welch's t-test, changes for the worse with p < %g are flagged.\n\n", cfg.Alpha)
+	fmt.Fprintf(w, "%-22s | %-14s | %-14s | %-9s | %-8s | %s\n", "metric", "base mean (k)", "new mean (k)", "change", "p-value", "verdict")
+	fmt.Fprintln(w, "--------------------------------------------------------------------------------------------")
+
+	baseSamples, newSamples := resultMetrics(base), resultMetrics(next)
+	var regressions []string
+	for i, metric := range compareMetricNames {
+		a, b := baseSamples[i], newSamples[i]
+		if len(a) == 0 && len(b) == 0 {
+			continue
+		}
+		meanA, meanB := stat.Mean(a, nil), stat.Mean(b, nil)
+		change := "n/a"
+		if len(a) > 0 && len(b) > 0 && meanA != 0 {
+			change = fmt.Sprintf("%+.2f%%", (meanB-meanA)/math.Abs(meanA)*100)
+		}
+
+		p := welchTest(a, b)
+		verdict := ""
+		switch {
+		case math.IsNaN(p):
+			verdict = "too few samples"
+		case p < cfg.Alpha && (meanB > meanA) != metric.higherIsBetter:
+			verdict = "REGRESSION"
+			regressions = append(regressions, fmt.Sprintf("%s: %.4g -> %.4g (%s, p = %.3g)", metric.name, meanA, meanB, change, p))
+		case p < cfg.Alpha:
+			verdict = "improvement"
+		}
+		fmt.Fprintf(w, "%-22s | %-14s | %-14s | %-9s | %-8.3g | %s\n", metric.name,
+			fmt.Sprintf("%.4g (%d)", meanA, len(a)), fmt.Sprintf("%.4g (%d)", meanB, len(b)), change, p, verdict)
+	}
+
+	if len(regressions) > 0 {
+		return &assertionError{Kind: "significant regression(s)", Violations: regressions}
+	}
+	fmt.Fprintln(w, "\nNo significant regressions.")
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// configDefaults are the settings that a config file and environment
+// variables can provide, so that institutional setups don't need long
+// command lines. Command-line flags take precedence over the environment,
+// which takes precedence over the config file.
+type configDefaults struct {
+	Backend   string `toml:"backend"`       // --backend, LATTICE_LAB_BACKEND
+	FplllPath string `toml:"fplll_path"`    // --fplll, LATTICE_LAB_FPLLL
+	Flatter   string `toml:"flatter_path"`  // --flatter, LATTICE_LAB_FLATTER
+	Libfplll  string `toml:"libfplll_path"` // --libfplll, LATTICE_LAB_LIBFPLLL
+	Sage      string `toml:"sage_path"`     // --sage, LATTICE_LAB_SAGE
+	TempDir   string `toml:"temp_dir"`      // --temp-dir, LATTICE_LAB_TEMP_DIR
+	Precision int    `toml:"precision"`     // --precision, LATTICE_LAB_PRECISION
+	FloatType string `toml:"float_type"`    // --float-type, LATTICE_LAB_FLOAT_TYPE
+	Strategy  string `toml:"strategy"`      // --strategy, LATTICE_LAB_STRATEGY
+	OutputDir string `toml:"output_dir"`    // --output-dir, LATTICE_LAB_OUTPUT_DIR
+	Jobs      int    `toml:"jobs"`          // --jobs, LATTICE_LAB_JOBS
+	// Durations are written as strings such as "10m".
+	Timeout      time.Duration `toml:"timeout"`       // --timeout, LATTICE_LAB_TIMEOUT
+	MaxTime      time.Duration `toml:"max_time"`      // --max-time, LATTICE_LAB_MAX_TIME
+	Retries      int           `toml:"retries"`       // --retries, LATTICE_LAB_RETRIES
+	RetryBackoff time.Duration `toml:"retry_backoff"` // --retry-backoff, LATTICE_LAB_RETRY_BACKOFF
+	Rerandomize  bool          `toml:"rerandomize"`   // --rerandomize, LATTICE_LAB_RERANDOMIZE
+	CacheDir     string        `toml:"cache_dir"`     // --cache, LATTICE_LAB_CACHE
+}
+
+// configPath returns the config file to read: $LATTICE_LAB_CONFIG if set,
+// otherwise ~/.lattice-lab.toml. explicit reports whether the path was
+// given by the user, in which case the file must exist.
+func configPath() (path string, explicit bool) {
+	if path := os.Getenv("LATTICE_LAB_CONFIG"); path != "" {
+		return path, true
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(home, ".lattice-lab.toml"), false
+}
+
+// loadConfigDefaults returns the built-in defaults overridden by the config
+// file and then by the environment. Unknown keys in the config file are
+// reported, since they are most likely typos.
+func loadConfigDefaults() (configDefaults, error) {
+	b := defaultBackend()
+	cfg := configDefaults{Backend: b.Name, FplllPath: b.Binary, Flatter: b.Flatter, Libfplll: b.Library, Sage: b.Sage, TempDir: b.TempDir, Jobs: b.Jobs, RetryBackoff: b.RetryBackoff}
+
+	if path, explicit := configPath(); path != "" {
+		md, err := toml.DecodeFile(path, &cfg)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+		case err != nil:
+			return cfg, fmt.Errorf("config file %s: %w", path, err)
+		case len(md.Undecoded()) > 0:
+			return cfg, fmt.Errorf("config file %s: unknown setting %q", path, md.Undecoded()[0].String())
+		}
+	}
+
+	for _, env := range []struct {
+		name string
+		dst  *string
+	}{
+		{"LATTICE_LAB_BACKEND", &cfg.Backend},
+		{"LATTICE_LAB_FPLLL", &cfg.FplllPath},
+		{"LATTICE_LAB_FLATTER", &cfg.Flatter},
+		{"LATTICE_LAB_LIBFPLLL", &cfg.Libfplll},
+		{"LATTICE_LAB_SAGE", &cfg.Sage},
+		{"LATTICE_LAB_TEMP_DIR", &cfg.TempDir},
+		{"LATTICE_LAB_FLOAT_TYPE", &cfg.FloatType},
+		{"LATTICE_LAB_STRATEGY", &cfg.Strategy},
+		{"LATTICE_LAB_OUTPUT_DIR", &cfg.OutputDir},
+		{"LATTICE_LAB_CACHE", &cfg.CacheDir},
+	} {
+		if v := os.Getenv(env.name); v != "" {
+			*env.dst = v
+		}
+	}
+	for _, env := range []struct {
+		name string
+		dst  *int
+	}{
+		{"LATTICE_LAB_PRECISION", &cfg.Precision},
+		{"LATTICE_LAB_JOBS", &cfg.Jobs},
+		{"LATTICE_LAB_RETRIES", &cfg.Retries},
+	} {
+		if v := os.Getenv(env.name); v != "" {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return cfg, fmt.Errorf("%s: invalid integer %q", env.name, v)
+			}
+			*env.dst = i
+		}
+	}
+	for _, env := range []struct {
+		name string
+		dst  *time.Duration
+	}{
+		{"LATTICE_LAB_TIMEOUT", &cfg.Timeout},
+		{"LATTICE_LAB_MAX_TIME", &cfg.MaxTime},
+		{"LATTICE_LAB_RETRY_BACKOFF", &cfg.RetryBackoff},
+	} {
+		if v := os.Getenv(env.name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return cfg, fmt.Errorf("%s: invalid duration %q", env.name, v)
+			}
+			*env.dst = d
+		}
+	}
+	if v := os.Getenv("LATTICE_LAB_RERANDOMIZE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("LATTICE_LAB_RERANDOMIZE: invalid boolean %q", v)
+		}
+		cfg.Rerandomize = b
+	}
+	return cfg, nil
+}
+
+// outputDir, if set, is the directory relative output paths are resolved
+// against (--output-dir).
+var outputDir string
+
+// outputPath resolves a relative output path against outputDir, creating
+// the directory if needed (except in a dry run). Standard output ("" or
+// "-") and absolute paths are returned unchanged.
+func outputPath(path string) (string, error) {
+	if outputDir == "" || path == "" || path == "-" || filepath.IsAbs(path) {
+		return path, nil
+	}
+	if dryRun == nil {
+		if err := os.MkdirAll(outputDir, 0o755); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(outputDir, path), nil
+}
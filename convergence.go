@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"time"
+)
+
+// convergedReduce runs a BKZ or slide step one tour at a time, each tour
+// being an fplll call with -bkzmaxloops 1 on the basis the one before left,
+// and fits the GSA line to the profile after every tour. It stops once a
+// tour raises the slope by less than step.SlopeTolerance, as Lab 2 only
+// needs the profile BKZ converges to and fplll's own tours go on long
+// after it stops moving. The step's tour limit and time limit still apply
+// to the tours together, and flatter, if used, preprocesses the basis once
+// before the first. In a dry run only the first tour is planned.
+func convergedReduce(ctx context.Context, basis Basis, step reductionStep) (Basis, error) {
+	tour := step
+	tour.SlopeTolerance = 0
+	tour.MaxLoops = 1
+	start := time.Now()
+	basis, err := flatterPreprocess(ctx, basis)
+	if err != nil {
+		return nil, err
+	}
+	slope := basis.Profile().Slope()
+	for tours := 1; ; tours++ {
+		if step.MaxTime > 0 {
+			left := step.MaxTime - time.Since(start).Seconds()
+			if left <= 0 {
+				slog.Info("BKZ time limit reached before the slope converged", "step", step.String(), "tours", tours-1)
+				return basis, nil
+			}
+			tour.MaxTime = math.Ceil(left)
+		}
+		reduced, err := blockReduce(ctx, basis, tour)
+		if err != nil {
+			return nil, err
+		}
+		if dryRun != nil {
+			return reduced, nil
+		}
+		next := reduced.Profile().Slope()
+		basis = reduced
+		slog.Debug("BKZ tour", "step", step.String(), "tour", tours, "slope", next, "improvement", next-slope)
+		if next-slope < step.SlopeTolerance {
+			slog.Info("GSA slope converged", "step", step.String(), "tours", tours, "slope", next)
+			return basis, nil
+		}
+		if step.MaxLoops > 0 && tours >= step.MaxLoops {
+			slog.Info("BKZ tour limit reached before the slope converged", "step", step.String(), "tours", tours, "slope", next)
+			return basis, nil
+		}
+		slope = next
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// crossBackend is a solver the crosscheck command runs instances through.
+// A backend has a reduction, an SVP solver or both.
+type crossBackend struct {
+	Name string
+	// Unavailable says why the backend can't run on this machine, or
+	// returns "".
+	Unavailable func() string
+	// Reduce BKZ-reduces basis with block size beta.
+	Reduce func(ctx context.Context, basis Basis, beta int) (Basis, error)
+	// SVP returns a shortest non-zero vector of the lattice of basis.
+	SVP func(ctx context.Context, basis Basis) ([]*big.Int, error)
+}
+
+// crossBackends are the backends crosscheck knows, in the order they are
+// run unless -backends gives another; the first is the reference:
+// fplll, SageMath, flatter followed by fplll's BKZ, and the native
+// enumeration, which LLL-reduces with fplll.
+var crossBackends = []crossBackend{
+	{
+		Name:        "fplll",
+		Unavailable: benchBackends["fplll"].Unavailable,
+		Reduce:      benchBackends["fplll"].Reduce,
+		SVP: func(ctx context.Context, basis Basis) ([]*big.Int, error) {
+			return shortestVector(ctx, basis, "")
+		},
+	},
+	{
+		Name:        "sage",
+		Unavailable: sageAvailable,
+		Reduce:      benchBackends["sage"].Reduce,
+		SVP:         sageShortestVector,
+	},
+	{
+		Name: "flatter",
+		Unavailable: func() string {
+			if backend.Flatter == "" {
+				return "flatter not found (--flatter)"
+			}
+			return benchBackends["fplll"].Unavailable()
+		},
+		Reduce: func(ctx context.Context, basis Basis, beta int) (Basis, error) {
+			reduced, err := flatterReduce(ctx, basis, lllOptions{})
+			if err != nil {
+				return nil, err
+			}
+			return bkzReduce(ctx, reduced, beta, bkzOptions{})
+		},
+	},
+	{
+		Name:        "native",
+		Unavailable: benchBackends["fplll"].Unavailable,
+		SVP:         nativeShortestVector,
+	},
+}
+
+// crossBackendNames returns the names of crossBackends in order.
+func crossBackendNames() []string {
+	names := make([]string, len(crossBackends))
+	for i, b := range crossBackends {
+		names[i] = b.Name
+	}
+	return names
+}
+
+// nativeShortestVector finds a shortest vector by the native enumeration
+// of boundedSVP, within the norm of the first vector of the LLL-reduced
+// basis, which bounds λ1 from above.
+func nativeShortestVector(ctx context.Context, basis Basis) ([]*big.Int, error) {
+	reduced, err := enumerationBasis(ctx, basis)
+	if err != nil {
+		return nil, err
+	}
+	// Widened so that b_1 itself is within the strict bound.
+	radius := math.Sqrt(vectorNormSquared(reduced[0])) * (1 + 1e-9)
+	return boundedSVP(ctx, reduced, radius)
+}
+
+// crosscheckConfig holds the arguments of the crosscheck command.
+type crosscheckConfig struct {
+	Dims     []int
+	Trials   int
+	Beta     int
+	Q        int64
+	Backends []crossBackend
+	// ProfileTolerance is the largest difference, in bits, allowed between
+	// an entry of a reduced profile and that of the first backend.
+	ProfileTolerance float64
+}
+
+// parseCrosscheckFlags builds a crosscheckConfig from the command line.
+func parseCrosscheckFlags(args []string) (crosscheckConfig, error) {
+	fs := flag.NewFlagSet("crosscheck", flag.ContinueOnError)
+	dims := fs.String("n", "20,30,40", "comma-separated ranks of the random bases")
+	trials := fs.Int("trials", 2, "random bases per rank")
+	beta := fs.Int("beta", 10, "BKZ block size of the reductions")
+	q := fs.Int64("q", 100003, "coefficient bound of the random bases")
+	backends := fs.String("backends", "all", "all or a comma-separated list of "+strings.Join(crossBackendNames(), ", "))
+	tol := fs.Float64("profile-tol", 0.5, "largest difference of a reduced profile entry from the first backend's, in bits")
+	if err := fs.Parse(args); err != nil {
+		return crosscheckConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return crosscheckConfig{}, fmt.Errorf("usage: lattice-labs crosscheck [-n ranks] [-trials k] [-beta b] [-q q] [-backends list] [-profile-tol bits]")
+	}
+	cfg := crosscheckConfig{Trials: *trials, Beta: *beta, Q: *q, ProfileTolerance: *tol}
+	dimList, err := parseIntList(*dims)
+	if err != nil {
+		return cfg, fmt.Errorf("-n: %w", err)
+	}
+	for _, n := range dimList {
+		if n < 2 {
+			return cfg, fmt.Errorf("-n: rank must be at least 2, got %d", n)
+		}
+		cfg.Dims = append(cfg.Dims, int(n))
+	}
+	if cfg.Trials < 1 {
+		return cfg, fmt.Errorf("-trials must be at least 1")
+	}
+	if cfg.Q < 2 {
+		return cfg, fmt.Errorf("-q must be at least 2")
+	}
+	if err := bkzStep(cfg.Beta).validate(); err != nil {
+		return cfg, fmt.Errorf("-beta: %w", err)
+	}
+	if !(cfg.ProfileTolerance >= 0) {
+		return cfg, fmt.Errorf("-profile-tol must not be negative, got %g", cfg.ProfileTolerance)
+	}
+	if *backends == "all" {
+		cfg.Backends = crossBackends
+		return cfg, nil
+	}
+	for _, name := range strings.Split(*backends, ",") {
+		name = strings.TrimSpace(name)
+		i := slices.IndexFunc(crossBackends, func(b crossBackend) bool { return b.Name == name })
+		if i < 0 {
+			return cfg, fmt.Errorf("-backends: unknown backend %q (want all or %s)", name, strings.Join(crossBackendNames(), ", "))
+		}
+		cfg.Backends = append(cfg.Backends, crossBackends[i])
+	}
+	return cfg, nil
+}
+
+// runCrosscheck runs the same random bases through every available backend
+// of cfg and compares the results: the squared norms of the shortest
+// vectors, which must agree exactly and be those of lattice vectors, the
+// reduced bases, which must span the input lattice exactly and be
+// BKZ-reduced (see checkBKZReduced), and their profiles, which must agree
+// with the first backend's within cfg.ProfileTolerance. One row per instance
+// is written to w; the disagreements are returned as an *assertionError.
+// Unavailable backends are listed with the reason and skipped.
+func runCrosscheck(ctx context.Context, w io.Writer, cfg crosscheckConfig) error {
+	if dryRun != nil {
+		return fmt.Errorf("crosscheck compares what the backends compute and has no dry run")
+	}
+	// A cached fplll result would keep fplll's output out of the check.
+	defer func(c *fplllCache) { resultCache = c }(resultCache)
+	resultCache = nil
+
+	var backends []crossBackend
+	for _, b := range cfg.Backends {
+		if reason := b.Unavailable(); reason != "" {
+			fmt.Fprintf(w, "%s: unavailable: %s\n", b.Name, reason)
+			continue
+		}
+		backends = append(backends, b)
+	}
+	if len(backends) == 0 {
+		return fmt.Errorf("no backend available")
+	}
+	names := make([]string, len(backends))
+	for i, b := range backends {
+		names[i] = b.Name
+	}
+	fmt.Fprintf(w, "Cross-check of %s (SVP and BKZ-%d, q %d)\n\n", strings.Join(names, ", "), cfg.Beta, cfg.Q)
+	fmt.Fprintf(w, "%-6s | %-5s | %-10s | %-13s | %-8s | %s\n", "n", "Trial", "λ1", "Max Δ profile", "Lattices", "Result")
+	fmt.Fprintln(w, "----------------------------------------------------------------")
+
+	var violations []string
+	q := big.NewInt(cfg.Q)
+	for _, n := range cfg.Dims {
+		for trial := 1; trial <= cfg.Trials; trial++ {
+			basis := genRandomBasisFrom(trialSource("crosscheck", int64(n), cfg.Q, int64(trial)), n, q)
+			found := crosscheckInstance(ctx, basis, backends, cfg)
+			if ctx.Err() != nil {
+				return nil
+			}
+			result := "ok"
+			if len(found.violations) > 0 {
+				result = fmt.Sprintf("%d disagreement(s)", len(found.violations))
+			}
+			for _, v := range found.violations {
+				violations = append(violations, fmt.Sprintf("n %d trial %d: %s", n, trial, v))
+			}
+			fmt.Fprintf(w, "%-6d | %-5d | %-10s | %-13s | %-8s | %s\n", n, trial, found.lambda1, found.profileDiff, found.lattices, result)
+		}
+	}
+	if len(violations) > 0 {
+		return &assertionError{Kind: "cross-backend disagreement(s)", Violations: violations}
+	}
+	return nil
+}
+
+// crosscheckResult is the row of one instance of runCrosscheck, formatted,
+// and the disagreements found.
+type crosscheckResult struct {
+	lambda1, profileDiff, lattices string
+	violations                     []string
+}
+
+// crosscheckInstance runs basis through the backends as described for
+// runCrosscheck.
+func crosscheckInstance(ctx context.Context, basis Basis, backends []crossBackend, cfg crosscheckConfig) crosscheckResult {
+	r := crosscheckResult{lambda1: "-", profileDiff: "-", lattices: "-"}
+	fail := func(format string, args ...any) {
+		r.violations = append(r.violations, fmt.Sprintf(format, args...))
+	}
+
+	var refName string
+	var refNorm *big.Int
+	for _, b := range backends {
+		if b.SVP == nil {
+			continue
+		}
+		v, err := b.SVP(ctx, basis)
+		if err == nil {
+			err = verifyLatticeVector(basis, v)
+		}
+		if ctx.Err() != nil {
+			return r
+		}
+		if err != nil {
+			fail("%s SVP: %v", b.Name, err)
+			continue
+		}
+		switch norm := normSquared(v); {
+		case refNorm == nil:
+			refName, refNorm = b.Name, norm
+			r.lambda1 = fmt.Sprintf("%.2f", math.Sqrt(vectorNormSquared(v)))
+		case norm.Cmp(refNorm) != 0:
+			fail("λ1² is %s by %s but %s by %s", norm, b.Name, refNorm, refName)
+		}
+	}
+
+	var refProfile []float64
+	worst, reductions, profiles, differ := 0.0, 0, 0, false
+	for _, b := range backends {
+		if b.Reduce == nil {
+			continue
+		}
+		reduced, err := b.Reduce(ctx, basis, cfg.Beta)
+		if ctx.Err() != nil {
+			return r
+		}
+		if err != nil {
+			fail("%s BKZ-%d: %v", b.Name, cfg.Beta, err)
+			continue
+		}
+		reductions++
+		if err := sameLattice(basis, reduced); err != nil {
+			differ = true
+			fail("%s's reduced basis spans another lattice: %v", b.Name, err)
+			continue
+		}
+		if err := checkBKZReduced(ctx, reduced, cfg.Beta, 0); err != nil {
+			if ctx.Err() != nil {
+				return r
+			}
+			fail("%s's basis is not BKZ-%d reduced: %v", b.Name, cfg.Beta, err)
+		}
+		profile := reduced.Profile()
+		profiles++
+		if refProfile == nil {
+			refName, refProfile = b.Name, profile
+			continue
+		}
+		d, at := profileDeviation(profile, refProfile)
+		worst = max(worst, d)
+		if d > cfg.ProfileTolerance || math.IsNaN(d) {
+			fail("%s's profile differs from %s's by %.3f bits at index %d", b.Name, refName, d, at+1)
+		}
+	}
+	switch {
+	case differ:
+		r.lattices = "DIFFER"
+	case reductions > 0:
+		r.lattices = "equal"
+	}
+	if profiles > 1 {
+		r.profileDiff = fmt.Sprintf("%.3f", worst)
+	}
+	return r
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// writeCSVFiles writes the results into dir as CSV files with a header row:
+// lab1.csv holds one row per Lab 1 instance, lab2_profile.csv one row per
+// profile index (none for a reduction that failed or timed out), sweep.csv
+// one row per grid-search combination and metadata.csv one row per run
+// metadata entry. Files are only created for
+// the parts of the results that are present.
+func writeCSVFiles(dir string, res *runResults) error {
+	if len(res.Lab1) > 0 {
+		records := [][]string{{"run", "n", "trial", "volume", "gh", "lambda1", "relative_error_percent", "oracle_seconds", "status"}}
+		for run, lab := range res.Lab1 {
+			for _, row := range lab.Rows {
+				records = append(records, []string{
+					strconv.Itoa(run),
+					strconv.Itoa(row.N),
+					strconv.Itoa(row.Trial),
+					formatCSVFloat(float64(row.Volume)),
+					formatCSVFloat(float64(row.GH)),
+					formatCSVFloat(float64(row.Lambda1)),
+					formatCSVFloat(float64(row.RelativeError)),
+					formatCSVFloat(row.OracleSeconds),
+					row.Status,
+				})
+			}
+		}
+		if err := writeCSVFile(filepath.Join(dir, "lab1.csv"), records); err != nil {
+			return err
+		}
+	}
+
+	if len(res.Lab2) > 0 {
+		records := [][]string{{"run", "rank", "q", "index", "log2_norm", "error"}}
+		for run, lab := range res.Lab2 {
+			for i, v := range lab.Profile {
+				errBound := ""
+				if i < len(lab.ProfileError) {
+					errBound = formatCSVFloat(float64(lab.ProfileError[i]))
+				}
+				records = append(records, []string{
+					strconv.Itoa(run),
+					strconv.Itoa(lab.Rank),
+					lab.Q,
+					strconv.Itoa(i),
+					formatCSVFloat(v),
+					errBound,
+				})
+			}
+		}
+		if err := writeCSVFile(filepath.Join(dir, "lab2_profile.csv"), records); err != nil {
+			return err
+		}
+	}
+
+	if len(res.Sweep) > 0 {
+		records := [][]string{{"n", "beta", "q", "gh", "b1", "delta0", "slope", "seconds", "timed_out", "failed"}}
+		for _, row := range res.Sweep {
+			records = append(records, []string{
+				strconv.Itoa(row.N),
+				strconv.Itoa(row.Beta),
+				strconv.FormatInt(row.Q, 10),
+				formatCSVFloat(row.GH),
+				formatCSVFloat(row.B1),
+				formatCSVFloat(row.Delta),
+				formatCSVFloat(row.Slope),
+				formatCSVFloat(row.Seconds),
+				strconv.Itoa(row.TimedOut),
+				strconv.Itoa(row.Failed),
+			})
+		}
+		if err := writeCSVFile(filepath.Join(dir, "sweep.csv"), records); err != nil {
+			return err
+		}
+	}
+
+	if res.Metadata != nil {
+		records := [][]string{{"key", "value"}}
+		for _, kv := range res.Metadata.entries() {
+			records = append(records, []string{kv[0], kv[1]})
+		}
+		if err := writeCSVFile(filepath.Join(dir, "metadata.csv"), records); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatCSVFloat formats a float with the shortest exact representation.
+func formatCSVFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// writeCSVFile creates path and writes all records to it.
+func writeCSVFile(path string, records [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(records); err != nil {
+		return err
+	}
+	return f.Close()
+}
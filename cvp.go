@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"slices"
+)
+
+// isCVP reports whether args are those of an fplll CVP call. fplll -a cvp
+// reads the basis and then the target vector from standard input; the rows
+// passed to runFplll for such a call end with the target, which
+// writeFplllInput writes after the basis.
+func isCVP(args []string) bool {
+	return len(args) >= 2 && args[0] == "-a" && args[1] == "cvp"
+}
+
+// writeFplllInput writes the standard input of an fplll call with args: the
+// rows as a basis, or for CVP all but the last as the basis followed by the
+// last as the target vector.
+func writeFplllInput(w io.Writer, rows [][]*big.Int, args []string) error {
+	if !isCVP(args) {
+		return writeBasis(w, rows)
+	}
+	if err := writeBasis(w, rows[:len(rows)-1]); err != nil {
+		return err
+	}
+	return writeVector(w, rows[len(rows)-1])
+}
+
+// cvpOracle finds the lattice point of basis closest to target with fplll
+// -a cvp and returns it with its distance from the target. Failures are
+// logged and returned. In a dry run nothing is computed and the target is
+// returned at distance 0.
+func cvpOracle(ctx context.Context, basis Basis, target []*big.Int) ([]*big.Int, float64, error) {
+	if len(target) != len(basis[0]) {
+		return nil, 0, fmt.Errorf("target has %d entries, the basis vectors %d", len(target), len(basis[0]))
+	}
+	args := backend.fplllArgs("cvp")
+	if dryRun != nil {
+		planFplll(basis, args...)
+		return target, 0, nil
+	}
+	var point []*big.Int
+	err := backend.runFplllCached(ctx, append(slices.Clip(basis), target), args, func(r io.Reader) (err error) {
+		if point, err = backend.Version.dialect().Vector(r); err == nil && len(point) != len(target) {
+			err = fmt.Errorf("%d entries, expected %d", len(point), len(target))
+		}
+		return err
+	})
+	if err != nil {
+		logFplllError("cvp", len(basis), err)
+		return nil, 0, err
+	}
+	diff := make([]*big.Int, len(point))
+	for i := range point {
+		diff[i] = new(big.Int).Sub(point[i], target[i])
+	}
+	return point, math.Sqrt(vectorNormSquared(diff)), nil
+}
+
+// cvpConfig holds the arguments of the cvp command.
+type cvpConfig struct {
+	Input    string // basis file, or "-" for standard input
+	From, To string // matrix formats of the basis and the output
+	// Targets are the target vectors given with -target; without any, the
+	// input holds the basis in fplll format followed by the targets, as
+	// fplll -a cvp reads it.
+	Targets [][]*big.Int
+	// Distance prints the distance of each target from the lattice instead
+	// of the closest point.
+	Distance bool
+	// Method is fplll for fplll's exact CVP, babai for Babai's nearest
+	// plane algorithm (see nearestPlane), which finds a close point fast, or
+	// embedding for an SVP call on Kannan's embedding (CVPViaEmbedding).
+	Method string
+}
+
+// targetList collects the -target flags of the cvp command.
+type targetList [][]*big.Int
+
+func (t *targetList) String() string { return fmt.Sprint(len(*t), " targets") }
+
+func (t *targetList) Set(s string) error {
+	rows, err := scanBracketRows(bytes.NewReader([]byte("[" + s + "]")))
+	if err != nil {
+		return err
+	}
+	if len(rows) != 1 {
+		return fmt.Errorf("want one vector such as \"[1 2 3]\", got %q", s)
+	}
+	*t = append(*t, rows[0])
+	return nil
+}
+
+// parseCVPFlags builds a cvpConfig from the cvp command line.
+func parseCVPFlags(args []string) (cvpConfig, error) {
+	fs := flag.NewFlagSet("cvp", flag.ContinueOnError)
+	from := fs.String("from", "auto", "format of the input basis when -target is given: auto or one of "+basisFormatNames())
+	to := fs.String("to", "fplll", "format of the output: one of "+basisFormatNames())
+	var targets targetList
+	fs.Var(&targets, "target", "target vector such as \"[1 2 3]\"; may be repeated (default: the vectors after the basis in the input)")
+	distance := fs.Bool("distance", false, "print the distance of each target from the lattice instead of the closest point")
+	method := fs.String("method", "fplll", "fplll (the closest point), babai (Babai's nearest plane, a close point) or embedding (the closest point by Kannan's embedding and SVP)")
+	if err := fs.Parse(args); err != nil {
+		return cvpConfig{}, err
+	}
+	if fs.NArg() > 1 {
+		return cvpConfig{}, fmt.Errorf("usage: lattice-labs cvp [-target vec]... [-from fmt] [-to fmt] [-distance] [-method fplll|babai|embedding] [file|-]")
+	}
+	if *from != "auto" && basisReaders[*from] == nil {
+		return cvpConfig{}, fmt.Errorf("unknown basis format %q (want auto, %s)", *from, basisFormatNames())
+	}
+	if basisWriters[*to] == nil {
+		return cvpConfig{}, fmt.Errorf("unknown basis format %q (want %s)", *to, basisFormatNames())
+	}
+	if *method != "fplll" && *method != "babai" && *method != "embedding" {
+		return cvpConfig{}, fmt.Errorf("-method must be fplll, babai or embedding, got %q", *method)
+	}
+	cfg := cvpConfig{Input: "-", From: *from, To: *to, Targets: targets, Distance: *distance, Method: *method}
+	if fs.NArg() == 1 {
+		cfg.Input = fs.Arg(0)
+	}
+	return cfg, nil
+}
+
+// loadCVPInput reads the basis and the targets of the cvp command: the basis
+// alone if cfg has targets, otherwise fplll's CVP input, a matrix followed
+// by bare vectors.
+func loadCVPInput(cfg cvpConfig) (Basis, [][]*big.Int, error) {
+	if len(cfg.Targets) > 0 {
+		basis, err := loadPipeBasis(cfg.Input, cfg.From)
+		return basis, cfg.Targets, err
+	}
+	source := "standard input"
+	var r io.Reader = os.Stdin
+	if cfg.Input != "-" {
+		f, err := os.Open(cfg.Input)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		source, r = cfg.Input, f
+	}
+	scan, err := scanBrackets(bufio.NewReader(r))
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", source, err)
+	}
+	var basis Basis
+	var targets [][]*big.Int
+	for i, row := range scan.rows {
+		switch {
+		case scan.depths[i] >= 2 && len(targets) == 0:
+			basis = append(basis, row)
+		case scan.depths[i] == 1:
+			targets = append(targets, row)
+		default:
+			return nil, nil, fmt.Errorf("reading %s: basis row %d after a target vector", source, i+1)
+		}
+	}
+	if len(basis) == 0 || len(targets) == 0 {
+		return nil, nil, fmt.Errorf("reading %s: want a basis followed by target vectors, as for fplll -a cvp, or -target", source)
+	}
+	return basis, targets, nil
+}
+
+// runCVP finds the closest lattice point to every target, with fplll or by
+// embedding, or with babai a close one, and writes the points to w, one per
+// line in fplll format and as the rows of a matrix in the other formats, or
+// with cfg.Distance the distances, one per line.
+func runCVP(ctx context.Context, w io.Writer, cfg cvpConfig) error {
+	basis, targets, err := loadCVPInput(cfg)
+	if err != nil {
+		return err
+	}
+	decode := cvpOracle
+	switch cfg.Method {
+	case "embedding":
+		decode = CVPViaEmbedding
+	case "babai":
+		np, err := newNearestPlane(basis)
+		if err != nil {
+			return err
+		}
+		decode = func(_ context.Context, _ Basis, target []*big.Int) ([]*big.Int, float64, error) {
+			point, errVec, err := np.Decode(ratVector(target))
+			if err != nil {
+				return nil, 0, err
+			}
+			d, _ := ratNormSquared(errVec).Float64()
+			return point, math.Sqrt(d), nil
+		}
+	}
+	var points [][]*big.Int
+	var distances []float64
+	for _, target := range targets {
+		point, dist, err := decode(ctx, basis, target)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			switch cfg.Method {
+			case "babai":
+				return fmt.Errorf("nearest plane: %w", err)
+			case "embedding":
+				return fmt.Errorf("embedding: %w", err)
+			}
+			return fmt.Errorf("fplll -a cvp: %w", err)
+		}
+		points, distances = append(points, point), append(distances, dist)
+	}
+	if dryRun != nil {
+		return nil
+	}
+	bw := bufio.NewWriter(w)
+	switch {
+	case cfg.Distance:
+		for _, d := range distances {
+			fmt.Fprintln(bw, formatCSVFloat(d))
+		}
+	case cfg.To == "fplll":
+		for _, p := range points {
+			writeVector(bw, p)
+		}
+	default:
+		if err := basisWriters[cfg.To](bw, points); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,688 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultDaemonAddr is the address the daemon listens on and the submit and
+// jobs commands talk to by default.
+const defaultDaemonAddr = "localhost:7080"
+
+// Statuses of a daemon job.
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobDone      = "done"
+	jobFailed    = "failed"
+	jobCancelled = "cancelled"
+)
+
+// Files in the directory of a daemon job.
+const (
+	jobFile        = "job.json"         // the daemonJob record
+	jobExperiments = "experiments.yaml" // the submitted experiment file, if any
+	jobOutput      = "output.txt"       // standard output of the job
+	jobLog         = "log.txt"          // standard error of the job
+)
+
+// jobStopDelay is how long a cancelled job may take to write its completed
+// results after being interrupted before it is killed.
+const jobStopDelay = 30 * time.Second
+
+// maxJobRequest is the largest job submission the daemon accepts, in bytes.
+const maxJobRequest = 16 << 20
+
+// jobCommands are the commands a daemon job may run; a job without one runs
+// the default labs.
+var jobCommands = map[string]bool{"run": true, "sweep": true, "continue": true, "bench": true}
+
+var (
+	errInvalidJob  = errors.New("invalid job")
+	errJobNotFound = errors.New("no such job")
+	errJobFinished = errors.New("job already finished")
+)
+
+// daemonJob is a job of the daemon queue. It is kept as job.json in the
+// directory of the job, which is also the working directory of the job, so
+// relative output paths such as --csv results end up next to it.
+type daemonJob struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name,omitempty"`
+	// Args are the lattice-labs arguments of the job: global flags and
+	// optionally a command. Jobs with an experiment file run it with the
+	// run command after them.
+	Args       []string   `json:"args,omitempty"`
+	Experiment bool       `json:"experiment,omitempty"`
+	Status     string     `json:"status"`
+	Submitted  time.Time  `json:"submitted"`
+	Started    *time.Time `json:"started,omitempty"`
+	Finished   *time.Time `json:"finished,omitempty"`
+	ExitCode   int        `json:"exit_code,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// command describes what the job runs, for job lists.
+func (j daemonJob) command() string {
+	args := j.Args
+	if j.Experiment {
+		args = append(slices.Clip(args), "run", jobExperiments)
+	}
+	if len(args) == 0 {
+		return "(default labs)"
+	}
+	return strings.Join(args, " ")
+}
+
+// jobRequest is the body of a job submission.
+type jobRequest struct {
+	Name string   `json:"name,omitempty"`
+	Args []string `json:"args,omitempty"`
+	// Experiment is the content of an experiment definition file, in YAML
+	// or JSON, that the job runs.
+	Experiment string `json:"experiment,omitempty"`
+}
+
+// jobDaemon is a persistent queue of jobs, each a lattice-labs run in a
+// process of its own, and the HTTP API to submit, list and cancel them.
+// Every job has a directory under dir holding its record, its experiment
+// file and its output, and the records are rewritten atomically on every
+// change of status, so the queue survives a restart of the daemon.
+type jobDaemon struct {
+	dir string
+	exe string // the lattice-labs executable running the jobs
+
+	mu      sync.Mutex
+	jobs    map[int64]*daemonJob
+	nextID  int64
+	cancels map[int64]context.CancelFunc // of the running jobs
+	wake    chan struct{}                // signalled when a job is queued
+}
+
+// openJobDaemon opens the job queue in dir, creating the directory if
+// needed. Jobs that were running when the daemon stopped are queued again;
+// they start over.
+func openJobDaemon(dir string) (*jobDaemon, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	d := &jobDaemon{
+		dir:     dir,
+		exe:     exe,
+		jobs:    map[int64]*daemonJob{},
+		cancels: map[int64]context.CancelFunc{},
+		wake:    make(chan struct{}, 1),
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		id, err := strconv.ParseInt(e.Name(), 10, 64)
+		if err != nil || !e.IsDir() {
+			continue
+		}
+		d.nextID = max(d.nextID, id)
+		path := filepath.Join(dir, e.Name(), jobFile)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // a submission that didn't complete
+		}
+		if err != nil {
+			return nil, err
+		}
+		job := &daemonJob{}
+		if err := json.Unmarshal(data, job); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if job.Status == jobRunning {
+			job.Status, job.Started = jobQueued, nil
+			if err := d.save(job); err != nil {
+				return nil, err
+			}
+			slog.Info("requeued job interrupted by the daemon stopping", "job", job.ID)
+		}
+		d.jobs[job.ID] = job
+	}
+	return d, nil
+}
+
+// jobDir returns the directory of job id.
+func (d *jobDaemon) jobDir(id int64) string {
+	return filepath.Join(d.dir, strconv.FormatInt(id, 10))
+}
+
+// save writes the record of job. d.mu must be held, or the daemon not yet
+// serving.
+func (d *jobDaemon) save(job *daemonJob) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(d.jobDir(job.ID), jobFile), append(data, '\n'))
+}
+
+// signal wakes an idle runner, if any.
+func (d *jobDaemon) signal() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// submit checks a job request and queues it. Arguments that lattice-labs
+// would reject, commands that aren't jobCommands and invalid experiment
+// files are reported as errInvalidJob.
+func (d *jobDaemon) submit(req jobRequest) (daemonJob, error) {
+	if _, rest, err := parseGlobalFlags(req.Args); err != nil {
+		return daemonJob{}, fmt.Errorf("%w: %w", errInvalidJob, err)
+	} else if len(rest) > 0 && req.Experiment != "" {
+		return daemonJob{}, fmt.Errorf("%w: a job with an experiment file can't also run %s", errInvalidJob, rest[0])
+	} else if len(rest) > 0 && !jobCommands[rest[0]] {
+		return daemonJob{}, fmt.Errorf("%w: command %q can't run as a job (want run, sweep, continue, bench or none)", errInvalidJob, rest[0])
+	}
+
+	d.mu.Lock()
+	d.nextID++
+	job := &daemonJob{ID: d.nextID, Name: req.Name, Args: req.Args, Status: jobQueued, Submitted: time.Now()}
+	d.mu.Unlock()
+	dir := d.jobDir(job.ID)
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return daemonJob{}, err
+	}
+	if req.Experiment != "" {
+		path := filepath.Join(dir, jobExperiments)
+		err := os.WriteFile(path, []byte(req.Experiment), 0o644)
+		if err == nil {
+			if _, err = loadExperiments(path); err != nil {
+				err = fmt.Errorf("%w: %w", errInvalidJob, err)
+			}
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return daemonJob{}, err
+		}
+		job.Experiment = true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.save(job); err != nil {
+		os.RemoveAll(dir)
+		return daemonJob{}, err
+	}
+	d.jobs[job.ID] = job
+	d.signal()
+	slog.Info("job queued", "job", job.ID, "name", job.Name, "command", job.command())
+	return *job, nil
+}
+
+// list returns the jobs in the order they were submitted.
+func (d *jobDaemon) list() []daemonJob {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	jobs := make([]daemonJob, 0, len(d.jobs))
+	for _, job := range d.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+// lookup returns the job with the given ID, as a decimal string.
+func (d *jobDaemon) lookup(id string) (daemonJob, error) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	job, ok := d.jobs[n]
+	if err != nil || !ok {
+		return daemonJob{}, fmt.Errorf("%w %s", errJobNotFound, id)
+	}
+	return *job, nil
+}
+
+// cancel cancels a queued job, or interrupts a running one, which is
+// marked cancelled once it has written its completed results.
+func (d *jobDaemon) cancel(id string) (daemonJob, error) {
+	job, err := d.lookup(id)
+	if err != nil {
+		return job, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	j := d.jobs[job.ID]
+	switch j.Status {
+	case jobQueued:
+		now := time.Now()
+		j.Status, j.Finished = jobCancelled, &now
+		if err := d.save(j); err != nil {
+			return *j, err
+		}
+		slog.Info("job cancelled", "job", j.ID)
+	case jobRunning:
+		d.cancels[j.ID]()
+		slog.Info("interrupting job", "job", j.ID)
+	default:
+		return *j, fmt.Errorf("%w: job %d is %s", errJobFinished, j.ID, j.Status)
+	}
+	return *j, nil
+}
+
+// next marks the oldest queued job running and returns it with a context
+// cancelled by cancel, or returns false if no job is queued.
+func (d *jobDaemon) next(ctx context.Context) (daemonJob, context.Context, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var job *daemonJob
+	queued := 0
+	for _, j := range d.jobs {
+		if j.Status == jobQueued {
+			queued++
+			if job == nil || j.ID < job.ID {
+				job = j
+			}
+		}
+	}
+	if job == nil {
+		return daemonJob{}, nil, false
+	}
+	if queued > 1 {
+		d.signal() // for another idle runner
+	}
+	now := time.Now()
+	job.Status, job.Started = jobRunning, &now
+	if err := d.save(job); err != nil {
+		slog.Warn("saving job", "job", job.ID, "err", err)
+	}
+	jobCtx, cancel := context.WithCancel(ctx)
+	d.cancels[job.ID] = cancel
+	return *job, jobCtx, true
+}
+
+// runQueue runs queued jobs one at a time until ctx is cancelled.
+func (d *jobDaemon) runQueue(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, jobCtx, ok := d.next(ctx)
+		if !ok {
+			select {
+			case <-d.wake:
+			case <-ctx.Done():
+			}
+			continue
+		}
+		slog.Info("job started", "job", job.ID, "command", job.command())
+		err := d.execute(jobCtx, job)
+		d.finish(ctx, jobCtx, job.ID, err)
+	}
+}
+
+// execute runs job in its directory with its output going to the job
+// files. Cancelling ctx interrupts the job as SIGINT would, so that it
+// writes the results it completed, and kills it after jobStopDelay.
+func (d *jobDaemon) execute(ctx context.Context, job daemonJob) error {
+	dir := d.jobDir(job.ID)
+	args := job.Args
+	if job.Experiment {
+		args = append(slices.Clip(args), "run", jobExperiments)
+	}
+	out, err := os.Create(filepath.Join(dir, jobOutput))
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	log, err := os.Create(filepath.Join(dir, jobLog))
+	if err != nil {
+		return err
+	}
+	defer log.Close()
+
+	cmd := exec.CommandContext(ctx, d.exe, args...)
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = out, log
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = jobStopDelay
+	return cmd.Run()
+}
+
+// finish records how job id ended. A job stopped because the daemon is
+// stopping (ctx is cancelled) is queued again to run after a restart.
+func (d *jobDaemon) finish(ctx, jobCtx context.Context, id int64, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	job := d.jobs[id]
+	now := time.Now()
+	switch {
+	case ctx.Err() != nil:
+		job.Status, job.Started = jobQueued, nil
+	case jobCtx.Err() != nil:
+		job.Status, job.Finished = jobCancelled, &now
+	case err != nil:
+		job.Status, job.Finished, job.Error = jobFailed, &now, err.Error()
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			job.ExitCode = exit.ExitCode()
+		}
+	default:
+		job.Status, job.Finished = jobDone, &now
+	}
+	d.cancels[id]()
+	delete(d.cancels, id)
+	if err := d.save(job); err != nil {
+		slog.Warn("saving job", "job", id, "err", err)
+	}
+	slog.Info("job ended", "job", id, "status", job.Status, "err", job.Error)
+}
+
+// handler returns the HTTP API of the daemon:
+//
+//	POST /jobs              queue a jobRequest, answer the daemonJob
+//	GET  /jobs              list the jobs
+//	GET  /jobs/{id}         the job
+//	GET  /jobs/{id}/output  its standard output so far
+//	GET  /jobs/{id}/log     its standard error so far
+//	POST /jobs/{id}/cancel  cancel or interrupt it
+func (d *jobDaemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", func(w http.ResponseWriter, r *http.Request) {
+		var req jobRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJobRequest)).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("%v: %v", errInvalidJob, err), http.StatusBadRequest)
+			return
+		}
+		job, err := d.submit(req)
+		writeJobResponse(w, http.StatusCreated, job, err)
+	})
+	mux.HandleFunc("GET /jobs", func(w http.ResponseWriter, r *http.Request) {
+		writeJobResponse(w, http.StatusOK, d.list(), nil)
+	})
+	mux.HandleFunc("GET /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		job, err := d.lookup(r.PathValue("id"))
+		writeJobResponse(w, http.StatusOK, job, err)
+	})
+	for _, file := range []struct{ path, name string }{{"output", jobOutput}, {"log", jobLog}} {
+		mux.HandleFunc("GET /jobs/{id}/"+file.path, func(w http.ResponseWriter, r *http.Request) {
+			job, err := d.lookup(r.PathValue("id"))
+			if err != nil {
+				writeJobResponse(w, 0, nil, err)
+				return
+			}
+			http.ServeFile(w, r, filepath.Join(d.jobDir(job.ID), file.name))
+		})
+	}
+	mux.HandleFunc("POST /jobs/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		job, err := d.cancel(r.PathValue("id"))
+		writeJobResponse(w, http.StatusOK, job, err)
+	})
+	return mux
+}
+
+// writeJobResponse writes v as JSON with the given status, or err with the
+// status that matches it.
+func writeJobResponse(w http.ResponseWriter, status int, v any, err error) {
+	switch {
+	case errors.Is(err, errInvalidJob):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errJobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errJobFinished):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		slog.Warn("daemon request failed", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(v)
+	}
+}
+
+// daemonConfig holds the arguments of the daemon command.
+type daemonConfig struct {
+	Addr     string // address of the HTTP API
+	Dir      string // directory of the job queue
+	Parallel int    // jobs run at once
+}
+
+// parseDaemonFlags builds a daemonConfig from the daemon command line.
+func parseDaemonFlags(args []string) (daemonConfig, error) {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	addr := fs.String("addr", defaultDaemonAddr, "address of the HTTP API (it has no authentication: keep it on a trusted network)")
+	dir := fs.String("dir", "lattice-lab-jobs", "directory of the job queue, the job files and their outputs")
+	parallel := fs.Int("parallel", 1, "jobs run at once")
+	if err := fs.Parse(args); err != nil {
+		return daemonConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return daemonConfig{}, fmt.Errorf("usage: lattice-labs daemon [-addr host:port] [-dir dir] [-parallel n]")
+	}
+	cfg := daemonConfig{Addr: *addr, Parallel: *parallel}
+	if cfg.Parallel < 1 {
+		return cfg, fmt.Errorf("-parallel must be at least 1, got %d", cfg.Parallel)
+	}
+	var err error
+	if cfg.Dir, err = outputPath(*dir); err != nil {
+		return cfg, fmt.Errorf("-dir: %w", err)
+	}
+	return cfg, nil
+}
+
+// runDaemon serves the job queue in cfg.Dir on cfg.Addr and runs up to
+// cfg.Parallel of its jobs at once until ctx is cancelled. Running jobs are
+// then interrupted and queued again for the next start.
+func runDaemon(ctx context.Context, cfg daemonConfig) error {
+	d, err := openJobDaemon(cfg.Dir)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: d.handler()}
+	stop := context.AfterFunc(ctx, func() { srv.Shutdown(context.Background()) })
+	defer stop()
+
+	var wg sync.WaitGroup
+	for range cfg.Parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.runQueue(ctx)
+		}()
+	}
+	slog.Info("daemon listening", "url", "http://"+ln.Addr().String()+"/jobs", "dir", cfg.Dir, "parallel", cfg.Parallel, "jobs", len(d.jobs))
+	err = srv.Serve(ln)
+	if ctx.Err() == nil {
+		srv.Close()
+	}
+	wg.Wait()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// jobClientConfig holds the arguments of the submit and jobs commands.
+type jobClientConfig struct {
+	Server string // base URL of the daemon
+	// Request is the job to submit.
+	Request jobRequest
+	// ID selects a job to show, print the Show file of or Cancel; without
+	// it the jobs command lists all jobs.
+	ID     string
+	Show   string // "output" or "log"
+	Cancel bool
+}
+
+// parseSubmitFlags builds a jobClientConfig from the submit command line:
+// the flags, then the lattice-labs arguments of the job.
+func parseSubmitFlags(args []string) (jobClientConfig, error) {
+	fs := flag.NewFlagSet("submit", flag.ContinueOnError)
+	server := fs.String("server", "http://"+defaultDaemonAddr, "URL of the daemon")
+	name := fs.String("name", "", "name of the job in job lists")
+	file := fs.String("file", "", "experiment definition file for the job to run")
+	if err := fs.Parse(args); err != nil {
+		return jobClientConfig{}, err
+	}
+	cfg := jobClientConfig{Server: *server, Request: jobRequest{Name: *name, Args: fs.Args()}}
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Request.Experiment = string(data)
+	}
+	return cfg, nil
+}
+
+// parseJobsFlags builds a jobClientConfig from the jobs command line.
+func parseJobsFlags(args []string) (jobClientConfig, error) {
+	fs := flag.NewFlagSet("jobs", flag.ContinueOnError)
+	server := fs.String("server", "http://"+defaultDaemonAddr, "URL of the daemon")
+	output := fs.Bool("output", false, "print the standard output of the job")
+	log := fs.Bool("log", false, "print the standard error of the job")
+	cancel := fs.Bool("cancel", false, "cancel the job, or interrupt it if it is running")
+	if err := fs.Parse(args); err != nil {
+		return jobClientConfig{}, err
+	}
+	cfg := jobClientConfig{Server: *server, ID: fs.Arg(0), Cancel: *cancel}
+	switch {
+	case *output:
+		cfg.Show = "output"
+	case *log:
+		cfg.Show = "log"
+	}
+	flags := 0
+	for _, set := range []bool{*output, *log, *cancel} {
+		if set {
+			flags++
+		}
+	}
+	if fs.NArg() > 1 || flags > 1 || flags > 0 && cfg.ID == "" {
+		return cfg, fmt.Errorf("usage: lattice-labs jobs [-server url] [[-output | -log | -cancel] ID]")
+	}
+	return cfg, nil
+}
+
+// runSubmit queues cfg.Request on the daemon and prints its job ID.
+func runSubmit(ctx context.Context, w io.Writer, cfg jobClientConfig) error {
+	body, err := json.Marshal(cfg.Request)
+	if err != nil {
+		return err
+	}
+	var job daemonJob
+	if err := daemonCall(ctx, http.MethodPost, cfg.Server+"/jobs", body, &job); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, job.ID)
+	return nil
+}
+
+// runJobList lists the jobs of the daemon, or shows, prints a file of or
+// cancels the job cfg.ID.
+func runJobList(ctx context.Context, w io.Writer, cfg jobClientConfig) error {
+	url := cfg.Server + "/jobs"
+	switch {
+	case cfg.ID == "":
+		var jobs []daemonJob
+		if err := daemonCall(ctx, http.MethodGet, url, nil, &jobs); err != nil {
+			return err
+		}
+		printJobTable(w, jobs)
+		return nil
+	case cfg.Show != "":
+		var out bytes.Buffer
+		if err := daemonCall(ctx, http.MethodGet, url+"/"+cfg.ID+"/"+cfg.Show, nil, &out); err != nil {
+			return err
+		}
+		_, err := w.Write(out.Bytes())
+		return err
+	}
+	method, url := http.MethodGet, url+"/"+cfg.ID
+	if cfg.Cancel {
+		method, url = http.MethodPost, url+"/cancel"
+	}
+	var job daemonJob
+	if err := daemonCall(ctx, method, url, nil, &job); err != nil {
+		return err
+	}
+	printJobTable(w, []daemonJob{job})
+	if job.Error != "" {
+		fmt.Fprintf(w, "\nError: %s\n", job.Error)
+	}
+	return nil
+}
+
+// printJobTable prints one row per job with its status, submission time,
+// run time and name or command.
+func printJobTable(w io.Writer, jobs []daemonJob) {
+	fmt.Fprintf(w, "%-6s | %-9s | %-19s | %-10s | %s\n", "ID", "Status", "Submitted", "Time", "Job")
+	fmt.Fprintln(w, "-------------------------------------------------------------------")
+	for _, job := range jobs {
+		elapsed := ""
+		if job.Started != nil {
+			end := time.Now()
+			if job.Finished != nil {
+				end = *job.Finished
+			}
+			elapsed = end.Sub(*job.Started).Round(time.Second).String()
+		}
+		what := job.command()
+		if job.Name != "" {
+			what = job.Name
+		}
+		fmt.Fprintf(w, "%-6d | %-9s | %-19s | %-10s | %s\n", job.ID, job.Status, job.Submitted.Local().Format(time.DateTime), elapsed, what)
+	}
+}
+
+// daemonCall sends a request with the given JSON body, if any, to the
+// daemon and decodes its JSON answer into into, or copies it there if into
+// is a *bytes.Buffer. Error answers are returned as errors.
+func daemonCall(ctx context.Context, method, url string, body []byte, into any) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("daemon: %s", cmp.Or(strings.TrimSpace(string(msg)), resp.Status))
+	}
+	if buf, ok := into.(*bytes.Buffer); ok {
+		_, err = buf.ReadFrom(resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(into)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	mrand "math/rand/v2"
+	"slices"
+)
+
+// decodeConfig holds the arguments of the decode command.
+type decodeConfig struct {
+	Dims []int
+	Beta int
+	Q    int64
+	// Radii are the expected error norms, as multiples of the radius
+	// Babai's algorithm always decodes, half the shortest ‖b*ᵢ‖.
+	Radii   []float64
+	Targets int
+	// Candidates and Tries are those of the randomized decoder.
+	Candidates int
+	Tries      int
+}
+
+// parseDecodeFlags builds a decodeConfig from the command line.
+func parseDecodeFlags(args []string) (decodeConfig, error) {
+	fs := flag.NewFlagSet("decode", flag.ContinueOnError)
+	dims := fs.String("n", "20,30", "comma-separated ranks of the random bases")
+	beta := fs.Int("beta", 10, "BKZ block size the bases are reduced with")
+	q := fs.Int64("q", 100003, "coefficient bound of the random bases")
+	radii := fs.String("radius", "0.5,1,1.5,2,3", "comma-separated expected error norms, in units of half the shortest Gram-Schmidt vector")
+	targets := fs.Int("targets", 50, "targets decoded per rank and radius")
+	candidates := fs.Int("candidates", 3, "planes the randomized decoder chooses among at every level")
+	tries := fs.Int("tries", 10, "decodings the randomized decoder keeps the closest of")
+	if err := fs.Parse(args); err != nil {
+		return decodeConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return decodeConfig{}, fmt.Errorf("usage: lattice-labs decode [-n ranks] [-beta b] [-q q] [-radius r,..] [-targets k] [-candidates c] [-tries t]")
+	}
+	cfg := decodeConfig{Beta: *beta, Q: *q, Targets: *targets, Candidates: *candidates, Tries: *tries}
+	dimList, err := parseIntList(*dims)
+	if err != nil {
+		return cfg, fmt.Errorf("-n: %w", err)
+	}
+	for _, n := range dimList {
+		if n < 2 {
+			return cfg, fmt.Errorf("-n: rank must be at least 2, got %d", n)
+		}
+		cfg.Dims = append(cfg.Dims, int(n))
+	}
+	if cfg.Radii, err = parseFloatList(*radii); err != nil {
+		return cfg, fmt.Errorf("-radius: %w", err)
+	}
+	for _, r := range cfg.Radii {
+		if !(r > 0) {
+			return cfg, fmt.Errorf("-radius: radii must be positive, got %g", r)
+		}
+	}
+	if cfg.Q < 2 {
+		return cfg, fmt.Errorf("-q must be at least 2")
+	}
+	if err := bkzStep(cfg.Beta).validate(); err != nil {
+		return cfg, fmt.Errorf("-beta: %w", err)
+	}
+	if cfg.Targets < 1 {
+		return cfg, fmt.Errorf("-targets must be at least 1")
+	}
+	if cfg.Candidates < 1 {
+		return cfg, fmt.Errorf("-candidates must be at least 1")
+	}
+	if cfg.Tries < 1 {
+		return cfg, fmt.Errorf("-tries must be at least 1")
+	}
+	return cfg, nil
+}
+
+// runDecode measures how far the randomized nearest plane decoder
+// (nearestPlane.DecodeRandomized) extends the decoding radius of Babai's.
+// For every rank, a random basis is BKZ-reduced; then, for every radius,
+// cfg.Targets targets are drawn as a random lattice point plus a Gaussian
+// error of that expected norm, and both decoders are asked for the point.
+// Babai's algorithm is certain to find it within half the shortest ‖b*ᵢ‖,
+// the unit of the radii, and fails more and more often beyond; the
+// randomized decoder, told the width of the error, takes the other planes
+// near the target at the levels where that is likely. One row per rank and
+// radius with the success rates of both is written to w.
+func runDecode(ctx context.Context, w io.Writer, cfg decodeConfig) error {
+	if dryRun != nil {
+		return fmt.Errorf("decode reduces bases and has no dry run")
+	}
+	fmt.Fprintf(w, "Decoding radius of Babai and randomized nearest planes (BKZ-%d, q %d, %d candidates, %d tries)\n\n", cfg.Beta, cfg.Q, cfg.Candidates, cfg.Tries)
+	fmt.Fprintf(w, "%-6s | %-8s | %-10s | %-10s | %s\n", "n", "Radius", "Babai", "Randomized", "Gain")
+	fmt.Fprintln(w, "------------------------------------------------------")
+
+	q := big.NewInt(cfg.Q)
+	for _, n := range cfg.Dims {
+		basis := genRandomBasisFrom(trialSource("decode", int64(n), cfg.Q), n, q)
+		reduced, err := bkzReduce(ctx, basis, cfg.Beta, bkzOptions{})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("n %d: BKZ-%d: %w", n, cfg.Beta, err)
+		}
+		if err := checkVolumePreserved(basis, reduced); err != nil {
+			return fmt.Errorf("n %d: BKZ-%d: %w", n, cfg.Beta, err)
+		}
+		np, err := newNearestPlane(reduced)
+		if err != nil {
+			return fmt.Errorf("n %d: %w", n, err)
+		}
+		least := slices.Min(reduced.Profile())
+		unit := math.Exp2(least) / 2
+		for _, radius := range cfg.Radii {
+			// A Gaussian error of width σ per coordinate has norm about σ√n.
+			sigma := radius * unit / math.Sqrt(float64(n))
+			rng := trialRand("decode", int64(n), cfg.Q, int64(math.Float64bits(radius)))
+			opts := randomizedDecoding{Candidates: cfg.Candidates, Sigma: sigma, Tries: cfg.Tries}
+			babai, randomized := 0, 0
+			for range cfg.Targets {
+				if ctx.Err() != nil {
+					return nil
+				}
+				point, target := decodeTarget(rng, reduced, sigma)
+				if got, _, err := np.Decode(target); err != nil {
+					return err
+				} else if equalRows(got, point) {
+					babai++
+				}
+				if got, _, err := np.DecodeRandomized(rng, target, opts); err != nil {
+					return err
+				} else if equalRows(got, point) {
+					randomized++
+				}
+			}
+			b, r := float64(babai)/float64(cfg.Targets), float64(randomized)/float64(cfg.Targets)
+			fmt.Fprintf(w, "%-6d | %-8g | %-10s | %-10s | %+.1f%%\n", n, radius, fmt.Sprintf("%.1f%%", 100*b), fmt.Sprintf("%.1f%%", 100*r), 100*(r-b))
+		}
+	}
+	return nil
+}
+
+// decodeTarget returns a random point of the lattice of basis, with
+// coefficients uniform in [-5, 5], and a target off it by an error whose
+// coordinates are drawn from the discrete Gaussian of width sigma. The
+// decoders are translation invariant, so the point only keeps them honest.
+func decodeTarget(rng *mrand.Rand, basis Basis, sigma float64) (point []*big.Int, target []*big.Rat) {
+	x := make([]float64, len(basis))
+	for i := range x {
+		x[i] = float64(rng.Int64N(11) - 5)
+	}
+	point = combine(basis, x)
+	target = ratVector(point)
+	for k := range target {
+		target[k].Add(target[k], new(big.Rat).SetInt64(sampleZ(rng, sigma, 0)))
+	}
+	return point, target
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// deltaPoint is an entry of a δ0 table: the root Hermite factor BKZ-β
+// reaches in practice.
+type deltaPoint struct {
+	Beta  int
+	Delta float64
+}
+
+// deltaTable is a table of δ0(β), by increasing β with δ0 not increasing.
+type deltaTable []deltaPoint
+
+// defaultDeltaTable is the δ0 table the package ships: LLL and BKZ-20 and
+// BKZ-28 as Gama and Nguyen measured them on random lattices (2008), then
+// the block sizes the BKZ 2.0 simulation of Chen and Nguyen (2011) needs for
+// δ0 from 1.01 down to 1.005.
+var defaultDeltaTable = deltaTable{
+	{2, 1.0219}, {20, 1.0128}, {28, 1.0109},
+	{85, 1.010}, {106, 1.009}, {133, 1.008}, {168, 1.007}, {216, 1.006}, {286, 1.005},
+}
+
+// minDeltaTableBeta is the least block size a δ0 table must reach, from
+// where rootHermiteFactor is above 1 and extends it.
+const minDeltaTableBeta = 40
+
+// deltas is the δ0 table in use: defaultDeltaTable, or the one
+// --delta-table loads. The GSA simulation (SimulateGSAProfile), the LWE
+// estimate and the block-size inverses all read δ0 off it.
+var deltas = defaultDeltaTable
+
+// validate checks that t is a table At can interpolate.
+func (t deltaTable) validate() error {
+	if len(t) == 0 {
+		return fmt.Errorf("the δ0 table is empty")
+	}
+	for i, p := range t {
+		switch {
+		case p.Beta < 2:
+			return fmt.Errorf("δ0 table: block size must be at least 2, got %d", p.Beta)
+		case !(p.Delta > 1) || math.IsInf(p.Delta, 0):
+			return fmt.Errorf("δ0 table: δ0 of β %d must be above 1, got %g", p.Beta, p.Delta)
+		case i > 0 && p.Beta <= t[i-1].Beta:
+			return fmt.Errorf("δ0 table: block sizes must increase, got %d after %d", p.Beta, t[i-1].Beta)
+		case i > 0 && p.Delta > t[i-1].Delta:
+			return fmt.Errorf("δ0 table: δ0 must not grow with β, got %g at β %d after %g at β %d", p.Delta, p.Beta, t[i-1].Delta, t[i-1].Beta)
+		}
+	}
+	if last := t[len(t)-1].Beta; last < minDeltaTableBeta {
+		return fmt.Errorf("δ0 table: the last block size must be at least %d, got %d", minDeltaTableBeta, last)
+	}
+	return nil
+}
+
+// At returns δ0(β): the entry of β, interpolated linearly between the
+// entries around it, or the first entry below the table. Beyond the table
+// the asymptotic rootHermiteFactor takes over, its distance to 1 scaled to
+// meet the last entry, so that δ0 stays continuous and decreasing. t must
+// be valid.
+func (t deltaTable) At(beta int) float64 {
+	if beta <= t[0].Beta {
+		return t[0].Delta
+	}
+	for i := 1; i < len(t); i++ {
+		if beta <= t[i].Beta {
+			lo, hi := t[i-1], t[i]
+			return lerp(float64(lo.Beta), lo.Delta, float64(hi.Beta), hi.Delta, float64(beta))
+		}
+	}
+	last := t[len(t)-1]
+	return 1 + (rootHermiteFactor(beta)-1)*(last.Delta-1)/(rootHermiteFactor(last.Beta)-1)
+}
+
+// lerp returns the value at x of the line through (x0, y0) and (x1, y1).
+func lerp(x0, y0, x1, y1, x float64) float64 {
+	return y0 + (x-x0)/(x1-x0)*(y1-y0)
+}
+
+// merge returns the entries of t followed by those of base beyond its last
+// block size: a table calibrated at small block sizes, extended by base.
+// Entries of base above the last δ0 of t are left out; small ranks reach
+// better δ0 than the shipped table assumes, until larger β catch up.
+func (t deltaTable) merge(base deltaTable) deltaTable {
+	merged := append(deltaTable{}, t...)
+	for _, p := range base {
+		if len(merged) == 0 || p.Beta > merged[len(merged)-1].Beta && p.Delta <= merged[len(merged)-1].Delta {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// WriteCSV writes t to w as CSV with a header row, beta,delta, in the
+// format loadDeltaTable reads.
+func (t deltaTable) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"beta", "delta"})
+	for _, p := range t {
+		cw.Write([]string{strconv.Itoa(p.Beta), formatCSVFloat(p.Delta)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// loadDeltaTable reads a δ0 table as WriteCSV writes it and checks it.
+func loadDeltaTable(path string) (deltaTable, error) {
+	f, err := os.Open(expandHome(path))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 || len(records[0]) != 2 || records[0][0] != "beta" || records[0][1] != "delta" {
+		return nil, fmt.Errorf("%s is not a δ0 table: want the header beta,delta", path)
+	}
+	var t deltaTable
+	for i, rec := range records[1:] {
+		beta, err := strconv.Atoi(rec[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, i+2, err)
+		}
+		delta, err := strconv.ParseFloat(rec[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, i+2, err)
+		}
+		t = append(t, deltaPoint{Beta: beta, Delta: delta})
+	}
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// calibrateConfig holds the arguments of the calibrate command.
+type calibrateConfig struct {
+	Rank   int
+	Q      int64
+	Betas  []int
+	Trials int
+	// Out is the file the calibrated table is written to, if any.
+	Out string
+}
+
+// parseCalibrateFlags builds a calibrateConfig from the command line.
+func parseCalibrateFlags(args []string) (calibrateConfig, error) {
+	fs := flag.NewFlagSet("calibrate", flag.ContinueOnError)
+	rank := fs.Int("n", 60, "rank of the random bases")
+	q := fs.Int64("q", 100003, "coefficient bound of the random bases")
+	betas := fs.String("beta", "2,10,20,30", "comma-separated BKZ block sizes to measure")
+	trials := fs.Int("trials", 3, "random bases per block size")
+	out := fs.String("o", "", "write the calibrated table to this file, for --delta-table")
+	if err := fs.Parse(args); err != nil {
+		return calibrateConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return calibrateConfig{}, fmt.Errorf("usage: lattice-labs calibrate [-n rank] [-q q] [-beta betas] [-trials k] [-o file]")
+	}
+	cfg := calibrateConfig{Rank: *rank, Q: *q, Trials: *trials, Out: *out}
+	if cfg.Rank < 2 {
+		return cfg, fmt.Errorf("-n: rank must be at least 2, got %d", cfg.Rank)
+	}
+	if cfg.Q < 2 {
+		return cfg, fmt.Errorf("-q must be at least 2")
+	}
+	betaList, err := parseIntList(*betas)
+	if err != nil {
+		return cfg, fmt.Errorf("-beta: %w", err)
+	}
+	for i, beta := range betaList {
+		if err := bkzStep(int(beta)).validate(); err != nil {
+			return cfg, fmt.Errorf("-beta: %w", err)
+		}
+		if i > 0 && int(beta) <= cfg.Betas[i-1] {
+			return cfg, fmt.Errorf("-beta: block sizes must increase, got %d after %d", beta, cfg.Betas[i-1])
+		}
+		cfg.Betas = append(cfg.Betas, int(beta))
+	}
+	if cfg.Trials < 1 {
+		return cfg, fmt.Errorf("-trials must be at least 1")
+	}
+	return cfg, nil
+}
+
+// runCalibrate measures δ0(β) as Lab 2 does: cfg.Trials random bases of
+// rank cfg.Rank, seeded by --seed, are BKZ-β reduced for every block size,
+// and the mean Profile.RHF of the reduced bases is written to w next to
+// the δ0 the table in use predicts. The measurements, extended beyond the
+// largest block size by the table in use (deltaTable.merge), make the
+// calibrated table, which is written to cfg.Out for --delta-table if it is
+// a valid one.
+func runCalibrate(ctx context.Context, w io.Writer, cfg calibrateConfig) error {
+	if dryRun != nil {
+		return fmt.Errorf("calibrate reduces random bases and has no dry run")
+	}
+	fmt.Fprintf(w, "δ0 calibration (rank %d, q %d, %d bases per block size)\n\n", cfg.Rank, cfg.Q, cfg.Trials)
+	fmt.Fprintf(w, "%-6s | %-10s | %-10s | %s\n", "Beta", "Measured", "Std dev", "Table")
+	fmt.Fprintln(w, "-------------------------------------------")
+
+	q := big.NewInt(cfg.Q)
+	var measured deltaTable
+	for _, beta := range cfg.Betas {
+		rhfs := make([]float64, cfg.Trials)
+		for trial := range rhfs {
+			basis := genRandomBasisFrom(trialSource("calibrate", int64(cfg.Rank), cfg.Q, int64(trial+1)), cfg.Rank, q)
+			reduced, err := bkzReduce(ctx, basis, beta, bkzOptions{})
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("BKZ-%d trial %d: %w", beta, trial+1, err)
+			}
+			if err := checkVolumePreserved(basis, reduced); err != nil {
+				return fmt.Errorf("BKZ-%d trial %d: %w", beta, trial+1, err)
+			}
+			rhfs[trial] = reduced.Profile().RHF()
+		}
+		mean, std := stat.MeanStdDev(rhfs, nil)
+		if cfg.Trials == 1 {
+			std = 0
+		}
+		fmt.Fprintf(w, "%-6d | %-10.6f | %-10.6f | %.6f\n", beta, mean, std, deltas.At(beta))
+		measured = append(measured, deltaPoint{Beta: beta, Delta: mean})
+	}
+	if cfg.Out == "" {
+		return nil
+	}
+	calibrated := measured.merge(deltas)
+	if err := calibrated.validate(); err != nil {
+		return fmt.Errorf("not writing %s: %w (more trials or a larger rank may help)", cfg.Out, err)
+	}
+	path, err := outputPath(cfg.Out)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := calibrated.WriteCSV(f); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nCalibrated table written to %s (use it with --delta-table)\n", path)
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// dryRun, if set, receives a description of every fplll invocation and output
+// file instead of them being executed or written (--dry-run). The labs
+// then continue with the unreduced basis and a placeholder λ1 of 1.5 times
+// the Gaussian Heuristic.
+var dryRun io.Writer
+
+// dryRunMu keeps the description of each call together when several calls
+// are planned concurrently (--jobs).
+var dryRunMu sync.Mutex
+
+// planFplll describes an fplll call on basis to dryRun.
+func planFplll(basis Basis, args ...string) {
+	planCommand(backend.Binary, basis, args...)
+}
+
+// planCommand records to dryRun that binary would be run with args on
+// basis, like planFplll for executables other than fplll.
+func planCommand(binary string, basis Basis, args ...string) {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+	cols := 0
+	if len(basis) > 0 {
+		cols = len(basis[0])
+	}
+	fmt.Fprintf(dryRun, "[dry-run] %s < %dx%d basis on standard input\n", strings.Join(append([]string{binary}, args...), " "), len(basis), cols)
+}
+
+// planEnvironment describes to dryRun which fplll binary would be used.
+func planEnvironment() {
+	path, err := resolveFplll(backend.Binary)
+	if err != nil {
+		fmt.Fprintf(dryRun, "[dry-run] fplll: %v\n", err)
+		return
+	}
+	fmt.Fprintf(dryRun, "[dry-run] fplll resolves to %s\n", path)
+	if backend.Flatter != "" {
+		fmt.Fprintf(dryRun, "[dry-run] flatter resolves to %s\n", backend.Flatter)
+	}
+	if backend.Library != "" {
+		fmt.Fprintf(dryRun, "[dry-run] fplll calls go through libfplll at %s\n", backend.Library)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"slices"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// reversedDualBasis returns a basis of the dual lattice of basis, the
+// rows of (B·Bᵀ)⁻¹·B in reverse order, scaled by the least common
+// denominator of their entries so that it is integral, and that scale. Its
+// profile is basis.Profile().Dual() shifted by log2(scale). The rows of
+// basis must be linearly independent.
+func reversedDualBasis(basis Basis) (dual Basis, scale *big.Int, err error) {
+	inv, err := invertRat(basis.Gram())
+	if err != nil {
+		return nil, nil, fmt.Errorf("the basis has linearly dependent rows: %w", err)
+	}
+	scale, scaled := integralMultiple(mulRatInt(inv, basis))
+	slices.Reverse(scaled)
+	return scaled, scale, nil
+}
+
+// dualConfig holds the arguments of the dual command.
+type dualConfig struct {
+	Dims   []int
+	Trials int
+	Beta   int
+	Q      int64
+	// Tolerance is the largest difference, in bits, allowed between the
+	// profile of the reversed dual basis and the reversed-negated primal
+	// profile.
+	Tolerance float64
+}
+
+// parseDualFlags builds a dualConfig from the command line.
+func parseDualFlags(args []string) (dualConfig, error) {
+	fs := flag.NewFlagSet("dual", flag.ContinueOnError)
+	dims := fs.String("n", "20,30,40", "comma-separated ranks of the random bases")
+	trials := fs.Int("trials", 2, "random bases per rank")
+	beta := fs.Int("beta", 10, "BKZ block size of the primal and the dual reduction")
+	q := fs.Int64("q", 100003, "coefficient bound of the random bases")
+	tol := fs.Float64("tol", 1e-6, "largest difference of a dual profile entry from the reversed-negated primal one, in bits")
+	if err := fs.Parse(args); err != nil {
+		return dualConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return dualConfig{}, fmt.Errorf("usage: lattice-labs dual [-n ranks] [-trials k] [-beta b] [-q q] [-tol bits]")
+	}
+	cfg := dualConfig{Trials: *trials, Beta: *beta, Q: *q, Tolerance: *tol}
+	dimList, err := parseIntList(*dims)
+	if err != nil {
+		return cfg, fmt.Errorf("-n: %w", err)
+	}
+	for _, n := range dimList {
+		if n < 2 {
+			return cfg, fmt.Errorf("-n: rank must be at least 2, got %d", n)
+		}
+		cfg.Dims = append(cfg.Dims, int(n))
+	}
+	if cfg.Trials < 1 {
+		return cfg, fmt.Errorf("-trials must be at least 1")
+	}
+	if cfg.Q < 2 {
+		return cfg, fmt.Errorf("-q must be at least 2")
+	}
+	if err := bkzStep(cfg.Beta).validate(); err != nil {
+		return cfg, fmt.Errorf("-beta: %w", err)
+	}
+	if !(cfg.Tolerance >= 0) {
+		return cfg, fmt.Errorf("-tol must not be negative, got %g", cfg.Tolerance)
+	}
+	return cfg, nil
+}
+
+// runDual runs the primal/dual symmetry experiment on random bases: each
+// is BKZ-reduced, and the profile of its reversed dual basis, computed
+// exactly by reversedDualBasis, must be the reversed-negated primal
+// profile (Profile.Dual) within cfg.Tolerance bits. The reversed dual basis
+// is then BKZ-reduced with the same block size, and the slopes of the two
+// reduced profiles are compared: BKZ reduces a lattice and its dual about
+// equally well, so the slopes should agree up to the spread between
+// trials. One row per instance is written to w; profiles off the symmetry
+// are returned as an *assertionError, while the slopes are only reported.
+func runDual(ctx context.Context, w io.Writer, cfg dualConfig) error {
+	if dryRun != nil {
+		return fmt.Errorf("dual compares reduced profiles and has no dry run")
+	}
+	fmt.Fprintf(w, "Primal/dual symmetry of BKZ-%d (q %d)\n\n", cfg.Beta, cfg.Q)
+	fmt.Fprintf(w, "%-6s | %-5s | %-12s | %-12s | %-10s | %s\n", "n", "Trial", "Primal slope", "Dual slope", "Max Δ dual", "Result")
+	fmt.Fprintln(w, "----------------------------------------------------------------------")
+
+	var violations []string
+	var primalSlopes, dualSlopes []float64
+	q := big.NewInt(cfg.Q)
+	for _, n := range cfg.Dims {
+		for trial := 1; trial <= cfg.Trials; trial++ {
+			basis := genRandomBasisFrom(trialSource("dual", int64(n), cfg.Q, int64(trial)), n, q)
+			r, err := dualInstance(ctx, basis, cfg)
+			if ctx.Err() != nil {
+				return nil
+			}
+			result := "ok"
+			switch {
+			case err != nil:
+				result = "FAILED"
+				violations = append(violations, fmt.Sprintf("n %d trial %d: %v", n, trial, err))
+			case !(r.deviation <= cfg.Tolerance):
+				result = "ASYMMETRIC"
+				violations = append(violations, fmt.Sprintf("n %d trial %d: the dual profile differs from the reversed-negated primal one by %.3g bits at index %d", n, trial, r.deviation, r.at+1))
+			}
+			if err != nil {
+				fmt.Fprintf(w, "%-6d | %-5d | %-12s | %-12s | %-10s | %s\n", n, trial, "-", "-", "-", result)
+				continue
+			}
+			primalSlopes, dualSlopes = append(primalSlopes, r.primalSlope), append(dualSlopes, r.dualSlope)
+			fmt.Fprintf(w, "%-6d | %-5d | %-12.6f | %-12.6f | %-10.3g | %s\n", n, trial, r.primalSlope, r.dualSlope, r.deviation, result)
+		}
+	}
+	if len(primalSlopes) > 0 {
+		p, d := stat.Mean(primalSlopes, nil), stat.Mean(dualSlopes, nil)
+		fmt.Fprintf(w, "\nMean slope: primal %.6f, dual %.6f (difference %.2f%%)\n", p, d, 100*math.Abs(d-p)/math.Abs(p))
+	}
+	if len(violations) > 0 {
+		return &assertionError{Kind: "primal/dual asymmetry(ies)", Violations: violations}
+	}
+	return nil
+}
+
+// dualResult is what dualInstance measures on one basis.
+type dualResult struct {
+	primalSlope, dualSlope float64
+	// deviation is the largest difference between the profile of the
+	// reversed dual basis and the reversed-negated primal profile, at
+	// index at.
+	deviation float64
+	at        int
+}
+
+// dualInstance runs one basis through the experiment of runDual.
+func dualInstance(ctx context.Context, basis Basis, cfg dualConfig) (dualResult, error) {
+	var r dualResult
+	primal, err := bkzReduce(ctx, basis, cfg.Beta, bkzOptions{})
+	if err != nil {
+		return r, fmt.Errorf("primal BKZ-%d: %w", cfg.Beta, err)
+	}
+	profile := primal.Profile()
+	dual, scale, err := reversedDualBasis(primal)
+	if err != nil {
+		return r, err
+	}
+	// The dual basis is integral only scaled, which shifts its profile.
+	shift := log2Int(scale)
+	measured := slices.Clone(dual.Profile())
+	for i := range measured {
+		measured[i] -= shift
+	}
+	r.deviation, r.at = profileDeviation(measured, profile.Dual())
+
+	reducedDual, err := bkzReduce(ctx, dual, cfg.Beta, bkzOptions{})
+	if err != nil {
+		return r, fmt.Errorf("dual BKZ-%d: %w", cfg.Beta, err)
+	}
+	if err := checkVolumePreserved(dual, reducedDual); err != nil {
+		return r, fmt.Errorf("dual BKZ-%d: %w", cfg.Beta, err)
+	}
+	r.primalSlope, r.dualSlope = profile.Slope(), reducedDual.Profile().Slope()
+	return r, nil
+}
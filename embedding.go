@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// errEmbeddingMissed is returned by CVPViaEmbedding when the shortest vector
+// of the embedding lattice isn't the embedded error: the target is too far
+// from the lattice for Kannan's embedding to find its closest point.
+var errEmbeddingMissed = errors.New("the shortest vector of the embedding is not the target's error; the target may be too far from the lattice")
+
+// embeddingFactor returns the embedding factor M CVPViaEmbedding uses for
+// basis, ⌈GH(L)/2⌉ for the Gaussian Heuristic GH(L) of its lattice, and at
+// least 1. With it the embedded error (t-v, M) is shorter than GH(L), the
+// predicted λ1, for every target within √3/2·GH(L) of the lattice, while
+// the vectors using the target row k ≥ 2 times have a last entry of kM ≥
+// GH(L) and are predicted to lose to it.
+func embeddingFactor(basis Basis) *big.Int {
+	gh := gaussianHeuristic(basis.LogVolume(), len(basis))
+	m, _ := big.NewFloat(math.Ceil(gh / 2)).Int(nil)
+	if m.Sign() <= 0 {
+		m.SetInt64(1)
+	}
+	return m
+}
+
+// embed returns Kannan's embedding of target into the lattice of basis with
+// factor m: the rows (bᵢ, 0) followed by (target, m).
+func embed(basis Basis, target []*big.Int, m *big.Int) Basis {
+	embedded := make(Basis, len(basis)+1)
+	for i, row := range basis {
+		embedded[i] = append(append(make([]*big.Int, 0, len(row)+1), row...), new(big.Int))
+	}
+	embedded[len(basis)] = append(append(make([]*big.Int, 0, len(target)+1), target...), m)
+	return embedded
+}
+
+// CVPViaEmbedding finds the lattice point of basis closest to target by
+// Kannan's embedding, and returns it with its distance from the target, as
+// cvpOracle does: the shortest vector of the lattice of embed(basis,
+// target, M) is ±(target - v, M) for the closest point v if the target is
+// close enough, and v is read off it. M is chosen from the Gaussian
+// Heuristic by embeddingFactor, and the shortest vector found with
+// svpOracle, so that CVP is an SVP call on a lattice of one more dimension.
+// If the shortest vector doesn't use the target row exactly once,
+// errEmbeddingMissed is returned. In a dry run the SVP call is planned and
+// the target returned at distance 0.
+func CVPViaEmbedding(ctx context.Context, basis Basis, target []*big.Int) ([]*big.Int, float64, error) {
+	if len(target) != len(basis[0]) {
+		return nil, 0, fmt.Errorf("target has %d entries, the basis vectors %d", len(target), len(basis[0]))
+	}
+	m := embeddingFactor(basis)
+	_, shortest, err := svpOracle(ctx, embed(basis, target, m), 0, "")
+	if err != nil {
+		return nil, 0, err
+	}
+	if dryRun != nil {
+		return target, 0, nil
+	}
+	errVec := shortest[:len(target)]
+	switch last := shortest[len(target)]; {
+	case last.Cmp(m) == 0:
+	case new(big.Int).Neg(last).Cmp(m) == 0:
+		for i, e := range errVec {
+			errVec[i] = new(big.Int).Neg(e)
+		}
+	default:
+		return nil, 0, fmt.Errorf("embedding factor %v: %w", m, errEmbeddingMissed)
+	}
+	point := make([]*big.Int, len(target))
+	for i := range point {
+		point[i] = new(big.Int).Sub(target[i], errVec[i])
+	}
+	return point, math.Sqrt(vectorNormSquared(errVec)), nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/big"
+	"slices"
+)
+
+// gsoFloat is the Gram-Schmidt orthogonalization of a basis in float64, as
+// enumeration needs it: mu[i][j] = <b_i, b*_j> / ‖b*_j‖² for j < i and
+// r[i] = ‖b*_i‖². It is accurate enough on LLL-reduced bases of the ranks
+// enumeration can handle.
+type gsoFloat struct {
+	mu [][]float64
+	r  []float64
+}
+
+// newGSOFloat computes the orthogonalization of basis from its exact Gram
+// matrix by the Cholesky recurrence.
+func newGSOFloat(basis Basis) gsoFloat {
+	g := intMatrixOf(basis).gram().float64Rows()
+	n := len(g)
+	gso := gsoFloat{mu: make([][]float64, n), r: make([]float64, n)}
+	a := make([][]float64, n) // a[i][j] = mu[i][j]·r[j]
+	for i := range n {
+		gso.mu[i], a[i] = make([]float64, i), make([]float64, i)
+		for j := range i {
+			s := g[i][j]
+			for k := range j {
+				s -= gso.mu[j][k] * a[i][k]
+			}
+			a[i][j], gso.mu[i][j] = s, s/gso.r[j]
+		}
+		s := g[i][i]
+		for k := range i {
+			s -= gso.mu[i][k] * a[i][k]
+		}
+		gso.r[i] = s
+	}
+	return gso
+}
+
+// enumSlack widens the float64 radius test of enumeration so that vectors
+// on its boundary aren't lost to rounding; visit checks them exactly.
+const enumSlack = 1e-9
+
+// enumCheckInterval is how many enumeration nodes are visited between
+// checks for cancellation.
+const enumCheckInterval = 1 << 16
+
+// enumerator is Schnorr-Euchner enumeration of the lattice vectors
+// Σ x_i b_i with ‖·‖² at most bound: a depth-first search over the
+// coefficients from the last to the first, visiting the values of each
+// x_i in order of their distance from its center. Of every pair ±v only the
+// vector whose last non-zero coefficient is positive is visited, and the
+// zero vector never. visit may lower bound to shrink the search.
+type enumerator struct {
+	ctx   context.Context
+	gso   gsoFloat
+	x     []float64
+	bound float64
+	visit func(x []float64)
+	nodes int64
+	err   error
+}
+
+// newEnumerator prepares the enumeration of the vectors of basis within
+// squared radius bound.
+func newEnumerator(ctx context.Context, basis Basis, bound float64) *enumerator {
+	return &enumerator{ctx: ctx, gso: newGSOFloat(basis), x: make([]float64, len(basis)), bound: bound}
+}
+
+// run enumerates and returns ctx's error if it was cancelled on the way.
+func (e *enumerator) run() error {
+	if n := len(e.x); n > 0 {
+		e.search(n-1, 0, true)
+	}
+	slog.Debug("enumeration finished", "rank", len(e.x), "nodes", e.nodes)
+	return e.err
+}
+
+// search chooses x_i, the coefficients above it being set, with the
+// squared length of the projection so far in partial. zeroAbove says that
+// all coefficients above are zero.
+func (e *enumerator) search(i int, partial float64, zeroAbove bool) {
+	e.nodes++
+	if e.nodes%enumCheckInterval == 0 {
+		if e.err = e.ctx.Err(); e.err != nil {
+			return
+		}
+	}
+	c := 0.0
+	for j := i + 1; j < len(e.x); j++ {
+		c -= e.x[j] * e.gso.mu[j][i]
+	}
+	// Candidates above and below the center, each side in order of
+	// distance; the nearer of the two is taken next.
+	up := math.Round(c)
+	down := up - 1
+	r := e.gso.r[i]
+	for {
+		limit := e.bound * (1 + enumSlack)
+		dUp := partial + (up-c)*(up-c)*r
+		dDown := partial + (down-c)*(down-c)*r
+		upOK := dUp <= limit
+		downOK := !zeroAbove && dDown <= limit // x_i < 0 would give -v
+		if !upOK && !downOK {
+			return
+		}
+		var xi, d float64
+		if upOK && (!downOK || dUp <= dDown) {
+			xi, d = up, dUp
+			up++
+		} else {
+			xi, d = down, dDown
+			down--
+		}
+		e.x[i] = xi
+		switch {
+		case i > 0:
+			e.search(i-1, d, zeroAbove && xi == 0)
+		case !zeroAbove || xi != 0:
+			e.visit(e.x)
+		}
+		if e.err != nil {
+			return
+		}
+	}
+}
+
+// combine returns the lattice vector Σ x_i b_i.
+func combine(basis Basis, x []float64) []*big.Int {
+	v := make([]*big.Int, len(basis[0]))
+	for j := range v {
+		v[j] = new(big.Int)
+	}
+	coeff, term := new(big.Int), new(big.Int)
+	for i, xi := range x {
+		if xi == 0 {
+			continue
+		}
+		coeff.SetInt64(int64(xi))
+		for j, b := range basis[i] {
+			v[j].Add(v[j], term.Mul(coeff, b))
+		}
+	}
+	return v
+}
+
+// normSquared returns the exact squared Euclidean norm of v.
+func normSquared(v []*big.Int) *big.Int {
+	sum, sq := new(big.Int), new(big.Int)
+	for _, x := range v {
+		sum.Add(sum, sq.Mul(x, x))
+	}
+	return sum
+}
+
+// compareRadius compares a squared norm with radius², returning -1, 0 or
+// +1 as the norm is below, on or beyond the radius.
+func compareRadius(normSq *big.Int, radius float64) int {
+	r := big.NewFloat(radius)
+	return new(big.Float).SetInt(normSq).Cmp(r.Mul(r, r))
+}
+
+// enumerationBasis LLL-reduces basis with fplll for enumeration. The rows
+// of a dependent basis that LLL leaves vanishing at the top are dropped.
+func enumerationBasis(ctx context.Context, basis Basis) (Basis, error) {
+	reduced, err := lllReduce(ctx, basis)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(reduced, func(row []*big.Int) bool { return normSquared(row).Sign() == 0 }), nil
+}
+
+// errNoVectorInRadius is returned by radius-bounded SVP queries when the
+// lattice has no non-zero vector shorter than the radius.
+var errNoVectorInRadius = errors.New("no non-zero lattice vector shorter than the radius")
+
+// boundedSVP answers the query "is there a non-zero vector shorter than
+// radius": it LLL-reduces the basis with fplll and enumerates the vectors
+// below the radius natively, shrinking the radius to every shorter vector
+// found, and returns the shortest one. fplll's command line can't bound an
+// SVP search, and a small radius prunes most of the enumeration tree. If
+// there is no such vector errNoVectorInRadius is returned.
+func boundedSVP(ctx context.Context, basis Basis, radius float64) ([]*big.Int, error) {
+	reduced, err := enumerationBasis(ctx, basis)
+	if err != nil {
+		return nil, err
+	}
+	var vector []*big.Int
+	var vectorNorm *big.Int
+	e := newEnumerator(ctx, reduced, radius*radius)
+	e.visit = func(x []float64) {
+		v := combine(reduced, x)
+		norm := normSquared(v)
+		if compareRadius(norm, radius) >= 0 || vector != nil && norm.Cmp(vectorNorm) >= 0 {
+			return
+		}
+		vector, vectorNorm = v, norm
+		e.bound, _ = new(big.Float).SetInt(norm).Float64()
+	}
+	if err := e.run(); err != nil {
+		return nil, err
+	}
+	if vector == nil {
+		return nil, fmt.Errorf("%w %g", errNoVectorInRadius, radius)
+	}
+	return vector, nil
+}
+
+// countVectors returns the number of non-zero lattice vectors of norm at
+// most radius, v and -v counted separately, as the point-counting form of
+// the Gaussian Heuristic and kissing numbers need them. Like boundedSVP it
+// LLL-reduces the basis with fplll and enumerates natively, since fplll has
+// no counting mode; the cost grows with the count, so the radius should
+// stay within a small multiple of λ1. In a dry run only the LLL call is
+// planned and 0 is returned.
+func countVectors(ctx context.Context, basis Basis, radius float64) (int64, error) {
+	reduced, err := enumerationBasis(ctx, basis)
+	if err != nil || dryRun != nil {
+		return 0, err
+	}
+	var count int64
+	e := newEnumerator(ctx, reduced, radius*radius)
+	e.visit = func(x []float64) {
+		if compareRadius(normSquared(combine(reduced, x)), radius) <= 0 {
+			count += 2
+		}
+	}
+	if err := e.run(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+)
+
+// costModel is a core-SVP cost model: the log2 of the cost of one SVP call
+// in dimension β, which core-SVP takes as the cost of BKZ-β and of the
+// attacks that need it, ignoring the number of calls and the
+// polynomial factors, so that it errs on the side of security.
+type costModel struct {
+	Name        string
+	Description string
+	Log2Cost    func(beta float64) float64
+}
+
+// costModels are the core-SVP cost models estimate knows, in the order it
+// prints them.
+var costModels = []costModel{
+	{"classical", "sieving, 2^(0.292β) (Becker, Ducas, Gama and Laarhoven)", func(b float64) float64 { return 0.292 * b }},
+	{"quantum", "quantum sieving, 2^(0.265β) (Laarhoven)", func(b float64) float64 { return 0.265 * b }},
+	{"paranoid", "the sieving lower bound, 2^(0.2075β) (NewHope)", func(b float64) float64 { return 0.2075 * b }},
+	{"enum", "enumeration, 2^(0.270β·ln β - 1.019β + 16.1) (Chen and Nguyen's simulation, fitted)", func(b float64) float64 {
+		return 0.270189*b*math.Log(b) - 1.0192*b + 16.10
+	}},
+	{"enum-qsl", "quasi-linear enumeration, 2^(0.125β·log2 β - 0.547β + 10.4) (Albrecht et al.)", func(b float64) float64 {
+		return 0.125*b*math.Log2(b) - 0.547*b + 10.4
+	}},
+}
+
+// costModelNames returns the names of costModels, for usage messages.
+func costModelNames() string {
+	names := make([]string, len(costModels))
+	for i, m := range costModels {
+		names[i] = m.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// lookupCostModel returns the cost model called name.
+func lookupCostModel(name string) (costModel, error) {
+	i := slices.IndexFunc(costModels, func(m costModel) bool { return m.Name == name })
+	if i < 0 {
+		return costModel{}, fmt.Errorf("unknown cost model %q (want %s)", name, costModelNames())
+	}
+	return costModels[i], nil
+}
+
+// SecurityBits returns the bit security core-SVP under model assigns to an
+// attack that needs BKZ-β: log2 of the cost of an SVP call in dimension β.
+func (m costModel) SecurityBits(beta int) float64 {
+	return m.Log2Cost(float64(beta))
+}
+
+// RequiredBeta returns the least block size whose core-SVP cost under model
+// reaches bits, the β the best attack on a scheme must need for it to
+// claim that security. bits must be finite.
+func (m costModel) RequiredBeta(bits float64) int {
+	beta := 2
+	for m.SecurityBits(beta) < bits {
+		beta++
+	}
+	return beta
+}
+
+// estimateConfig holds the arguments of the estimate command.
+type estimateConfig struct {
+	Betas []int
+	// Bits are security levels to print the required block sizes of.
+	Bits   []float64
+	Models []costModel
+}
+
+// parseEstimateFlags builds an estimateConfig from the command line.
+func parseEstimateFlags(args []string) (estimateConfig, error) {
+	fs := flag.NewFlagSet("estimate", flag.ContinueOnError)
+	betas := fs.String("beta", "100,200,300,400,500", "comma-separated block sizes an attack needs")
+	bits := fs.String("bits", "", "comma-separated security levels to print the required block sizes of, such as 128,192,256")
+	models := fs.String("model", "all", "comma-separated cost models, or all: "+costModelNames())
+	if err := fs.Parse(args); err != nil {
+		return estimateConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return estimateConfig{}, fmt.Errorf("usage: lattice-labs estimate [-beta betas] [-bits levels] [-model models]")
+	}
+	var cfg estimateConfig
+	betaList, err := parseIntList(*betas)
+	if err != nil {
+		return cfg, fmt.Errorf("-beta: %w", err)
+	}
+	for _, beta := range betaList {
+		if beta < 2 {
+			return cfg, fmt.Errorf("-beta: block size must be at least 2, got %d", beta)
+		}
+		cfg.Betas = append(cfg.Betas, int(beta))
+	}
+	if *bits != "" {
+		if cfg.Bits, err = parseFloatList(*bits); err != nil {
+			return cfg, fmt.Errorf("-bits: %w", err)
+		}
+		for _, b := range cfg.Bits {
+			if !(b > 0) || b > 1e6 {
+				return cfg, fmt.Errorf("-bits: security levels must be in (0, 1e6], got %g", b)
+			}
+		}
+	}
+	if *models == "all" {
+		cfg.Models = costModels
+		return cfg, nil
+	}
+	for _, name := range strings.Split(*models, ",") {
+		m, err := lookupCostModel(strings.TrimSpace(name))
+		if err != nil {
+			return cfg, fmt.Errorf("-model: %w", err)
+		}
+		cfg.Models = append(cfg.Models, m)
+	}
+	return cfg, nil
+}
+
+// runEstimate writes to w the bit security every cost model of cfg assigns
+// to each block size, one row per block size, then the block size each
+// model requires for every level of cfg.Bits, and a legend of the models.
+func runEstimate(w io.Writer, cfg estimateConfig) error {
+	bw := bufio.NewWriter(w)
+	header := fmt.Sprintf("%-6s", "Beta")
+	for _, m := range cfg.Models {
+		header += fmt.Sprintf(" | %-9s", m.Name)
+	}
+	fmt.Fprintf(bw, "%s\n%s\n", strings.TrimRight(header, " "), strings.Repeat("-", 6+12*len(cfg.Models)))
+	for _, beta := range cfg.Betas {
+		row := fmt.Sprintf("%-6d", beta)
+		for _, m := range cfg.Models {
+			row += fmt.Sprintf(" | %-9.1f", m.SecurityBits(beta))
+		}
+		fmt.Fprintln(bw, strings.TrimRight(row, " "))
+	}
+	if len(cfg.Bits) > 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, bits := range cfg.Bits {
+		required := make([]string, len(cfg.Models))
+		for i, m := range cfg.Models {
+			required[i] = fmt.Sprintf("%s %d", m.Name, m.RequiredBeta(bits))
+		}
+		fmt.Fprintf(bw, "Required β for %g bits: %s\n", bits, strings.Join(required, ", "))
+	}
+	fmt.Fprintln(bw)
+	for _, m := range cfg.Models {
+		fmt.Fprintf(bw, "%-9s  %s\n", m.Name, m.Description)
+	}
+	return bw.Flush()
+}
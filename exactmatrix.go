@@ -0,0 +1,311 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Exact linear algebra over the integers and the rationals, for the checks
+// that must not depend on floating point: lattice membership, equality of
+// lattices, unimodularity of transformations and dual bases. Matrices are
+// slices of rows; the functions never modify their arguments.
+
+// errNoSolution is returned by solveRat for a system without a solution.
+var errNoSolution = errors.New("the system has no solution")
+
+// dependentColumnError is returned by solveRat and invertRat when column
+// Col (counted from 0) of the matrix is a linear combination of the
+// columns before it, so that a solution isn't unique.
+type dependentColumnError struct{ Col int }
+
+func (e dependentColumnError) Error() string {
+	return fmt.Sprintf("column %d depends linearly on the columns before it", e.Col+1)
+}
+
+// identityMatrix returns the n×n identity matrix.
+func identityMatrix(n int) [][]*big.Int {
+	m := make([][]*big.Int, n)
+	for i := range m {
+		m[i] = make([]*big.Int, n)
+		for j := range m[i] {
+			m[i][j] = new(big.Int)
+		}
+		m[i][i].SetInt64(1)
+	}
+	return m
+}
+
+// mulMatrices returns the product a·b of integer matrices.
+func mulMatrices(a, b [][]*big.Int) [][]*big.Int {
+	cols := 0
+	if len(b) > 0 {
+		cols = len(b[0])
+	}
+	p := make([][]*big.Int, len(a))
+	term := new(big.Int)
+	for i, row := range a {
+		p[i] = make([]*big.Int, cols)
+		for j := range cols {
+			sum := new(big.Int)
+			for k, x := range row {
+				if x.Sign() != 0 {
+					sum.Add(sum, term.Mul(x, b[k][j]))
+				}
+			}
+			p[i][j] = sum
+		}
+	}
+	return p
+}
+
+// transpose returns the transpose of the matrix m, sharing its entries.
+func transpose(m [][]*big.Int) [][]*big.Int {
+	if len(m) == 0 {
+		return nil
+	}
+	t := make([][]*big.Int, len(m[0]))
+	for i := range t {
+		t[i] = make([]*big.Int, len(m))
+		for j := range m {
+			t[i][j] = m[j][i]
+		}
+	}
+	return t
+}
+
+// augmentedMatrix returns a copy of the integer matrix m with extra zero
+// columns appended to every row.
+func augmentedMatrix(m [][]*big.Int, extra int) [][]*big.Int {
+	a := make([][]*big.Int, len(m))
+	for i, row := range m {
+		a[i] = make([]*big.Int, len(row)+extra)
+		for j, v := range row {
+			a[i][j] = new(big.Int).Set(v)
+		}
+		for j := len(row); j < len(a[i]); j++ {
+			a[i][j] = new(big.Int)
+		}
+	}
+	return a
+}
+
+// gaussJordan brings the integer matrix a in place to reduced row echelon
+// form scaled by an integer d, by fraction-free Gauss-Jordan elimination:
+// pivots are chosen among the first cols columns only, so that the columns
+// after them can hold right-hand sides, and every row is updated as in
+// determinant,
+//
+//	aᵢⱼ = (aₖₖ·aᵢⱼ - aᵢₖ·aₖⱼ) / d
+//
+// with the previous pivot d, which divides exactly since every entry stays
+// a minor of a. No rationals and their gcds are involved, and the entries
+// stay below Hadamard's bound. It returns the pivot columns in order, whose
+// number is the rank of the first cols columns, and d, the last pivot:
+// row i of the result has d in column pivots[i] and 0 in the other pivot
+// columns, so dividing it by d gives the rational reduced row echelon form.
+func gaussJordan(a [][]*big.Int, cols int) (pivots []int, d *big.Int) {
+	d = big.NewInt(1)
+	var t big.Int
+	row := 0
+	for col := 0; col < cols && row < len(a); col++ {
+		p := row
+		for p < len(a) && a[p][col].Sign() == 0 {
+			p++
+		}
+		if p == len(a) {
+			continue
+		}
+		a[row], a[p] = a[p], a[row]
+		pivot := a[row][col]
+		for r := range a {
+			if r == row {
+				continue
+			}
+			f := new(big.Int).Set(a[r][col])
+			for k := range a[r] {
+				if k == col {
+					continue
+				}
+				a[r][k].Mul(a[r][k], pivot)
+				a[r][k].Sub(a[r][k], t.Mul(f, a[row][k]))
+				a[r][k].Quo(a[r][k], d)
+			}
+			a[r][col].SetInt64(0)
+		}
+		d = new(big.Int).Set(pivot)
+		pivots = append(pivots, col)
+		row++
+	}
+	return pivots, d
+}
+
+// matrixRank returns the rank of the integer matrix m.
+func matrixRank(m [][]*big.Int) int {
+	if len(m) == 0 {
+		return 0
+	}
+	pivots, _ := gaussJordan(augmentedMatrix(m, 0), len(m[0]))
+	return len(pivots)
+}
+
+// firstNonPivot returns the first of the columns 0..cols-1 missing from
+// pivots, or -1 if every column is a pivot column.
+func firstNonPivot(pivots []int, cols int) int {
+	for i := range cols {
+		if i >= len(pivots) || pivots[i] != i {
+			return i
+		}
+	}
+	return -1
+}
+
+// solveRat returns the unique rational solution x of a·x = b. If a has
+// linearly dependent columns it returns a dependentColumnError, and if the
+// system has no solution an error wrapping errNoSolution.
+func solveRat(a [][]*big.Int, b []*big.Int) ([]*big.Rat, error) {
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("%d equations but %d right-hand sides", len(a), len(b))
+	}
+	if len(a) == 0 {
+		return nil, nil
+	}
+	n := len(a[0])
+	aug := augmentedMatrix(a, 1)
+	for i, v := range b {
+		aug[i][n].Set(v)
+	}
+	pivots, d := gaussJordan(aug, n)
+	if c := firstNonPivot(pivots, n); c >= 0 {
+		return nil, dependentColumnError{Col: c}
+	}
+	for _, row := range aug[n:] {
+		if row[n].Sign() != 0 {
+			return nil, errNoSolution
+		}
+	}
+	x := make([]*big.Rat, n)
+	for i := range x {
+		x[i] = new(big.Rat).SetFrac(aug[i][n], d)
+	}
+	return x, nil
+}
+
+// invertRat returns the rational inverse of the square integer matrix a,
+// or a dependentColumnError if a is singular.
+func invertRat(a [][]*big.Int) ([][]*big.Rat, error) {
+	n := len(a)
+	for i, row := range a {
+		if len(row) != n {
+			return nil, fmt.Errorf("row %d has %d entries, expected %d for a square matrix", i+1, len(row), n)
+		}
+	}
+	aug := augmentedMatrix(a, n)
+	for i := range n {
+		aug[i][n+i].SetInt64(1)
+	}
+	pivots, d := gaussJordan(aug, n)
+	if c := firstNonPivot(pivots, n); c >= 0 {
+		return nil, dependentColumnError{Col: c}
+	}
+	inv := make([][]*big.Rat, n)
+	for i, row := range aug {
+		inv[i] = make([]*big.Rat, n)
+		for j, v := range row[n:] {
+			inv[i][j] = new(big.Rat).SetFrac(v, d)
+		}
+	}
+	return inv, nil
+}
+
+// mulRatInt returns the product a·b of a rational and an integer matrix.
+func mulRatInt(a [][]*big.Rat, b [][]*big.Int) [][]*big.Rat {
+	cols := 0
+	if len(b) > 0 {
+		cols = len(b[0])
+	}
+	p := make([][]*big.Rat, len(a))
+	var t big.Rat
+	for i, row := range a {
+		p[i] = make([]*big.Rat, cols)
+		for j := range cols {
+			sum := new(big.Rat)
+			for k, x := range row {
+				if x.Sign() != 0 && b[k][j].Sign() != 0 {
+					sum.Add(sum, t.Mul(x, t.SetInt(b[k][j])))
+				}
+			}
+			p[i][j] = sum
+		}
+	}
+	return p
+}
+
+// integralMultiple returns the least positive integer s whose multiple
+// s·m of the rational matrix m is integral, the least common multiple of
+// the denominators, and s·m.
+func integralMultiple(m [][]*big.Rat) (s *big.Int, scaled [][]*big.Int) {
+	s = big.NewInt(1)
+	var gcd, t big.Int
+	for _, row := range m {
+		for _, v := range row {
+			d := v.Denom()
+			s.Mul(s, t.Quo(d, gcd.GCD(nil, nil, s, d)))
+		}
+	}
+	scaled = make([][]*big.Int, len(m))
+	for i, row := range m {
+		scaled[i] = make([]*big.Int, len(row))
+		for j, v := range row {
+			scaled[i][j] = new(big.Int).Mul(v.Num(), t.Quo(s, v.Denom()))
+		}
+	}
+	return s, scaled
+}
+
+// determinant returns the determinant of the square integer matrix m,
+// computed exactly by Bareiss's fraction-free elimination: every division
+// is exact and the intermediate entries are minors of m, so they never
+// exceed Hadamard's bound on the determinant.
+func determinant(m [][]*big.Int) *big.Int {
+	n := len(m)
+	a := make([][]*big.Int, n)
+	for i, row := range m {
+		a[i] = make([]*big.Int, n)
+		for j := range n {
+			a[i][j] = new(big.Int).Set(row[j])
+		}
+	}
+	negate := false
+	prev, t := big.NewInt(1), new(big.Int)
+	for k := 0; k < n-1; k++ {
+		if a[k][k].Sign() == 0 {
+			p := k + 1
+			for p < n && a[p][k].Sign() == 0 {
+				p++
+			}
+			if p == n {
+				return new(big.Int)
+			}
+			a[k], a[p] = a[p], a[k]
+			negate = !negate
+		}
+		for i := k + 1; i < n; i++ {
+			for j := k + 1; j < n; j++ {
+				// a[i][j] = (a[i][j]·a[k][k] - a[i][k]·a[k][j]) / prev
+				a[i][j].Mul(a[i][j], a[k][k])
+				a[i][j].Sub(a[i][j], t.Mul(a[i][k], a[k][j]))
+				a[i][j].Quo(a[i][j], prev)
+			}
+		}
+		prev = a[k][k]
+	}
+	if n == 0 {
+		return big.NewInt(1)
+	}
+	det := a[n-1][n-1]
+	if negate {
+		det.Neg(det)
+	}
+	return det
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// ints returns the integer matrix with the given rows.
+func ints(rows ...[]int64) [][]*big.Int {
+	return [][]*big.Int(basisOf(rows...))
+}
+
+// intVector returns the integer vector with the given entries.
+func intVector(v ...int64) []*big.Int {
+	return ints(v)[0]
+}
+
+func TestSolveRat(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a    [][]*big.Int
+		b    []*big.Int
+		want []*big.Rat // nil for an error
+		err  error
+	}{
+		{"square", ints([]int64{2, 1}, []int64{1, 3}), intVector(3, 5), []*big.Rat{big.NewRat(4, 5), big.NewRat(7, 5)}, nil},
+		{"pivot swap", ints([]int64{0, 2}, []int64{3, 0}), intVector(4, 9), []*big.Rat{big.NewRat(3, 1), big.NewRat(2, 1)}, nil},
+		{"overdetermined", ints([]int64{1, 0}, []int64{0, 1}, []int64{1, 1}), intVector(2, -3, -1), []*big.Rat{big.NewRat(2, 1), big.NewRat(-3, 1)}, nil},
+		{"inconsistent", ints([]int64{1, 0}, []int64{0, 1}, []int64{1, 1}), intVector(2, -3, 0), nil, errNoSolution},
+		{"singular", ints([]int64{1, 2}, []int64{2, 4}), intVector(1, 2), nil, dependentColumnError{Col: 1}},
+		{"rank-deficient", ints([]int64{1, 2, 3}, []int64{4, 5, 6}, []int64{7, 8, 9}), intVector(1, 1, 1), nil, dependentColumnError{Col: 2}},
+		{"underdetermined", ints([]int64{1, 1, 0}, []int64{0, 1, 1}), intVector(1, 1), nil, dependentColumnError{Col: 2}},
+		{"empty", nil, nil, nil, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			x, err := solveRat(tc.a, tc.b)
+			switch {
+			case tc.err != nil:
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("solveRat() = %v, %v, want error %v", x, err, tc.err)
+				}
+				return
+			case err != nil:
+				t.Fatal(err)
+			case len(x) != len(tc.want):
+				t.Fatalf("solveRat() = %v, want %v", x, tc.want)
+			}
+			for i := range x {
+				if x[i].Cmp(tc.want[i]) != 0 {
+					t.Errorf("x[%d] = %s, want %s", i, x[i].RatString(), tc.want[i].RatString())
+				}
+			}
+		})
+	}
+	if _, err := solveRat(ints([]int64{1, 0}, []int64{0, 1}), intVector(1)); err == nil {
+		t.Error("solveRat accepted 2 equations with 1 right-hand side")
+	}
+}
+
+func TestInvertRat(t *testing.T) {
+	for _, a := range [][][]*big.Int{
+		ints([]int64{2, 1}, []int64{1, 1}),
+		ints([]int64{0, 1, 0}, []int64{0, 0, 2}, []int64{3, 0, 0}),
+		ints([]int64{4, -2, 1}, []int64{3, 6, -4}, []int64{2, 1, 8}),
+	} {
+		inv, err := invertRat(a)
+		if err != nil {
+			t.Fatalf("invertRat(%v): %v", a, err)
+		}
+		for i, row := range inv {
+			for j := range a[0] {
+				v, want := new(big.Rat), big.NewRat(0, 1)
+				for k, x := range row {
+					v.Add(v, new(big.Rat).Mul(x, new(big.Rat).SetInt(a[k][j])))
+				}
+				if i == j {
+					want.SetInt64(1)
+				}
+				if v.Cmp(want) != 0 {
+					t.Errorf("inverse of %v times the matrix has %s at (%d, %d)", a, v.RatString(), i+1, j+1)
+				}
+			}
+		}
+	}
+	var dep dependentColumnError
+	if _, err := invertRat(ints([]int64{1, 2, 3}, []int64{2, 4, 6}, []int64{0, 1, 1})); !errors.As(err, &dep) || dep.Col != 2 {
+		t.Errorf("invertRat of a singular matrix: %v, want column 3 dependent", err)
+	}
+	if _, err := invertRat(ints([]int64{1, 2, 3}, []int64{4, 5, 6})); err == nil {
+		t.Error("invertRat accepted a 2×3 matrix")
+	}
+}
+
+func TestDeterminant(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		m    [][]*big.Int
+		want int64
+	}{
+		{"1×1", ints([]int64{-7}), -7},
+		{"diagonal", ints([]int64{2, 0, 0}, []int64{0, 3, 0}, []int64{0, 0, 5}), 30},
+		{"pivot swap", ints([]int64{0, 1}, []int64{1, 0}), -1},
+		{"zero pivot", ints([]int64{0, 2, 1}, []int64{0, 1, 3}, []int64{4, 0, 0}), 20},
+		{"dense", ints([]int64{4, -2, 1}, []int64{3, 6, -4}, []int64{2, 1, 8}), 263},
+		{"singular", ints([]int64{1, 2, 3}, []int64{4, 5, 6}, []int64{7, 8, 9}), 0},
+		{"zero column", ints([]int64{0, 1}, []int64{0, 2}), 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := determinant(tc.m); got.Cmp(big.NewInt(tc.want)) != 0 {
+				t.Errorf("determinant() = %s, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestGaussJordan checks the scaled reduced row echelon form and the rank
+// on a rank-deficient matrix with a right-hand side column left out of the
+// pivots.
+func TestGaussJordan(t *testing.T) {
+	a := ints(
+		[]int64{0, 2, 4, 1},
+		[]int64{1, 1, 1, 2},
+		[]int64{2, 4, 6, 5},
+	)
+	pivots, d := gaussJordan(a, 3)
+	if len(pivots) != 2 || pivots[0] != 0 || pivots[1] != 1 {
+		t.Fatalf("pivots %v, want [0 1]", pivots)
+	}
+	// Row 3 is row 1 plus twice row 2, so the system is consistent and the
+	// rational form is [[1, 0, -1, 3/2], [0, 1, 2, 1/2], [0, 0, 0, 0]].
+	want := [][]*big.Rat{
+		{big.NewRat(1, 1), big.NewRat(0, 1), big.NewRat(-1, 1), big.NewRat(3, 2)},
+		{big.NewRat(0, 1), big.NewRat(1, 1), big.NewRat(2, 1), big.NewRat(1, 2)},
+		{big.NewRat(0, 1), big.NewRat(0, 1), big.NewRat(0, 1), big.NewRat(0, 1)},
+	}
+	for i, row := range a {
+		for j, v := range row {
+			if got := new(big.Rat).SetFrac(v, d); got.Cmp(want[i][j]) != 0 {
+				t.Errorf("entry (%d, %d) is %s/%s, want %s", i+1, j+1, v, d, want[i][j].RatString())
+			}
+		}
+	}
+	if r := matrixRank(ints([]int64{1, 2, 3}, []int64{2, 4, 6}, []int64{1, 0, 1})); r != 2 {
+		t.Errorf("matrixRank() = %d, want 2", r)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// experiment is one entry of an experiment definition file. It names the lab
+// to run, how bases are generated, the lab parameters, the reduction pipeline
+// applied to each basis, the oracle, the number of trials and where the
+// results are written. Zero-valued fields fall back to the lab defaults.
+type experiment struct {
+	Name      string           `json:"name" yaml:"name"`
+	Lab       int              `json:"lab" yaml:"lab"`
+	Generator string           `json:"generator" yaml:"generator"`
+	Params    experimentParams `json:"params" yaml:"params"`
+	Reduction []reductionStep  `json:"reduction" yaml:"reduction"`
+	Oracle    string           `json:"oracle" yaml:"oracle"`
+	SVPMethod string           `json:"svp_method" yaml:"svp_method"`
+	// ApproxFactor switches Lab 1 to the approximate oracle (approxSVP).
+	ApproxFactor float64      `json:"approx_factor" yaml:"approx_factor"`
+	Trials       int          `json:"trials" yaml:"trials"`
+	Outputs      []outputSpec `json:"outputs" yaml:"outputs"`
+}
+
+// experimentParams are the numeric parameters shared by the labs. Lab 1 uses
+// the dimension range, Lab 2 uses the rank; both use q.
+type experimentParams struct {
+	Q      int64 `json:"q" yaml:"q"`
+	MinDim int   `json:"min_dim" yaml:"min_dim"`
+	MaxDim int   `json:"max_dim" yaml:"max_dim"`
+	Step   int   `json:"step" yaml:"step"`
+	Rank   int   `json:"rank" yaml:"rank"`
+	// Index is the index of the sublattices or superlattices of Lab 1's
+	// sublattice and superlattice generators.
+	Index int64 `json:"index" yaml:"index"`
+}
+
+// outputSpec selects an output format and destination. An empty path or "-"
+// means standard output.
+type outputSpec struct {
+	Format string `json:"format" yaml:"format"`
+	Path   string `json:"path" yaml:"path"`
+}
+
+// experimentSuite is the top-level object of an experiment definition file.
+type experimentSuite struct {
+	Experiments []experiment `json:"experiments" yaml:"experiments"`
+}
+
+// loadExperiments reads an experiment definition file. Files ending in .json
+// are decoded as JSON, everything else as YAML.
+func loadExperiments(path string) ([]experiment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var suite experimentSuite
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &suite)
+	} else {
+		err = yaml.Unmarshal(data, &suite)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(suite.Experiments) == 0 {
+		return nil, fmt.Errorf("%s defines no experiments", path)
+	}
+
+	for i := range suite.Experiments {
+		if err := suite.Experiments[i].validate(); err != nil {
+			return nil, fmt.Errorf("experiment %d (%s): %w", i+1, suite.Experiments[i].Name, err)
+		}
+	}
+	return suite.Experiments, nil
+}
+
+// validate checks the experiment for unsupported settings before anything
+// expensive is run.
+func (e *experiment) validate() error {
+	// The name becomes a directory of the result directory outputs.
+	if strings.ContainsAny(e.Name, `/\`) || e.Name == "." || e.Name == ".." {
+		return fmt.Errorf("name %q can't be used as a directory name", e.Name)
+	}
+	if e.Lab != 1 && e.Lab != 2 {
+		return fmt.Errorf("lab must be 1 or 2, got %d", e.Lab)
+	}
+	switch e.Generator {
+	case "", "random":
+		if e.Params.Index != 0 {
+			return fmt.Errorf("params.index applies to the sublattice and superlattice generators only")
+		}
+	case "sublattice", "superlattice":
+		if e.Lab != 1 {
+			return fmt.Errorf("the %s generator applies to Lab 1 only", e.Generator)
+		}
+		if e.Params.Index < 1 {
+			return fmt.Errorf("the %s generator needs params.index >= 1, got %d", e.Generator, e.Params.Index)
+		}
+	default:
+		return fmt.Errorf("unknown generator %q (want random, sublattice or superlattice)", e.Generator)
+	}
+	if e.Oracle != "" && e.Oracle != "fplll" {
+		return fmt.Errorf("unknown oracle %q", e.Oracle)
+	}
+	if err := validateSVPMethod(e.SVPMethod); err != nil {
+		return err
+	}
+	if err := validateApproxFactor(e.ApproxFactor); err != nil {
+		return err
+	}
+	if e.ApproxFactor != 0 && e.SVPMethod != "" {
+		return fmt.Errorf("svp_method applies to the exact oracle only, not with approx_factor")
+	}
+	if e.Params.Step < 0 || e.Params.MinDim < 0 || e.Params.MaxDim < 0 || e.Params.Rank < 0 {
+		return fmt.Errorf("dimensions and step must be positive")
+	}
+	if e.Params.Q != 0 && e.Params.Q < 2 {
+		return fmt.Errorf("params.q must be at least 2, got %d", e.Params.Q)
+	}
+	if cfg := e.lab1Config(); e.Lab == 1 && cfg.MinDim > cfg.MaxDim {
+		return fmt.Errorf("params.min_dim %d is above params.max_dim %d", cfg.MinDim, cfg.MaxDim)
+	}
+	if e.Trials < 0 {
+		return fmt.Errorf("trials must be positive, got %d", e.Trials)
+	}
+	for _, step := range e.Reduction {
+		if err := step.validate(); err != nil {
+			return err
+		}
+	}
+	for _, out := range e.Outputs {
+		if !validOutputFormat(out.Format) {
+			return fmt.Errorf("unknown output format %q", out.Format)
+		}
+	}
+	return nil
+}
+
+// lab1Config builds the Lab 1 parameters for the experiment.
+func (e *experiment) lab1Config() lab1Config {
+	cfg := defaultLab1Config()
+	if e.Params.Q != 0 {
+		cfg.Q = big.NewInt(e.Params.Q)
+	}
+	if e.Params.MinDim != 0 {
+		cfg.MinDim = e.Params.MinDim
+	}
+	if e.Params.MaxDim != 0 {
+		cfg.MaxDim = e.Params.MaxDim
+	}
+	if e.Params.Step != 0 {
+		cfg.Step = e.Params.Step
+	}
+	if e.Trials != 0 {
+		cfg.Trials = e.Trials
+	}
+	cfg.Reduction = e.Reduction
+	cfg.SVPMethod = e.SVPMethod
+	cfg.ApproxFactor = e.ApproxFactor
+	cfg.Generator, cfg.Index = e.Generator, e.Params.Index
+	return cfg
+}
+
+// lab2Config builds the Lab 2 parameters for the experiment.
+func (e *experiment) lab2Config() lab2Config {
+	cfg := defaultLab2Config()
+	if e.Params.Q != 0 {
+		cfg.Q = big.NewInt(e.Params.Q)
+	}
+	if e.Params.Rank != 0 {
+		cfg.Rank = e.Params.Rank
+	}
+	if len(e.Reduction) > 0 {
+		cfg.Reduction = e.Reduction
+	}
+	return cfg
+}
+
+// runExperiment executes a single experiment and returns its results.
+// Experiments without outputs write to the defaults given on the command line.
+// If ctx is cancelled the results completed so far are still rendered.
+func runExperiment(ctx context.Context, e experiment, defaults []outputSpec) (*runResults, error) {
+	specs := e.Outputs
+	if len(specs) == 0 {
+		specs = defaults
+	}
+	sinks, err := openOutputs(specs)
+	if err != nil {
+		return nil, err
+	}
+	defer sinks.Close()
+
+	res := &runResults{}
+	switch e.Lab {
+	case 1:
+		// Lab 1 repeats its trials per dimension itself.
+		res.Lab1 = append(res.Lab1, runLab1Verification(ctx, sinks.log, e.lab1Config()))
+	case 2:
+		for trial := 0; trial < max(e.Trials, 1) && ctx.Err() == nil; trial++ {
+			if trial > 0 {
+				fmt.Fprintln(sinks.log)
+			}
+			lab := runLab2Verification(ctx, sinks.log, e.lab2Config())
+			if ctx.Err() == nil {
+				res.Lab2 = append(res.Lab2, lab)
+			}
+		}
+	}
+	return res, sinks.render(res)
+}
+
+// runExperimentFile loads an experiment definition file and executes all of
+// its experiments in sequence, returning the results of each. The progress
+// banners go to log. Directory outputs among the defaults get one
+// subdirectory per experiment so that experiments don't overwrite each
+// other's files. Once ctx is cancelled no further experiment is started.
+func runExperimentFile(ctx context.Context, log io.Writer, path string, defaults []outputSpec) ([]*runResults, error) {
+	experiments, err := loadExperiments(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*runResults
+	for i, e := range experiments {
+		name := e.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		specs := make([]outputSpec, len(defaults))
+		for j, spec := range defaults {
+			if resultDirWriters[spec.Format] != nil {
+				dir := e.Name
+				if dir == "" {
+					dir = fmt.Sprintf("experiment-%d", i+1)
+				}
+				spec.Path = filepath.Join(spec.Path, dir)
+			}
+			specs[j] = spec
+		}
+
+		fmt.Fprintf(log, "=== Experiment %s (%d/%d) ===\n\n", name, i+1, len(experiments))
+		res, err := runExperiment(ctx, e, specs)
+		if err != nil {
+			return results, fmt.Errorf("experiment %s: %w", name, err)
+		}
+		results = append(results, res)
+		fmt.Fprintln(log)
+		if ctx.Err() != nil {
+			return results, nil
+		}
+	}
+
+	fmt.Fprintln(log, "=== All experiments completed ===")
+	return results, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// defaultFlatter is the name flatter (Ryan and Heninger's fast lattice
+// reduction, https://github.com/keeganryan/flatter) is looked up by unless
+// --flatter names another executable or is "off".
+const defaultFlatter = "flatter"
+
+// errNoFlatter is returned for flatter steps when flatter wasn't found.
+var errNoFlatter = errors.New("flatter is not available (install it or point --flatter at it)")
+
+// useFlatter replaces backend.Flatter by the absolute path of the flatter
+// executable, or by "" if it is "off" or can't be found, in which case
+// BKZ and slide steps run without flatter preprocessing and flatter steps
+// fail. Only a flatter named explicitly is warned about when missing.
+func useFlatter() {
+	if backend.Flatter == "" || backend.Flatter == "off" {
+		backend.Flatter = ""
+		return
+	}
+	path, err := exec.LookPath(expandHome(backend.Flatter))
+	if err == nil {
+		path, err = filepath.Abs(path)
+	}
+	if err != nil {
+		if backend.Flatter != defaultFlatter {
+			slog.Warn("flatter not found; BKZ runs without flatter preprocessing", "flatter", backend.Flatter, "err", err)
+		} else {
+			slog.Debug("flatter not found", "err", err)
+		}
+		backend.Flatter = ""
+		return
+	}
+	slog.Info("using flatter for LLL preprocessing before BKZ", "path", path)
+	backend.Flatter = path
+}
+
+// flatterArgs returns the flatter arguments for the LLL options: flatter
+// takes δ with -delta, but has no η.
+func flatterArgs(opts lllOptions) []string {
+	if opts.Delta > 0 {
+		return []string{"-delta", strconv.FormatFloat(opts.Delta, 'g', -1, 64)}
+	}
+	return nil
+}
+
+// flatterReduce reduces basis with flatter, which reaches the quality of
+// LLL orders of magnitude faster than fplll's LLL on large bases with huge
+// entries. The basis goes to flatter's standard input and the reduced basis
+// is read from its standard output, both in fplll's format. The call runs
+// locally, limited to backend.Timeout like fplll calls, and is neither
+// cached nor retried. In a dry run the basis is returned as it is.
+func flatterReduce(ctx context.Context, basis Basis, opts lllOptions) (Basis, error) {
+	args := flatterArgs(opts)
+	if dryRun != nil {
+		binary := backend.Flatter
+		if binary == "" {
+			binary = defaultFlatter
+		}
+		planCommand(binary, basis, args...)
+		return basis, nil
+	}
+	if backend.Flatter == "" {
+		return nil, errNoFlatter
+	}
+	out, err := runExternal(ctx, "flatter", backend.Flatter, args, basis)
+	if err != nil {
+		return nil, err
+	}
+	reduced, err := scanBracketRows(bytes.NewReader(out))
+	if err == nil {
+		err = checkReducedBasis(basis, reduced)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("flatter's output: %w", err)
+	}
+	return reduced, nil
+}
+
+// flatterPreprocess reduces basis with flatter before a BKZ or slide step
+// if flatter is available, so that fplll's own LLL pass starts from a
+// reduced basis. A failure of flatter is logged and the basis returned as
+// it is, since fplll can do without.
+func flatterPreprocess(ctx context.Context, basis Basis) (Basis, error) {
+	if backend.Flatter == "" {
+		return basis, nil
+	}
+	reduced, err := flatterReduce(ctx, basis, lllOptions{})
+	switch {
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case err != nil:
+		slog.Warn("flatter preprocessing failed, leaving the LLL pass to fplll", "rank", len(basis), "err", err)
+		return basis, nil
+	}
+	return reduced, nil
+}
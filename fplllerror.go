@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// fplllFailure classifies why an fplll call failed, so that the error can
+// say what to do about it.
+type fplllFailure int
+
+const (
+	failureOther        fplllFailure = iota
+	failureNotInstalled              // the fplll executable could not be found or started
+	failureBadInput                  // fplll could not read the basis
+	failurePrecision                 // floating-point trouble in the GSO, e.g. at high dimension
+	failureBadOutput                 // fplll's output doesn't have the format of its version
+)
+
+// hint returns advice for the failure, or "" if there is none.
+func (f fplllFailure) hint() string {
+	switch f {
+	case failureNotInstalled:
+		return "fplll does not seem to be installed: install it (e.g. apt install fplll-tools or brew install fplll) or set its path with --fplll"
+	case failureBadInput:
+		return "fplll could not read the basis: check that it is an integer matrix in fplll format"
+	case failurePrecision:
+		return "fplll ran out of floating-point precision: retry with --precision, e.g. --precision 200, or a wider --float-type such as dd or qd"
+	case failureBadOutput:
+		return "this fplll version may print its results differently; run with -vv to see its version and command line"
+	}
+	return ""
+}
+
+// precisionMessages are fplll status messages caused by insufficient
+// floating-point precision.
+var precisionMessages = []string{
+	"infinite number in gso",
+	"infinite loop in babai",
+	"infinite loop in lll",
+	"increase of gso in hlll",
+	"not enough precision",
+}
+
+// bkzLimitMessages are the fplll status messages of a BKZ reduction stopped
+// by -bkzmaxloops or -bkzmaxtime. fplll still prints the basis reached but
+// exits with the status, so such a call is not a failure.
+var bkzLimitMessages = []string{
+	"loops limit exceeded in bkz",
+	"time limit exceeded in bkz",
+}
+
+// stoppedAtBKZLimit reports whether fplll's standard error says BKZ stopped
+// at one of its limits.
+func stoppedAtBKZLimit(stderr string) bool {
+	return containsAny(strings.ToLower(stderr), bkzLimitMessages)
+}
+
+// badInputMessages are fragments of fplll's complaints about its input.
+var badInputMessages = []string{
+	"cannot read",
+	"invalid matrix",
+	"invalid input",
+	"bad matrix",
+	"parse error",
+}
+
+// fplllError is a failed fplll call with what fplll printed on standard
+// error.
+type fplllError struct {
+	Failure fplllFailure
+	Stderr  string // last lines of standard error, joined into one line
+	Err     error
+}
+
+// newFplllError wraps the error of an fplll call, classifying it by the
+// error and by what fplll printed on standard error.
+func newFplllError(err error, stderr string) *fplllError {
+	e := &fplllError{Stderr: summarizeStderr(stderr), Err: err}
+	lower := strings.ToLower(stderr)
+	switch {
+	case errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission):
+		e.Failure = failureNotInstalled
+	case containsAny(lower, precisionMessages):
+		e.Failure = failurePrecision
+	case containsAny(lower, badInputMessages):
+		e.Failure = failureBadInput
+	}
+	return e
+}
+
+// Error implements error.
+func (e *fplllError) Error() string {
+	msg := e.Err.Error()
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	if hint := e.Failure.hint(); hint != "" {
+		msg += " (" + hint + ")"
+	}
+	return msg
+}
+
+// Unwrap returns the error of the call.
+func (e *fplllError) Unwrap() error {
+	return e.Err
+}
+
+// maxStderrLines is how many trailing lines of fplll's standard error are
+// kept in an error; fplll prints the cause last.
+const maxStderrLines = 5
+
+// summarizeStderr joins the last non-empty lines of stderr into one line.
+func summarizeStderr(stderr string) string {
+	var lines []string
+	for _, line := range strings.Split(stderr, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > maxStderrLines {
+		lines = lines[len(lines)-maxStderrLines:]
+	}
+	return strings.Join(lines, "; ")
+}
+
+// containsAny reports whether s contains any of the substrings.
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// fplllVersion is the version of the fplll executable in use, as detected
+// at startup from "fplll --version". Major is 0 if it is unknown.
+type fplllVersion struct {
+	Major, Minor, Patch int
+	// Line is the first line printed by fplll --version, or a note
+	// explaining why the version is unknown.
+	Line string
+}
+
+// String returns the version number, e.g. "5.4.5", or "unknown".
+func (v fplllVersion) String() string {
+	if v.Major == 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// versionNumber matches the version number in the output of fplll --version.
+var versionNumber = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// detectFplllVersion runs binary --version and parses the version number it
+// prints. If fplll cannot be run, the error is returned together with an
+// unknown version whose Line says why; if it prints no version number, the
+// version is unknown too.
+func detectFplllVersion(binary string) (fplllVersion, error) {
+	out, err := exec.Command(binary, "--version").CombinedOutput()
+	if err != nil {
+		return fplllVersion{Line: "unavailable (" + err.Error() + ")"}, err
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	v := fplllVersion{Line: line}
+	if m := versionNumber.FindStringSubmatch(line); m != nil {
+		v.Major, _ = strconv.Atoi(m[1])
+		v.Minor, _ = strconv.Atoi(m[2])
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+// fplllDialect is how a range of fplll versions prints its results, with
+// the parsers reading them.
+type fplllDialect struct {
+	Name string
+	// MinMajor is the first major version printing this way.
+	MinMajor int
+	Matrix   func(r io.Reader) (Basis, error)
+	Vector   func(r io.Reader) ([]*big.Int, error)
+}
+
+// fplllDialects lists the known output formats, newest first.
+//
+// fplll 5 prints a matrix as [[a b]\n[c d]\n] and the vector of -a svp as
+// [a b]; its output is checked against these shapes, so that a change of
+// format is reported rather than misread. The output of older versions is
+// read leniently: every bracketed row counts.
+var fplllDialects = []fplllDialect{
+	{Name: "fplll 5", MinMajor: 5, Matrix: readNestedMatrix, Vector: readBracketVector},
+	{Name: "fplll 4", MinMajor: 4, Matrix: scanBracketRows, Vector: readAnyVector},
+}
+
+// lenientDialect reads the output of fplll versions that could not be
+// detected.
+var lenientDialect = fplllDialect{Name: "unknown fplll", Matrix: scanBracketRows, Vector: readAnyVector}
+
+// dialect returns the output format of the detected fplll version.
+func (v fplllVersion) dialect() fplllDialect {
+	if v.Major == 0 {
+		return lenientDialect
+	}
+	for _, d := range fplllDialects {
+		if v.Major >= d.MinMajor {
+			return d
+		}
+	}
+	return lenientDialect
+}
+
+// checkFplllVersion logs how the output of the detected fplll version will
+// be read, warning about versions older than the known ones and about
+// unknown versions. That fplll could not be run at all is only noted, since
+// not every command needs it and those that do report the failure.
+func checkFplllVersion(v fplllVersion, err error) {
+	d := v.dialect()
+	switch {
+	case err != nil:
+		slog.Debug("could not run fplll --version", "err", err)
+	case v.Major == 0:
+		slog.Warn("could not determine the fplll version; its output is read leniently", "version", v.Line)
+	case d.MinMajor == 0:
+		slog.Warn("fplll version is older than the supported ones; its output is read leniently", "version", v.String())
+	default:
+		slog.Debug("fplll version detected", "version", v.String(), "format", d.Name)
+	}
+}
+
+// readNestedMatrix reads a matrix as fplll 5 prints it: rows of integers
+// inside one outer pair of brackets.
+func readNestedMatrix(r io.Reader) (Basis, error) {
+	scan, err := scanBrackets(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := scan.checkShape(2); err != nil {
+		return nil, fmt.Errorf("expected a matrix [[...]...]: %w", err)
+	}
+	return scan.rows, nil
+}
+
+// readBracketVector reads a vector as fplll 5 prints it: one bracketed list
+// of integers.
+func readBracketVector(r io.Reader) ([]*big.Int, error) {
+	scan, err := scanBrackets(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := scan.checkShape(1); err != nil {
+		return nil, fmt.Errorf("expected a vector [...]: %w", err)
+	}
+	if len(scan.rows) != 1 {
+		return nil, fmt.Errorf("expected a vector [...]: found %d", len(scan.rows))
+	}
+	return scan.rows[0], nil
+}
+
+// readAnyVector reads the first bracketed row of the output as the vector.
+func readAnyVector(r io.Reader) ([]*big.Int, error) {
+	rows, err := scanBracketRows(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no vector found")
+	}
+	return rows[0], nil
+}
+
+// checkMatrix checks that a matrix read from an external tool has the
+// shape the caller expects, rows rows of cols entries each. The readers
+// only check the brackets, so a truncated or ragged output would otherwise
+// pass and turn into a wrong profile.
+func checkMatrix(m Basis, rows, cols int) error {
+	if len(m) != rows {
+		return fmt.Errorf("%d rows, expected %d", len(m), rows)
+	}
+	for i, row := range m {
+		if len(row) != cols {
+			return fmt.Errorf("row %d has %d entries, expected %d", i+1, len(row), cols)
+		}
+	}
+	return nil
+}
+
+// checkReducedBasis checks a reduction of input read from an external tool,
+// a basis or a Gram matrix: its shape with checkMatrix, and its rank.
+// Reductions turn linearly dependent rows into zero rows, so zero rows are
+// only accepted as far as the exact rank of input, which is computed only
+// then, falls short of full; otherwise they are rows the tool lost. A
+// reduction of full rank that spans another lattice is left to
+// checkVolumePreserved.
+func checkReducedBasis(input, reduced Basis) error {
+	if len(input) == 0 {
+		return checkMatrix(reduced, 0, 0)
+	}
+	if err := checkMatrix(reduced, len(input), len(input[0])); err != nil {
+		return err
+	}
+	zero := 0
+	for _, row := range reduced {
+		if normSquared(row).Sign() == 0 {
+			zero++
+		}
+	}
+	if zero == 0 {
+		return nil
+	}
+	if rank := matrixRank(input); rank > len(reduced)-zero {
+		return fmt.Errorf("%d zero rows, but the input has rank %d of %d", zero, rank, len(input))
+	}
+	return nil
+}
+
+// checkShape reports whether the scanned text consists of balanced brackets
+// with all rows at the given nesting depth and nothing else.
+func (s bracketScan) checkShape(depth int) error {
+	switch {
+	case !s.balanced:
+		return fmt.Errorf("unbalanced brackets")
+	case s.stray != "":
+		return fmt.Errorf("unexpected text %q", s.stray)
+	case len(s.rows) == 0:
+		return fmt.Errorf("no rows found")
+	}
+	for i, d := range s.depths {
+		if d != depth {
+			return fmt.Errorf("row %d is nested %d deep, expected %d", i+1, d, depth)
+		}
+	}
+	return nil
+}
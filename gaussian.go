@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
+	mrand "math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// gaussTailCut is how many σ from its center sampleZ draws at most. The
+// mass of the discrete Gaussian beyond it is below 2^-100, so the samples
+// are as good for any σ and center.
+const gaussTailCut = 12
+
+// sampleZ returns an integer drawn from the discrete Gaussian D_{ℤ,σ,c},
+// which gives x a probability proportional to exp(-(x-c)²/(2σ²)), by
+// rejection sampling from the uniform distribution on the integers within
+// gaussTailCut·σ of c. It takes about 10 candidates for σ ≥ 1 and fewer
+// below, whatever the center; sigma must be positive.
+func sampleZ(rng *mrand.Rand, sigma, center float64) int64 {
+	lo := int64(math.Ceil(center - gaussTailCut*sigma))
+	hi := int64(math.Floor(center + gaussTailCut*sigma))
+	if hi < lo {
+		// σ is so small that the nearest integer takes all the mass.
+		return int64(math.Round(center))
+	}
+	for {
+		x := lo + rng.Int64N(hi-lo+1)
+		d := float64(x) - center
+		if rng.Float64() < math.Exp(-d*d/(2*sigma*sigma)) {
+			return x
+		}
+	}
+}
+
+// kleinSampler is Klein's sampler of the discrete Gaussian over a lattice,
+// in the form of Gentry, Peikert and Vaikuntanathan: randomized Babai
+// nearest plane, drawing the coefficient of each bᵢ from sampleZ around
+// the projection of the remaining center, from the last vector to the
+// first. The Gram-Schmidt vectors are precomputed once per basis.
+type kleinSampler struct {
+	basis Basis
+	gso   gsoFloat
+	// star holds the Gram-Schmidt vectors b*ᵢ.
+	star [][]float64
+}
+
+// newKleinSampler prepares sampling over the lattice of basis, whose rows
+// must be linearly independent. The better the basis is reduced, the
+// shorter its Gram-Schmidt vectors and the smaller the σ it samples well.
+func newKleinSampler(basis Basis) kleinSampler {
+	gso := newGSOFloat(basis)
+	rows := intMatrixOf(basis).float64Rows()
+	star := make([][]float64, len(rows))
+	for i, row := range rows {
+		star[i] = append([]float64(nil), row...)
+		for j := range i {
+			for k := range star[i] {
+				star[i][k] -= gso.mu[i][j] * star[j][k]
+			}
+		}
+	}
+	return kleinSampler{basis: basis, gso: gso, star: star}
+}
+
+// MinSigma returns the least σ with which Sample is guaranteed to be
+// statistically close to D_{L,σ,c}: max ‖b*ᵢ‖ times the smoothing factor
+// √(ln(2n+4)/π) of GPV, converted from their width s to σ = s/√(2π).
+// Below it the samples still lie in the lattice but follow a distribution
+// skewed by the basis.
+func (s kleinSampler) MinSigma() float64 {
+	maxR := 0.0
+	for _, r := range s.gso.r {
+		maxR = max(maxR, r)
+	}
+	n := float64(len(s.basis))
+	return math.Sqrt(maxR) * math.Sqrt(math.Log(2*n+4)/math.Pi) / math.Sqrt(2*math.Pi)
+}
+
+// Sample returns a lattice vector drawn from the discrete Gaussian
+// D_{L,σ,c} centred on center, or on the origin if center is nil.
+func (s kleinSampler) Sample(rng *mrand.Rand, sigma float64, center []float64) []*big.Int {
+	_, cols := s.basis.Dims()
+	t := make([]float64, cols)
+	copy(t, center)
+	x := make([]float64, len(s.basis))
+	for i := len(s.basis) - 1; i >= 0; i-- {
+		c := 0.0
+		for k, v := range s.star[i] {
+			c += t[k] * v
+		}
+		c /= s.gso.r[i]
+		z := sampleZ(rng, sigma/math.Sqrt(s.gso.r[i]), c)
+		x[i] = float64(z)
+		// t -= z·bᵢ, i.e. z·b*ᵢ plus its part along the b*ⱼ before.
+		for k, v := range s.star[i] {
+			t[k] -= float64(z) * v
+		}
+		for j := range i {
+			if m := s.gso.mu[i][j]; m != 0 {
+				for k, v := range s.star[j] {
+					t[k] -= float64(z) * m * v
+				}
+			}
+		}
+	}
+	return combine(s.basis, x)
+}
+
+// sampleConfig holds the arguments of the sample command.
+type sampleConfig struct {
+	Input    string // basis file, or "-" for standard input
+	From, To string // matrix formats of the basis and the output
+	Sigma    float64
+	Count    int
+	Center   []float64 // nil for the origin
+}
+
+// parseSampleFlags builds a sampleConfig from the sample command line.
+func parseSampleFlags(args []string) (sampleConfig, error) {
+	fs := flag.NewFlagSet("sample", flag.ContinueOnError)
+	from := fs.String("from", "auto", "format of the input basis: auto or one of "+basisFormatNames())
+	to := fs.String("to", "fplll", "format of the output: one of "+basisFormatNames())
+	sigma := fs.Float64("sigma", 0, "width σ of the discrete Gaussian, exp(-‖x-c‖²/(2σ²))")
+	count := fs.Int("count", 1, "number of vectors to sample")
+	center := fs.String("center", "", "center such as \"[0.5 1 -2]\" (default: the origin)")
+	if err := fs.Parse(args); err != nil {
+		return sampleConfig{}, err
+	}
+	if fs.NArg() > 1 {
+		return sampleConfig{}, fmt.Errorf("usage: lattice-labs sample -sigma s [-count k] [-center vec] [-from fmt] [-to fmt] [file|-]")
+	}
+	if *from != "auto" && basisReaders[*from] == nil {
+		return sampleConfig{}, fmt.Errorf("unknown basis format %q (want auto, %s)", *from, basisFormatNames())
+	}
+	if basisWriters[*to] == nil {
+		return sampleConfig{}, fmt.Errorf("unknown basis format %q (want %s)", *to, basisFormatNames())
+	}
+	if !(*sigma > 0) || math.IsInf(*sigma, 0) {
+		return sampleConfig{}, fmt.Errorf("-sigma must be positive, got %g", *sigma)
+	}
+	if *count < 1 {
+		return sampleConfig{}, fmt.Errorf("-count must be at least 1, got %d", *count)
+	}
+	cfg := sampleConfig{Input: "-", From: *from, To: *to, Sigma: *sigma, Count: *count}
+	if *center != "" {
+		c, err := parseFloatVector(*center)
+		if err != nil {
+			return sampleConfig{}, fmt.Errorf("-center: %w", err)
+		}
+		cfg.Center = c
+	}
+	if fs.NArg() == 1 {
+		cfg.Input = fs.Arg(0)
+	}
+	return cfg, nil
+}
+
+// parseFloatVector parses a vector of real numbers such as "[0.5 1 -2]".
+func parseFloatVector(s string) ([]float64, error) {
+	inner, open := strings.CutPrefix(strings.TrimSpace(s), "[")
+	inner, closed := strings.CutSuffix(inner, "]")
+	if !open || !closed {
+		return nil, fmt.Errorf("want a vector such as \"[0.5 1 -2]\", got %q", s)
+	}
+	var v []float64
+	for _, field := range strings.Fields(inner) {
+		x, err := strconv.ParseFloat(field, 64)
+		if err != nil || math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, fmt.Errorf("bad entry %q", field)
+		}
+		v = append(v, x)
+	}
+	return v, nil
+}
+
+// runSample writes cfg.Count vectors sampled from the discrete Gaussian over
+// the lattice of the input basis to w, as the rows of a matrix. The
+// samples are seeded by --seed. A σ below what the basis guarantees (see
+// kleinSampler.MinSigma) is warned about.
+func runSample(w io.Writer, cfg sampleConfig) error {
+	basis, err := loadPipeBasis(cfg.Input, cfg.From)
+	if err != nil {
+		return err
+	}
+	if len(basis) == 0 {
+		return errors.New("the basis is empty")
+	}
+	if _, cols := basis.Dims(); cfg.Center != nil && len(cfg.Center) != cols {
+		return fmt.Errorf("the center has %d entries, the basis vectors %d", len(cfg.Center), cols)
+	}
+	if matrixRank(basis) < len(basis) {
+		return errors.New("the basis has linearly dependent rows")
+	}
+	sampler := newKleinSampler(basis)
+	if least := sampler.MinSigma(); cfg.Sigma < least {
+		slog.Warn("σ is below the least the basis samples D_{L,σ} closely with; reduce the basis first", "sigma", cfg.Sigma, "min_sigma", least)
+	}
+	rng := trialRand("sample", int64(len(basis)), int64(math.Float64bits(cfg.Sigma)))
+	samples := make(Basis, cfg.Count)
+	for i := range samples {
+		samples[i] = sampler.Sample(rng, cfg.Sigma, cfg.Center)
+	}
+	return samples.WriteFormat(w, cfg.To)
+}
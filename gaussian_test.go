@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math"
+	mrand "math/rand/v2"
+	"testing"
+)
+
+// TestSampleZ checks the support, mean and variance of sampleZ with a fixed
+// seed: the samples lie within gaussTailCut·σ of the center, and for σ ≥ 1
+// the discrete Gaussian has the mean c and a variance within 1% of σ².
+func TestSampleZ(t *testing.T) {
+	const samples = 40000
+	rng := mrand.New(mrand.NewPCG(1, 2))
+	for _, tc := range []struct{ sigma, center float64 }{
+		{1, 0},
+		{3.2, 0.5},
+		{10, -17.25},
+		{2.5, 1e6},
+	} {
+		var sum, sumSq float64
+		for range samples {
+			x := float64(sampleZ(rng, tc.sigma, tc.center))
+			if math.Abs(x-tc.center) > gaussTailCut*tc.sigma {
+				t.Fatalf("σ %g, center %g: sample %g outside the tail cut", tc.sigma, tc.center, x)
+			}
+			sum += x
+			sumSq += (x - tc.center) * (x - tc.center)
+		}
+		mean, variance := sum/samples, sumSq/samples
+		// The standard error of the mean is σ/√samples, that of the
+		// variance about σ²·√(2/samples); allow 5 of each.
+		if math.Abs(mean-tc.center) > 5*tc.sigma/math.Sqrt(samples) {
+			t.Errorf("σ %g, center %g: mean %g", tc.sigma, tc.center, mean)
+		}
+		if want := tc.sigma * tc.sigma; math.Abs(variance-want) > 0.01*want+5*want*math.Sqrt(2.0/samples) {
+			t.Errorf("σ %g, center %g: variance %g, want about %g", tc.sigma, tc.center, variance, want)
+		}
+	}
+	// A tiny σ puts all the mass on the nearest integer.
+	for range 100 {
+		if x := sampleZ(rng, 0.01, 3.4); x != 3 {
+			t.Fatalf("σ 0.01 around 3.4 gave %d", x)
+		}
+	}
+}
+
+// TestKleinSampler checks that the samples are lattice vectors and that,
+// well above MinSigma, their mean is the center.
+func TestKleinSampler(t *testing.T) {
+	const samples = 4000
+	basis := basisOf([]int64{5, 1, 0}, []int64{-1, 5, 1}, []int64{0, -1, 5})
+	s := newKleinSampler(basis)
+	sigma := 4 * s.MinSigma()
+	center := []float64{3.5, -2, 10}
+	rng := mrand.New(mrand.NewPCG(3, 4))
+	sum := make([]float64, len(center))
+	for range samples {
+		v := s.Sample(rng, sigma, center)
+		if _, err := latticeCoordinates(basis, v); err != nil {
+			t.Fatalf("sample %v is not in the lattice: %v", v, err)
+		}
+		for j, x := range v {
+			f, _ := x.Float64()
+			sum[j] += f
+		}
+	}
+	for j, c := range center {
+		if mean := sum[j] / samples; math.Abs(mean-c) > 5*sigma/math.Sqrt(samples) {
+			t.Errorf("coordinate %d: mean %g, want about %g", j+1, mean, c)
+		}
+	}
+}
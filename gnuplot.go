@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// writeGnuplotFiles writes every Lab 2 profile to dir as profile_<run>.dat
+// together with a ready-to-run gnuplot script profiles.gp. The script plots
+// each profile with its fitted GSA line and, when the reduction ended with
+// BKZ, the line with the slope the GSA predicts for that block size. Running
+// "gnuplot profiles.gp" inside dir renders profile_<run>.png.
+func writeGnuplotFiles(dir string, res *runResults) error {
+	if len(res.Lab2) == 0 {
+		return nil
+	}
+
+	var script strings.Builder
+	script.WriteString("# Generated by lattice-labs. Run with: gnuplot profiles.gp\n")
+	if res.Metadata != nil {
+		for _, kv := range res.Metadata.entries() {
+			fmt.Fprintf(&script, "# %s: %s\n", kv[0], kv[1])
+		}
+	}
+	script.WriteString("set terminal png size 900,560\n")
+	script.WriteString("set xlabel \"index i\"\n")
+	script.WriteString("set ylabel \"log2 ||b_i*||\"\n")
+	script.WriteString("set key top right\n")
+	script.WriteString("set grid\n")
+
+	for run, lab := range res.Lab2 {
+		if len(lab.Profile) < 2 {
+			// Interrupted, failed or timed out: there is nothing to plot.
+			continue
+		}
+		dataFile := fmt.Sprintf("profile_%d.dat", run)
+		var data strings.Builder
+		data.WriteString("# index log2_norm\n")
+		for i, v := range lab.Profile {
+			fmt.Fprintf(&data, "%d %.6f\n", i, v)
+		}
+		if err := os.WriteFile(filepath.Join(dir, dataFile), []byte(data.String()), 0o644); err != nil {
+			return err
+		}
+
+		slope, intercept, r2 := lab.Profile.Fit()
+		fmt.Fprintf(&script, "\n# Run %d: rank %d, q = %s, %s\n", run, lab.Rank, lab.Q, describeReduction(lab.Reduction))
+		fmt.Fprintf(&script, "set output \"profile_%d.png\"\n", run)
+		fmt.Fprintf(&script, "set title \"Basis profile (rank %d, %s)\"\n", lab.Rank, latexReduction(lab.Reduction))
+		fmt.Fprintf(&script, "fit%d(x) = %.6f*x %+.6f\n", run, slope, intercept)
+
+		plot := fmt.Sprintf("plot \"%s\" using 1:2 with linespoints title \"profile\", \\\n     fit%d(x) title \"fitted GSA line (slope %.4f, R^2 %.3f)\" dashtype 2",
+			dataFile, run, slope, r2)
+		if beta := finalBlockSize(lab.Reduction); beta >= 2 {
+			// The predicted line crosses the fitted one at its centre.
+			expected := expectedGSASlope(beta)
+			fmt.Fprintf(&script, "gsa%d(x) = %.6f %+.6f*x\n", run, lab.Profile.GSALine(beta)[0], expected)
+			plot += fmt.Sprintf(", \\\n     gsa%d(x) title \"expected GSA slope for beta=%d (%.4f)\" dashtype 3", run, beta, expected)
+		}
+		script.WriteString(plot + "\n")
+	}
+
+	return os.WriteFile(filepath.Join(dir, "profiles.gp"), []byte(script.String()), 0o644)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
+	mrand "math/rand/v2"
+)
+
+// gramInputArgs make fplll's LLL read a Gram matrix G = B·Bᵀ instead of a
+// basis B and print the reduced Gram matrix U·G·Uᵀ. Lattices given by an
+// inner product, such as ideal and module lattices over number fields,
+// have small Gram matrices while every integral basis of them has large
+// entries; reducing the Gram matrix avoids the blow-up.
+var gramInputArgs = []string{"-t", "gram"}
+
+// readsGram reports whether the fplll version reduces Gram matrices, which
+// fplll does from 5.3.
+func (v fplllVersion) readsGram() bool {
+	return v.Major > 5 || v.Major == 5 && v.Minor >= 3
+}
+
+// isGram reports whether args are those of an fplll call on a Gram matrix.
+func isGram(args []string) bool {
+	for i := range len(args) - 1 {
+		if args[i] == gramInputArgs[0] && args[i+1] == gramInputArgs[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGram reports whether g can be the Gram matrix of a basis: square,
+// symmetric and with a positive diagonal. That it is positive definite is
+// left to fplll.
+func checkGram(g [][]*big.Int) error {
+	for i, row := range g {
+		if len(row) != len(g) {
+			return fmt.Errorf("Gram matrix is not square: row %d has %d entries, expected %d", i+1, len(row), len(g))
+		}
+		if row[i].Sign() <= 0 {
+			return fmt.Errorf("Gram matrix has a non-positive diagonal entry %s in row %d", row[i], i+1)
+		}
+		for j := range i {
+			if row[j].Cmp(g[j][i]) != 0 {
+				return fmt.Errorf("Gram matrix is not symmetric: entries (%d,%d) and (%d,%d) differ", i+1, j+1, j+1, i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// gramLLL LLL-reduces the lattice of Gram matrix g with fplll and returns
+// the reduced Gram matrix. It fails if the detected fplll can't read Gram
+// matrices. Failures are logged and returned; in a dry run g is returned.
+func gramLLL(ctx context.Context, g [][]*big.Int, opts lllOptions) ([][]*big.Int, error) {
+	args := backend.fplllArgs("lll", append(opts.args(), gramInputArgs...)...)
+	if dryRun != nil {
+		planFplll(g, args...)
+		return g, nil
+	}
+	if !backend.Version.readsGram() {
+		return nil, fmt.Errorf("fplll %s does not reduce Gram matrices (5.3 or later does)", backend.Version)
+	}
+	var reduced [][]*big.Int
+	err := backend.runFplllCached(ctx, g, args, func(r io.Reader) (err error) {
+		if reduced, err = backend.Version.dialect().Matrix(r); err == nil {
+			err = checkReducedBasis(g, reduced)
+		}
+		return err
+	})
+	if err != nil {
+		logFplllError("lll", len(g), err)
+		return nil, err
+	}
+	return reduced, nil
+}
+
+// gramProfile computes the Gram-Schmidt profile of the lattice of Gram
+// matrix g with bigFloatProfileAt, doubling the mantissa while some norm
+// is unresolved, up to the mantissa that resolves every non-zero norm.
+func gramProfile(g [][]*big.Int) []float64 {
+	bits := 0
+	for _, row := range g {
+		for _, v := range row {
+			bits = max(bits, v.BitLen())
+		}
+	}
+	maxPrec := uint(len(g)*bits) + 53
+	for prec := uint(2*bits + 53); ; prec *= 2 {
+		prec = min(prec, maxPrec)
+		profile, ok := bigFloatProfileAt(g, prec)
+		if ok || prec == maxPrec {
+			return profile
+		}
+		slog.Debug("increasing the precision of the Gram-Schmidt profile", "precision", 2*prec)
+	}
+}
+
+// gramVolume returns the volume √det(g) of the lattice of Gram matrix g,
+// computed from the exact determinant and rounded once to 53 bits.
+func gramVolume(g [][]*big.Int) *big.Float {
+	v := new(big.Float).SetPrec(53).SetInt(determinant(g))
+	return v.Sqrt(v)
+}
+
+// gramLogVolume returns log2 of the volume of the lattice of Gram matrix g,
+// or -Inf if g is singular.
+func gramLogVolume(g [][]*big.Int) float64 {
+	det := determinant(g)
+	if det.Sign() <= 0 {
+		return math.Inf(-1)
+	}
+	return log2Int(det) / 2
+}
+
+// rerandomizeGram returns the Gram matrix U·g·Uᵀ of another basis of the
+// same lattice, U being the unimodular matrix rerandomizeBasis applies with
+// a generator seeded by seed.
+func rerandomizeGram(g [][]*big.Int, seed func() *mrand.Rand) [][]*big.Int {
+	ug := rerandomizeBasis(g, seed()) // U·g
+	return rerandomizeBasis(transpose(ug), seed())
+}
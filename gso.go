@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"runtime"
+	"runtime/trace"
+	"sort"
+	"strings"
+	"sync"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// gsoMethods maps the names of the Gram-Schmidt profile implementations to
+// them. unstable is empty if the method trusts its result, and otherwise
+// says why it doesn't, e.g. because the rows are linearly dependent or
+// numerical errors showed.
+var gsoMethods = map[string]func(m *intMatrix) (profile []float64, unstable string){
+	"householder": householderProfile,
+	"cholesky":    choleskyProfile,
+	"block": func(m *intMatrix) ([]float64, string) {
+		return blockProfile(m, runtime.GOMAXPROCS(0))
+	},
+	"classical": classicalProfile,
+	"stream":    streamProfile,
+	"bigfloat": func(m *intMatrix) ([]float64, string) {
+		return bigFloatProfile(m, gsoPrecision), ""
+	},
+}
+
+// gsoMethod is the implementation used by computeGramSchmidtProfile (--gso).
+var gsoMethod = "householder"
+
+// gsoMethodNames returns the names of the profile implementations for usage
+// messages.
+func gsoMethodNames() string {
+	names := make([]string, 0, len(gsoMethods))
+	for name := range gsoMethods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// computeGramSchmidtProfile computes the log2 of Gram-Schmidt vector norms
+// with gsoMethod. Bases with entries beyond float64 precision, and bases
+// whose float64 profile turns out unstable, get the big.Float profile
+// whatever the method. With certifyProfiles the profile is checked against
+// the exact one, which is returned instead.
+func computeGramSchmidtProfile(basis Basis) []float64 {
+	if len(basis) == 0 {
+		return nil
+	}
+	defer trace.StartRegion(context.Background(), "gram-schmidt").End()
+	m := intMatrixOf(basis)
+	profile := floatProfile(m)
+	if certifyProfiles {
+		exact := rationalProfile(m)
+		checkProfile(profile, exact)
+		return exact
+	}
+	return profile
+}
+
+// floatProfile computes the profile of m as described for
+// computeGramSchmidtProfile, in floating point.
+func floatProfile(m *intMatrix) []float64 {
+	if gsoMethod != "bigfloat" && profileNeedsBigFloat(m) {
+		return bigFloatProfile(m, gsoPrecision)
+	}
+	profile, unstable := gsoMethods[gsoMethod](m)
+	if unstable == "" {
+		unstable = unstableProfile(m, profile)
+	}
+	if unstable == "" {
+		return profile
+	}
+	slog.Info("Gram-Schmidt profile is numerically unstable, recomputing it with big.Float",
+		"method", gsoMethod, "rank", m.rows, "reason", unstable)
+	profile = bigFloatProfile(m, gsoPrecision)
+	if dependent := countVanishing(profile); dependent > 0 {
+		slog.Warn("rows of the basis depend linearly on earlier ones; their profile entries are -50",
+			"rank", m.rows, "dependent", dependent)
+	}
+	return profile
+}
+
+// minSignificantBits is how many of float64's 53 bits must survive the
+// cancellation from bᵢ to b*ᵢ for a float64 profile entry to be trusted.
+const minSignificantBits = 26
+
+// maxOrthogonalityLoss is the largest cosine between consecutive
+// Gram-Schmidt vectors a float64 Gram-Schmidt may leave.
+const maxOrthogonalityLoss = 1e-6
+
+// unstableProfile checks a float64 profile of m for the signs of numerical
+// breakdown and says which one it found, or returns "". A norm that vanished
+// (the -50 sentinel) or came out non-finite stands for a negative or lost
+// squared norm; a norm below 2^-minSignificantBits times the norm of its
+// row has lost too many bits to cancellation.
+func unstableProfile(m *intMatrix, profile []float64) string {
+	for i, row := range m.float64Rows() {
+		p := profile[i]
+		switch {
+		case math.IsNaN(p) || math.IsInf(p, 0):
+			return fmt.Sprintf("‖b*%d‖ is not finite", i+1)
+		case p == -50:
+			return fmt.Sprintf("‖b*%d‖² vanished or came out negative", i+1)
+		case p < log2Norm(floats.Dot(row, row))-(float64EntryBits-minSignificantBits):
+			return fmt.Sprintf("‖b*%d‖ lost more than %d bits to cancellation", i+1, float64EntryBits-minSignificantBits)
+		}
+	}
+	return ""
+}
+
+// orthogonalityLoss returns "" if consecutive Gram-Schmidt vectors are
+// orthogonal to within maxOrthogonalityLoss, and otherwise describes the
+// first pair that isn't. Vanishing vectors are skipped.
+func orthogonalityLoss(ortho [][]float64, normSq []float64) string {
+	for i := 1; i < len(ortho); i++ {
+		if normSq[i-1] <= 1e-10 || normSq[i] <= 1e-10 {
+			continue
+		}
+		cos := math.Abs(floats.Dot(ortho[i-1], ortho[i])) / math.Sqrt(normSq[i-1]*normSq[i])
+		if cos > maxOrthogonalityLoss {
+			return fmt.Sprintf("b*%d and b*%d are not orthogonal (cosine %.1e)", i, i+1, cos)
+		}
+	}
+	return ""
+}
+
+// countVanishing returns the number of -50 sentinels in profile.
+func countVanishing(profile []float64) int {
+	n := 0
+	for _, p := range profile {
+		if p == -50 {
+			n++
+		}
+	}
+	return n
+}
+
+// householderProfile computes the profile from the QR decomposition
+// Bᵀ = Q·R, which gonum computes with Householder reflections: the diagonal
+// of R holds the norms ‖b*ᵢ‖ up to sign. Unlike Gram-Schmidt in float64,
+// Householder QR is backward stable, so the profiles of ill-conditioned
+// reduced bases stay accurate. There are no norms if there are more rows
+// than columns, which makes them linearly dependent.
+func householderProfile(m *intMatrix) (profile []float64, unstable string) {
+	if m.rows > m.cols {
+		return nil, "more rows than columns"
+	}
+	var qr mat.QR
+	qr.Factorize(mat.NewDense(m.rows, m.cols, m.float64s()).T())
+	var r mat.Dense
+	qr.RTo(&r)
+	profile = make([]float64, m.rows)
+	for i := range profile {
+		d := r.At(i, i)
+		profile[i] = log2Norm(d * d)
+	}
+	return profile, ""
+}
+
+// choleskyProfile computes the profile from the factorization G = Rᵀ·R of
+// the Gram matrix G = B·Bᵀ: R is the R factor of the QR decomposition of
+// Bᵀ, so the diagonal of R holds the norms ‖b*ᵢ‖. G is computed exactly and
+// rounded once. The factorization fails if a squared norm comes out zero or
+// negative, i.e. if G is not positive definite in float64. Since the
+// cancellation happens in the squared norms, the result is also unstable if
+// any ‖b*ᵢ‖² lost more than minSignificantBits bits relative to Gᵢᵢ.
+func choleskyProfile(m *intMatrix) (profile []float64, unstable string) {
+	g := mat.NewSymDense(m.rows, m.gram().float64s())
+	var chol mat.Cholesky
+	if !chol.Factorize(g) {
+		return nil, "the Gram matrix is not positive definite in float64"
+	}
+	var r mat.TriDense
+	chol.UTo(&r)
+	profile = make([]float64, m.rows)
+	for i := range profile {
+		profile[i] = math.Log2(r.At(i, i))
+		if 2*profile[i] < math.Log2(g.At(i, i))-(float64EntryBits-minSignificantBits) {
+			unstable = fmt.Sprintf("‖b*%d‖² lost more than %d bits to cancellation", i+1, float64EntryBits-minSignificantBits)
+		}
+	}
+	return profile, unstable
+}
+
+// classicalProfile computes the profile with the classical Gram-Schmidt
+// process in float64, giving norms that vanish in float64 a log2 of -50. The
+// result is unstable if the vectors lost their orthogonality.
+func classicalProfile(m *intMatrix) (profile []float64, unstable string) {
+	B := m.float64Rows()
+	n, cols := m.rows, m.cols
+
+	// Perform Gram-Schmidt orthogonalization
+	profile = make([]float64, n)
+	orthoBasis := make([][]float64, n)
+	// normSq[k] is the squared norm of orthoBasis[k].
+	normSq := make([]float64, n)
+
+	for i := 0; i < n; i++ {
+		// Copy current vector
+		orthoBasis[i] = make([]float64, cols)
+		copy(orthoBasis[i], B[i])
+
+		// Orthogonalize against previous vectors
+		for k := 0; k < i; k++ {
+			dot := 0.0
+
+			for j := 0; j < cols; j++ {
+				dot += B[i][j] * orthoBasis[k][j]
+			}
+
+			if normSq[k] > 1e-10 {
+				coeff := dot / normSq[k]
+				for j := 0; j < cols; j++ {
+					orthoBasis[i][j] -= coeff * orthoBasis[k][j]
+				}
+			}
+		}
+
+		// Compute norm and take log2
+		norm := 0.0
+		for j := 0; j < cols; j++ {
+			norm += orthoBasis[i][j] * orthoBasis[i][j]
+		}
+		normSq[i] = norm
+
+		profile[i] = log2Norm(norm)
+	}
+
+	return profile, orthogonalityLoss(orthoBasis, normSq)
+}
+
+// log2Norm returns the profile entry log2(√normSq), or -50 for norms that
+// vanish in float64.
+func log2Norm(normSq float64) float64 {
+	if normSq > 1e-10 {
+		return math.Log2(math.Sqrt(normSq))
+	}
+	return -50 // Very small norm
+}
+
+// gsoBlockSize is the number of rows blockProfile orthogonalizes before
+// updating the rows below them; a block of rows stays in the CPU cache while
+// the later rows stream past it.
+const gsoBlockSize = 32
+
+// blockProfile computes the profile with block modified Gram-Schmidt: the
+// rows are orthogonalized in blocks of gsoBlockSize, and once a block is
+// done the projections of all later rows away from it are computed by
+// workers goroutines, each updating its own share of the rows. The result is
+// unstable if the vectors lost their orthogonality.
+func blockProfile(m *intMatrix, workers int) (profile []float64, unstable string) {
+	n := m.rows
+	ortho := m.float64Rows()
+	// normSq[k] is the squared norm of ortho[k] once the row is done.
+	normSq := make([]float64, n)
+	project := func(v []float64, k int) {
+		if normSq[k] > 1e-10 {
+			floats.AddScaled(v, -floats.Dot(v, ortho[k])/normSq[k], ortho[k])
+		}
+	}
+
+	for start := 0; start < n; start += gsoBlockSize {
+		end := min(start+gsoBlockSize, n)
+		for i := start; i < end; i++ {
+			for k := start; k < i; k++ {
+				project(ortho[i], k)
+			}
+			normSq[i] = floats.Dot(ortho[i], ortho[i])
+		}
+		forEachRow(end, n, workers, func(i int) {
+			for k := start; k < end; k++ {
+				project(ortho[i], k)
+			}
+		})
+	}
+
+	profile = make([]float64, n)
+	for i, norm := range normSq {
+		profile[i] = log2Norm(norm)
+	}
+	return profile, orthogonalityLoss(ortho, normSq)
+}
+
+// forEachRow calls f for every i in [lo, hi), splitting the range into
+// contiguous shares for at most workers goroutines. It returns when all
+// calls have returned.
+func forEachRow(lo, hi, workers int, f func(i int)) {
+	if hi <= lo {
+		return
+	}
+	workers = max(1, min(workers, hi-lo))
+	if workers == 1 {
+		for i := lo; i < hi; i++ {
+			f(i)
+		}
+		return
+	}
+	var wg sync.WaitGroup
+	share := (hi - lo + workers - 1) / workers
+	for from := lo; from < hi; from += share {
+		to := min(from+share, hi)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := from; i < to; i++ {
+				f(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// BenchmarkBlockProfile measures how the block-parallel profile scales with
+// the number of workers and with the rank, on the random bases the bench
+// command uses; worker counts beyond GOMAXPROCS show the cost of
+// oversubscription:
+//
+//	go test -run '^$' -bench BlockProfile
+func BenchmarkBlockProfile(b *testing.B) {
+	const q = 100003
+	for _, n := range []int{100, 200, 400} {
+		m := intMatrixOf(genRandomBasisFrom(trialSource("bench", int64(n), q), n, big.NewInt(q)))
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("n=%d/workers=%d", n, workers), func(b *testing.B) {
+				for range b.N {
+					if _, unstable := blockProfile(m, workers); unstable != "" {
+						b.Fatalf("unstable: %s", unstable)
+					}
+				}
+			})
+		}
+	}
+}
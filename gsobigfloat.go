@@ -0,0 +1,135 @@
+package main
+
+import (
+	"cmp"
+	"log/slog"
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// float64EntryBits is the size in bits of the largest basis entries that
+// float64 represents exactly. The float64 profile implementations round
+// larger entries, so computeGramSchmidtProfile computes such profiles with
+// big.Float instead.
+const float64EntryBits = 53
+
+// gsoPrecision is the mantissa in bits of the big.Float profile
+// (--gso-precision); 0 chooses it from the entry sizes.
+var gsoPrecision uint
+
+// bigFloatProfile computes the profile in big.Float arithmetic with the
+// given mantissa. If prec is 0, it starts with autoGSOPrecision and doubles
+// the mantissa while some norm is too small to be resolved, up to the
+// mantissa that resolves every nonzero norm of an integer basis; norms that
+// are still unresolved then are zero.
+func bigFloatProfile(m *intMatrix, prec uint) []float64 {
+	g := m.gram().bigRows()
+	if prec != 0 {
+		profile, ok := bigFloatProfileAt(g, prec)
+		if !ok {
+			slog.Warn("some Gram-Schmidt norms are below the resolution of --gso-precision and reported as vanishing", "precision", prec)
+		}
+		return profile
+	}
+	// ‖b*ᵢ‖² is a fraction with denominator det(G₁..ᵢ₋₁) ≤ Πⱼ Gⱼⱼ, so
+	// maxPrec bits resolve every nonzero norm.
+	maxPrec := uint(m.rows*(2*m.maxBits()+bits.Len(uint(m.cols)))) + 53
+	for prec = autoGSOPrecision(m); ; prec *= 2 {
+		prec = min(prec, maxPrec)
+		profile, ok := bigFloatProfileAt(g, prec)
+		if ok || prec == maxPrec {
+			return profile
+		}
+		slog.Debug("increasing the precision of the Gram-Schmidt profile", "precision", 2*prec)
+	}
+}
+
+// bigFloatLogVolume returns log2 of the volume of the lattice of m, the sum
+// of its big.Float profile: the Gram-Schmidt recurrences on the exact Gram
+// matrix are its Cholesky decomposition, whose diagonal multiplies to the
+// determinant, and the mantissa bigFloatProfile chooses keeps every norm to
+// float64's precision, so the sum never overflows and is as accurate as
+// the log of the exact determinant. Vanishing norms make it -Inf.
+func bigFloatLogVolume(m *intMatrix) float64 {
+	profile := Profile(bigFloatProfile(m, 0))
+	if countVanishing(profile) > 0 {
+		return math.Inf(-1)
+	}
+	return profile.LogVolume()
+}
+
+// bigFloatProfileAt computes the profile from the exact Gram matrix g with
+// a mantissa of prec bits. It runs the Gram-Schmidt recurrences on g, as
+// fplll does: for j < i
+//
+//	rᵢⱼ = Gᵢⱼ - Σₖ<ⱼ μⱼₖ·rᵢₖ,  μᵢⱼ = rᵢⱼ / rⱼⱼ,  rᵢᵢ = Gᵢᵢ - Σₖ<ᵢ μᵢₖ·rᵢₖ
+//
+// where rᵢᵢ = ‖b*ᵢ‖². A norm is resolved if rᵢᵢ keeps 53 significant bits
+// of the cancellation from Gᵢᵢ; unresolved norms are treated as vanishing
+// and get a log2 of -50, as in classicalProfile, and ok is false.
+func bigFloatProfileAt(g Basis, prec uint) (profile []float64, ok bool) {
+	n := len(g)
+	newFloat := func() *big.Float { return new(big.Float).SetPrec(prec) }
+	r := make([][]*big.Float, n)
+	mu := make([][]*big.Float, n)
+	// vanishing[j] is set if b*ⱼ is treated as zero.
+	vanishing := make([]bool, n)
+	profile = make([]float64, n)
+	ok = true
+	t := newFloat()
+	for i := 0; i < n; i++ {
+		r[i] = make([]*big.Float, i+1)
+		mu[i] = make([]*big.Float, i)
+		for j := 0; j <= i; j++ {
+			rij := newFloat().SetInt(g[i][j])
+			for k := 0; k < j; k++ {
+				rij.Sub(rij, t.Mul(mu[j][k], r[i][k]))
+			}
+			r[i][j] = rij
+			if j < i {
+				mu[i][j] = newFloat()
+				// Vanishing b*ⱼ contributes nothing, as in classicalProfile.
+				if !vanishing[j] {
+					mu[i][j].Quo(rij, r[j][j])
+				}
+			}
+		}
+		rii := r[i][i]
+		if g[i][i].Sign() == 0 || rii.Sign() <= 0 || rii.MantExp(nil) < g[i][i].BitLen()+53-int(prec) {
+			vanishing[i] = true
+			ok = ok && g[i][i].Sign() == 0
+			profile[i] = -50 // Very small norm
+			continue
+		}
+		profile[i] = log2Sqrt(rii)
+	}
+	return profile, ok
+}
+
+// log2Sqrt returns log2(√x) for a positive x, also beyond the float64
+// range.
+func log2Sqrt(x *big.Float) float64 {
+	var mant big.Float
+	exp := x.MantExp(&mant)
+	f, _ := mant.Float64()
+	return (float64(exp) + math.Log2(f)) / 2
+}
+
+// autoGSOPrecision returns a mantissa for bigFloatProfile: enough bits to
+// hold every Gram matrix entry exactly, plus float64's 53 bits for the
+// result and another 64 bits against the cancellation in the recurrences.
+func autoGSOPrecision(m *intMatrix) uint {
+	return uint(2*m.maxBits()+bits.Len(uint(m.cols))) + 53 + 64
+}
+
+// profileNeedsBigFloat reports whether the entries of m are too large for
+// the float64 profile implementations, logging the switch to big.Float.
+func profileNeedsBigFloat(m *intMatrix) bool {
+	if b := m.maxBits(); b > float64EntryBits {
+		slog.Debug("basis entries exceed float64 precision, computing the profile with big.Float",
+			"bits", b, "precision", cmp.Or(gsoPrecision, autoGSOPrecision(m)))
+		return true
+	}
+	return false
+}
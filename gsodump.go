@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"slices"
+)
+
+// gsoDumpFile stands for the file of fplll's -bkzdumpgso option in the
+// arguments of a call (--tour-profiles). runFplll replaces it with a
+// temporary file of its own, so that the arguments, and with them the
+// result cache key and the jobs sent to remote workers, don't depend on
+// where the file is.
+const gsoDumpFile = "{gso-dump}"
+
+// tourProfile is one record of fplll's GSO dump: the profile of the basis
+// at a step of BKZ or slide reduction.
+type tourProfile struct {
+	Step    string  `json:"step"` // fplll's label, e.g. Input, the end of a tour, or Output
+	Tour    int     `json:"tour"` // fplll's loop counter
+	Seconds float64 `json:"seconds"`
+	Profile Profile `json:"profile"` // log2 ‖b*_i‖
+}
+
+// parseGSODump reads the file fplll -bkzdumpgso writes: a JSON list with
+// one object per step, e.g.
+//
+//	[
+//	        {
+//	                "step": "Input",
+//	                "loop": -1,
+//	                "time": 0.000000,
+//	                "norms": [9.51 9.48 ...]
+//	        },
+//	...
+//	]
+//
+// whose norms are the natural logarithms of ‖b*_i‖², rank of them. The list
+// of a reduction that was stopped lacks its end; the complete records are
+// returned. A record with another number of norms is an error, returned
+// with the records before it.
+func parseGSODump(r io.Reader, rank int) ([]tourProfile, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("expected a JSON list of GSO records")
+	}
+	var profiles []tourProfile
+	for dec.More() {
+		var rec struct {
+			Step  string    `json:"step"`
+			Loop  int       `json:"loop"`
+			Time  float64   `json:"time"`
+			Norms []float64 `json:"norms"`
+		}
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return profiles, fmt.Errorf("GSO record %d: %w", len(profiles)+1, err)
+		}
+		if len(rec.Norms) != rank {
+			return profiles, fmt.Errorf("GSO record %d has %d norms, expected %d", len(profiles)+1, len(rec.Norms), rank)
+		}
+		p := tourProfile{Step: rec.Step, Tour: rec.Loop, Seconds: rec.Time, Profile: make([]float64, len(rec.Norms))}
+		for i, ln := range rec.Norms {
+			p.Profile[i] = ln / (2 * math.Ln2)
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+// withGSODumpFile returns args with gsoDumpFile replaced by the path of a
+// new temporary file in dir, and the path; args without it are returned as
+// they are with an empty path.
+func withGSODumpFile(args []string, dir string) ([]string, string, error) {
+	i := slices.Index(args, gsoDumpFile)
+	if i < 0 {
+		return args, "", nil
+	}
+	f, err := os.CreateTemp(dir, "lattice-labs-gso-*.json")
+	if err != nil {
+		return nil, "", fmt.Errorf("creating the GSO dump file in %s: %w", dir, err)
+	}
+	f.Close()
+	args = slices.Clone(args)
+	args[i] = f.Name()
+	return args, f.Name(), nil
+}
+
+// readGSODump parses the GSO dump file at path of a basis of the given
+// rank. A dump that can't be read is logged and whatever could be parsed
+// returned, since the reduction itself succeeded.
+func readGSODump(path string, rank int) []tourProfile {
+	f, err := os.Open(path)
+	if err != nil {
+		slog.Warn("reading fplll's GSO dump", "err", err)
+		return nil
+	}
+	defer f.Close()
+	profiles, err := parseGSODump(f, rank)
+	if err != nil {
+		slog.Warn("reading fplll's GSO dump", "path", path, "err", err)
+	}
+	return profiles
+}
+
+// writeTourProfiles writes one row per profile of the GSO dump: the step,
+// fplll's time, log2 ‖b*_1‖ and the slope and R² of the fitted GSA line,
+// unless there are none.
+func writeTourProfiles(w io.Writer, profiles []tourProfile) {
+	if len(profiles) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%d profile(s) from fplll's GSO dump:\n", len(profiles))
+	fmt.Fprintf(w, "%-8s | %-5s | %-9s | %-9s | %-9s | %s\n", "Step", "Tour", "Time", "log2 ‖b1‖", "Slope", "R²")
+	fmt.Fprintln(w, "---------------------------------------------------------------")
+	for _, p := range profiles {
+		if len(p.Profile) == 0 {
+			continue
+		}
+		slope, _, r2 := p.Profile.Fit()
+		fmt.Fprintf(w, "%-8s | %-5d | %-9s | %-9.3f | %-9.5f | %.4f\n",
+			p.Step, p.Tour, fmt.Sprintf("%.3fs", p.Seconds), p.Profile[0], slope, r2)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"math/big"
+)
+
+// certifyProfiles makes computeGramSchmidtProfile compute every profile
+// exactly as well, check the floating-point one against it and return the
+// exact one (--exact-profile).
+var certifyProfiles bool
+
+// profileTolerance is the largest difference, in log2 of a norm, between a
+// floating-point profile and the exact one that isn't reported.
+const profileTolerance = 1e-6
+
+// integralGSO returns the integral Gram-Schmidt data of the rows with Gram
+// matrix g, by the fraction-free Gram-Schmidt of Cohen's "A Course in
+// Computational Algebraic Number Theory" (algorithm 2.6.7), which only
+// divides exactly: indep lists the rows that don't depend linearly on the
+// rows before them, d[k] is the Gram determinant of the first k of them,
+// with d[0] = 1, and lambda[i][k] = d[k+1]·μᵢⱼ, j = indep[k], are the
+// integral coefficients of every row i on the independent rows before it.
+// The k-th independent row has ‖b*‖² = d[k+1]/d[k], a dependent row b* = 0.
+// The integers grow with the rank, so this is meant for moderate ranks.
+func integralGSO(g [][]*big.Int) (indep []int, d []*big.Int, lambda [][]*big.Int) {
+	n := len(g)
+	d = []*big.Int{big.NewInt(1)}
+	lambda = make([][]*big.Int, n)
+	var t big.Int
+	for i := range n {
+		li := make([]*big.Int, len(indep)+1)
+		for jp := range li {
+			j, lj := i, li
+			if jp < len(indep) {
+				j, lj = indep[jp], lambda[indep[jp]]
+			}
+			u := new(big.Int).Set(g[i][j])
+			for k := range jp {
+				u.Mul(u, d[k+1])
+				u.Sub(u, t.Mul(li[k], lj[k]))
+				u.Quo(u, d[k])
+			}
+			li[jp] = u
+		}
+		k := len(indep)
+		lambda[i] = li[:k]
+		if li[k].Sign() != 0 {
+			indep = append(indep, i)
+			d = append(d, li[k])
+		}
+	}
+	return indep, d, lambda
+}
+
+// independentGSO is integralGSO for rows that must be linearly independent,
+// as size reduction and the reducedness checks need, so that d[i+1]/d[i]
+// is ‖b*ᵢ‖² and lambda[i][j] = d[j+1]·μᵢⱼ for j < i.
+func independentGSO(g [][]*big.Int) (d []*big.Int, lambda [][]*big.Int, err error) {
+	indep, d, lambda := integralGSO(g)
+	for i := range g {
+		if i >= len(indep) || indep[i] != i {
+			return nil, nil, fmt.Errorf("basis row %d depends linearly on the rows before it", i+1)
+		}
+	}
+	return d, lambda, nil
+}
+
+// rationalNorms returns the squared Gram-Schmidt norms ‖b*ᵢ‖² of the rows
+// of m as exact fractions, from integralGSO on the exact Gram matrix: rows
+// that depend linearly on the previous ones have a norm of 0.
+func rationalNorms(m *intMatrix) []*big.Rat {
+	indep, d, _ := integralGSO(m.gram().bigRows())
+	norms := make([]*big.Rat, m.rows)
+	for i := range norms {
+		norms[i] = new(big.Rat)
+	}
+	for k, i := range indep {
+		norms[i].SetFrac(d[k+1], d[k])
+	}
+	return norms
+}
+
+// rationalProfile computes the profile from rationalNorms. Its entries are
+// the exact log2 of the norms rounded to float64, except that vanishing
+// norms get a log2 of -50, as in classicalProfile.
+func rationalProfile(m *intMatrix) []float64 {
+	norms := rationalNorms(m)
+	profile := make([]float64, len(norms))
+	for i, norm := range norms {
+		if norm.Sign() == 0 {
+			profile[i] = -50 // Very small norm
+			continue
+		}
+		profile[i] = (log2Int(norm.Num()) - log2Int(norm.Denom())) / 2
+	}
+	return profile
+}
+
+// log2Int returns log2(x) for a positive x, also beyond the float64 range.
+func log2Int(x *big.Int) float64 {
+	return log2Sqrt(new(big.Float).SetInt(x)) * 2
+}
+
+// profileDeviation returns the largest difference between the entries of a
+// profile and the exact one, and its index; NaN entries count as the
+// largest.
+func profileDeviation(profile, exact []float64) (worst float64, at int) {
+	for i := range exact {
+		if d := math.Abs(profile[i] - exact[i]); d > worst || math.IsNaN(d) {
+			worst, at = d, i
+			if math.IsNaN(d) {
+				break
+			}
+		}
+	}
+	return worst, at
+}
+
+// checkProfile compares a floating-point profile with the exact one and
+// warns about the largest difference if it exceeds profileTolerance.
+func checkProfile(profile, exact []float64) {
+	worst, at := profileDeviation(profile, exact)
+	if worst > profileTolerance || math.IsNaN(worst) {
+		slog.Warn("floating-point Gram-Schmidt profile deviates from the exact one", "method", gsoMethod,
+			"rank", len(exact), "index", at+1, "deviation", worst, "exact", exact[at], "computed", profile[at])
+		return
+	}
+	slog.Debug("Gram-Schmidt profile matches the exact one", "method", gsoMethod, "rank", len(exact), "deviation", worst)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"testing"
+)
+
+// basisOf returns the basis with the given integer rows.
+func basisOf(rows ...[]int64) Basis {
+	b := make(Basis, len(rows))
+	for i, row := range rows {
+		b[i] = make([]*big.Int, len(row))
+		for j, v := range row {
+			b[i][j] = big.NewInt(v)
+		}
+	}
+	return b
+}
+
+// qaryBasis returns the basis [[q·I, 0], [A, I]] of a q-ary lattice of rank
+// n with k rows of q, A filled deterministically from seed.
+func qaryBasis(n, k int, q, seed int64) Basis {
+	rows := make([][]int64, n)
+	x := seed
+	for i := range rows {
+		rows[i] = make([]int64, n)
+		if i < k {
+			rows[i][i] = q
+			continue
+		}
+		for j := range k {
+			x = (x*1103515245 + 12345) % (1 << 31)
+			rows[i][j] = x % q
+		}
+		rows[i][i] = 1
+	}
+	return basisOf(rows...)
+}
+
+// gsoTestBases are fixed integer bases the floating-point profiles are
+// checked on: orthogonal, rectangular and small ones, which every method
+// must get right, and unreduced knapsack and q-ary ones, on which Cholesky
+// and classical Gram-Schmidt lose too many bits and may only report so.
+var gsoTestBases = []struct {
+	name  string
+	basis Basis
+	// stable is whether no method may find the basis unstable.
+	stable bool
+}{
+	{"diagonal", basisOf([]int64{1, 0, 0, 0}, []int64{0, 2, 0, 0}, []int64{0, 0, 3, 0}, []int64{0, 0, 0, 4}), true},
+	{"rectangular", basisOf([]int64{1, 2, 3}, []int64{4, 5, 6}), true},
+	{"small", basisOf(
+		[]int64{3, -1, 4, 1, -5},
+		[]int64{9, 2, -6, 5, 3},
+		[]int64{-5, 8, 9, -7, 9},
+		[]int64{3, 2, -3, 8, 4},
+		[]int64{6, -2, 6, 4, -3},
+	), true},
+	{"q-ary", qaryBasis(12, 6, 97, 1), true},
+	{"knapsack", basisOf(
+		[]int64{1, 0, 0, 0, 0, 912873},
+		[]int64{0, 1, 0, 0, 0, 445120},
+		[]int64{0, 0, 1, 0, 0, 731002},
+		[]int64{0, 0, 0, 1, 0, 128731},
+		[]int64{0, 0, 0, 0, 1, 998244},
+	), false},
+	{"q-ary large", qaryBasis(40, 20, 1<<20+7, 2), false},
+}
+
+// TestProfileMethodsMatchExact checks every entry of gsoMethods against the
+// exact rational profile: a method must match it within profileTolerance
+// or report the basis unstable, so that computeGramSchmidtProfile falls
+// back to big.Float, and never be silently wrong.
+func TestProfileMethodsMatchExact(t *testing.T) {
+	methods := make([]string, 0, len(gsoMethods))
+	for name := range gsoMethods {
+		methods = append(methods, name)
+	}
+	sort.Strings(methods)
+	for _, tc := range gsoTestBases {
+		m := intMatrixOf(tc.basis)
+		exact := rationalProfile(m)
+		for _, method := range methods {
+			t.Run(fmt.Sprintf("%s/%s", tc.name, method), func(t *testing.T) {
+				profile, unstable := gsoMethods[method](m)
+				switch {
+				case unstable != "" && tc.stable:
+					t.Fatalf("unstable: %s", unstable)
+				case unstable != "":
+					t.Skipf("unstable, detected: %s", unstable)
+				case len(profile) != len(exact):
+					t.Fatalf("profile has %d entries, want %d", len(profile), len(exact))
+				}
+				if worst, at := profileDeviation(profile, exact); !(worst <= profileTolerance) {
+					t.Errorf("entry %d is %g, exact %g (deviation %g)", at+1, profile[at], exact[at], worst)
+				}
+			})
+		}
+	}
+}
+
+// TestRationalProfile checks the exact profile on bases whose Gram-Schmidt
+// norms are known, including a dependent row.
+func TestRationalProfile(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		basis Basis
+		want  []float64
+	}{
+		{"diagonal", basisOf([]int64{2, 0}, []int64{0, 8}), []float64{1, 3}},
+		{"sheared", basisOf([]int64{4, 0}, []int64{3, 16}), []float64{2, 4}},
+		{"dependent", basisOf([]int64{1, 1}, []int64{2, 2}, []int64{0, 1}), []float64{0.5, -50, -0.5}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := rationalProfile(intMatrixOf(tc.basis))
+			if worst, at := profileDeviation(got, tc.want); !(worst <= 1e-12) {
+				t.Errorf("entry %d is %g, want %g", at+1, got[at], tc.want[at])
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"runtime/trace"
+)
+
+// profileBounds makes Lab 2 and continue certify their profiles with
+// interval arithmetic and report the error bounds (--profile-bounds).
+var profileBounds bool
+
+// interval is a closed interval [lo, hi] of real numbers. Operations round
+// lo down and hi up, so the result contains every value the operation can
+// take on the operands.
+type interval struct {
+	lo, hi *big.Float
+}
+
+// newBound returns a big.Float of precision prec rounding in mode.
+func newBound(prec uint, mode big.RoundingMode) *big.Float {
+	return new(big.Float).SetPrec(prec).SetMode(mode)
+}
+
+// intInterval returns the smallest interval of precision prec containing x.
+func intInterval(x *big.Int, prec uint) interval {
+	return interval{
+		lo: newBound(prec, big.ToNegativeInf).SetInt(x),
+		hi: newBound(prec, big.ToPositiveInf).SetInt(x),
+	}
+}
+
+// sub returns a - b.
+func (a interval) sub(b interval, prec uint) interval {
+	return interval{
+		lo: newBound(prec, big.ToNegativeInf).Sub(a.lo, b.hi),
+		hi: newBound(prec, big.ToPositiveInf).Sub(a.hi, b.lo),
+	}
+}
+
+// mul returns a·b.
+func (a interval) mul(b interval, prec uint) interval {
+	out := interval{}
+	for _, x := range []*big.Float{a.lo, a.hi} {
+		for _, y := range []*big.Float{b.lo, b.hi} {
+			lo := newBound(prec, big.ToNegativeInf).Mul(x, y)
+			hi := newBound(prec, big.ToPositiveInf).Mul(x, y)
+			if out.lo == nil || lo.Cmp(out.lo) < 0 {
+				out.lo = lo
+			}
+			if out.hi == nil || hi.Cmp(out.hi) > 0 {
+				out.hi = hi
+			}
+		}
+	}
+	return out
+}
+
+// quo returns a/b for an interval b of positive numbers.
+func (a interval) quo(b interval, prec uint) interval {
+	out := interval{}
+	for _, x := range []*big.Float{a.lo, a.hi} {
+		for _, y := range []*big.Float{b.lo, b.hi} {
+			lo := newBound(prec, big.ToNegativeInf).Quo(x, y)
+			hi := newBound(prec, big.ToPositiveInf).Quo(x, y)
+			if out.lo == nil || lo.Cmp(out.lo) < 0 {
+				out.lo = lo
+			}
+			if out.hi == nil || hi.Cmp(out.hi) > 0 {
+				out.hi = hi
+			}
+		}
+	}
+	return out
+}
+
+// intervalNorms returns intervals containing the squared Gram-Schmidt norms
+// ‖b*ᵢ‖² of the rows of m, computed with the recurrences of
+// bigFloatProfileAt in interval arithmetic of precision prec on the exact
+// Gram matrix. Once a norm's interval contains 0, the coefficients of the
+// later rows can't be bounded, and their norms are returned as nil.
+func intervalNorms(m *intMatrix, prec uint) []*interval {
+	g := m.gram().bigRows()
+	n := len(g)
+	r := make([][]interval, n)
+	mu := make([][]interval, n)
+	norms := make([]*interval, n)
+	for i := 0; i < n; i++ {
+		r[i] = make([]interval, i+1)
+		mu[i] = make([]interval, i)
+		for j := 0; j <= i; j++ {
+			rij := intInterval(g[i][j], prec)
+			for k := 0; k < j; k++ {
+				rij = rij.sub(mu[j][k].mul(r[i][k], prec), prec)
+			}
+			r[i][j] = rij
+			if j < i {
+				mu[i][j] = rij.quo(r[j][j], prec)
+			}
+		}
+		norms[i] = &r[i][i]
+		if r[i][i].lo.Sign() <= 0 {
+			return norms[:i+1]
+		}
+	}
+	return norms
+}
+
+// certifyProfile returns for every entry of profile, the log2 of the
+// Gram-Schmidt norms of basis, a bound on its error: the true value lies
+// within ±ε of the entry. The bounds come from interval arithmetic with a
+// mantissa of gsoPrecision bits (or autoGSOPrecision), plus the rounding of
+// the float64 logarithms. Entries that can't be certified get +Inf.
+func certifyProfile(basis Basis, profile []float64) []float64 {
+	defer trace.StartRegion(context.Background(), "profile-bounds").End()
+	m := intMatrixOf(basis)
+	prec := gsoPrecision
+	if prec == 0 {
+		prec = autoGSOPrecision(m)
+	}
+	norms := intervalNorms(m, prec)
+	bounds := make([]float64, len(profile))
+	for i := range bounds {
+		bounds[i] = math.Inf(1)
+		if i >= len(norms) || norms[i].lo.Sign() <= 0 {
+			continue
+		}
+		lo, hi := log2Sqrt(norms[i].lo), log2Sqrt(norms[i].hi)
+		eps := max(profile[i]-lo, hi-profile[i])
+		// log2Sqrt is accurate to a few float64 ulps.
+		bounds[i] = eps + 1e-15*max(1, math.Abs(profile[i]))
+	}
+	return bounds
+}
+
+// slopeErrorBound returns a bound on the error of the slope fitted by
+// Profile.Fit to a profile whose entries are within ±bounds[i] of the
+// true values. The slope is Σ wᵢ·profile[i] with wᵢ = (i - x̄)/Σ(i - x̄)², so
+// its error is at most Σ |wᵢ|·bounds[i].
+func slopeErrorBound(bounds []float64) float64 {
+	n := float64(len(bounds))
+	if len(bounds) < 2 {
+		return 0
+	}
+	mean := (n - 1) / 2
+	var sxx, sum float64
+	for i := range bounds {
+		sxx += (float64(i) - mean) * (float64(i) - mean)
+	}
+	for i, eps := range bounds {
+		sum += math.Abs(float64(i)-mean) / sxx * eps
+	}
+	return sum
+}
+
+// writeProfileBounds reports the certified error of a profile and of its
+// fitted GSA slope, and whether the slope is numerically meaningful.
+func writeProfileBounds(w io.Writer, profile Profile, bounds []float64) {
+	uncertified, worst := 0, 0.0
+	for _, eps := range bounds {
+		if math.IsInf(eps, 1) {
+			uncertified++
+		} else {
+			worst = max(worst, eps)
+		}
+	}
+	if uncertified > 0 {
+		fmt.Fprintf(w, "%d of %d profile entries could not be certified; retry with a larger --gso-precision.\n", uncertified, len(bounds))
+		return
+	}
+	slope := profile.Slope()
+	slopeErr := slopeErrorBound(bounds)
+	fmt.Fprintf(w, "Certified error: profile entries within ±%.1e, GSA slope %.4f ± %.1e.\n", worst, slope, slopeErr)
+	if slopeErr >= 0.01*math.Abs(slope) {
+		fmt.Fprintln(w, "The error bound exceeds 1% of the slope: the slope is not numerically meaningful.")
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"runtime"
+	"strings"
+
+	"gonum.org/v1/gonum/floats"
+)
+
+// streamProfileRank is the rank from which the profile command streams a
+// basis file through streamGSO instead of loading it (-stream auto).
+const streamProfileRank = 1000
+
+// streamGSO computes a Gram-Schmidt profile one row at a time. It keeps only
+// the unit vectors b*ᵢ/‖b*ᵢ‖ of the independent rows seen so far, back to
+// back in one float64 slice, and never the basis itself: a basis that takes
+// some 50 bytes per entry as big.Int rows, plus their float64 copies and
+// the factorization workspace, streams through in 8 bytes per entry.
+type streamGSO struct {
+	cols    int
+	q       []float64 // unit Gram-Schmidt vectors, row-major
+	coef    []float64 // projections of the current row on them
+	profile []float64
+	// unstable says why the profile can't be trusted, or is "".
+	unstable string
+}
+
+// newStreamGSO returns a streamGSO for rows of cols entries, with room for
+// rank of them (0 if unknown).
+func newStreamGSO(rank, cols int) *streamGSO {
+	return &streamGSO{cols: cols, q: make([]float64, 0, rank*cols), profile: make([]float64, 0, rank)}
+}
+
+// add orthogonalizes row, which it overwrites, against the rows so far and
+// appends its profile entry. The row is projected twice, classical
+// Gram-Schmidt with reorthogonalization ("twice is enough"), which keeps
+// the orthogonality of Householder QR while the projections on all earlier
+// vectors are independent dot products, computed by GOMAXPROCS goroutines.
+// Vanishing norms get a log2 of -50, as in classicalProfile.
+func (s *streamGSO) add(row []float64) {
+	k := len(s.q) / s.cols
+	s.coef = append(s.coef[:0], make([]float64, k)...)
+	normSq := floats.Dot(row, row)
+	for pass := 0; pass < 2; pass++ {
+		forEachRow(0, k, runtime.GOMAXPROCS(0), func(j int) {
+			s.coef[j] = floats.Dot(row, s.q[j*s.cols:(j+1)*s.cols])
+		})
+		for j, c := range s.coef {
+			floats.AddScaled(row, -c, s.q[j*s.cols:(j+1)*s.cols])
+		}
+	}
+	orthoSq := floats.Dot(row, row)
+	entry := log2Norm(orthoSq)
+	s.profile = append(s.profile, entry)
+	if entry == -50 {
+		return
+	}
+	if s.unstable == "" && entry < log2Norm(normSq)-(float64EntryBits-minSignificantBits) {
+		s.unstable = fmt.Sprintf("‖b*%d‖ lost more than %d bits to cancellation", len(s.profile), float64EntryBits-minSignificantBits)
+	}
+	floats.Scale(1/math.Sqrt(orthoSq), row)
+	s.q = append(s.q, row...)
+}
+
+// addInts adds a row of integers, which must have cols entries of at most
+// float64EntryBits bits.
+func (s *streamGSO) addInts(row []*big.Int) error {
+	i := len(s.profile) + 1
+	if len(row) != s.cols {
+		return fmt.Errorf("row %d has %d entries, expected %d", i, len(row), s.cols)
+	}
+	f := make([]float64, s.cols)
+	for j, v := range row {
+		if v.BitLen() > float64EntryBits {
+			return fmt.Errorf("row %d: entry beyond 2^%d, which the float64 streaming profile would round (use -stream off)", i, float64EntryBits)
+		}
+		f[j], _ = v.Float64()
+	}
+	s.add(f)
+	return nil
+}
+
+// streamProfile computes the profile of m with streamGSO, the --gso stream
+// method.
+func streamProfile(m *intMatrix) (profile []float64, unstable string) {
+	s := newStreamGSO(m.rows, m.cols)
+	for i := 0; i < m.rows; i++ {
+		s.add(m.float64Row(i, make([]float64, m.cols)))
+	}
+	return s.profile, s.unstable
+}
+
+// streamBasisProfile computes the profile of the basis in data, in fplll,
+// NTL or CSV format, parsing and orthogonalizing one row at a time. rank is
+// the expected number of rows, used to size the buffers only.
+func streamBasisProfile(data []byte, format string, rank int) (profile []float64, unstable string, err error) {
+	var s *streamGSO
+	onRow := func(row []*big.Int) error {
+		if s == nil {
+			s = newStreamGSO(rank, len(row))
+		}
+		return s.addInts(row)
+	}
+	switch format {
+	case "fplll", "ntl":
+		var scan bracketScan
+		err = walkBrackets(bytes.NewReader(data), &scan, func(row []*big.Int, _ int) error { return onRow(row) })
+	case "csv":
+		err = walkCSVRows(data, onRow)
+	default:
+		return nil, "", fmt.Errorf("%s bases can't be streamed (want fplll, ntl or csv)", format)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("%s format: %w", format, err)
+	}
+	if s == nil {
+		return nil, "", fmt.Errorf("%s format: no basis vectors found", format)
+	}
+	return s.profile, s.unstable, nil
+}
+
+// walkCSVRows parses the rows of a CSV basis as readCSVBasis does and passes
+// each one to onRow, without keeping them.
+func walkCSVRows(data []byte, onRow func(row []*big.Int) error) error {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.ReuseRecord = true
+	for i := 1; ; i++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for j := range record {
+			record[j] = strings.TrimSpace(record[j])
+		}
+		row, err := parseIntegers(record)
+		if err != nil {
+			return fmt.Errorf("row %d: %w", i, err)
+		}
+		if err := onRow(row); err != nil {
+			return err
+		}
+	}
+}
+
+// countBasisRows counts the rows of a basis in data without parsing its
+// entries: the non-empty innermost bracketed lists in fplll and NTL format,
+// the non-blank lines in CSV. It returns 0 for other formats.
+func countBasisRows(data []byte, format string) int {
+	rows := 0
+	switch format {
+	case "fplll", "ntl":
+		inRow, filled := false, false
+		for _, c := range data {
+			switch {
+			case c == '[':
+				inRow, filled = true, false
+			case c == ']':
+				if inRow && filled {
+					rows++
+				}
+				inRow = false
+			case !isEntrySeparator(c):
+				filled = true
+			}
+		}
+	case "csv":
+		for len(data) > 0 {
+			line := data
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				line, data = data[:i], data[i+1:]
+			} else {
+				data = nil
+			}
+			if len(bytes.TrimSpace(line)) > 0 {
+				rows++
+			}
+		}
+	}
+	return rows
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+)
+
+// float64MaxExponent is the largest binary exponent of a finite float64:
+// Gram entries of more bits overflow to +Inf when rounded.
+const float64MaxExponent = 1023
+
+// healthReport is what basisHealth finds out about the numerics of a basis.
+type healthReport struct {
+	Rank, Dim int
+	// MaxBits and MeanBits are the sizes of the largest and of the average
+	// non-zero entry; Wide counts the entries float64 rounds.
+	MaxBits  int
+	MeanBits float64
+	Wide     int
+	GramBits int // the size of the largest Gram matrix entry
+	// Reference is the big.Float profile, at the mantissa Precision that
+	// bigFloatProfile chooses; Dependent counts its vanishing norms.
+	Reference Profile
+	Precision uint
+	Dependent int
+	// LogCond is log2 of max ‖bᵢ‖ / min ‖b*ᵢ‖, a lower bound on the
+	// condition number κ(B), whose square bounds κ(B·Bᵀ) from below.
+	LogCond float64
+	Methods []methodHealth
+	// GSOPrecisionOK is whether --gso-precision, if set, resolves every
+	// norm (see bigFloatProfileAt).
+	GSOPrecisionOK bool
+}
+
+// methodHealth is how one float64 profile implementation fares on a basis.
+type methodHealth struct {
+	Name string
+	// Error is the largest difference from the reference profile, in bits
+	// (NaN for a lost entry), and Unstable what made floatProfile reject
+	// the result, if anything did.
+	Error    float64
+	Unstable string
+}
+
+// silent reports whether the method's profile is off without floatProfile
+// noticing, so that computeGramSchmidtProfile would return it.
+func (h methodHealth) silent() bool {
+	return h.Unstable == "" && !(h.Error <= profileTolerance)
+}
+
+// healthMethods are the float64 profile implementations basisHealth
+// tries; stream gives the same profile as classical.
+var healthMethods = []string{"householder", "cholesky", "block", "classical"}
+
+// basisHealth measures the numerics of basis: the sizes of its entries and
+// of its Gram matrix, the condition estimate, and the error of every
+// float64 profile implementation against the big.Float profile, which
+// resolves every norm.
+func basisHealth(basis Basis) healthReport {
+	m := intMatrixOf(basis)
+	r := healthReport{Rank: m.rows, Dim: m.cols, MaxBits: m.maxBits(), GramBits: m.gram().maxBits()}
+	nonZero := 0
+	for _, row := range basis {
+		for _, v := range row {
+			if v.Sign() == 0 {
+				continue
+			}
+			nonZero++
+			r.MeanBits += float64(v.BitLen())
+			if v.BitLen() > float64EntryBits {
+				r.Wide++
+			}
+		}
+	}
+	if nonZero > 0 {
+		r.MeanBits /= float64(nonZero)
+	}
+	r.Precision = autoGSOPrecision(m)
+	r.Reference = bigFloatProfile(m, 0)
+	r.Dependent = countVanishing(r.Reference)
+
+	maxNorm, minStar := math.Inf(-1), math.Inf(1)
+	for i, row := range basis {
+		maxNorm = max(maxNorm, log2Int(normSquared(row))/2)
+		if r.Reference[i] != -50 {
+			minStar = min(minStar, r.Reference[i])
+		}
+	}
+	r.LogCond = maxNorm - minStar
+
+	for _, name := range healthMethods {
+		profile, unstable := gsoMethods[name](m)
+		h := methodHealth{Name: name, Error: math.NaN(), Unstable: unstable}
+		if len(profile) == m.rows {
+			h.Error, _ = profileDeviation(profile, r.Reference)
+			if h.Unstable == "" {
+				h.Unstable = unstableProfile(m, profile)
+			}
+		}
+		r.Methods = append(r.Methods, h)
+	}
+	r.GSOPrecisionOK = true
+	if gsoPrecision != 0 {
+		_, r.GSOPrecisionOK = bigFloatProfileAt(m.gram().bigRows(), gsoPrecision)
+	}
+	return r
+}
+
+// warnings returns what the user of the basis should know before trusting
+// its profiles, worst first: profiles that would be wrong without any sign
+// of it, then the fallbacks the package takes on its own.
+func (r healthReport) warnings() []string {
+	var w []string
+	for _, h := range r.Methods {
+		if h.silent() && h.Name == gsoMethod {
+			w = append(w, fmt.Sprintf("--gso %s is off by up to %.2g bits with no sign of instability: its profiles of this basis are silently wrong; use --gso bigfloat or --exact-profile", h.Name, h.Error))
+		}
+	}
+	if !r.GSOPrecisionOK {
+		w = append(w, fmt.Sprintf("--gso-precision %d leaves some norms unresolved; %d bits or 0 (automatic) resolve them", gsoPrecision, r.Precision))
+	}
+	if r.Wide > 0 {
+		w = append(w, fmt.Sprintf("%d entries exceed float64's %d bits; profiles are computed with big.Float whatever --gso says", r.Wide, float64EntryBits))
+	}
+	if r.GramBits > float64MaxExponent {
+		w = append(w, fmt.Sprintf("Gram matrix entries of %d bits overflow float64, so --gso cholesky can't run", r.GramBits))
+	}
+	if r.Dependent > 0 {
+		w = append(w, fmt.Sprintf("%d rows depend linearly on earlier ones; their profile entries are -50", r.Dependent))
+	}
+	for _, h := range r.Methods {
+		if h.Unstable != "" && h.Name == gsoMethod {
+			w = append(w, fmt.Sprintf("--gso %s is unstable on this basis (%s); profiles fall back to big.Float, which is slower", h.Name, h.Unstable))
+		}
+	}
+	return w
+}
+
+// runHealth writes the numerical health report of the input basis to w:
+// its sizes, the condition estimate and the bits of float64 it leaves, how
+// close each float64 profile implementation comes to the big.Float one,
+// and warnings, notably if the implementation --gso selects would return a
+// wrong profile without noticing.
+func runHealth(w io.Writer, cfg pipeConfig) error {
+	basis, err := loadPipeBasis(cfg.Input, cfg.From)
+	if err != nil {
+		return err
+	}
+	r := basisHealth(basis)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "Numerical health of a basis of rank %d in dimension %d\n\n", r.Rank, r.Dim)
+	fmt.Fprintf(bw, "Entries:     at most %d bits, %.1f on average; %d beyond float64's %d\n", r.MaxBits, r.MeanBits, r.Wide, float64EntryBits)
+	fmt.Fprintf(bw, "Gram matrix: entries of at most %d bits\n", r.GramBits)
+	fmt.Fprintf(bw, "Condition:   log2 κ(B) ≥ %.1f, log2 κ(B·Bᵀ) ≥ %.1f (max ‖bᵢ‖ / min ‖b*ᵢ‖)\n", r.LogCond, 2*r.LogCond)
+	fmt.Fprintf(bw, "float64:     %s left after Householder QR, %s after Cholesky\n", bitsLeft(r.LogCond), bitsLeft(2*r.LogCond))
+	fmt.Fprintf(bw, "big.Float:   %d-bit mantissa chosen", r.Precision)
+	if gsoPrecision != 0 {
+		fmt.Fprintf(bw, "; --gso-precision %d %s", gsoPrecision, map[bool]string{true: "suffices", false: "does not suffice"}[r.GSOPrecisionOK])
+	}
+	fmt.Fprintf(bw, "\nVolume:      log2 %.4g\n\n", r.Reference.LogVolume())
+
+	fmt.Fprintf(bw, "%-12s | %-16s | %s\n", "Method", "Max error (bits)", "Verdict")
+	fmt.Fprintln(bw, "----------------------------------------------")
+	for _, h := range r.Methods {
+		verdict := "ok"
+		switch {
+		case h.Unstable != "":
+			verdict = "unstable, detected: " + h.Unstable
+		case h.silent():
+			verdict = "WRONG, undetected"
+		}
+		if h.Name == gsoMethod {
+			verdict += " (--gso)"
+		}
+		fmt.Fprintf(bw, "%-12s | %-16.3g | %s\n", h.Name, h.Error, verdict)
+	}
+	if warnings := r.warnings(); len(warnings) > 0 {
+		fmt.Fprintln(bw)
+		for _, warning := range warnings {
+			fmt.Fprintf(bw, "Warning: %s\n", warning)
+		}
+	}
+	return bw.Flush()
+}
+
+// bitsLeft formats the significant bits of float64 left after an error
+// amplification of 2^logCond.
+func bitsLeft(logCond float64) string {
+	if left := float64EntryBits - logCond; left >= 1 {
+		return fmt.Sprintf("about %.0f bits", math.Floor(left))
+	}
+	return "no bits"
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+)
+
+// htmlReportTemplate is the single-page report. Everything, including the
+// profile plots, is inlined so the file can be shared on its own.
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{"statusText": statusText}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Lattice Heuristics Lab Report</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.75em; text-align: right; }
+th { background: #f0f0f0; }
+dl { display: grid; grid-template-columns: max-content auto; gap: 0.25em 1em; }
+dt { font-weight: bold; }
+svg { background: #fafafa; border: 1px solid #ddd; }
+</style>
+</head>
+<body>
+<h1>Lattice Heuristics Lab Report</h1>
+
+<h2>Run metadata</h2>
+<dl>
+{{range .Metadata}}<dt>{{.Key}}</dt><dd>{{.Value}}</dd>
+{{end}}</dl>
+
+{{range $i, $lab := .Lab1}}
+<h2>Lab 1: Gaussian Heuristic (q = {{$lab.Q}})</h2>
+<table>
+<tr><th>n</th><th>trial</th><th>GH prediction</th><th>SVP norm</th><th>relative error</th><th>oracle time (s)</th></tr>
+{{range $lab.Rows}}<tr><td>{{.N}}</td><td>{{.Trial}}</td><td>{{printf "%.2f" .GH}}</td>{{if .Status}}<td colspan="2">{{statusText .Status}}</td>{{else}}<td>{{printf "%.2f" .Lambda1}}</td><td>{{printf "%.2f" .RelativeError}}%</td>{{end}}<td>{{printf "%.3f" .OracleSeconds}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{range .Lab2}}
+<h2>Lab 2: Geometric Series Assumption (rank {{.Rank}}, q = {{.Q}})</h2>
+{{if .Status}}<p>Reduction: {{.Reduction}}. No profile: the reduction {{statusText .Status}} after {{printf "%.1f" .ReductionSeconds}} s.</p>
+{{else}}<p>Reduction: {{.Reduction}}. Fitted GSA line: slope {{printf "%.4f" .Slope}}, intercept {{printf "%.2f" .Intercept}}, R² = {{printf "%.4f" .R2}}.</p>
+{{end}}
+{{.Plot}}
+{{end}}
+
+{{if .Sweep}}
+<h2>Grid search</h2>
+<table>
+<tr><th>n</th><th>β</th><th>q</th><th>GH</th><th>‖b1‖</th><th>δ0</th><th>slope</th><th>time (s)</th><th>timed out</th><th>failed</th></tr>
+{{range .Sweep}}<tr><td>{{.N}}</td><td>{{.Beta}}</td><td>{{.Q}}</td><td>{{printf "%.2f" .GH}}</td><td>{{printf "%.2f" .B1}}</td><td>{{printf "%.5f" .Delta}}</td><td>{{printf "%.4f" .Slope}}</td><td>{{printf "%.2f" .Seconds}}</td><td>{{.TimedOut}}</td><td>{{.Failed}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// htmlMetadataEntry is one key/value line of the report's metadata block.
+type htmlMetadataEntry struct {
+	Key, Value string
+}
+
+// htmlProfile is a Lab 2 result prepared for the report.
+type htmlProfile struct {
+	lab2Result
+	Reduction string
+	Slope     float64
+	Intercept float64
+	R2        float64
+	Plot      template.HTML
+}
+
+// writeHTMLReport renders the results as a self-contained HTML page with
+// result tables, inline SVG profile plots with their fitted GSA lines, and
+// run metadata.
+func writeHTMLReport(w io.Writer, res *runResults) error {
+	data := struct {
+		Metadata []htmlMetadataEntry
+		Lab1     []lab1Result
+		Lab2     []htmlProfile
+		Sweep    []sweepRow
+	}{
+		Lab1:  res.Lab1,
+		Sweep: res.Sweep,
+	}
+	if res.Metadata != nil {
+		for _, kv := range res.Metadata.entries() {
+			data.Metadata = append(data.Metadata, htmlMetadataEntry{kv[0], kv[1]})
+		}
+	}
+
+	for _, lab := range res.Lab2 {
+		slope, intercept, r2 := lab.Profile.Fit()
+		data.Lab2 = append(data.Lab2, htmlProfile{
+			lab2Result: lab,
+			Reduction:  describeReduction(lab.Reduction),
+			Slope:      slope,
+			Intercept:  intercept,
+			R2:         r2,
+			Plot:       profileSVG(lab.Profile, slope, intercept),
+		})
+	}
+
+	return htmlReportTemplate.Execute(w, data)
+}
+
+// profileSVG draws the profile as a polyline with the fitted line
+// slope*i + intercept dashed on top of it.
+func profileSVG(profile []float64, slope, intercept float64) template.HTML {
+	const width, height, margin = 640.0, 320.0, 40.0
+	if len(profile) < 2 {
+		return ""
+	}
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range profile {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	if hi == lo {
+		hi = lo + 1
+	}
+	last := float64(len(profile) - 1)
+	x := func(i float64) float64 { return margin + i/last*(width-2*margin) }
+	y := func(v float64) float64 { return height - margin - (v-lo)/(hi-lo)*(height-2*margin) }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">`, width, height, width, height)
+	fmt.Fprintf(&b, `<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="#999"/>`, margin, height-margin, width-margin, height-margin)
+	fmt.Fprintf(&b, `<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="#999"/>`, margin, margin, margin, height-margin)
+	fmt.Fprintf(&b, `<text x="%g" y="%g" font-size="12">%.2f</text>`, 2.0, margin, hi)
+	fmt.Fprintf(&b, `<text x="%g" y="%g" font-size="12">%.2f</text>`, 2.0, height-margin, lo)
+	fmt.Fprintf(&b, `<text x="%g" y="%g" font-size="12" text-anchor="middle">index i</text>`, width/2, height-10)
+
+	b.WriteString(`<polyline fill="none" stroke="#1f77b4" stroke-width="2" points="`)
+	for i, v := range profile {
+		fmt.Fprintf(&b, "%.1f,%.1f ", x(float64(i)), y(v))
+	}
+	b.WriteString(`"/>`)
+	fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#d62728" stroke-dasharray="6,4"/>`,
+		x(0), y(intercept), x(last), y(slope*last+intercept))
+	b.WriteString(`</svg>`)
+
+	return template.HTML(b.String())
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+	"strconv"
+)
+
+// intMatrix is an integer matrix that keeps its entries as int64 while they
+// all fit and switches to *big.Int as soon as one doesn't. The entries of
+// generated and reduced bases are far below the int64 range, so conversions,
+// Gram matrices and formatting mostly run on machine integers; the exact
+// results of the big.Int code are kept when they are not.
+type intMatrix struct {
+	rows, cols int
+	small      []int64      // row-major entries while the matrix is small
+	large      [][]*big.Int // entries once promoted; small is nil then
+}
+
+// newIntMatrix returns a zero rows×cols matrix.
+func newIntMatrix(rows, cols int) *intMatrix {
+	return &intMatrix{rows: rows, cols: cols, small: make([]int64, rows*cols)}
+}
+
+// intMatrixOf returns the matrix with the given rows, which must all have
+// the length of the first one. It shares no memory with basis.
+func intMatrixOf(basis [][]*big.Int) *intMatrix {
+	cols := 0
+	if len(basis) > 0 {
+		cols = len(basis[0])
+	}
+	m := newIntMatrix(len(basis), cols)
+	for i, row := range basis {
+		for j, v := range row {
+			m.setBig(i, j, v)
+		}
+	}
+	return m
+}
+
+// isSmall reports whether the entries are stored as int64.
+func (m *intMatrix) isSmall() bool {
+	return m.large == nil
+}
+
+// setBig sets entry (i, j) to v, promoting the matrix if v doesn't fit in
+// an int64.
+func (m *intMatrix) setBig(i, j int, v *big.Int) {
+	if m.isSmall() && v.IsInt64() {
+		m.small[i*m.cols+j] = v.Int64()
+		return
+	}
+	m.promote()
+	m.large[i][j].Set(v)
+}
+
+// promote converts the entries to *big.Int; it does nothing if they already
+// are.
+func (m *intMatrix) promote() {
+	if !m.isSmall() {
+		return
+	}
+	m.large = make([][]*big.Int, m.rows)
+	for i := range m.large {
+		m.large[i] = make([]*big.Int, m.cols)
+		for j := range m.large[i] {
+			m.large[i][j] = big.NewInt(m.small[i*m.cols+j])
+		}
+	}
+	m.small = nil
+}
+
+// maxBits returns the bit length of the entry of largest absolute value.
+func (m *intMatrix) maxBits() int {
+	n := 0
+	if m.isSmall() {
+		for _, v := range m.small {
+			n = max(n, bits.Len64(absInt64(v)))
+		}
+		return n
+	}
+	for _, row := range m.large {
+		for _, v := range row {
+			n = max(n, v.BitLen())
+		}
+	}
+	return n
+}
+
+// bigRows returns the entries as rows of new *big.Int values.
+func (m *intMatrix) bigRows() [][]*big.Int {
+	out := make([][]*big.Int, m.rows)
+	for i := range out {
+		out[i] = make([]*big.Int, m.cols)
+		for j := range out[i] {
+			if m.isSmall() {
+				out[i][j] = big.NewInt(m.small[i*m.cols+j])
+			} else {
+				out[i][j] = new(big.Int).Set(m.large[i][j])
+			}
+		}
+	}
+	return out
+}
+
+// float64Rows returns the entries rounded to float64, row by row.
+func (m *intMatrix) float64Rows() [][]float64 {
+	out := make([][]float64, m.rows)
+	for i := range out {
+		out[i] = m.float64Row(i, make([]float64, m.cols))
+	}
+	return out
+}
+
+// float64Row stores the entries of row i rounded to float64 in dst, which
+// has length cols, and returns it.
+func (m *intMatrix) float64Row(i int, dst []float64) []float64 {
+	for j := range dst {
+		if m.isSmall() {
+			dst[j] = float64(m.small[i*m.cols+j])
+		} else {
+			dst[j], _ = m.large[i][j].Float64()
+		}
+	}
+	return dst
+}
+
+// float64s returns the entries rounded to float64 in row-major order, as
+// gonum's mat.NewDense takes them.
+func (m *intMatrix) float64s() []float64 {
+	out := make([]float64, 0, m.rows*m.cols)
+	for _, row := range m.float64Rows() {
+		out = append(out, row...)
+	}
+	return out
+}
+
+// gram returns the Gram matrix B·Bᵀ of the rows, exactly. It is computed in
+// int64 arithmetic unless an entry of m is large or a product or sum
+// overflows, in which case it is recomputed with *big.Int.
+func (m *intMatrix) gram() *intMatrix {
+	g := newIntMatrix(m.rows, m.rows)
+	if m.isSmall() {
+		if m.gramSmall(g) {
+			return g
+		}
+		g = newIntMatrix(m.rows, m.rows)
+	}
+	rows := m.bigRows()
+	g.promote()
+	var prod big.Int
+	for i := 0; i < m.rows; i++ {
+		for j := 0; j <= i; j++ {
+			sum := g.large[i][j]
+			for k := 0; k < m.cols; k++ {
+				sum.Add(sum, prod.Mul(rows[i][k], rows[j][k]))
+			}
+			g.large[j][i].Set(sum)
+		}
+	}
+	return g
+}
+
+// gramSmall fills g with the Gram matrix of the small matrix m and reports
+// whether it fit in int64. If the largest entry rules out an overflow, the
+// products are summed without checks.
+func (m *intMatrix) gramSmall(g *intMatrix) bool {
+	var maxAbs uint64
+	for _, v := range m.small {
+		maxAbs = max(maxAbs, absInt64(v))
+	}
+	if m.cols == 0 || maxAbs <= uint64(math.Sqrt(float64(math.MaxInt64/m.cols)))-1 {
+		for i := 0; i < m.rows; i++ {
+			ri := m.small[i*m.cols : (i+1)*m.cols]
+			for j := 0; j <= i; j++ {
+				g.small[i*m.rows+j] = dotInt64(ri, m.small[j*m.cols:(j+1)*m.cols])
+				g.small[j*m.rows+i] = g.small[i*m.rows+j]
+			}
+		}
+		return true
+	}
+	for i := 0; i < m.rows; i++ {
+		ri := m.small[i*m.cols : (i+1)*m.cols]
+		for j := 0; j <= i; j++ {
+			rj := m.small[j*m.cols : (j+1)*m.cols]
+			var sum int64
+			for k, a := range ri {
+				p, ok := mulInt64(a, rj[k])
+				if !ok {
+					return false
+				}
+				if sum, ok = addInt64(sum, p); !ok {
+					return false
+				}
+			}
+			g.small[i*m.rows+j] = sum
+			g.small[j*m.rows+i] = sum
+		}
+	}
+	return true
+}
+
+// dotInt64 returns the dot product of a and b, which have the same length,
+// without overflow checks. It sums four products at a time, which lets the
+// compiler drop the bounds checks.
+func dotInt64(a, b []int64) int64 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 int64
+	k := 0
+	for ; k+4 <= len(a); k += 4 {
+		s0 += a[k] * b[k]
+		s1 += a[k+1] * b[k+1]
+		s2 += a[k+2] * b[k+2]
+		s3 += a[k+3] * b[k+3]
+	}
+	for ; k < len(a); k++ {
+		s0 += a[k] * b[k]
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// mulInt64 returns a·b and whether it didn't overflow.
+func mulInt64(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	neg := (a < 0) != (b < 0)
+	limit := uint64(math.MaxInt64)
+	if neg {
+		limit++ // -2⁶³ still fits
+	}
+	hi, lo := bits.Mul64(absInt64(a), absInt64(b))
+	if hi != 0 || lo > limit {
+		return 0, false
+	}
+	if neg {
+		return int64(-lo), true
+	}
+	return int64(lo), true
+}
+
+// addInt64 returns a+b and whether it didn't overflow.
+func addInt64(a, b int64) (int64, bool) {
+	s := a + b
+	return s, (s > a) == (b > 0)
+}
+
+// absInt64 returns |v| as a uint64, which holds it even for math.MinInt64.
+func absInt64(v int64) uint64 {
+	if v < 0 {
+		return uint64(-v)
+	}
+	return uint64(v)
+}
+
+// appendRow appends the entries of row i in decimal to dst, separated by sep.
+func (m *intMatrix) appendRow(dst []byte, i int, sep string) []byte {
+	for j := 0; j < m.cols; j++ {
+		if j > 0 {
+			dst = append(dst, sep...)
+		}
+		if m.isSmall() {
+			dst = strconv.AppendInt(dst, m.small[i*m.cols+j], 10)
+		} else {
+			dst = m.large[i][j].Append(dst, 10)
+		}
+	}
+	return dst
+}
+
+// parseEntry parses a decimal integer, taking the fast strconv path for the
+// common case of one that fits in an int64.
+func parseEntry(field []byte) (*big.Int, bool) {
+	if v, err := strconv.ParseInt(string(field), 10, 64); err == nil {
+		return big.NewInt(v), true
+	}
+	return new(big.Int).SetString(string(field), 10)
+}
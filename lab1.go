@@ -1,14 +1,20 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"crypto/rand"
 	"fmt"
+	"io"
+	"log/slog"
 	"math"
 	"math/big"
 	"os"
-	"os/exec"
-	"strconv"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"gonum.org/v1/gonum/mat"
 )
@@ -16,9 +22,9 @@ import (
 // toBigIntMatrix converts a gonum.org/v1/mat.Dense matrix of float64s
 // into a 2D slice of *big.Int. This is a utility for when we need to
 // handle matrices with integer coefficients that may exceed the capacity of int64.
-func toBigIntMatrix(m *mat.Dense) [][]*big.Int {
+func toBigIntMatrix(m *mat.Dense) Basis {
 	rows, cols := m.Dims()
-	result := make([][]*big.Int, rows)
+	result := make(Basis, rows)
 
 	for i := 0; i < rows; i++ {
 		result[i] = make([]*big.Int, cols)
@@ -34,139 +40,313 @@ func toBigIntMatrix(m *mat.Dense) [][]*big.Int {
 // genRandomBasis generates a "hard" random square lattice basis of the given rank.
 // It populates a matrix with large random numbers drawn from [0, q), ensuring
 // a high-determinant lattice that is a good candidate for reduction algorithms.
-func genRandomBasis(rank int, q *big.Int) [][]*big.Int {
-	basis := make([][]*big.Int, rank)
+func genRandomBasis(rank int, q *big.Int) Basis {
+	return genRandomBasisFrom(basisSource, rank, q)
+}
+
+// genRandomBasisFrom is genRandomBasis drawing from src instead of the run's
+// basisSource, e.g. from the stream of a single trial.
+func genRandomBasisFrom(src io.Reader, rank int, q *big.Int) Basis {
+	basis := make(Basis, rank)
 	for i := 0; i < rank; i++ {
 		basis[i] = make([]*big.Int, rank)
 		for j := 0; j < rank; j++ {
 			// Generate a large random integer for each entry
-			randVal, _ := rand.Int(rand.Reader, q)
+			randVal, _ := rand.Int(src, q)
 			basis[i][j] = new(big.Int).Set(randVal)
 		}
 	}
 	return basis
 }
 
-// latticeVolume calculates the volume of the lattice spanned by the given basis.
-// The volume is defined as the square root of the determinant of B * B^T.
-// The basis is temporarily converted to float64 for use with the gonum/mat library.
-func latticeVolume(basis [][]*big.Int) *big.Float {
-	size := len(basis)
-
-	// Convert basis to gonum Dense matrix
-	B := mat.NewDense(size, size, nil)
-	for i := 0; i < size; i++ {
-		for j := 0; j < size; j++ {
-			val, _ := basis[i][j].Float64()
-			B.Set(i, j, val)
-		}
-	}
-
-	// Calculate B * B^T
-	BT := mat.NewDense(size, size, nil)
-	BT.Copy(B)
-	BT.T()
-
-	BBT := mat.NewDense(size, size, nil)
-	BBT.Mul(B, BT)
-
-	// Calculate determinant
-	det := mat.Det(BBT)
-
-	// Return square root of determinant as big.Float
-	vol := big.NewFloat(math.Sqrt(math.Abs(det)))
-	return vol
-}
-
 // gaussianHeuristic computes the predicted length of the shortest non-zero vector
 // in a lattice of a given rank and volume, based on the Gaussian Heuristic formula.
-func gaussianHeuristic(vol *big.Float, rank int) *big.Float {
+// It takes log2 of the volume, which stays finite for every rank where the volume
+// itself overflows a float64.
+func gaussianHeuristic(logVol float64, rank int) float64 {
 	// GH(L) = sqrt(n/(2*pi*e)) * vol(L)^(1/n)
 	n := float64(rank)
+	return math.Sqrt(n/(2*math.Pi*math.E)) * math.Exp2(logVol/n)
+}
 
-	// Calculate sqrt(n/(2*pi*e))
-	coefficient := math.Sqrt(n / (2 * math.Pi * math.E))
-
-	// Calculate vol^(1/n)
-	volFloat64, _ := vol.Float64()
-	volPowerN := math.Pow(volFloat64, 1.0/n)
-
-	// Combine
-	result := coefficient * volPowerN
-
-	return big.NewFloat(result)
+// log2Float returns log2 of v from its mantissa and exponent, finite however
+// far v exceeds the range of float64; log2 of 0 is -Inf.
+func log2Float(v *big.Float) float64 {
+	mant := new(big.Float)
+	exp := v.MantExp(mant)
+	m, _ := mant.Float64()
+	return math.Log2(m) + float64(exp)
 }
 
 // writeBasisToFile writes a basis matrix to a file in fplll format
-func writeBasisToFile(basis [][]*big.Int, filename string) error {
+func writeBasisToFile(basis Basis, filename string) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	if err := writeBasis(file, basis); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
 
-	// Write in fplll format: [rows] [cols] followed by the matrix
-	rows := len(basis)
-	cols := len(basis[0])
+// writeBasis writes a basis matrix to w in fplll format, one row per line.
+func writeBasis(w io.Writer, basis Basis) error {
+	bw := bufio.NewWriter(w)
 
-	fmt.Fprintf(file, "[")
-	for i := 0; i < rows; i++ {
-		fmt.Fprintf(file, "[")
-		for j := 0; j < cols; j++ {
-			fmt.Fprintf(file, "%s", basis[i][j].String())
-			if j < cols-1 {
-				fmt.Fprintf(file, " ")
-			}
-		}
-		fmt.Fprintf(file, "]")
-		if i < rows-1 {
-			fmt.Fprintf(file, "\n")
+	// Write in fplll format: [rows] [cols] followed by the matrix
+	m := intMatrixOf(basis)
+	line := []byte{'['}
+	for i := 0; i < m.rows; i++ {
+		line = append(line, '[')
+		line = m.appendRow(line, i, " ")
+		line = append(line, ']')
+		if i < m.rows-1 {
+			line = append(line, '\n')
 		}
+		bw.Write(line)
+		line = line[:0]
 	}
-	fmt.Fprintf(file, "]\n")
+	bw.WriteString("]\n")
+
+	return bw.Flush()
+}
 
+// svpMethods are the values of fplll -m an SVP call can run with: proved
+// keeps fplll's guarantees on the floating-point computations, heuristic
+// and fast drop them for speed. "" leaves the choice to fplll.
+var svpMethods = []string{"proved", "heuristic", "fast"}
+
+// validateSVPMethod checks that method is "" or one of svpMethods.
+func validateSVPMethod(method string) error {
+	if method != "" && !slices.Contains(svpMethods, method) {
+		return fmt.Errorf("unknown SVP method %q (want %s)", method, strings.Join(svpMethods, ", "))
+	}
 	return nil
 }
 
+// svpArgs returns the fplll arguments of an SVP call with the given method.
+func svpArgs(method string) []string {
+	if method == "" {
+		return backend.fplllArgs("svp")
+	}
+	return backend.fplllArgs("svp", "-m", method)
+}
+
+// svpCommand describes the fplll call of an SVP query, as reductionStep's
+// command does for reductions.
+func svpCommand(method string) string {
+	if method == "" {
+		return "fplll -a svp"
+	}
+	return "fplll -a svp -m " + method
+}
+
 // svpOracle finds the shortest non-zero vector in the lattice using fplll command line tool.
-// It writes the basis to a temporary file, calls fplll -a svp, and parses the result.
-func svpOracle(basis [][]*big.Int, radius float64) float64 {
-	// Write basis to temporary file
-	tmpFile := "/tmp/lattice_basis.txt"
-	err := writeBasisToFile(basis, tmpFile)
+// It passes the basis to fplll -a svp on standard input and returns the
+// squared norm of the vector found together with the vector. method is one
+// of svpMethods or "" for fplll's default. A positive radius bounds the
+// search: the shortest vector shorter than radius is found by boundedSVP
+// instead, and errNoVectorInRadius returned if there is none. The vector is
+// checked exactly to be a non-zero vector of the lattice. If fplll fails,
+// times out or gives no such vector, the error is returned instead. In a
+// dry run nothing is computed and radius² is returned as a placeholder.
+func svpOracle(ctx context.Context, basis Basis, radius float64, method string) (float64, []*big.Int, error) {
+	if dryRun != nil {
+		if radius > 0 {
+			planFplll(basis, backend.fplllArgs("lll")...)
+		} else {
+			planFplll(basis, svpArgs(method)...)
+		}
+		return radius * radius, nil, nil
+	}
+	var vector []*big.Int
+	var err error
+	if radius > 0 {
+		vector, err = boundedSVP(ctx, basis, radius)
+	} else {
+		vector, err = shortestVector(ctx, basis, method)
+	}
 	if err != nil {
-		fmt.Printf("Error writing basis to file: %v\n", err)
-		return 0
+		return 0, nil, err
+	}
+	if len(vector) == 0 {
+		return 0, nil, fmt.Errorf("fplll -a svp returned an empty vector")
+	}
+	if err := verifyLatticeVector(basis, vector); err != nil {
+		slog.Error("SVP oracle returned a wrong vector", "rank", len(basis), "err", err)
+		return 0, nil, err
 	}
-	defer os.Remove(tmpFile)
 
-	// Call fplll -a svp
-	cmd := exec.Command("fplll", "-a", "svp", tmpFile)
-	output, err := cmd.Output()
+	return vectorNormSquared(vector), vector, nil
+}
+
+// shortestVector runs fplll -a svp with the given method on the basis and
+// returns the shortest non-zero vector it prints. Failures are logged and
+// returned.
+func shortestVector(ctx context.Context, basis Basis, method string) ([]*big.Int, error) {
+	// Call fplll -a svp; the output should be in format [val1 val2 val3 ...]
+	var vector []*big.Int
+	err := backend.runFplllCached(ctx, basis, svpArgs(method), func(r io.Reader) (err error) {
+		if vector, err = backend.Version.dialect().Vector(r); err == nil && len(vector) != len(basis[0]) {
+			err = fmt.Errorf("%d entries, expected %d", len(vector), len(basis[0]))
+		}
+		return err
+	})
 	if err != nil {
-		fmt.Printf("Error running fplll: %v\n", err)
-		return 0
+		logFplllError("svp", len(basis), err)
+		return nil, err
 	}
+	return vector, nil
+}
 
-	// Parse the output to extract the shortest vector and compute its norm
-	outputStr := strings.TrimSpace(string(output))
-
-	// The output should be in format [val1 val2 val3 ...]
-	if strings.HasPrefix(outputStr, "[") && strings.HasSuffix(outputStr, "]") {
-		vectorStr := outputStr[1 : len(outputStr)-1] // Remove brackets
-		coords := strings.Fields(vectorStr)
+// validateApproxFactor checks an approximation factor: 0 for exact SVP, or
+// positive.
+func validateApproxFactor(factor float64) error {
+	if factor < 0 || math.IsNaN(factor) || math.IsInf(factor, 0) {
+		return fmt.Errorf("approximation factor must be positive, got %g", factor)
+	}
+	return nil
+}
 
-		norm := 0.0
-		for _, coord := range coords {
-			if val, err := strconv.ParseFloat(coord, 64); err == nil {
-				norm += val * val
+// approxBetaStep is how much the block size grows between the BKZ rounds of
+// approxSVP.
+const approxBetaStep = 10
+
+// approxSVP is the approximate SVP oracle: it looks for a non-zero lattice
+// vector of norm at most factor·gh by progressive BKZ, i.e. LLL and then
+// BKZ with block sizes growing by approxBetaStep, each round starting from
+// the basis of the one before, until the first basis vector is short enough.
+// The last round has block size rank, which solves SVP, so a vector is
+// always found; its squared norm is returned with the vector and the block
+// size of the round that found it (0 if LLL sufficed). Like svpOracle's, the
+// vector is checked to be in the lattice of basis. In a dry run only the
+// LLL call is planned and (factor·gh)² is returned as a placeholder.
+func approxSVP(ctx context.Context, basis Basis, gh, factor float64) (float64, []*big.Int, int, error) {
+	target := factor * gh
+	if dryRun != nil {
+		planFplll(basis, backend.fplllArgs("lll")...)
+		return target * target, nil, 0, nil
+	}
+	reduced, err := lllReduce(ctx, basis)
+	beta := 0
+	for err == nil {
+		normSquared := vectorNormSquared(reduced[0])
+		if math.Sqrt(normSquared) <= target || beta == len(basis) {
+			if err := verifyLatticeVector(basis, reduced[0]); err != nil {
+				slog.Error("approximate SVP oracle returned a wrong vector", "rank", len(basis), "beta", beta, "err", err)
+				return 0, nil, beta, err
 			}
+			return normSquared, reduced[0], beta, nil
 		}
-		return norm
+		beta = min(beta+approxBetaStep, len(basis))
+		reduced, err = bkzReduce(ctx, reduced, beta, bkzOptions{})
 	}
+	return 0, nil, beta, err
+}
 
-	// If parsing fails, return a reasonable estimate
-	return radius * radius
+// vectorNormSquared returns the squared Euclidean norm of v as a float64.
+func vectorNormSquared(v []*big.Int) float64 {
+	f, _ := new(big.Float).SetInt(normSquared(v)).Float64()
+	return f
+}
+
+// lab1Config holds the parameters of a Gaussian Heuristic sweep: the range of
+// random basis coefficients, the dimensions to visit, how many independent
+// trials to run per dimension, any reduction applied before the oracle and
+// the fplll method of the oracle (see svpMethods). A non-zero ApproxFactor
+// replaces the exact oracle with approxSVP for vectors within that multiple
+// of the Gaussian Heuristic.
+type lab1Config struct {
+	Q            *big.Int
+	MinDim       int
+	MaxDim       int
+	Step         int
+	Trials       int
+	Reduction    []reductionStep
+	SVPMethod    string
+	ApproxFactor float64
+	// Generator is "sublattice" or "superlattice" to replace each random
+	// basis by a random sublattice or superlattice of index Index of its
+	// lattice (see randomSublattice and randomSuperlattice); "" or
+	// "random" keeps the random bases.
+	Generator string
+	Index     int64
+}
+
+// defaultLab1Config returns the parameters used by the classic Lab 1 run.
+func defaultLab1Config() lab1Config {
+	return lab1Config{Q: big.NewInt(131), MinDim: 30, MaxDim: 60, Step: 2, Trials: 1}
+}
+
+// lab1Row is the outcome of a single Lab 1 instance.
+type lab1Row struct {
+	N             int       `json:"n"`
+	Trial         int       `json:"trial"`
+	Volume        jsonFloat `json:"volume"`
+	GH            jsonFloat `json:"gh"`
+	Lambda1       jsonFloat `json:"lambda1"`
+	RelativeError jsonFloat `json:"relative_error_percent"`
+	OracleSeconds float64   `json:"oracle_seconds"`
+	Beta          int       `json:"beta,omitempty"`   // block size at which the approximate oracle succeeded
+	Status        string    `json:"status,omitempty"` // "timeout" or "failed" if fplll gave no λ1; λ1 is then NaN
+	// Vector is the shortest vector found, of norm λ1, checked to lie in
+	// the lattice; with the saved basis (--save-bases) it makes the row
+	// independently checkable.
+	Vector []*big.Int `json:"vector,omitempty"`
+}
+
+// lab1Result collects all rows of a Lab 1 run together with its parameters.
+type lab1Result struct {
+	Q         string          `json:"q"`
+	MinDim    int             `json:"min_dim"`
+	MaxDim    int             `json:"max_dim"`
+	Step      int             `json:"step"`
+	Trials    int             `json:"trials"`
+	Reduction []reductionStep `json:"reduction,omitempty"`
+	SVPMethod string          `json:"svp_method,omitempty"`
+	// ApproxFactor is set for runs of the approximate oracle, whose λ1 is
+	// the norm of the vector found, an upper bound on the true λ1.
+	ApproxFactor float64 `json:"approx_factor,omitempty"`
+	// Generator and Index are set for runs on sublattices or
+	// superlattices of the random lattices.
+	Generator string    `json:"generator,omitempty"`
+	Index     int64     `json:"index,omitempty"`
+	Rows      []lab1Row `json:"rows"`
+	Seconds   float64   `json:"seconds"`
+}
+
+// genBasis generates the basis of rank n of a Lab 1 instance: a random
+// basis, or a random sublattice or superlattice of its lattice as
+// cfg.Generator selects. A superlattice is returned scaled to be integral,
+// which the relative error of the Gaussian Heuristic doesn't see.
+func (cfg lab1Config) genBasis(n int) Basis {
+	basis := genRandomBasis(n, cfg.Q)
+	switch cfg.Generator {
+	case "sublattice":
+		return randomSublattice(basisSource, basis, cfg.Index)
+	case "superlattice":
+		super, _ := randomSuperlattice(basisSource, basis, cfg.Index)
+		return super
+	}
+	return basis
+}
+
+// lab1Instance is one random basis of a Lab 1 run, numbered in the order
+// the instances are generated and reported.
+type lab1Instance struct {
+	index    int
+	n, trial int
+	basis    Basis
+}
+
+// lab1Outcome is the row computed for an instance; ok is false if ctx was
+// cancelled before the oracle finished.
+type lab1Outcome struct {
+	index int
+	row   lab1Row
+	ok    bool
 }
 
 // runLab1Verification orchestrates the primary experiment of Lab 1.
@@ -175,41 +355,219 @@ func svpOracle(basis [][]*big.Int, radius float64) float64 {
 // 2. Predicts the shortest vector norm using the Gaussian Heuristic.
 // 3. Finds the actual shortest vector norm using the SVP oracle.
 // 4. Prints the predicted norm, the actual norm, and the relative error.
-func runLab1Verification() {
-	fmt.Println("--- Running Lab 1: Verifying the Gaussian Heuristic ---")
-	fmt.Println("Using FPLLL command-line tool for accurate SVP computation.")
+// The log is written to w and the collected rows are returned. If ctx is
+// cancelled the run stops and returns the rows completed so far.
+//
+// The instances are solved by backend.Jobs workers. The bases are still
+// generated one after the other, so a seeded run yields the same instances
+// for any number of jobs, and the rows are printed and returned in order.
+func runLab1Verification(ctx context.Context, w io.Writer, cfg lab1Config) lab1Result {
+	start := time.Now()
+	result := lab1Result{
+		Q:            cfg.Q.String(),
+		MinDim:       cfg.MinDim,
+		MaxDim:       cfg.MaxDim,
+		Step:         cfg.Step,
+		Trials:       cfg.Trials,
+		Reduction:    cfg.Reduction,
+		SVPMethod:    cfg.SVPMethod,
+		ApproxFactor: cfg.ApproxFactor,
+	}
+	if cfg.Generator == "sublattice" || cfg.Generator == "superlattice" {
+		result.Generator, result.Index = cfg.Generator, cfg.Index
+	}
+
+	fmt.Fprintln(w, "--- Running Lab 1: Verifying the Gaussian Heuristic ---")
+	fmt.Fprintln(w, "Using FPLLL command-line tool for accurate SVP computation.")
+	if cfg.SVPMethod != "" {
+		fmt.Fprintf(w, "SVP method: %s.\n", cfg.SVPMethod)
+	}
+	if cfg.ApproxFactor > 0 {
+		fmt.Fprintf(w, "Approximate SVP: progressive BKZ until a vector within %g·GH is found; the SVP norm is its length.\n", cfg.ApproxFactor)
+	}
 	// This q now defines the range of entries for our random basis
-	q := big.NewInt(131)
-	fmt.Printf("Target q for random coefficients: %s. Iterating from n=30 to n=60...\n\n", q.String())
+	q := cfg.Q
+	fmt.Fprintf(w, "Target q for random coefficients: %s. Iterating from n=%d to n=%d...\n", q.String(), cfg.MinDim, cfg.MaxDim)
+	if result.Generator != "" {
+		fmt.Fprintf(w, "Each lattice is replaced by a random %s of index %d.\n", result.Generator, result.Index)
+	}
+	fmt.Fprintln(w)
 
-	fmt.Printf("%-4s | %-13s | %-13s | %-14s\n", "n", "GH Prediction", "SVP Norm", "Relative Error")
-	fmt.Println("------------------------------------------------------")
+	fmt.Fprintf(w, "%-4s | %-13s | %-13s | %-14s\n", "n", "GH Prediction", "SVP Norm", "Relative Error")
+	fmt.Fprintln(w, "------------------------------------------------------")
 
-	for n := 30; n <= 60; n += 2 {
-		// NOTE: We are replacing genBasis with genRandomBasis.
-		// The rank of this lattice is simply n.
-		basis := genRandomBasis(n, q)
+	progress := &sharedProgress{ev: progressEvent{Lab: "Lab 1", Q: q.String(), Trials: cfg.Trials}}
+	if cfg.MaxDim >= cfg.MinDim {
+		progress.ev.Total = ((cfg.MaxDim-cfg.MinDim)/cfg.Step + 1) * cfg.Trials
+	}
 
-		// The rank is n, not m+n
-		rank := n
+	// Generate the instances in order and hand them to the workers.
+	todo := make(chan lab1Instance)
+	go func() {
+		defer close(todo)
+		index := 0
+		for n := cfg.MinDim; n <= cfg.MaxDim; n += cfg.Step {
+			for trial := 1; trial <= cfg.Trials; trial++ {
+				// NOTE: We are replacing genBasis with genRandomBasis.
+				// The rank of this lattice is simply n.
+				inst := lab1Instance{index: index, n: n, trial: trial, basis: cfg.genBasis(n)}
+				select {
+				case todo <- inst:
+				case <-ctx.Done():
+					return
+				}
+				index++
+			}
+		}
+	}()
+
+	outcomes := make(chan lab1Outcome)
+	var wg sync.WaitGroup
+	for range max(backend.Jobs, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for inst := range todo {
+				outcomes <- solveLab1Instance(ctx, cfg, inst, progress)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	// Print the rows in order as soon as all earlier ones are in.
+	pending := make(map[int]lab1Row)
+	next := 0
+	emit := func(row lab1Row) {
+		// The dimension 'n' is now the total rank
+		if row.Status != "" {
+			fmt.Fprintf(w, "%-4d | %-13.2f | %-13s | %-14s\n", row.N, row.GH, statusText(row.Status), "-")
+			result.Rows = append(result.Rows, row)
+			return
+		}
+		fmt.Fprintf(w, "%-4d | %-13.2f | %-13.2f | %-13.2f%%", row.N, row.GH, row.Lambda1, row.RelativeError)
+		if row.Beta > 0 {
+			fmt.Fprintf(w, " | BKZ-%d", row.Beta)
+		}
+		fmt.Fprintln(w)
+		result.Rows = append(result.Rows, row)
+	}
+	for out := range outcomes {
+		if !out.ok {
+			continue
+		}
+		pending[out.index] = out.row
+		for row, ok := pending[next]; ok; row, ok = pending[next] {
+			emit(row)
+			delete(pending, next)
+			next++
+		}
+	}
+	// Interrupted: keep the rows completed after the first missing one too.
+	rest := make([]int, 0, len(pending))
+	for index := range pending {
+		rest = append(rest, index)
+	}
+	sort.Ints(rest)
+	for _, index := range rest {
+		emit(pending[index])
+	}
 
-		// Calculate lattice volume
-		vol := latticeVolume(basis)
+	if ctx.Err() != nil {
+		fmt.Fprintln(w, "\nLab 1 interrupted.")
+	} else {
+		fmt.Fprintln(w, "\nLab 1 finished.")
+	}
+	result.Seconds = time.Since(start).Seconds()
+	return result
+}
 
-		// Calculate Gaussian heuristic prediction
-		gh := gaussianHeuristic(vol, rank)
-		ghFloat, _ := gh.Float64()
+// solveLab1Instance applies the reduction pipeline of cfg to the instance,
+// calls the SVP oracle and compares λ1 with the Gaussian Heuristic.
+func solveLab1Instance(ctx context.Context, cfg lab1Config, inst lab1Instance, progress *sharedProgress) lab1Outcome {
+	if ctx.Err() != nil {
+		return lab1Outcome{index: inst.index}
+	}
+	lattice := newLattice(inst.basis)
 
-		// Call SVP oracle
-		svpNormSquared := svpOracle(basis, 1.5*ghFloat)
-		svpNorm := math.Sqrt(svpNormSquared)
+	// Calculate lattice volume
+	vol := lattice.Volume()
 
-		// Calculate relative error
-		relativeError := math.Abs(svpNorm-ghFloat) / svpNorm * 100
+	// Calculate Gaussian heuristic prediction
+	gh := lattice.GH()
+	ghFloat, _ := gh.Float64()
 
-		// The dimension 'n' is now the total rank
-		fmt.Printf("%-4d | %-13.2f | %-13.2f | %-13.2f%%\n", n, ghFloat, svpNorm, relativeError)
+	// Optional preprocessing requested by the experiment; the lattice
+	// keeps the volume, which reduction doesn't change.
+	if len(cfg.Reduction) > 0 {
+		progress.running(inst.n, inst.trial, reductionCommands(cfg.Reduction))
 	}
-
-	fmt.Println("\nLab 1 finished.")
+	volFloat, _ := vol.Float64()
+	// failed is the row of an instance whose reduction or oracle call did
+	// not succeed: it has no λ1.
+	failed := func(err error, seconds float64) lab1Outcome {
+		progress.completed(inst.n, inst.trial, seconds, nil)
+		return lab1Outcome{index: inst.index, ok: true, row: lab1Row{
+			N:             inst.n,
+			Trial:         inst.trial,
+			Volume:        jsonFloat(volFloat),
+			GH:            jsonFloat(ghFloat),
+			Lambda1:       jsonFloat(math.NaN()),
+			RelativeError: jsonFloat(math.NaN()),
+			OracleSeconds: seconds,
+			Status:        failureStatus(err),
+		}}
+	}
+	err := lattice.Reduce(ctx, cfg.Reduction)
+	if ctx.Err() != nil {
+		return lab1Outcome{index: inst.index}
+	}
+	if err != nil {
+		return failed(err, 0)
+	}
+	basis := lattice.Basis()
+
+	// Call SVP oracle
+	oracleStart := time.Now()
+	var svpNormSquared float64
+	var vector []*big.Int
+	beta := 0
+	if cfg.ApproxFactor > 0 {
+		progress.running(inst.n, inst.trial, "progressive fplll -a bkz")
+		svpNormSquared, vector, beta, err = approxSVP(ctx, basis, ghFloat, cfg.ApproxFactor)
+	} else {
+		progress.running(inst.n, inst.trial, svpCommand(cfg.SVPMethod))
+		svpNormSquared, vector, err = svpOracle(ctx, basis, 0, cfg.SVPMethod)
+		if dryRun != nil {
+			// Nothing was computed; the prediction stands in for λ1.
+			svpNormSquared = ghFloat * ghFloat
+		}
+	}
+	oracleTime := time.Since(oracleStart)
+	if ctx.Err() != nil {
+		return lab1Outcome{index: inst.index}
+	}
+	if err != nil {
+		return failed(err, oracleTime.Seconds())
+	}
+	progress.completed(inst.n, inst.trial, oracleTime.Seconds(), nil)
+	archiveBasis(savedBasis{Lab: "Lab 1", N: inst.n, Q: cfg.Q.String(), Trial: inst.trial, Reduction: cfg.Reduction}, basis, vector)
+	svpNorm := math.Sqrt(svpNormSquared)
+
+	// Calculate relative error
+	relativeError := math.Abs(svpNorm-ghFloat) / svpNorm * 100
+
+	return lab1Outcome{index: inst.index, ok: true, row: lab1Row{
+		N:             inst.n,
+		Trial:         inst.trial,
+		Volume:        jsonFloat(volFloat),
+		GH:            jsonFloat(ghFloat),
+		Lambda1:       jsonFloat(svpNorm),
+		RelativeError: jsonFloat(relativeError),
+		OracleSeconds: oracleTime.Seconds(),
+		Beta:          beta,
+		Vector:        vector,
+	}}
 }
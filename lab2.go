@@ -1,188 +1,257 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	"math/big"
-	"os"
-	"os/exec"
 	"strconv"
-	"strings"
+	"time"
 )
 
 // runBKZ performs BKZ reduction on a given basis using the fplll command line tool.
-// It writes the basis to a temporary file, calls fplll -a bkz, and parses the reduced basis
-// to compute the Gram-Schmidt profile using Go's matrix operations.
-func runBKZ(basis [][]*big.Int, beta int) []float64 {
-	rank := len(basis)
-
-	// Write basis to temporary file
-	tmpFile := "/tmp/lattice_basis_bkz.txt"
-	err := writeBasisToFile(basis, tmpFile)
-	if err != nil {
-		fmt.Printf("Error writing basis to file: %v\n", err)
-		return make([]float64, rank)
-	}
-	defer os.Remove(tmpFile)
-
-	// Call fplll -a bkz with specified block size
-	cmd := exec.Command("fplll", "-a", "bkz", "-b", strconv.Itoa(beta), tmpFile)
-	output, err := cmd.Output()
+// It passes the basis to fplll -a bkz on standard input and parses the reduced basis
+// to compute the Gram-Schmidt profile using Go's matrix operations. opts bound
+// the tours of BKZ.
+func runBKZ(ctx context.Context, basis Basis, beta int, opts bkzOptions) ([]float64, error) {
+	reducedBasis, err := bkzReduce(ctx, basis, beta, opts)
 	if err != nil {
-		fmt.Printf("Error running fplll BKZ: %v\n", err)
-		return make([]float64, rank)
-	}
-
-	// Parse the reduced basis from output
-	reducedBasis := parseMatrixOutput(string(output))
-
-	if reducedBasis == nil || len(reducedBasis) != rank {
-		fmt.Printf("Error parsing BKZ output\n")
-		return make([]float64, rank)
+		return nil, err
 	}
 
 	// Compute Gram-Schmidt profile
-	profile := computeGramSchmidtProfile(reducedBasis)
+	profile := reducedBasis.Profile()
 
-	return profile
+	return profile, nil
 }
 
-// parseMatrixOutput parses the matrix output from fplll
-func parseMatrixOutput(output string) [][]*big.Int {
-	lines := strings.Split(strings.TrimSpace(output), "\n")
-	if len(lines) == 0 {
-		return nil
-	}
+// bkzReduce runs fplll -a bkz with the given block size and BKZ options and
+// returns the reduced basis.
+func bkzReduce(ctx context.Context, basis Basis, beta int, opts bkzOptions) (Basis, error) {
+	return fplllReduce(ctx, basis, "bkz", append([]string{"-b", strconv.Itoa(beta)}, opts.args()...)...)
+}
 
-	var matrix [][]*big.Int
+// slideReduce runs fplll -a sld, Gama and Nguyen's slide reduction, with the
+// given block size and BKZ options and returns the reduced basis.
+func slideReduce(ctx context.Context, basis Basis, beta int, opts bkzOptions) (Basis, error) {
+	return fplllReduce(ctx, basis, "sld", append([]string{"-b", strconv.Itoa(beta)}, opts.args()...)...)
+}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || (!strings.HasPrefix(line, "[") && !strings.Contains(line, "[")) {
-			continue
-		}
+// hkzReduce runs fplll -a hkz and returns the HKZ-reduced basis. Its cost
+// grows like that of SVP in the full rank, so it suits small ranks only.
+func hkzReduce(ctx context.Context, basis Basis) (Basis, error) {
+	return fplllReduce(ctx, basis, "hkz")
+}
 
-		// Remove brackets and parse row
-		line = strings.Trim(line, "[]")
-		if line == "" {
-			continue
-		}
+// lllReduce runs fplll -a lll with the default parameters and returns the
+// reduced basis.
+func lllReduce(ctx context.Context, basis Basis) (Basis, error) {
+	return fplllReduce(ctx, basis, "lll")
+}
 
-		coords := strings.Fields(line)
-		if len(coords) == 0 {
-			continue
-		}
+// fplllReduce runs a basis reduction algorithm of fplll (selected with -a)
+// with extra command-line arguments and parses the reduced basis it prints.
+// Failures are logged and returned; a timeout wraps errTimeout.
+func fplllReduce(ctx context.Context, basis Basis, algo string, args ...string) (Basis, error) {
+	rank := len(basis)
 
-		var row []*big.Int
-		for _, coord := range coords {
-			val := new(big.Int)
-			if _, ok := val.SetString(coord, 10); ok {
-				row = append(row, val)
-			}
-		}
+	cmdArgs := backend.fplllArgs(algo, args...)
+	if dryRun != nil {
+		planFplll(basis, cmdArgs...)
+		return basis, nil
+	}
 
-		if len(row) > 0 {
-			matrix = append(matrix, row)
+	// Call fplll -a <algo> with the requested options and parse the reduced
+	// basis as fplll prints it.
+	var reducedBasis Basis
+	err := backend.runFplllCached(ctx, basis, cmdArgs, func(r io.Reader) (err error) {
+		if reducedBasis, err = backend.Version.dialect().Matrix(r); err == nil {
+			err = checkReducedBasis(basis, reducedBasis)
 		}
+		return err
+	})
+	if err != nil {
+		logFplllError(algo, rank, err)
+		return nil, err
 	}
 
-	return matrix
+	return reducedBasis, nil
 }
 
-// computeGramSchmidtProfile computes the log2 of Gram-Schmidt vector norms
-func computeGramSchmidtProfile(basis [][]*big.Int) []float64 {
-	n := len(basis)
-	if n == 0 {
-		return nil
-	}
+// rootHermiteFactor returns the root Hermite factor δ0 that BKZ with block
+// size beta is expected to reach, using the asymptotic estimate
+// δ0 = ((β/(2πe)) · (πβ)^(1/β))^(1/(2(β-1))). The estimate is only accurate
+// for β above roughly 40 but gives the right trend for smaller blocks.
+func rootHermiteFactor(beta int) float64 {
+	b := float64(beta)
+	return math.Pow(b/(2*math.Pi*math.E)*math.Pow(math.Pi*b, 1/b), 1/(2*(b-1)))
+}
 
-	// Convert to float64 for numerical stability
-	B := make([][]float64, n)
-	for i := 0; i < n; i++ {
-		B[i] = make([]float64, n)
-		for j := 0; j < n; j++ {
-			if j < len(basis[i]) {
-				val, _ := basis[i][j].Float64()
-				B[i][j] = val
-			}
-		}
-	}
+// expectedGSASlope returns the slope of the log2 profile predicted by the
+// Geometric Series Assumption after BKZ-beta: ‖b_i*‖ shrinks by a factor of
+// about δ0² per index, so the slope is -2·log2(δ0), δ0 taken from the table
+// in use (deltas).
+func expectedGSASlope(beta int) float64 {
+	return -2 * math.Log2(deltas.At(beta))
+}
 
-	// Perform Gram-Schmidt orthogonalization
-	profile := make([]float64, n)
-	orthoBasis := make([][]float64, n)
-
-	for i := 0; i < n; i++ {
-		// Copy current vector
-		orthoBasis[i] = make([]float64, n)
-		copy(orthoBasis[i], B[i])
-
-		// Orthogonalize against previous vectors
-		for k := 0; k < i; k++ {
-			dot := 0.0
-			orthogNormSq := 0.0
-
-			for j := 0; j < n; j++ {
-				dot += B[i][j] * orthoBasis[k][j]
-				orthogNormSq += orthoBasis[k][j] * orthoBasis[k][j]
-			}
-
-			if orthogNormSq > 1e-10 {
-				coeff := dot / orthogNormSq
-				for j := 0; j < n; j++ {
-					orthoBasis[i][j] -= coeff * orthoBasis[k][j]
-				}
-			}
+// finalBlockSize returns the block size of the last BKZ or slide reduction
+// step of a pipeline, or 0 if the pipeline contains none or ends with HKZ
+// reduction, whose block is the whole basis.
+func finalBlockSize(steps []reductionStep) int {
+	for i := len(steps) - 1; i >= 0; i-- {
+		switch {
+		case steps[i].blockwise():
+			return steps[i].Beta
+		case steps[i].algo() == "hkz":
+			return 0
 		}
+	}
+	return 0
+}
 
-		// Compute norm and take log2
-		norm := 0.0
-		for j := 0; j < n; j++ {
-			norm += orthoBasis[i][j] * orthoBasis[i][j]
-		}
+// lab2Config holds the parameters of a Geometric Series Assumption run: the
+// rank and coefficient range of the random basis and the reduction pipeline
+// applied to it before the profile is taken.
+type lab2Config struct {
+	Rank      int
+	Q         *big.Int
+	Reduction []reductionStep
+}
 
-		if norm > 1e-10 {
-			profile[i] = math.Log2(math.Sqrt(norm))
-		} else {
-			profile[i] = -50 // Very small norm
-		}
+// defaultLab2Config returns the parameters used by the classic Lab 2 run.
+func defaultLab2Config() lab2Config {
+	return lab2Config{
+		Rank: 30,
+		// Use a large prime for the coefficient range to ensure a "hard" lattice
+		Q:         big.NewInt(100003),
+		Reduction: []reductionStep{bkzStep(28)}, // Increased from 20 to 28 for clearer GSA profile
 	}
+}
 
-	return profile
+// lab2Result is the outcome of a Lab 2 run: its parameters, the profile of
+// the reduced basis and how long the reduction took.
+type lab2Result struct {
+	Rank             int             `json:"rank"`
+	Q                string          `json:"q"`
+	Reduction        []reductionStep `json:"reduction"`
+	Profile          Profile         `json:"profile"`
+	ProfileError     []jsonFloat     `json:"profile_error,omitempty"` // certified ±ε of each entry (--profile-bounds)
+	ReductionSeconds float64         `json:"reduction_seconds"`
+	Seconds          float64         `json:"seconds"`
+	Status           string          `json:"status,omitempty"` // "timeout" or "failed" if the reduction gave no profile
+	// Stopped is set if the reduction ran out of its time budget
+	// (--max-time); the profile is that of the best basis it reached.
+	Stopped bool      `json:"stopped,omitempty"`
+	Tours   []bkzTour `json:"tours,omitempty"` // BKZ and slide tours reported by fplll (--tour-stats)
+	// TourProfiles are the profiles after every tour (--tour-profiles).
+	TourProfiles []tourProfile `json:"tour_profiles,omitempty"`
+	// Steps report on the basis after every step of a pipeline of more
+	// than one, and Vector is the shortest vector of its last enum step.
+	Steps  []stepReport `json:"steps,omitempty"`
+	Vector []*big.Int   `json:"vector,omitempty"`
 }
 
 // runLab2Verification orchestrates the experiment for Lab 2.
 // It generates a random lattice basis, runs the powerful BKZ reduction algorithm
 // on it, and then prints the resulting basis profile. The linearity of this
 // profile in a plot is evidence for the Geometric Series Assumption.
-func runLab2Verification() {
-	fmt.Println("--- Running Lab 2: Verifying the Geometric Series Assumption ---")
-	fmt.Println("Using FPLLL command-line tool for accurate BKZ reduction.")
-
-	rank := 30
-	beta := 28 // Increased from 20 to 28 for clearer GSA profile
-	// Use a large prime for the coefficient range to ensure a "hard" lattice
-	q := big.NewInt(100003) // A reasonably large prime
-
-	fmt.Printf("Generating a random lattice of rank %d with coefficients up to %s.\n", rank, q.String())
+// The log is written to w and the profile is returned in the result. If ctx
+// is cancelled during the reduction the result has no profile and must be
+// discarded; if the reduction fails or times out the result has no profile
+// and its Status says why.
+func runLab2Verification(ctx context.Context, w io.Writer, cfg lab2Config) lab2Result {
+	start := time.Now()
+	fmt.Fprintln(w, "--- Running Lab 2: Verifying the Geometric Series Assumption ---")
+	fmt.Fprintln(w, "Using FPLLL command-line tool for accurate BKZ reduction.")
+
+	rank := cfg.Rank
+	q := cfg.Q
+
+	fmt.Fprintf(w, "Generating a random lattice of rank %d with coefficients up to %s.\n", rank, q.String())
 	// Pass 'q' to the new generator
 	basis := genRandomBasis(rank, q)
 
-	fmt.Printf("Running BKZ reduction with block size beta = %d...\n", beta)
-	profile := runBKZ(basis, beta)
-
-	fmt.Println("BKZ finished.")
-	fmt.Println("Basis Profile (log2 of Gram-Schmidt norms):")
+	fmt.Fprintf(w, "Running %s...\n", describeReduction(cfg.Reduction))
+	ev := progressEvent{Lab: "Lab 2", N: rank, Beta: finalBlockSize(cfg.Reduction), Q: q.String(), Trial: 1, Trials: 1, Total: 1,
+		Backend: reductionCommands(cfg.Reduction)}
+	reportProgress(ev)
+	reductionStart := time.Now()
+	var tours tourLog
+	pipeline := reductionPipeline{Steps: cfg.Reduction, MaxTime: backend.MaxTime, CheckVolume: true}
+	if len(cfg.Reduction) > 1 {
+		pipeline.Report = func(r stepReport) { writeStepReport(w, r) }
+	}
+	state, err := pipeline.run(withTourLog(ctx, &tours), basis)
+	reduced := state.Basis
+	reductionTime := time.Since(reductionStart)
+	if ctx.Err() != nil {
+		fmt.Fprintln(w, "\nLab 2 interrupted.")
+		return lab2Result{Rank: rank, Q: q.String(), Reduction: cfg.Reduction}
+	}
+	if err != nil {
+		ev.Backend, ev.Done, ev.Seconds = "", 1, reductionTime.Seconds()
+		reportProgress(ev)
+		if errors.Is(err, errTimeout) {
+			fmt.Fprintf(w, "\n%s timed out after %s.\n", reductionCommands(cfg.Reduction), backend.Timeout)
+		} else {
+			fmt.Fprintf(w, "\n%s failed; no profile was computed.\n", reductionCommands(cfg.Reduction))
+		}
+		return lab2Result{Rank: rank, Q: q.String(), Reduction: cfg.Reduction, ReductionSeconds: reductionTime.Seconds(),
+			Seconds: time.Since(start).Seconds(), Status: failureStatus(err)}
+	}
+	profile := reduced.Profile()
+	archiveBasis(savedBasis{Lab: "Lab 2", N: rank, Q: q.String(), Trial: 1, Reduction: cfg.Reduction}, reduced, nil)
+	ev.Backend, ev.Done, ev.Profile, ev.Seconds = "", 1, profile, reductionTime.Seconds()
+	reportProgress(ev)
+
+	if state.Stopped {
+		fmt.Fprintf(w, "Reduction stopped at the time budget of %s; the basis is the best reached.\n", backend.MaxTime)
+	} else {
+		fmt.Fprintln(w, "Reduction finished.")
+	}
+	if state.Vector != nil {
+		fmt.Fprintf(w, "Shortest vector found: norm %.2f.\n", math.Sqrt(vectorNormSquared(state.Vector)))
+	}
+	writeTours(w, tours.Tours())
+	writeTourProfiles(w, tours.Profiles())
+	fmt.Fprintln(w, "Basis Profile (log2 of Gram-Schmidt norms):")
 
 	// Format the profile output
-	fmt.Print("[")
+	fmt.Fprint(w, "[")
 	for i, val := range profile {
 		if i > 0 {
-			fmt.Print(", ")
+			fmt.Fprint(w, ", ")
 		}
-		fmt.Printf("%.2f", val)
+		fmt.Fprintf(w, "%.2f", val)
+	}
+	fmt.Fprintln(w, "]")
+	var bounds []jsonFloat
+	if profileBounds {
+		eps := certifyProfile(reduced, profile)
+		writeProfileBounds(w, profile, eps)
+		bounds = jsonFloats(eps)
 	}
-	fmt.Println("]")
 
-	fmt.Println("\nLab 2 finished. Plot this profile data to visually check for linearity.")
+	fmt.Fprintln(w)
+	writeASCIIProfilePlot(w, profile)
+
+	fmt.Fprintln(w, "\nLab 2 finished. Plot this profile data to visually check for linearity.")
+
+	return lab2Result{
+		Rank:             rank,
+		Q:                q.String(),
+		Reduction:        cfg.Reduction,
+		Profile:          profile,
+		ProfileError:     bounds,
+		ReductionSeconds: reductionTime.Seconds(),
+		Seconds:          time.Since(start).Seconds(),
+		Tours:            tours.Tours(),
+		TourProfiles:     tours.Profiles(),
+		Steps:            state.Reports,
+		Vector:           state.Vector,
+		Stopped:          state.Stopped,
+	}
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeLaTeXTables renders the result tables as booktabs-style LaTeX table
+// environments, ready to be pasted into a write-up. The document must load
+// the booktabs package.
+func writeLaTeXTables(w io.Writer, res *runResults) error {
+	var b strings.Builder
+
+	// Run metadata as comments, so the tables can be traced back to a run.
+	if res.Metadata != nil {
+		for _, kv := range res.Metadata.entries() {
+			fmt.Fprintf(&b, "%% %s: %s\n", kv[0], kv[1])
+		}
+		b.WriteString("\n")
+	}
+
+	for _, lab := range res.Lab1 {
+		b.WriteString("\\begin{table}[ht]\n\\centering\n")
+		b.WriteString("\\begin{tabular}{rrrr}\n\\toprule\n")
+		b.WriteString("$n$ & GH prediction & $\\lambda_1$ & Relative error (\\%) \\\\\n\\midrule\n")
+		for _, row := range lab.Rows {
+			if row.Status != "" {
+				fmt.Fprintf(&b, "%d & %.2f & \\multicolumn{2}{c}{%s} \\\\\n", row.N, row.GH, statusText(row.Status))
+				continue
+			}
+			fmt.Fprintf(&b, "%d & %.2f & %.2f & %.2f \\\\\n", row.N, row.GH, row.Lambda1, row.RelativeError)
+		}
+		b.WriteString("\\bottomrule\n\\end{tabular}\n")
+		fmt.Fprintf(&b, "\\caption{Gaussian Heuristic versus SVP norm for random lattices with entries in $[0, %s)$.}\n", lab.Q)
+		b.WriteString("\\end{table}\n\n")
+	}
+
+	if len(res.Lab2) > 0 {
+		b.WriteString("\\begin{table}[ht]\n\\centering\n")
+		b.WriteString("\\begin{tabular}{rrlrrr}\n\\toprule\n")
+		b.WriteString("Rank & $q$ & Reduction & $\\log_2\\|\\mathbf{b}_1^*\\|$ & Slope & $R^2$ \\\\\n\\midrule\n")
+		for _, lab := range res.Lab2 {
+			if lab.Status != "" {
+				fmt.Fprintf(&b, "%d & %s & %s & \\multicolumn{3}{c}{%s} \\\\\n", lab.Rank, lab.Q, latexReduction(lab.Reduction), statusText(lab.Status))
+				continue
+			}
+			slope, _, r2 := lab.Profile.Fit()
+			first := 0.0
+			if len(lab.Profile) > 0 {
+				first = lab.Profile[0]
+			}
+			fmt.Fprintf(&b, "%d & %s & %s & %.2f & %.4f & %.4f \\\\\n",
+				lab.Rank, lab.Q, latexReduction(lab.Reduction), first, slope, r2)
+		}
+		b.WriteString("\\bottomrule\n\\end{tabular}\n")
+		b.WriteString("\\caption{Fitted GSA lines of reduced basis profiles.}\n")
+		b.WriteString("\\end{table}\n\n")
+	}
+
+	if len(res.Sweep) > 0 {
+		b.WriteString("\\begin{table}[ht]\n\\centering\n")
+		b.WriteString("\\begin{tabular}{rrrrrrr}\n\\toprule\n")
+		b.WriteString("$n$ & $\\beta$ & $q$ & GH & $\\|\\mathbf{b}_1\\|$ & $\\delta_0$ & Slope \\\\\n\\midrule\n")
+		for _, row := range res.Sweep {
+			fmt.Fprintf(&b, "%d & %d & %d & %.2f & %.2f & %.5f & %.4f \\\\\n",
+				row.N, row.Beta, row.Q, row.GH, row.B1, row.Delta, row.Slope)
+		}
+		b.WriteString("\\bottomrule\n\\end{tabular}\n")
+		b.WriteString("\\caption{BKZ grid search over $(n, \\beta, q)$.}\n")
+		b.WriteString("\\end{table}\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// latexReduction formats a reduction pipeline compactly, e.g. "LLL, BKZ-20".
+func latexReduction(steps []reductionStep) string {
+	if len(steps) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(steps))
+	for i, step := range steps {
+		switch step.algo() {
+		case "bkz":
+			parts[i] = fmt.Sprintf("BKZ-%d", step.Beta)
+		case "pbkz":
+			parts[i] = fmt.Sprintf("PBKZ-%d", step.Beta)
+		case "sld":
+			parts[i] = fmt.Sprintf("Slide-%d", step.Beta)
+		default:
+			parts[i] = strings.ToUpper(step.algo())
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+)
+
+// Lattice is the lattice of a basis together with the invariants the labs
+// ask about it: the volume, the Gaussian Heuristic, the Hermite normal form
+// and the shortest vector known so far. Each is computed the first time it
+// is asked for and kept, also across reductions of the basis with Reduce,
+// which change the basis but not the lattice. A Lattice is not safe for
+// concurrent use.
+type Lattice struct {
+	basis  Basis
+	volume *big.Float
+	gh     *big.Float
+	hnf    Basis
+	// short is the shortest vector known, and shortNorm its squared norm.
+	short     []*big.Int
+	shortNorm *big.Int
+}
+
+// newLattice returns the lattice of basis, whose rows must be linearly
+// independent.
+func newLattice(basis Basis) *Lattice {
+	l := &Lattice{basis: basis}
+	l.offerRows(basis)
+	return l
+}
+
+// Basis returns the current basis of the lattice.
+func (l *Lattice) Basis() Basis { return l.basis }
+
+// Rank returns the rank of the lattice.
+func (l *Lattice) Rank() int { return len(l.basis) }
+
+// Volume returns the volume of the lattice; see Basis.Volume.
+func (l *Lattice) Volume() *big.Float {
+	if l.volume == nil {
+		l.volume = l.basis.Volume()
+	}
+	return l.volume
+}
+
+// LogVolume returns log2 of the volume of the lattice, taken from Volume
+// so that both cost one volume computation.
+func (l *Lattice) LogVolume() float64 {
+	return log2Float(l.Volume())
+}
+
+// GH returns the Gaussian Heuristic of the lattice, the predicted norm of
+// its shortest non-zero vectors; see gaussianHeuristic.
+func (l *Lattice) GH() *big.Float {
+	if l.gh == nil {
+		l.gh = big.NewFloat(gaussianHeuristic(l.LogVolume(), l.Rank()))
+	}
+	return l.gh
+}
+
+// HNF returns the Hermite normal form of the lattice, which is the same
+// for all of its bases; see hermiteNormalForm.
+func (l *Lattice) HNF() (Basis, error) {
+	if l.hnf == nil {
+		h, err := hermiteNormalForm(l.basis)
+		if err != nil {
+			return nil, err
+		}
+		l.hnf = h
+	}
+	return l.hnf, nil
+}
+
+// ShortVector returns the shortest non-zero vector known of the lattice:
+// the shortest of the basis vectors it had and the vectors offered with
+// Offer.
+func (l *Lattice) ShortVector() []*big.Int { return l.short }
+
+// Offer records v as the shortest vector known if it is shorter than the
+// one known so far, and reports whether it was. v must be a non-zero
+// vector of the lattice, such as the verified result of an oracle; it is
+// not checked again.
+func (l *Lattice) Offer(v []*big.Int) bool {
+	norm := normSquared(v)
+	if norm.Sign() == 0 || l.shortNorm != nil && norm.Cmp(l.shortNorm) >= 0 {
+		return false
+	}
+	l.short, l.shortNorm = v, norm
+	return true
+}
+
+// offerRows offers every row of basis.
+func (l *Lattice) offerRows(basis Basis) {
+	for _, row := range basis {
+		l.Offer(row)
+	}
+}
+
+// Reduce applies the reduction steps to the basis as applyReduction does
+// and keeps the reduced basis, whose rows are offered as short vectors, as
+// is the shortest vector of an enum step. The cached invariants stay valid.
+func (l *Lattice) Reduce(ctx context.Context, steps []reductionStep) error {
+	state, err := reductionPipeline{Steps: steps, MaxTime: backend.MaxTime, CheckVolume: true}.run(ctx, l.basis)
+	if err != nil {
+		return err
+	}
+	l.basis = state.Basis
+	l.offerRows(state.Basis)
+	if state.Vector != nil {
+		l.Offer(state.Vector)
+	}
+	return nil
+}
+
+// hermiteNormalForm returns the Hermite normal form of the lattice of
+// basis: the unique basis in row echelon form whose pivots are positive
+// and whose entries above each pivot are reduced into [0, pivot). Two bases
+// span the same lattice exactly when their Hermite normal forms are equal.
+// It is computed exactly by Euclidean row operations, whose intermediate
+// entries can grow well beyond those of basis for large ranks.
+func hermiteNormalForm(basis Basis) (Basis, error) {
+	h := basis.Clone()
+	n, m := h.Dims()
+	q := new(big.Int)
+	// sub subtracts f times row p from row i.
+	sub := func(i, p int, f *big.Int) {
+		for j := range m {
+			h[i][j].Sub(h[i][j], q.Mul(f, h[p][j]))
+		}
+	}
+	row := 0
+	for col := 0; col < m && row < n; col++ {
+		// Euclid's algorithm on the column below row, pivoting on the
+		// entry of least absolute value until it divides all others.
+		for {
+			p := -1
+			for i := row; i < n; i++ {
+				if h[i][col].Sign() != 0 && (p < 0 || h[i][col].CmpAbs(h[p][col]) < 0) {
+					p = i
+				}
+			}
+			if p < 0 {
+				break
+			}
+			h[row], h[p] = h[p], h[row]
+			done := true
+			for i := row + 1; i < n; i++ {
+				if h[i][col].Sign() == 0 {
+					continue
+				}
+				sub(i, row, new(big.Int).Quo(h[i][col], h[row][col]))
+				done = done && h[i][col].Sign() == 0
+			}
+			if done {
+				break
+			}
+		}
+		if h[row][col].Sign() == 0 {
+			continue // no pivot in this column
+		}
+		if h[row][col].Sign() < 0 {
+			for _, v := range h[row] {
+				v.Neg(v)
+			}
+		}
+		for i := range row {
+			// Div rounds towards -∞ for a positive divisor.
+			sub(i, row, new(big.Int).Div(h[i][col], h[row][col]))
+		}
+		row++
+	}
+	if row < n {
+		return nil, fmt.Errorf("the basis has rank %d but %d rows: its rows are linearly dependent", row, n)
+	}
+	return h, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"runtime/trace"
+	"strings"
+)
+
+// libfplllInterface is the name of the C interface to libfplll that fplll
+// calls go through in process, built from libfplll/latticelabs_fplll.cpp:
+// libfplll itself only has a C++ API, which can't be called without cgo.
+const libfplllInterface = "latticelabs_fplll"
+
+// Statuses of a call through the C interface.
+const (
+	libfplllOK          = 0
+	libfplllFailed      = 1
+	libfplllUnsupported = 2
+)
+
+// fplllLibrary is the C interface to libfplll loaded at run time (see
+// loadFplllLibrary). Its run function takes the arguments of an fplll
+// command line, one per line, and fplll's standard input, and returns what
+// fplll would print, so that the output is parsed like the executable's,
+// with the status of the call.
+type fplllLibrary struct {
+	Path string
+	run  func(args, input string) (string, int32)
+}
+
+// libfplll is the loaded C interface to libfplll, or nil if fplll calls run
+// the executable; see useLibfplll.
+var libfplll *fplllLibrary
+
+// useLibfplll loads the C interface to libfplll named by backend.Library,
+// or searched for like libfplll if it is "", and replaces backend.Library
+// by its path, or by "" if it is "off" or can't be loaded, in which case
+// fplll calls run the executable. Only an interface named explicitly is
+// warned about when it can't be loaded.
+func useLibfplll() {
+	name := backend.Library
+	backend.Library = ""
+	if name == "off" {
+		return
+	}
+	path := expandHome(name)
+	if path == "" {
+		if path = findSharedLibrary(libfplllInterface); path == "" {
+			slog.Debug("libfplll interface not found; fplll calls run the executable", "name", "lib"+libfplllInterface)
+			return
+		}
+	}
+	lib, err := loadFplllLibrary(path)
+	if err != nil {
+		if name != "" {
+			slog.Warn("could not load libfplll; fplll calls run the executable", "path", path, "err", err)
+		} else {
+			slog.Debug("could not load libfplll", "path", path, "err", err)
+		}
+		return
+	}
+	slog.Info("calling libfplll in process", "path", path)
+	backend.Library = path
+	libfplll = lib
+}
+
+// call runs the fplll command line args on input through the library and
+// returns its output, or its error message, with the status of the call.
+func (l *fplllLibrary) call(args []string, input string) (string, int32) {
+	return l.run(strings.Join(args, "\n"), input)
+}
+
+// runFplllLibrary makes the fplll call of runFplll through libfplll if it is
+// loaded and supports args, and reports whether it did. A call in process
+// can't be killed, so calls limited by --timeout or a deadline of ctx run
+// the executable, as do those with arguments the C interface doesn't
+// cover, such as -v, -bkzdumpgso, -of and Gram matrices.
+func (b backendConfig) runFplllLibrary(ctx context.Context, basis Basis, args []string, parse func(io.Reader) error) (bool, error) {
+	if libfplll == nil || b.Timeout > 0 {
+		return false, nil
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return false, nil
+	}
+	var input bytes.Buffer
+	if err := writeBasis(&input, basis); err != nil {
+		return true, err
+	}
+	slog.Debug("calling libfplll", "args", args, "rank", len(basis))
+	defer trace.StartRegion(ctx, "libfplll").End()
+	out, status := libfplll.call(args, input.String())
+	switch {
+	case status == libfplllUnsupported:
+		slog.Debug("libfplll doesn't support the call, running fplll", "args", args)
+		return false, nil
+	case ctx.Err() != nil:
+		return true, ctx.Err()
+	case status != libfplllOK:
+		return true, newFplllError(errors.New("libfplll call failed"), out)
+	}
+	if err := parse(strings.NewReader(out)); err != nil {
+		return true, b.badOutputError(err, "")
+	}
+	return true, nil
+}
@@ -0,0 +1,281 @@
+// latticelabs_fplll is the C interface lattice-labs loads at run time to call
+// libfplll in process instead of running the fplll executable. libfplll only
+// has a C++ API of templates, which a Go program can't call without cgo, so
+// this file wraps the calls lattice-labs makes behind two C functions. Build
+// it against the installed fplll and put it next to libfplll, or point
+// --libfplll at it:
+//
+//	g++ -O2 -shared -fPIC -o liblatticelabs_fplll.so latticelabs_fplll.cpp -lfplll -lmpfr -lgmp
+//
+// (liblatticelabs_fplll.dylib with -dynamiclib on macOS.)
+//
+// latticelabs_fplll_run takes the arguments of an fplll command line, one
+// per line, and its standard input, and returns what fplll would print on
+// standard output, so that lattice-labs parses both the same way. It covers
+// -a lll, bkz, sld, hkz and svp with the options lattice-labs passes; any
+// other argument makes it return status 2, and lattice-labs runs the
+// executable instead.
+
+#include <cstdlib>
+#include <cstring>
+#include <exception>
+#include <sstream>
+#include <string>
+#include <vector>
+
+#include <fplll.h>
+
+using namespace fplll;
+
+namespace
+{
+
+const int status_ok          = 0;
+const int status_failed      = 1;
+const int status_unsupported = 2;
+
+struct options
+{
+  std::string algo;
+  int block_size          = 0;
+  double delta            = LLL_DEF_DELTA;
+  double eta              = LLL_DEF_ETA;
+  FloatType float_type    = FT_DEFAULT;
+  int precision           = 0;
+  std::string strategy;
+  int max_loops           = 0;
+  double max_time         = 0;
+  bool auto_abort         = false;
+  double gh_bound         = 0;
+  LLLMethod method        = LM_WRAPPER;
+};
+
+bool parse_float_type(const std::string &s, FloatType &ft)
+{
+  static const struct
+  {
+    const char *name;
+    FloatType type;
+  } types[] = {{"double", FT_DOUBLE}, {"longdouble", FT_LONG_DOUBLE}, {"dpe", FT_DPE},
+               {"dd", FT_DD},         {"qd", FT_QD},                  {"mpfr", FT_MPFR}};
+  for (const auto &t : types)
+  {
+    if (s == t.name)
+    {
+      ft = t.type;
+      return true;
+    }
+  }
+  return false;
+}
+
+// parse_method reads the LLL method of -m, as fplll's own parser does.
+bool parse_method(const std::string &s, LLLMethod &m)
+{
+  static const struct
+  {
+    const char *name;
+    LLLMethod method;
+  } methods[] = {{"wrapper", LM_WRAPPER},
+                 {"proved", LM_PROVED},
+                 {"heuristic", LM_HEURISTIC},
+                 {"fast", LM_FAST}};
+  for (const auto &t : methods)
+  {
+    if (s == t.name)
+    {
+      m = t.method;
+      return true;
+    }
+  }
+  return false;
+}
+
+// parse_args reads the arguments lattice-labs passes to fplll and reports
+// whether all of them are supported.
+bool parse_args(const std::vector<std::string> &args, options &o)
+{
+  for (size_t i = 0; i < args.size(); i++)
+  {
+    const std::string &a = args[i];
+    bool has_value       = i + 1 < args.size();
+    const std::string v  = has_value ? args[i + 1] : "";
+    if (a == "-a" && has_value)
+      o.algo = v;
+    else if (a == "-b" && has_value)
+      o.block_size = std::atoi(v.c_str());
+    else if (a == "-d" && has_value)
+      o.delta = std::atof(v.c_str());
+    else if (a == "-e" && has_value)
+      o.eta = std::atof(v.c_str());
+    else if (a == "-f" && has_value)
+    {
+      if (!parse_float_type(v, o.float_type))
+        return false;
+    }
+    else if (a == "-p" && has_value)
+      o.precision = std::atoi(v.c_str());
+    else if (a == "-s" && has_value)
+      o.strategy = v;
+    else if (a == "-bkzmaxloops" && has_value)
+      o.max_loops = std::atoi(v.c_str());
+    else if (a == "-bkzmaxtime" && has_value)
+      o.max_time = std::atof(v.c_str());
+    else if (a == "-bkzghbound" && has_value)
+      o.gh_bound = std::atof(v.c_str());
+    else if (a == "-m" && has_value)
+    {
+      if (!parse_method(v, o.method))
+        return false;
+    }
+    else if (a == "-bkzautoabort")
+    {
+      o.auto_abort = true;
+      continue;
+    }
+    else
+      return false;
+    i++;
+  }
+  return o.algo == "lll" || o.algo == "bkz" || o.algo == "sld" || o.algo == "hkz" ||
+         o.algo == "svp";
+}
+
+// block_reduce runs BKZ or slide reduction as fplll -a bkz and -a sld do.
+// Stopping at the tour or time limit is not a failure: the basis is the
+// one reduced so far.
+int block_reduce(ZZ_mat<mpz_t> &b, const options &o)
+{
+  // Without -s fplll runs without pruning, which BKZParam fills in for
+  // empty strategies.
+  std::vector<Strategy> strategies;
+  if (!o.strategy.empty())
+    strategies = load_strategies_json(strategy_full_path(o.strategy));
+  BKZParam param(o.block_size, strategies, o.delta);
+  param.flags = BKZ_DEFAULT;
+  if (o.algo == "sld")
+    param.flags |= BKZ_SLD_RED;
+  if (o.max_loops > 0)
+  {
+    param.flags |= BKZ_MAX_LOOPS;
+    param.max_loops = o.max_loops;
+  }
+  if (o.max_time > 0)
+  {
+    param.flags |= BKZ_MAX_TIME;
+    param.max_time = o.max_time;
+  }
+  if (o.auto_abort)
+    param.flags |= BKZ_AUTO_ABORT;
+  if (o.gh_bound > 0)
+  {
+    param.flags |= BKZ_GH_BND;
+    param.gh_factor = o.gh_bound;
+  }
+  int status = bkz_reduction(&b, nullptr, param, o.float_type, o.precision);
+  if (status == RED_BKZ_LOOPS_LIMIT || status == RED_BKZ_TIME_LIMIT)
+    return RED_SUCCESS;
+  return status;
+}
+
+// run performs the call and writes fplll's output to out, or the error to
+// it and returns status_failed.
+int run(const options &o, const char *input, std::ostringstream &out)
+{
+  ZZ_mat<mpz_t> b;
+  std::istringstream in(input);
+  in >> b;
+  if (b.get_rows() == 0)
+  {
+    out << "could not read a matrix";
+    return status_failed;
+  }
+  int status = RED_SUCCESS;
+  if (o.algo == "lll")
+    status = lll_reduction(b, o.delta, o.eta, o.method, o.float_type, o.precision);
+  else if (o.algo == "bkz" || o.algo == "sld")
+    status = block_reduce(b, o);
+  else if (o.algo == "hkz")
+    status = hkz_reduction(b, HKZ_DEFAULT, o.float_type, o.precision);
+  else
+  {
+    status = lll_reduction(b, o.delta, o.eta, o.method, o.float_type, o.precision);
+    std::vector<Z_NR<mpz_t>> coords;
+    if (status == RED_SUCCESS)
+      status = shortest_vector(b, coords);
+    if (status == RED_SUCCESS)
+    {
+      out << "[";
+      for (int j = 0; j < b.get_cols(); j++)
+      {
+        Z_NR<mpz_t> v, t;
+        v = 0;
+        for (size_t i = 0; i < coords.size(); i++)
+        {
+          t.mul(coords[i], b[i][j]);
+          v.add(v, t);
+        }
+        out << (j > 0 ? " " : "") << v;
+      }
+      out << "]\n";
+      return status_ok;
+    }
+  }
+  if (status != RED_SUCCESS)
+  {
+    out << "failure: " << get_red_status_str(status);
+    return status_failed;
+  }
+  out << b << "\n";
+  return status_ok;
+}
+
+char *copy_string(const std::string &s, long long *length)
+{
+  char *p = static_cast<char *>(std::malloc(s.size() + 1));
+  if (p == nullptr)
+    return nullptr;
+  std::memcpy(p, s.c_str(), s.size() + 1);
+  *length = static_cast<long long>(s.size());
+  return p;
+}
+
+}  // namespace
+
+extern "C"
+{
+
+  // latticelabs_fplll_run runs the fplll command line args, one argument per
+  // line, on input. It returns fplll's output with status 0, an error
+  // message with status 1, or nothing with status 2 if the arguments aren't
+  // supported. The result is allocated with malloc and freed with
+  // latticelabs_fplll_free; *length is its size without the final NUL.
+  char *latticelabs_fplll_run(const char *args, const char *input, long long *length, int *status)
+  {
+    std::vector<std::string> argv;
+    std::istringstream lines(args);
+    for (std::string line; std::getline(lines, line);)
+      argv.push_back(line);
+    options o;
+    *length = 0;
+    if (!parse_args(argv, o))
+    {
+      *status = status_unsupported;
+      return nullptr;
+    }
+    std::ostringstream out;
+    try
+    {
+      *status = run(o, input, out);
+    }
+    catch (const std::exception &e)
+    {
+      out.str("");
+      out << e.what();
+      *status = status_failed;
+    }
+    return copy_string(out.str(), length);
+  }
+
+  void latticelabs_fplll_free(char *p) { std::free(p); }
+}
@@ -0,0 +1,44 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+)
+
+// loadFplllLibrary loads the C interface to libfplll at path with dlopen,
+// through purego, so that the binary needs neither cgo nor libfplll to be
+// built.
+func loadFplllLibrary(path string) (*fplllLibrary, error) {
+	handle, err := purego.Dlopen(path, purego.RTLD_NOW|purego.RTLD_LOCAL)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"latticelabs_fplll_run", "latticelabs_fplll_free"} {
+		if _, err := purego.Dlsym(handle, name); err != nil {
+			purego.Dlclose(handle)
+			return nil, fmt.Errorf("%s is not the libfplll interface: %w", path, err)
+		}
+	}
+	var run func(args, input string, length *int64, status *int32) unsafe.Pointer
+	var free func(p unsafe.Pointer)
+	purego.RegisterLibFunc(&run, handle, "latticelabs_fplll_run")
+	purego.RegisterLibFunc(&free, handle, "latticelabs_fplll_free")
+	return &fplllLibrary{Path: path, run: func(args, input string) (string, int32) {
+		var length int64
+		var status int32
+		p := run(args, input, &length, &status)
+		if p == nil {
+			if status == libfplllOK {
+				return "", libfplllFailed
+			}
+			return "", status
+		}
+		defer free(p)
+		return strings.Clone(unsafe.String((*byte)(p), int(length))), status
+	}}, nil
+}
@@ -0,0 +1,14 @@
+//go:build !(linux || darwin || freebsd)
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// loadFplllLibrary reports that libfplll can't be loaded at run time here;
+// it is only loaded on Linux, macOS and FreeBSD.
+func loadFplllLibrary(path string) (*fplllLibrary, error) {
+	return nil, fmt.Errorf("loading libfplll at run time isn't supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// logLevel maps the -v/-vv verbosity flags to a slog level: warnings and
+// errors by default, informational messages with -v and the fplll command
+// lines and other debugging details with -vv.
+func logLevel(verbose, veryVerbose bool) slog.Level {
+	switch {
+	case veryVerbose:
+		return slog.LevelDebug
+	case verbose:
+		return slog.LevelInfo
+	default:
+		return slog.LevelWarn
+	}
+}
+
+// setupLogging installs the default slog logger writing to w in the given
+// format ("text" or "json") at the given level.
+func setupLogging(w io.Writer, format string, level slog.Level) error {
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch format {
+	case "", "text":
+		handler = slog.NewTextHandler(w, handlerOpts)
+	case "json":
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return fmt.Errorf("unknown log format %q", format)
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
+	mrand "math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// lweDistributionKinds are the distributions LWE secrets and errors can be
+// drawn from: uniform mod q, uniform in {0, 1} or {-1, 0, 1}, or the
+// discrete Gaussian of width σ, written gaussian:σ.
+var lweDistributionKinds = []string{"uniform", "binary", "ternary", "gaussian"}
+
+// lweDistribution is the distribution of the secret or the error of an LWE
+// instance; its entries are drawn independently.
+type lweDistribution struct {
+	Kind  string  // one of lweDistributionKinds
+	Sigma float64 // width of the discrete Gaussian
+}
+
+// parseLWEDistribution parses a distribution such as "ternary" or
+// "gaussian:3.2".
+func parseLWEDistribution(s string) (lweDistribution, error) {
+	kind, sigma, hasSigma := strings.Cut(s, ":")
+	if !slices.Contains(lweDistributionKinds, kind) {
+		return lweDistribution{}, fmt.Errorf("unknown distribution %q (want %s)", s, strings.Join(lweDistributionKinds, ", "))
+	}
+	if kind != "gaussian" {
+		if hasSigma {
+			return lweDistribution{}, fmt.Errorf("distribution %q takes no width", kind)
+		}
+		return lweDistribution{Kind: kind}, nil
+	}
+	x, err := strconv.ParseFloat(sigma, 64)
+	if err != nil || !(x > 0) || math.IsInf(x, 0) {
+		return lweDistribution{}, fmt.Errorf("want gaussian:σ with a positive σ, got %q", s)
+	}
+	return lweDistribution{Kind: kind, Sigma: x}, nil
+}
+
+func (d lweDistribution) String() string {
+	if d.Kind == "gaussian" {
+		return "gaussian:" + strconv.FormatFloat(d.Sigma, 'g', -1, 64)
+	}
+	return d.Kind
+}
+
+// sample draws an entry, centered: uniform entries lie in [-q/2, q/2).
+func (d lweDistribution) sample(rng *mrand.Rand, q int64) int64 {
+	switch d.Kind {
+	case "binary":
+		return rng.Int64N(2)
+	case "ternary":
+		return rng.Int64N(3) - 1
+	case "gaussian":
+		return sampleZ(rng, d.Sigma, 0)
+	}
+	return centerMod(rng.Int64N(q), q)
+}
+
+// bound returns the largest absolute value sample draws.
+func (d lweDistribution) bound(q int64) int64 {
+	switch d.Kind {
+	case "binary", "ternary":
+		return 1
+	case "gaussian":
+		return int64(math.Floor(gaussTailCut * d.Sigma))
+	}
+	return q / 2
+}
+
+// centerMod returns the representative of x mod q in [-q/2, q/2).
+func centerMod(x, q int64) int64 {
+	x %= q
+	if x < 0 {
+		x += q
+	}
+	if x >= q-q/2 {
+		x -= q
+	}
+	return x
+}
+
+// maxLWEModulus bounds q so that the products of entries below it, and
+// their sums reduced after every step, fit an int64.
+const maxLWEModulus = 1 << 31
+
+// LWEParams are the parameters of an LWE problem: the secret dimension N,
+// the number of samples M, the modulus Q and the distributions the secret
+// and the errors are drawn from.
+type LWEParams struct {
+	N, M          int
+	Q             int64
+	Secret, Error lweDistribution
+}
+
+// validate checks that p describes LWE instances Sample can draw.
+func (p LWEParams) validate() error {
+	switch {
+	case p.N < 1:
+		return fmt.Errorf("LWE dimension must be at least 1, got %d", p.N)
+	case p.M < 1:
+		return fmt.Errorf("LWE sample count must be at least 1, got %d", p.M)
+	case p.Q < 2 || p.Q >= maxLWEModulus:
+		return fmt.Errorf("LWE modulus must be in [2, 2^31), got %d", p.Q)
+	case p.Secret.Kind == "" || p.Error.Kind == "":
+		return fmt.Errorf("LWE secret and error distributions must be set")
+	}
+	return nil
+}
+
+// LWEInstance is an LWE instance b = A·s + e mod q with the secret and
+// the error it was drawn with, so that attacks on it can be checked.
+type LWEInstance struct {
+	LWEParams
+	A [][]int64 // M×N, entries in [0, q)
+	B []int64   // A·s + e mod q, entries in [0, q)
+	S []int64   // the secret, centered
+	E []int64   // the error, centered
+}
+
+// Sample draws an LWE instance with parameters p: A uniform, s and e from
+// their distributions. p must be valid.
+func (p LWEParams) Sample(rng *mrand.Rand) *LWEInstance {
+	inst := &LWEInstance{LWEParams: p, A: make([][]int64, p.M), B: make([]int64, p.M), S: make([]int64, p.N), E: make([]int64, p.M)}
+	for i := range inst.S {
+		inst.S[i] = p.Secret.sample(rng, p.Q)
+	}
+	for j := range inst.A {
+		inst.A[j] = make([]int64, p.N)
+		for i := range inst.A[j] {
+			inst.A[j][i] = rng.Int64N(p.Q)
+		}
+		inst.E[j] = p.Error.sample(rng, p.Q)
+	}
+	for j, row := range inst.A {
+		inst.B[j] = ((inst.mulRow(row, inst.S)+inst.E[j])%p.Q + p.Q) % p.Q
+	}
+	return inst
+}
+
+// mulRow returns ⟨row, s⟩ mod q, in [0, q).
+func (inst *LWEInstance) mulRow(row, s []int64) int64 {
+	sum := int64(0)
+	for i, a := range row {
+		sum = (sum + a*(s[i]%inst.Q)) % inst.Q
+	}
+	return (sum + inst.Q) % inst.Q
+}
+
+// PrimalBasis returns the basis of the primal embedding of inst, the
+// lattice of the vectors (b - A·s' + q·z, s', c) for integral s', z and c,
+// in which the secret and the error form the unusually short vector
+// (e, s, 1) of PrimalSolution when both are short: the rows of
+//
+//	[ q·I_M   0    0 ]
+//	[ -Aᵀ     I_N  0 ]
+//	[ bᵀ      0    1 ]
+//
+// of rank M+N+1 and volume q^M, Bai and Galbraith's embedding, on which
+// uSVP attacks run. With a uniform secret the vector isn't short.
+func (inst *LWEInstance) PrimalBasis() Basis {
+	dim := inst.M + inst.N + 1
+	basis := make(Basis, 0, dim)
+	newRow := func() []*big.Int {
+		row := make([]*big.Int, dim)
+		for k := range row {
+			row[k] = new(big.Int)
+		}
+		return row
+	}
+	for j := range inst.M {
+		row := newRow()
+		row[j].SetInt64(inst.Q)
+		basis = append(basis, row)
+	}
+	for i := range inst.N {
+		row := newRow()
+		for j, a := range inst.A {
+			row[j].SetInt64(-a[i])
+		}
+		row[inst.M+i].SetInt64(1)
+		basis = append(basis, row)
+	}
+	row := newRow()
+	for j, b := range inst.B {
+		row[j].SetInt64(b)
+	}
+	row[dim-1].SetInt64(1)
+	return append(basis, row)
+}
+
+// PrimalSolution returns the vector (e, s, 1) of the lattice of
+// PrimalBasis.
+func (inst *LWEInstance) PrimalSolution() []*big.Int {
+	v := make([]*big.Int, 0, inst.M+inst.N+1)
+	for _, e := range inst.E {
+		v = append(v, big.NewInt(e))
+	}
+	for _, s := range inst.S {
+		v = append(v, big.NewInt(s))
+	}
+	return append(v, big.NewInt(1))
+}
+
+// SecretFromPrimal reads the candidate secret off a vector of the lattice
+// of PrimalBasis, ±(e, s, 1) if it is the solution, and reports whether
+// the vector has the form of one, ±1 in the last entry.
+func (inst *LWEInstance) SecretFromPrimal(v []*big.Int) ([]int64, bool) {
+	if len(v) != inst.M+inst.N+1 || v[len(v)-1].CmpAbs(big.NewInt(1)) != 0 {
+		return nil, false
+	}
+	sign := int64(v[len(v)-1].Sign())
+	s := make([]int64, inst.N)
+	for i := range s {
+		x := v[inst.M+i]
+		if !x.IsInt64() {
+			return nil, false
+		}
+		s[i] = sign * x.Int64()
+	}
+	return s, true
+}
+
+// DualBasis returns the basis of the dual embedding of inst, the lattice
+// of the vectors (v, Aᵀ·v mod q): the rows of
+//
+//	[ I_M   A     ]
+//	[ 0     q·I_N ]
+//
+// of rank M+N and volume q^N. A short vector (v, w) of it makes
+// DualScore, ⟨v, b⟩ = ⟨w, s⟩ + ⟨v, e⟩ mod q, small when the secret and the
+// error are short, which distinguishes b from uniform; dual attacks run
+// on it.
+func (inst *LWEInstance) DualBasis() Basis {
+	dim := inst.M + inst.N
+	basis := make(Basis, dim)
+	for k := range basis {
+		basis[k] = make([]*big.Int, dim)
+		for l := range basis[k] {
+			basis[k][l] = new(big.Int)
+		}
+	}
+	for j, a := range inst.A {
+		basis[j][j].SetInt64(1)
+		for i, x := range a {
+			basis[j][inst.M+i].SetInt64(x)
+		}
+	}
+	for i := range inst.N {
+		basis[inst.M+i][inst.M+i].SetInt64(inst.Q)
+	}
+	return basis
+}
+
+// DualScore returns ⟨v, b⟩ mod q, centered, for a vector of the lattice of
+// DualBasis whose first M entries are v.
+func (inst *LWEInstance) DualScore(v []*big.Int) int64 {
+	sum, q := new(big.Int), big.NewInt(inst.Q)
+	var t big.Int
+	for j, b := range inst.B {
+		sum.Add(sum, t.Mul(v[j], big.NewInt(b)))
+	}
+	return centerMod(sum.Mod(sum, q).Int64(), inst.Q)
+}
+
+// CheckSecret returns the error b - A·s mod q, centered, that the
+// candidate secret s leaves, and reports whether s and that error are
+// within the bounds of their distributions: for any secret other than the
+// one of the instance the error is uniform and, unless q is small or the
+// error distribution uniform, almost never within them.
+func (inst *LWEInstance) CheckSecret(s []int64) ([]int64, bool) {
+	if len(s) != inst.N {
+		return nil, false
+	}
+	ok := true
+	for _, x := range s {
+		ok = ok && abs64(x) <= inst.Secret.bound(inst.Q)
+	}
+	e := make([]int64, inst.M)
+	for j, row := range inst.A {
+		e[j] = centerMod(inst.B[j]-inst.mulRow(row, s), inst.Q)
+		ok = ok && abs64(e[j]) <= inst.Error.bound(inst.Q)
+	}
+	return e, ok
+}
+
+// abs64 returns |x|.
+func abs64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// lweConfig holds the arguments of the lwe command.
+type lweConfig struct {
+	Params LWEParams
+	// Embedding is primal or dual: which embedding basis is written.
+	Embedding string
+	To        string // matrix format of the output
+}
+
+// parseLWEFlags builds an lweConfig from the lwe command line.
+func parseLWEFlags(args []string) (lweConfig, error) {
+	fs := flag.NewFlagSet("lwe", flag.ContinueOnError)
+	n := fs.Int("n", 10, "dimension of the secret")
+	m := fs.Int("m", 20, "number of samples")
+	q := fs.Int64("q", 3329, "modulus")
+	secret := fs.String("secret", "ternary", "secret distribution: uniform, binary, ternary or gaussian:σ")
+	errDist := fs.String("error", "gaussian:3.2", "error distribution: uniform, binary, ternary or gaussian:σ")
+	embedding := fs.String("embedding", "primal", "embedding basis to print: primal or dual")
+	to := fs.String("to", "fplll", "format of the output: one of "+basisFormatNames())
+	if err := fs.Parse(args); err != nil {
+		return lweConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return lweConfig{}, fmt.Errorf("usage: lattice-labs lwe [-n n] [-m m] [-q q] [-secret dist] [-error dist] [-embedding primal|dual] [-to fmt]")
+	}
+	cfg := lweConfig{Params: LWEParams{N: *n, M: *m, Q: *q}, Embedding: *embedding, To: *to}
+	var err error
+	if cfg.Params.Secret, err = parseLWEDistribution(*secret); err != nil {
+		return cfg, fmt.Errorf("-secret: %w", err)
+	}
+	if cfg.Params.Error, err = parseLWEDistribution(*errDist); err != nil {
+		return cfg, fmt.Errorf("-error: %w", err)
+	}
+	if err := cfg.Params.validate(); err != nil {
+		return cfg, err
+	}
+	if cfg.Embedding != "primal" && cfg.Embedding != "dual" {
+		return cfg, fmt.Errorf("-embedding must be primal or dual, got %q", cfg.Embedding)
+	}
+	if basisWriters[cfg.To] == nil {
+		return cfg, fmt.Errorf("unknown basis format %q (want %s)", cfg.To, basisFormatNames())
+	}
+	return cfg, nil
+}
+
+// runLWE samples an LWE instance, seeded by --seed, and writes the basis of
+// its primal or dual embedding to w. The secret and the error are logged at
+// Info level (-v), for checking what an attack on the basis finds.
+func runLWE(w io.Writer, cfg lweConfig) error {
+	p := cfg.Params
+	rng := trialRand("lwe", int64(p.N), int64(p.M), p.Q)
+	inst := p.Sample(rng)
+	slog.Info("LWE instance", "n", p.N, "m", p.M, "q", p.Q, "secret", p.Secret, "error", p.Error, "s", inst.S, "e", inst.E)
+	basis := inst.PrimalBasis()
+	if cfg.Embedding == "dual" {
+		basis = inst.DualBasis()
+	}
+	return basis.WriteFormat(w, cfg.To)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// lweEstimate is the outcome of one attack in the LWE estimate.
+type lweEstimate struct {
+	Attack  string // primal or dual
+	Beta    int
+	Samples int     // the number m of LWE samples used
+	Dim     int     // the dimension of the lattice reduced
+	Bits    float64 // log2 of the cost under the cost model
+}
+
+// primalSucceeds reports whether BKZ-β finds the secret in the primal
+// embedding of m samples (LWEInstance.PrimalBasis, of dimension d = m+n+1
+// and volume q^m): whether uniqueSVPSucceeds for the short vector, of norm
+// about σ√d, the secret being assumed distributed like the error. That
+// vector must moreover be below the Gaussian Heuristic of the lattice, or it
+// is not the one BKZ finds: with too few samples the secret isn't unique.
+func primalSucceeds(n, m int, q int64, sigma float64, beta int) bool {
+	d := m + n + 1
+	logVol := float64(m) * math.Log2(float64(q))
+	logNorm := math.Log2(sigma) + math.Log2(float64(d))/2
+	if logNorm >= math.Log2(float64(d)/(2*math.Pi*math.E))/2+logVol/float64(d) {
+		return false
+	}
+	return uniqueSVPSucceeds(d, logVol, logNorm, beta)
+}
+
+// uniqueSVPSucceeds reports whether BKZ-β finds a vector of log2 norm
+// logNorm, far below the Gaussian Heuristic, in a lattice of rank d and log2
+// volume logVol, by the 2016 estimate of Alkim, Ducas, Pöppelmann and
+// Schwabe: the projection of the vector on the last β Gram-Schmidt vectors,
+// of norm about ‖v‖·√(β/d), is shorter than b*_{d-β+1}, which the geometric
+// series assumption puts at δ^(2β-d)·vol^(1/d).
+func uniqueSVPSucceeds(d int, logVol, logNorm float64, beta int) bool {
+	if beta > d {
+		return false
+	}
+	lhs := logNorm + (math.Log2(float64(beta))-math.Log2(float64(d)))/2
+	rhs := float64(2*beta-d)*math.Log2(deltas.At(beta)) + logVol/float64(d)
+	return lhs <= rhs
+}
+
+// estimatePrimal returns the primal uSVP attack of the 2016 estimate with
+// at most maxM samples: the least β for which primalSucceeds with some
+// number of samples, the least such number, and the cost of BKZ-β under
+// model. It reports false if no β up to the dimension succeeds.
+func estimatePrimal(n, maxM int, q int64, sigma float64, model costModel) (lweEstimate, bool) {
+	for beta := 2; beta <= maxM+n+1; beta++ {
+		for m := 1; m <= maxM; m++ {
+			if primalSucceeds(n, m, q, sigma, beta) {
+				return lweEstimate{Attack: "primal", Beta: beta, Samples: m, Dim: m + n + 1, Bits: model.SecurityBits(beta)}, true
+			}
+		}
+	}
+	return lweEstimate{}, false
+}
+
+// sievedVectors is the log2 of the number of short vectors, per block size,
+// a sieve in dimension β returns along with the shortest, 2^(0.2075β),
+// which the dual attack uses before repeating the reduction.
+const sievedVectors = 0.2075
+
+// estimateDual returns the dual distinguishing attack of the 2016 estimate
+// with at most maxM samples: BKZ-β on the dual embedding of m samples
+// (LWEInstance.DualBasis, of dimension d = m+n and volume qⁿ) finds a
+// vector of norm ℓ = δ^(d-1)·q^(n/d), whose DualScore distinguishes b from
+// uniform with advantage ε = 4·exp(-2π²(ℓσ/q)²). About 1/ε² such vectors
+// are needed; one reduction yields 2^(0.2075β) of them, and is repeated for
+// the rest. The β and m of the least total cost under model are returned.
+func estimateDual(n, maxM int, q int64, sigma float64, model costModel) lweEstimate {
+	best := lweEstimate{Attack: "dual", Bits: math.Inf(1)}
+	logQ := math.Log2(float64(q))
+	for m := 1; m <= maxM; m++ {
+		d := m + n
+		for beta := 2; beta <= d; beta++ {
+			logL := float64(d-1)*math.Log2(deltas.At(beta)) + float64(n)*logQ/float64(d)
+			tau := math.Exp2(logL) * sigma / float64(q)
+			logEps := min(2-2*math.Pi*math.Pi*tau*tau*math.Log2E, 0)
+			repeats := max(-2*logEps-sievedVectors*float64(beta), 0)
+			if bits := model.SecurityBits(beta) + repeats; bits < best.Bits {
+				best = lweEstimate{Attack: "dual", Beta: beta, Samples: m, Dim: d, Bits: bits}
+			}
+		}
+	}
+	return best
+}
+
+// hardnessConfig holds the arguments of the hardness command.
+type hardnessConfig struct {
+	N, M  int
+	Q     int64
+	Sigma float64
+	Model costModel
+	// Validate runs the primal attack on Trials small instances and sets
+	// the block sizes it needed against the estimate.
+	Validate bool
+	Trials   int
+}
+
+// parseHardnessFlags builds a hardnessConfig from the command line.
+func parseHardnessFlags(args []string) (hardnessConfig, error) {
+	fs := flag.NewFlagSet("hardness", flag.ContinueOnError)
+	n := fs.Int("n", 512, "dimension of the secret")
+	m := fs.Int("m", 0, "number of samples available (default 2n)")
+	q := fs.Int64("q", 3329, "modulus")
+	sigma := fs.Float64("sigma", 1.22, "width σ of the secret and error distributions")
+	model := fs.String("model", "classical", "core-SVP cost model: one of "+costModelNames())
+	validate := fs.Bool("validate", false, "run the primal attack on instances with these parameters and compare the block sizes")
+	trials := fs.Int("trials", 3, "instances attacked with -validate")
+	if err := fs.Parse(args); err != nil {
+		return hardnessConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return hardnessConfig{}, fmt.Errorf("usage: lattice-labs hardness [-n n] [-m m] [-q q] [-sigma σ] [-model model] [-validate [-trials k]]")
+	}
+	cfg := hardnessConfig{N: *n, M: *m, Q: *q, Sigma: *sigma, Validate: *validate, Trials: *trials}
+	if cfg.M == 0 {
+		cfg.M = 2 * cfg.N
+	}
+	if err := (LWEParams{N: cfg.N, M: cfg.M, Q: cfg.Q, Secret: lweDistribution{Kind: "gaussian"}, Error: lweDistribution{Kind: "gaussian"}}).validate(); err != nil {
+		return cfg, err
+	}
+	if !(cfg.Sigma > 0) || math.IsInf(cfg.Sigma, 0) {
+		return cfg, fmt.Errorf("-sigma must be positive, got %g", cfg.Sigma)
+	}
+	var err error
+	if cfg.Model, err = lookupCostModel(*model); err != nil {
+		return cfg, fmt.Errorf("-model: %w", err)
+	}
+	if cfg.Trials < 1 {
+		return cfg, fmt.Errorf("-trials must be at least 1")
+	}
+	return cfg, nil
+}
+
+// runHardness writes the primal and dual attacks of the 2016 estimate for
+// the LWE parameters of cfg to w. With cfg.Validate it then runs
+// validatePrimal and compares the block sizes the attack needed with the
+// estimate for all m samples.
+func runHardness(ctx context.Context, w io.Writer, cfg hardnessConfig) error {
+	fmt.Fprintf(w, "LWE hardness, 2016 estimate (n %d, m ≤ %d, q %d, σ %g, %s model)\n\n", cfg.N, cfg.M, cfg.Q, cfg.Sigma, cfg.Model.Name)
+	fmt.Fprintf(w, "%-7s | %-5s | %-7s | %-9s | %s\n", "Attack", "Beta", "Samples", "Dimension", "Bits")
+	fmt.Fprintln(w, "------------------------------------------------")
+	primal, ok := estimatePrimal(cfg.N, cfg.M, cfg.Q, cfg.Sigma, cfg.Model)
+	if ok {
+		fmt.Fprintf(w, "%-7s | %-5d | %-7d | %-9d | %.1f\n", primal.Attack, primal.Beta, primal.Samples, primal.Dim, primal.Bits)
+	} else {
+		fmt.Fprintf(w, "%-7s | %-5s | %-7s | %-9s | %s\n", "primal", "-", "-", "-", "no β succeeds")
+	}
+	dual := estimateDual(cfg.N, cfg.M, cfg.Q, cfg.Sigma, cfg.Model)
+	fmt.Fprintf(w, "%-7s | %-5d | %-7d | %-9d | %.1f\n", dual.Attack, dual.Beta, dual.Samples, dual.Dim, dual.Bits)
+	if !cfg.Validate {
+		return nil
+	}
+	if dryRun != nil {
+		return fmt.Errorf("hardness -validate reduces lattices and has no dry run")
+	}
+	return validatePrimal(ctx, w, cfg)
+}
+
+// validatePrimal attacks cfg.Trials LWE instances with all cfg.M samples,
+// seeded by --seed, by progressive BKZ on their primal embedding: the block
+// size grows by 2 from LLL until a row of the reduced basis yields a secret
+// that LWEInstance.CheckSecret accepts. The block sizes that did are
+// written to w next to the least β primalSucceeds with for m = cfg.M. The
+// estimate is asymptotic, so at these sizes only the trend is expected to
+// match: both grow with n and σ and shrink with q.
+func validatePrimal(ctx context.Context, w io.Writer, cfg hardnessConfig) error {
+	p := LWEParams{N: cfg.N, M: cfg.M, Q: cfg.Q, Secret: lweDistribution{Kind: "gaussian", Sigma: cfg.Sigma}, Error: lweDistribution{Kind: "gaussian", Sigma: cfg.Sigma}}
+	d := p.M + p.N + 1
+	predicted := "none"
+	for beta := 2; beta <= d; beta++ {
+		if primalSucceeds(p.N, p.M, p.Q, cfg.Sigma, beta) {
+			predicted = fmt.Sprint(beta)
+			break
+		}
+	}
+	var observed []string
+	var betas []float64
+	for trial := 1; trial <= cfg.Trials; trial++ {
+		inst := p.Sample(trialRand("hardness", int64(p.N), int64(p.M), p.Q, int64(math.Float64bits(cfg.Sigma)), int64(trial)))
+		beta, err := primalAttack(ctx, inst)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("trial %d: %w", trial, err)
+		}
+		if beta == 0 {
+			observed = append(observed, "-")
+			continue
+		}
+		observed, betas = append(observed, fmt.Sprint(beta)), append(betas, float64(beta))
+	}
+	fmt.Fprintf(w, "\nValidation (primal, m %d, dimension %d): predicted β %s, observed β %s", p.M, d, predicted, strings.Join(observed, ", "))
+	if len(betas) > 0 {
+		fmt.Fprintf(w, " (mean %.1f)", stat.Mean(betas, nil))
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// primalAttack reduces the primal embedding of inst with progressive BKZ,
+// the block size growing by 2 from 2, and returns the first block size
+// after which a row of the basis yields an accepted secret, or 0 if none
+// up to the dimension does.
+func primalAttack(ctx context.Context, inst *LWEInstance) (int, error) {
+	basis := inst.PrimalBasis()
+	reduced := basis
+	for beta := 2; beta <= len(basis); beta += 2 {
+		next, err := bkzReduce(ctx, reduced, beta, bkzOptions{})
+		if err != nil {
+			return 0, fmt.Errorf("BKZ-%d: %w", beta, err)
+		}
+		if err := checkVolumePreserved(basis, next); err != nil {
+			return 0, fmt.Errorf("BKZ-%d: %w", beta, err)
+		}
+		reduced = next
+		for _, v := range reduced {
+			if s, ok := inst.SecretFromPrimal(v); ok {
+				if _, ok := inst.CheckSecret(s); ok {
+					return beta, nil
+				}
+			}
+		}
+	}
+	return 0, nil
+}
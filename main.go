@@ -1,22 +1,632 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"runtime/trace"
+	"strings"
+	"syscall"
+)
 
-// main is the entry point of the program. It executes the verification
-// experiments for both Lab 1 and Lab 2 in sequence and prints the
-// results to standard output in a formatted log.
+// options are the global command-line flags, given before any subcommand.
+type options struct {
+	// Output selects the format written to standard output: "text" for the
+	// formatted log or the name of a structured format such as "json".
+	Output string
+	// CSVDir, if set, is the directory receiving CSV exports of the results.
+	CSVDir string
+	// HTMLPath, if set, is the file receiving a self-contained HTML report.
+	HTMLPath string
+	// GnuplotDir, if set, is the directory receiving profile data files and
+	// a gnuplot script plotting them.
+	GnuplotDir string
+	// PlotDir, if set, is the directory receiving PNG and SVG plots.
+	PlotDir string
+	// DBPath, if set, is the SQLite database every result set is appended to.
+	DBPath string
+	// CacheDir, if set, is the directory caching fplll results across runs.
+	CacheDir string
+	// Coordinator, if set, is the address remote workers connect to, and
+	// SSHWorkers lists hosts to start workers on; every fplll call of the
+	// run is then sent to a worker.
+	Coordinator string
+	SSHWorkers  []string
+	// SaveBases, if set, is the directory archiving every reduced basis and
+	// shortest vector, from which the continue command picks them up.
+	SaveBases string
+	// OutputDir, if set, is the directory relative output paths are
+	// resolved against.
+	OutputDir string
+	// Backend selects the solver binary, its floating-point precision, how
+	// many calls run concurrently, how long each may take and how failed
+	// calls are retried.
+	Backend backendConfig
+	// GSO selects the implementation of the Gram-Schmidt profile, and
+	// GSOPrecision the mantissa of its big.Float one (0 for automatic).
+	GSO          string
+	GSOPrecision uint
+	// ProfileBounds certifies Lab 2 profiles with interval arithmetic.
+	ProfileBounds bool
+	// ExactProfile checks every profile against the exact rational one
+	// and reports the exact one.
+	ExactProfile bool
+	// DeltaTable is a δ0 table written by calibrate to use instead of
+	// defaultDeltaTable.
+	DeltaTable string
+	// TUI enables the status dashboard on standard error.
+	TUI bool
+	// Progress enables progress lines with ETA on standard error.
+	Progress bool
+	// LogFormat selects the format of diagnostic messages on standard
+	// error: "text" or "json".
+	LogFormat string
+	// LogLevel is the lowest level of diagnostic messages shown.
+	LogLevel slog.Level
+	// DryRun prints the planned fplll invocations and output files instead
+	// of executing or writing them.
+	DryRun bool
+	// Seed seeds the basis generator; 0 picks a random seed. The seed in use
+	// is recorded in the run metadata.
+	Seed uint64
+	// Pprof, if set, is the address serving net/http/pprof during the run.
+	Pprof string
+	// Trace, if set, is the file receiving an execution trace of the run.
+	Trace string
+	// Assert makes the run fail if the results violate Thresholds.
+	Assert     bool
+	Thresholds thresholds
+}
+
+// outputSpecs lists the destinations selected by the global flags.
+func (o options) outputSpecs() []outputSpec {
+	specs := []outputSpec{{Format: o.Output}}
+	if o.CSVDir != "" {
+		specs = append(specs, outputSpec{Format: "csv", Path: o.CSVDir})
+	}
+	if o.HTMLPath != "" {
+		specs = append(specs, outputSpec{Format: "html", Path: o.HTMLPath})
+	}
+	if o.GnuplotDir != "" {
+		specs = append(specs, outputSpec{Format: "gnuplot", Path: o.GnuplotDir})
+	}
+	if o.PlotDir != "" {
+		specs = append(specs, outputSpec{Format: "plot", Path: o.PlotDir})
+	}
+	if o.DBPath != "" {
+		specs = append(specs, outputSpec{Format: "sqlite", Path: o.DBPath})
+	}
+	return specs
+}
+
+// parseGlobalFlags parses the global flags and returns the remaining
+// arguments, i.e. the optional subcommand and its own arguments. Defaults
+// come from the config file and the environment (see loadConfigDefaults).
+func parseGlobalFlags(args []string) (options, []string, error) {
+	var opts options
+	defaults, err := loadConfigDefaults()
+	if err != nil {
+		return opts, nil, err
+	}
+	fs := flag.NewFlagSet("lattice-labs", flag.ContinueOnError)
+	fs.StringVar(&opts.Output, "output", "text", "format written to standard output: text, json, html or latex")
+	fs.StringVar(&opts.CSVDir, "csv", "", "write results and profiles as CSV files into this directory")
+	fs.StringVar(&opts.HTMLPath, "html", "", "write a self-contained HTML report to this file")
+	fs.StringVar(&opts.GnuplotDir, "gnuplot", "", "write profile data and a gnuplot script into this directory")
+	fs.StringVar(&opts.PlotDir, "plot", "", "render PNG and SVG plots into this directory")
+	fs.StringVar(&opts.DBPath, "db", "", "append every result (parameters, metrics, profiles, timings) to this SQLite database")
+	fs.StringVar(&opts.OutputDir, "output-dir", defaults.OutputDir, "resolve relative output paths (--csv, --html, --db, ...) against this directory")
+	fs.StringVar(&opts.Backend.Name, "backend", defaults.Backend, "lattice backend (only fplll; SageMath is chosen per command with reduce/svp -backend sage, flatter with -a flatter)")
+	fs.StringVar(&opts.Backend.Binary, "fplll", defaults.FplllPath, "fplll executable")
+	fs.StringVar(&opts.Backend.Sage, "sage", defaults.Sage, "SageMath executable of -backend sage")
+	fs.StringVar(&opts.Backend.TempDir, "temp-dir", defaults.TempDir, "directory for the temporary files of fplll calls (GSO dumps of --tour-profiles)")
+	fs.StringVar(&opts.Backend.Flatter, "flatter", defaults.Flatter, "flatter executable for flatter steps and LLL preprocessing before BKZ, used if found (off disables it)")
+	fs.StringVar(&opts.Backend.Library, "libfplll", defaults.Libfplll, "C interface to libfplll (liblatticelabs_fplll) that fplll calls go through in process, searched for if empty (off runs the executable)")
+	fs.IntVar(&opts.Backend.Precision, "precision", defaults.Precision, "floating-point precision of fplll in bits, with float type mpfr (0 lets fplll choose)")
+	fs.StringVar(&opts.Backend.Strategy, "strategy", defaults.Strategy, "pruning strategy file for BKZ and slide reduction (fplll -s), or default for fplll's own")
+	fs.BoolVar(&opts.Backend.TourStats, "tour-stats", false, "run BKZ and slide reduction verbosely (fplll -v) and report the CPU time, ‖b1‖, slope and enumeration nodes of every tour")
+	fs.BoolVar(&opts.Backend.TourProfiles, "tour-profiles", false, "have BKZ and slide reduction dump the basis profile after every tour (fplll -bkzdumpgso) and report the profiles")
+	fs.StringVar(&opts.Backend.FloatType, "float-type", defaults.FloatType, "floating-point type of fplll: "+strings.Join(fplllFloatTypes, ", ")+" (empty lets fplll choose)")
+	fs.IntVar(&opts.Backend.Jobs, "jobs", defaults.Jobs, "number of fplll calls run concurrently")
+	fs.DurationVar(&opts.Backend.Timeout, "timeout", defaults.Timeout, "time limit for each fplll call, e.g. 10m (0 means none)")
+	fs.DurationVar(&opts.Backend.MaxTime, "max-time", defaults.MaxTime, "time budget of each reduction run, after which it stops with the best basis so far, e.g. 1h (0 means none)")
+	fs.IntVar(&opts.Backend.Retries, "retries", defaults.Retries, "number of times a failed fplll call is repeated")
+	fs.DurationVar(&opts.Backend.RetryBackoff, "retry-backoff", defaults.RetryBackoff, "wait before the first retry, doubled for each further one")
+	fs.BoolVar(&opts.Backend.Rerandomize, "rerandomize", defaults.Rerandomize, "rerandomize the basis before each retry")
+	fs.StringVar(&opts.GSO, "gso", "householder", "Gram-Schmidt profile implementation: "+gsoMethodNames())
+	fs.UintVar(&opts.GSOPrecision, "gso-precision", 0, "mantissa in bits of the bigfloat Gram-Schmidt profile (0 chooses it from the entry sizes)")
+	fs.BoolVar(&opts.ExactProfile, "exact-profile", false, "check every Gram-Schmidt profile against the exact rational one and report the exact one")
+	fs.StringVar(&opts.DeltaTable, "delta-table", "", "δ0(β) table written by calibrate, for the GSA predictions, estimates and block-size choices")
+	fs.BoolVar(&opts.ProfileBounds, "profile-bounds", false, "report certified error bounds of Lab 2 profiles and GSA slopes (interval arithmetic)")
+	fs.StringVar(&opts.CacheDir, "cache", defaults.CacheDir, "cache fplll results in this directory, keyed by a hash of the basis and parameters, and reuse them")
+	fs.StringVar(&opts.Coordinator, "coordinator", "", "send fplll calls to remote workers connecting to this address, e.g. :7070")
+	sshWorkers := fs.String("ssh-workers", "", "comma-separated hosts to start workers on over ssh (lattice-labs must be on their PATH)")
+	fs.StringVar(&opts.SaveBases, "save-bases", "", "save every reduced basis and shortest vector into this directory, with a manifest for continue")
+	fs.BoolVar(&opts.TUI, "tui", false, "show a live status dashboard on standard error (terminals only)")
+	fs.BoolVar(&opts.Progress, "progress", false, "report instances completed, backend time and ETA on standard error")
+	fs.StringVar(&opts.LogFormat, "log", "text", "format of diagnostic messages on standard error: text or json")
+	verbose := fs.Bool("v", false, "also log informational messages")
+	veryVerbose := fs.Bool("vv", false, "also log debugging details such as fplll command lines")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "print the fplll invocations and outputs that would be used without running them")
+	fs.Uint64Var(&opts.Seed, "seed", 0, "seed of the basis generator, to repeat a recorded run (0 picks a random seed)")
+	fs.StringVar(&opts.Pprof, "pprof", "", "serve net/http/pprof on this address during the run, e.g. localhost:6060")
+	fs.StringVar(&opts.Trace, "trace", "", "write an execution trace of the run to this file, for go tool trace")
+	fs.BoolVar(&opts.Assert, "assert", false, "exit with status 3 if the results violate the verification thresholds")
+	fs.Float64Var(&opts.Thresholds.MaxGHError, "max-gh-error", 25, "with --assert, largest allowed relative GH error of a Lab 1 instance, in percent")
+	fs.Float64Var(&opts.Thresholds.MinR2, "min-r2", 0.9, "with --assert, smallest allowed R² of the GSA fit of a Lab 2 profile")
+	latex := fs.Bool("latex", false, "print the result tables as booktabs LaTeX (same as --output latex)")
+	if err := fs.Parse(args); err != nil {
+		return opts, nil, err
+	}
+	if *latex {
+		opts.Output = "latex"
+	}
+	for _, host := range strings.Split(*sshWorkers, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			opts.SSHWorkers = append(opts.SSHWorkers, host)
+		}
+	}
+	opts.LogLevel = logLevel(*verbose, *veryVerbose)
+	if !streamOutputFormat(opts.Output) {
+		return opts, nil, fmt.Errorf("unknown output format %q", opts.Output)
+	}
+	if err := opts.Backend.validate(); err != nil {
+		return opts, nil, err
+	}
+	if gsoMethods[opts.GSO] == nil {
+		return opts, nil, fmt.Errorf("unknown Gram-Schmidt implementation %q (%s)", opts.GSO, gsoMethodNames())
+	}
+	return opts, fs.Args(), nil
+}
+
+// Exit codes of the program.
+const (
+	exitError       = 1   // the run failed, e.g. invalid arguments or an unreadable file
+	exitNoVector    = 2   // svp -radius found no vector shorter than the radius
+	exitAssertion   = 3   // --assert found violated thresholds, compare regressions, crosscheck disagreements, dual asymmetries, SIS verification failures, volume changes or reduce -verify failures
+	exitInterrupted = 130 // the run was stopped by SIGINT or SIGTERM
+)
+
+// errInterrupted is returned by run when a signal stopped the labs after the
+// completed results were written.
+var errInterrupted = errors.New("interrupted, completed results were written")
+
+// main is the entry point of the program. Without a subcommand it executes
+// the verification experiments for both Lab 1 and Lab 2 in sequence and
+// prints the results to standard output in a formatted log (or in the format
+// selected with --output). A subcommand is dispatched to runCommand instead.
 func main() {
-	fmt.Println("=== Lattice Heuristics Lab Implementation ===")
-	fmt.Println()
+	opts, args, err := parseGlobalFlags(os.Args[1:])
+	if err == nil {
+		err = run(opts, args)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lattice-labs: %v\n", err)
+		var failed *assertionError
+		if errors.As(err, &failed) {
+			os.Exit(exitAssertion)
+		}
+		if errors.Is(err, errInterrupted) {
+			os.Exit(exitInterrupted)
+		}
+		if errors.Is(err, errNoVectorInRadius) {
+			os.Exit(exitNoVector)
+		}
+		os.Exit(exitError)
+	}
+}
+
+// run sets up logging and the requested progress reporters and executes the
+// subcommand in args, or the default labs if there is none. With --assert the
+// results of a real (not dry) run are then checked against the verification
+// thresholds.
+//
+// SIGINT and SIGTERM cancel the running fplll call and stop the labs; the
+// results completed so far are still written to every output. A second
+// signal terminates the program immediately.
+func run(opts options, args []string) error {
+	if err := setupLogging(os.Stderr, opts.LogFormat, opts.LogLevel); err != nil {
+		return err
+	}
+	backend, outputDir = opts.Backend, opts.OutputDir
+	gsoMethod, gsoPrecision, certifyProfiles = opts.GSO, opts.GSOPrecision, opts.ExactProfile
+	profileBounds = opts.ProfileBounds
+	if opts.DeltaTable != "" {
+		t, err := loadDeltaTable(opts.DeltaTable)
+		if err != nil {
+			return fmt.Errorf("--delta-table: %w", err)
+		}
+		deltas = t
+	}
+	if opts.Pprof != "" {
+		if err := startPprof(opts.Pprof); err != nil {
+			return err
+		}
+	}
+	if opts.Trace != "" {
+		stopTrace, err := startTrace(opts.Trace)
+		if err != nil {
+			return err
+		}
+		defer stopTrace()
+	}
+	useFlatter()
+	useLibfplll()
+	if opts.DryRun {
+		dryRun = stdout
+		planEnvironment()
+	}
+	if dryRun == nil {
+		useResolvedFplll()
+		var err error
+		backend.Version, err = detectFplllVersion(backend.Binary)
+		checkFplllVersion(backend.Version, err)
+	}
+	if backend.Strategy != "" {
+		if err := useStrategy(); err != nil {
+			return fmt.Errorf("--strategy: %w", err)
+		}
+	}
+	if opts.CacheDir != "" && dryRun == nil {
+		c, err := openFplllCache(opts.CacheDir)
+		if err != nil {
+			return fmt.Errorf("--cache: %w", err)
+		}
+		resultCache = c
+		defer c.logStats()
+	}
+	if (opts.Coordinator != "" || len(opts.SSHWorkers) > 0) && dryRun == nil {
+		pool, err := startWorkerPool(opts.Coordinator, opts.SSHWorkers)
+		if err != nil {
+			return err
+		}
+		workers = pool
+		defer pool.Close()
+	}
+	runInfo = newRunMetadata(seedBasisSource(opts.Seed))
+	if opts.SaveBases != "" {
+		dir, err := outputPath(opts.SaveBases)
+		if err != nil {
+			return fmt.Errorf("--save-bases: %w", err)
+		}
+		a, err := openBasisArchive(dir)
+		if err != nil {
+			return fmt.Errorf("--save-bases: %w", err)
+		}
+		archive = a
+	}
+	if len(args) == 0 || !quietCommands[args[0]] {
+		fmt.Fprintf(logWriter(opts), "Seed %d (repeat with --seed %d)\n\n", runInfo.Seed, runInfo.Seed)
+	}
+	if opts.Progress {
+		progressReporters = append(progressReporters, newProgressPrinter(os.Stderr).update)
+	}
+	if opts.TUI && isTerminal(os.Stderr) {
+		d := newDashboard(os.Stdout, os.Stderr)
+		defer d.Close()
+		stdout = d
+		progressReporters = append(progressReporters, d.update)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+			select {
+			case <-finished:
+				// Cancelled by the deferred stop after the run.
+				return
+			default:
+			}
+			slog.Warn("interrupted, writing completed results (signal again to quit immediately)")
+			// Restore the default behaviour for the second signal.
+			stop()
+		case <-finished:
+		}
+	}()
+
+	var results []*runResults
+	var err error
+	if len(args) > 0 {
+		taskCtx, task := trace.NewTask(ctx, args[0])
+		results, err = runCommand(taskCtx, opts, args[0], args[1:])
+		task.End()
+	} else {
+		results, err = runDefault(ctx, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return errInterrupted
+	}
+	if err := volumeChangeError(); err != nil {
+		return err
+	}
+	if opts.Assert && !opts.DryRun {
+		return checkAssertions(opts.Thresholds, results)
+	}
+	return nil
+}
+
+// quietCommands are the subcommands whose standard output carries only their
+// result, without the seed banner of the labs.
+var quietCommands = map[string]bool{"compare": true, "reduce": true, "svp": true, "count": true, "cvp": true, "convert": true, "profile": true, "health": true, "bench": true, "crosscheck": true, "dual": true, "decode": true, "sample": true, "lwe": true, "sis": true, "ntru": true, "estimate": true, "hardness": true, "recommend": true, "calibrate": true, "worker": true,
+	"daemon": true, "submit": true, "jobs": true}
+
+// runDefault runs Lab 1 and Lab 2 with their classic parameters.
+func runDefault(ctx context.Context, opts options) ([]*runResults, error) {
+	sinks, err := openOutputs(opts.outputSpecs())
+	if err != nil {
+		return nil, err
+	}
+	defer sinks.Close()
+	w := sinks.log
+
+	fmt.Fprintln(w, "=== Lattice Heuristics Lab Implementation ===")
+	fmt.Fprintln(w)
+
+	res := &runResults{}
 
 	// Run Lab 1: Gaussian Heuristic Verification
-	runLab1Verification()
+	taskCtx, task := trace.NewTask(ctx, "lab1")
+	res.Lab1 = append(res.Lab1, runLab1Verification(taskCtx, w, defaultLab1Config()))
+	task.End()
 
-	fmt.Println()
+	if ctx.Err() == nil {
+		fmt.Fprintln(w)
 
-	// Run Lab 2: Geometric Series Assumption Verification
-	runLab2Verification()
+		// Run Lab 2: Geometric Series Assumption Verification
+		taskCtx, task := trace.NewTask(ctx, "lab2")
+		lab2 := runLab2Verification(taskCtx, w, defaultLab2Config())
+		task.End()
+		if ctx.Err() == nil {
+			res.Lab2 = append(res.Lab2, lab2)
+		}
+	}
+
+	if ctx.Err() == nil {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "=== All experiments completed ===")
+	}
+
+	return []*runResults{res}, sinks.render(res)
+}
+
+// runCommand executes a named subcommand with its remaining arguments and
+// returns the result sets it produced.
+//
+//	run <experiments.yaml|json>   execute every experiment of a definition file
+//	sweep -n .. -beta .. -q ..    grid search over the Cartesian product of parameters
+//	compare [-db file] BASE NEW   compare two stored result sets and flag regressions
+//	reduce [-a algo] [-b beta] [file|-]  reduce a basis and print it
+//	svp [file|-]                  print a shortest vector of a basis
+//	count -radius r [file|-]      print the number of lattice vectors within a radius
+//	cvp [-target vec] [file|-]    print the lattice points closest to target vectors
+//	convert -to fmt [file|-]      rewrite a basis in another matrix format
+//	sample -sigma s [file|-]      print vectors drawn from the discrete Gaussian over a lattice
+//	lwe [-n ..] [-embedding ..]   print the primal or dual embedding basis of a random LWE instance
+//	profile [-stream ..] [file|-] print the Gram-Schmidt profile of a basis
+//	health [file|-]               report whether float64 and big.Float suffice for the profile of a basis
+//	continue [-a algo] [-b beta] ID|file  reduce a saved basis further
+//	bench [-n ..] [-workers ..]   time the Gram-Schmidt profile implementations
+//	crosscheck [-n ..] [-backends ..]  compare the results of the backends on random bases
+//	dual [-n ..] [-beta ..]       check the primal/dual profile symmetry of BKZ-reduced bases
+//	decode [-n ..] [-radius ..]   compare the decoding radius of Babai and randomized nearest planes
+//	sis [-n ..] [-m ..] [-beta ..]  solve random SIS instances and relate the norms to the profiles
+//	ntru [-n ..] [-beta ..]       recover toy NTRU keys by lattice reduction
+//	estimate [-beta ..] [-bits ..]  convert block sizes into core-SVP bit security
+//	hardness [-n ..] [-q ..] [-sigma ..]  estimate the primal and dual attacks on LWE
+//	recommend [-bits ..] [-fail ..]  search LWE parameters reaching a security level
+//	calibrate [-n ..] [-beta ..] [-o ..]  measure δ0(β) and write a table for --delta-table
+//	worker host:port | -stdio     run fplll calls for a coordinator
+//	daemon [-addr ..] [-dir ..]   serve a persistent job queue over HTTP
+//	submit [-file ..] [args ..]   queue a job on a daemon
+//	jobs [-cancel ..] [ID]        list, show or cancel the jobs of a daemon
+func runCommand(ctx context.Context, opts options, name string, args []string) ([]*runResults, error) {
+	switch name {
+	case "run":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("usage: lattice-labs run <experiments.yaml>")
+		}
+		return runExperimentFile(ctx, logWriter(opts), args[0], opts.outputSpecs())
+	case "sweep":
+		cfg, err := parseSweepFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		sinks, err := openOutputs(opts.outputSpecs())
+		if err != nil {
+			return nil, err
+		}
+		defer sinks.Close()
+		rows, err := runSweep(ctx, sinks.log, cfg)
+		if err != nil {
+			return nil, err
+		}
+		res := &runResults{Sweep: rows}
+		return []*runResults{res}, sinks.render(res)
+	case "continue":
+		cfg, err := parseContinueFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		sinks, err := openOutputs(opts.outputSpecs())
+		if err != nil {
+			return nil, err
+		}
+		defer sinks.Close()
+		lab, err := runContinue(ctx, sinks.log, cfg)
+		if err != nil {
+			return nil, err
+		}
+		res := &runResults{}
+		if ctx.Err() == nil {
+			res.Lab2 = append(res.Lab2, lab)
+		}
+		return []*runResults{res}, sinks.render(res)
+	case "compare":
+		cfg, err := parseCompareFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runCompare(stdout, cfg)
+	case "reduce":
+		cfg, err := parsePipeFlags(name, args, true)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runReducePipe(ctx, stdout, cfg)
+	case "svp":
+		cfg, err := parsePipeFlags(name, args, false)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runSVPPipe(ctx, stdout, cfg)
+	case "count":
+		cfg, err := parsePipeFlags(name, args, false)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runCountPipe(ctx, stdout, cfg)
+	case "cvp":
+		cfg, err := parseCVPFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runCVP(ctx, stdout, cfg)
+	case "convert":
+		cfg, err := parsePipeFlags(name, args, false)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runConvertPipe(stdout, cfg)
+	case "sample":
+		cfg, err := parseSampleFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runSample(stdout, cfg)
+	case "lwe":
+		cfg, err := parseLWEFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runLWE(stdout, cfg)
+	case "profile":
+		cfg, err := parsePipeFlags(name, args, false)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runProfilePipe(stdout, cfg)
+	case "health":
+		cfg, err := parsePipeFlags(name, args, false)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runHealth(stdout, cfg)
+	case "crosscheck":
+		cfg, err := parseCrosscheckFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runCrosscheck(ctx, stdout, cfg)
+	case "dual":
+		cfg, err := parseDualFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runDual(ctx, stdout, cfg)
+	case "decode":
+		cfg, err := parseDecodeFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runDecode(ctx, stdout, cfg)
+	case "sis":
+		cfg, err := parseSISFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runSIS(ctx, stdout, cfg)
+	case "ntru":
+		cfg, err := parseNTRUFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runNTRU(ctx, stdout, cfg)
+	case "estimate":
+		cfg, err := parseEstimateFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runEstimate(stdout, cfg)
+	case "hardness":
+		cfg, err := parseHardnessFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runHardness(ctx, stdout, cfg)
+	case "calibrate":
+		cfg, err := parseCalibrateFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runCalibrate(ctx, stdout, cfg)
+	case "recommend":
+		cfg, err := parseRecommendFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runRecommend(stdout, cfg)
+	case "bench":
+		cfg, err := parseBenchFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runBench(ctx, stdout, cfg)
+	case "worker":
+		cfg, err := parseWorkerFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runWorker(ctx, cfg)
+	case "daemon":
+		cfg, err := parseDaemonFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runDaemon(ctx, cfg)
+	case "submit":
+		cfg, err := parseSubmitFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runSubmit(ctx, stdout, cfg)
+	case "jobs":
+		cfg, err := parseJobsFlags(args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, runJobList(ctx, stdout, cfg)
+	default:
+		return nil, fmt.Errorf("unknown command %q", name)
+	}
+}
 
-	fmt.Println()
-	fmt.Println("=== All experiments completed ===")
+// logWriter returns the destination for progress banners: standard output
+// in text mode, nowhere when standard output carries a structured format.
+func logWriter(opts options) io.Writer {
+	if opts.Output == "" || opts.Output == "text" {
+		return stdout
+	}
+	return io.Discard
 }
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// errNotInLattice is returned for a vector reported by an oracle that is not
+// a non-zero vector of the lattice it was asked about.
+var errNotInLattice = errors.New("vector is not a non-zero lattice vector")
+
+// latticeCoordinates returns the integer coefficients x with Σ xᵢbᵢ = v
+// for the linearly independent rows bᵢ of basis. The coefficients are
+// guessed by solving the system in float64 and rounding, which is checked
+// exactly; if the guess is wrong, the system is solved exactly over the
+// rationals by solveRat. A vector outside the span or with a fractional
+// coefficient gives an error wrapping errNotInLattice.
+func latticeCoordinates(basis Basis, v []*big.Int) ([]*big.Int, error) {
+	if x, ok := roundedCoordinates(basis, v); ok {
+		return x, nil
+	}
+	for _, b := range basis {
+		if len(b) != len(v) {
+			return nil, fmt.Errorf("vector has %d entries, the basis vectors %d", len(v), len(b))
+		}
+	}
+	// Column i of Bᵀ is bᵢ.
+	coords, err := solveRat(transpose(basis), v)
+	var dep dependentColumnError
+	switch {
+	case errors.As(err, &dep):
+		return nil, fmt.Errorf("basis row %d depends linearly on the others", dep.Col+1)
+	case errors.Is(err, errNoSolution):
+		return nil, fmt.Errorf("%w: it lies outside the span of the basis", errNotInLattice)
+	case err != nil:
+		return nil, err
+	}
+	x := make([]*big.Int, len(coords))
+	for i, c := range coords {
+		if !c.IsInt() {
+			return nil, fmt.Errorf("%w: coefficient %d is %s", errNotInLattice, i+1, c.RatString())
+		}
+		x[i] = new(big.Int).Set(c.Num())
+	}
+	return x, nil
+}
+
+// verifyLatticeVector checks exactly that v is a non-zero vector of the
+// lattice of basis, so that an oracle's answer doesn't have to be trusted.
+func verifyLatticeVector(basis Basis, v []*big.Int) error {
+	if normSquared(v).Sign() == 0 {
+		return fmt.Errorf("%w: it is zero", errNotInLattice)
+	}
+	_, err := latticeCoordinates(basis, v)
+	return err
+}
+
+// roundedCoordinates solves Bᵀx = v by least squares in float64, rounds x
+// and reports whether Σ xᵢbᵢ = v holds exactly for the rounded x.
+func roundedCoordinates(basis Basis, v []*big.Int) ([]*big.Int, bool) {
+	n, m := len(basis), len(v)
+	if n == 0 || n > m || slices.ContainsFunc(basis, func(b []*big.Int) bool { return len(b) != m }) {
+		return nil, false
+	}
+	bt := mat.NewDense(m, n, nil)
+	for i, b := range basis {
+		for j, e := range b {
+			f, _ := new(big.Float).SetInt(e).Float64()
+			bt.Set(j, i, f)
+		}
+	}
+	rhs := mat.NewVecDense(m, nil)
+	for j, e := range v {
+		f, _ := new(big.Float).SetInt(e).Float64()
+		rhs.SetVec(j, f)
+	}
+	var sol mat.VecDense
+	if err := sol.SolveVec(bt, rhs); err != nil {
+		return nil, false
+	}
+	x := make([]*big.Int, n)
+	for i := range x {
+		xi := math.Round(sol.AtVec(i))
+		if math.IsNaN(xi) || math.Abs(xi) >= 1<<53 {
+			return nil, false
+		}
+		x[i] = big.NewInt(int64(xi))
+	}
+	sum, term := new(big.Int), new(big.Int)
+	for j := range m {
+		sum.SetInt64(0)
+		for i, b := range basis {
+			sum.Add(sum, term.Mul(x[i], b[j]))
+		}
+		if sum.Cmp(v[j]) != 0 {
+			return nil, false
+		}
+	}
+	return x, true
+}
+
+// sameLattice checks exactly that the rows of a and b span the same
+// lattice: that every row of each lies in the lattice of the other. Both
+// must be bases, i.e. have linearly independent rows.
+func sameLattice(a, b Basis) error {
+	if len(a) != len(b) {
+		return fmt.Errorf("ranks %d and %d differ", len(a), len(b))
+	}
+	for i, v := range b {
+		if _, err := latticeCoordinates(a, v); err != nil {
+			return fmt.Errorf("row %d of the second basis: %w", i+1, err)
+		}
+	}
+	for i, v := range a {
+		if _, err := latticeCoordinates(b, v); err != nil {
+			return fmt.Errorf("row %d of the first basis: %w", i+1, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	mrand "math/rand/v2"
+)
+
+// basisSource is the randomness used to generate bases. It is a ChaCha8
+// stream seeded by seedBasisSource, so that a run can be repeated exactly.
+var basisSource io.Reader = rand.Reader
+
+// seedBasisSource seeds basisSource with seed, or with a fresh random seed if
+// seed is 0, and returns the seed in use.
+func seedBasisSource(seed uint64) uint64 {
+	for seed == 0 {
+		var b [8]byte
+		rand.Read(b[:])
+		seed = binary.LittleEndian.Uint64(b[:])
+	}
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], seed)
+	basisSource = mrand.NewChaCha8(key)
+	return seed
+}
+
+// trialSource returns the basis randomness of one trial: a ChaCha8 stream
+// keyed by the run seed, the lab and the trial's parameters. Trials that run
+// concurrently draw from independent streams, so their bases depend only on
+// the seed and the parameters, not on scheduling or on which other trials
+// run (e.g. when a sweep is resumed).
+func trialSource(lab string, params ...int64) io.Reader {
+	return mrand.NewChaCha8(trialKey(lab, params...))
+}
+
+// trialRand is like trialSource but returns a generator of random numbers
+// rather than bytes.
+func trialRand(lab string, params ...int64) *mrand.Rand {
+	return mrand.New(mrand.NewChaCha8(trialKey(lab, params...)))
+}
+
+// trialKey derives the ChaCha8 key of a trial from the run seed, the lab and
+// the trial's parameters.
+func trialKey(lab string, params ...int64) [32]byte {
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, runInfo.Seed)
+	io.WriteString(h, lab)
+	for _, p := range params {
+		binary.Write(h, binary.LittleEndian, p)
+	}
+	var key [32]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// runMetadata records how a result set was produced: without it results
+// can't be compared or reproduced later. Parameters specific to a lab are
+// kept with the lab's own results.
+type runMetadata struct {
+	Seed         uint64 `json:"seed"`
+	FplllVersion string `json:"fplll_version"`
+	// Strategy is the pruning strategy file of BKZ, if any, with its
+	// SHA-256.
+	Strategy       string `json:"strategy,omitempty"`
+	StrategySHA256 string `json:"strategy_sha256,omitempty"`
+	// Flatter is the flatter executable that preprocessed the bases of
+	// BKZ and slide steps, if any.
+	Flatter string `json:"flatter,omitempty"`
+	// Libfplll is the C interface to libfplll the fplll calls went through,
+	// if it was loaded.
+	Libfplll    string    `json:"libfplll,omitempty"`
+	GoVersion   string    `json:"go_version"`
+	Hostname    string    `json:"hostname"`
+	Command     []string  `json:"command"`
+	Started     time.Time `json:"started"`
+	Finished    time.Time `json:"finished"`
+	WallSeconds float64   `json:"wall_seconds"`
+}
+
+// runInfo is the metadata of the current invocation, filled in by run.
+// Every result set is stamped with a copy when it is rendered.
+var runInfo runMetadata
+
+// newRunMetadata collects the metadata available at the start of a run.
+func newRunMetadata(seed uint64) runMetadata {
+	hostname, _ := os.Hostname()
+	return runMetadata{
+		Seed:           seed,
+		FplllVersion:   fplllVersionLine(),
+		Strategy:       backend.Strategy,
+		StrategySHA256: backend.StrategyDigest,
+		Flatter:        backend.Flatter,
+		Libfplll:       backend.Library,
+		GoVersion:      runtime.Version() + " " + runtime.GOOS + "/" + runtime.GOARCH,
+		Hostname:       hostname,
+		Command:        os.Args,
+		Started:        time.Now(),
+	}
+}
+
+// finished returns a copy of m completed with the current time.
+func (m runMetadata) finished() *runMetadata {
+	m.Finished = time.Now()
+	m.WallSeconds = m.Finished.Sub(m.Started).Seconds()
+	return &m
+}
+
+// entries returns the metadata as display key/value pairs.
+func (m *runMetadata) entries() [][2]string {
+	entries := [][2]string{
+		{"Seed", strconv.FormatUint(m.Seed, 10)},
+		{"fplll", m.FplllVersion},
+	}
+	if m.Strategy != "" {
+		strategy := m.Strategy
+		if m.StrategySHA256 != "" {
+			strategy += " (sha256 " + m.StrategySHA256[:12] + ")"
+		}
+		entries = append(entries, [2]string{"Strategy", strategy})
+	}
+	if m.Flatter != "" {
+		entries = append(entries, [2]string{"flatter", m.Flatter})
+	}
+	if m.Libfplll != "" {
+		entries = append(entries, [2]string{"libfplll", m.Libfplll})
+	}
+	return append(entries, [][2]string{
+		{"Go", m.GoVersion},
+		{"Host", m.Hostname},
+		{"Command", strings.Join(m.Command, " ")},
+		{"Started", m.Started.Format(time.RFC3339)},
+		{"Finished", m.Finished.Format(time.RFC3339)},
+		{"Wall time", time.Duration(m.WallSeconds * float64(time.Second)).Round(time.Millisecond).String()},
+	}...)
+}
+
+// fplllVersionLine returns the first line printed by "fplll --version" at
+// startup, or a note explaining why it is unknown.
+func fplllVersionLine() string {
+	if dryRun != nil {
+		return "not queried (dry run)"
+	}
+	return backend.Version.Line
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	mrand "math/rand/v2"
+)
+
+// ntruKeygenAttempts bounds how many f NTRUParams.GenerateKey draws before
+// giving up on finding one invertible mod q.
+const ntruKeygenAttempts = 100
+
+// NTRUParams are the parameters of toy NTRU over ℤ_q[x]/(x^N - 1): the
+// private key is a pair of ternary polynomials (f, g), the public key
+// h = g/f mod q.
+type NTRUParams struct {
+	N int
+	Q int64
+}
+
+// validate checks that p describes keys GenerateKey can generate.
+func (p NTRUParams) validate() error {
+	switch {
+	case p.N < 2:
+		return fmt.Errorf("NTRU degree must be at least 2, got %d", p.N)
+	case p.Q < 3 || p.Q >= maxLWEModulus:
+		return fmt.Errorf("NTRU modulus must be in [3, 2^31), got %d", p.Q)
+	}
+	return nil
+}
+
+// NTRUKeyPair is a toy NTRU key pair; polynomials are their N coefficients,
+// constant first.
+type NTRUKeyPair struct {
+	NTRUParams
+	F, G []int64 // the private key, coefficients in {-1, 0, 1}
+	H    []int64 // the public key g·f⁻¹ mod q, coefficients in [0, q)
+}
+
+// GenerateKey draws f and g with uniform ternary coefficients, redrawing f
+// until it is invertible mod q, and returns the key pair. p must be valid.
+func (p NTRUParams) GenerateKey(rng *mrand.Rand) (*NTRUKeyPair, error) {
+	ternary := func() []int64 {
+		v := make([]int64, p.N)
+		for i := range v {
+			v[i] = rng.Int64N(3) - 1
+		}
+		return v
+	}
+	for range ntruKeygenAttempts {
+		f := ternary()
+		fInv, ok := p.invert(f)
+		if !ok {
+			continue
+		}
+		g := ternary()
+		return &NTRUKeyPair{NTRUParams: p, F: f, G: g, H: p.multiply(g, fInv)}, nil
+	}
+	return nil, fmt.Errorf("no f invertible mod %d in %d attempts", p.Q, ntruKeygenAttempts)
+}
+
+// multiply returns a·b in ℤ_q[x]/(x^N - 1), coefficients in [0, q).
+func (p NTRUParams) multiply(a, b []int64) []int64 {
+	c := make([]int64, p.N)
+	for i, x := range a {
+		x = (x%p.Q + p.Q) % p.Q
+		for j, y := range b {
+			k := (i + j) % p.N
+			c[k] = (c[k] + x*((y%p.Q+p.Q)%p.Q)) % p.Q
+		}
+	}
+	return c
+}
+
+// invert returns the inverse of f in ℤ_q[x]/(x^N - 1), if it has one, by
+// solving F·u = 1 mod q for the circulant matrix F of f.
+func (p NTRUParams) invert(f []int64) ([]int64, bool) {
+	a := make([][]int64, p.N)
+	for j := range a {
+		a[j] = make([]int64, p.N+1)
+		for i := range p.N {
+			a[j][i] = (f[(j-i+p.N)%p.N]%p.Q + p.Q) % p.Q
+		}
+	}
+	a[0][p.N] = 1
+	pivots, err := rowReduceMod(a, p.Q)
+	if err != nil || len(pivots) != p.N || pivots[p.N-1] != p.N-1 {
+		return nil, false
+	}
+	u := make([]int64, p.N)
+	for i := range u {
+		u[i] = a[i][p.N]
+	}
+	return u, true
+}
+
+// Lattice returns the basis of the NTRU lattice of the public key, the
+// vectors (u, u·h mod q), as the rows of
+//
+//	[ I_N   H     ]
+//	[ 0     q·I_N ]
+//
+// for the circulant matrix H of h, of rank 2N and volume q^N. It contains
+// (f, g) and its rotations (xᵏ·f, xᵏ·g), which are much shorter than its
+// Gaussian Heuristic: recovering one of them is recovering the key.
+func (k *NTRUKeyPair) Lattice() Basis {
+	n := k.N
+	basis := make(Basis, 2*n)
+	for i := range basis {
+		basis[i] = make([]*big.Int, 2*n)
+		for j := range basis[i] {
+			basis[i][j] = new(big.Int)
+		}
+	}
+	for i := range n {
+		basis[i][i].SetInt64(1)
+		// The row of xⁱ·h: coefficient j is h_{j-i}.
+		for j := range n {
+			basis[i][n+j].SetInt64(k.H[(j-i+n)%n])
+		}
+		basis[n+i][n+i].SetInt64(k.Q)
+	}
+	return basis
+}
+
+// Rotation reports whether v is ±(xᵏ·f, xᵏ·g) for some k, and that k.
+func (k *NTRUKeyPair) Rotation(v []*big.Int) (int, bool) {
+	n := k.N
+	if len(v) != 2*n {
+		return 0, false
+	}
+	for shift := range n {
+		for _, sign := range []int64{1, -1} {
+			match := true
+			for j := 0; j < n && match; j++ {
+				i := (j - shift + n) % n
+				match = v[j].IsInt64() && v[j].Int64() == sign*k.F[i] &&
+					v[n+j].IsInt64() && v[n+j].Int64() == sign*k.G[i]
+			}
+			if match {
+				return shift, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// AttackBeta returns the block size the ntru lab attacks keys with for
+// -beta auto: the least one with which BKZ is expected to find a key of the
+// NTRU lattice, of rank 2N and volume q^N, the key (f, g) having norm about
+// √(4N/3) for ternary coefficients (BetaForUniqueSVP), or 2N, reducing the
+// whole basis, if none is.
+func (p NTRUParams) AttackBeta() int {
+	d := 2 * p.N
+	beta, ok := BetaForUniqueSVP(d, float64(p.N)*math.Log2(float64(p.Q)), math.Sqrt(float64(4*p.N)/3), d)
+	if !ok {
+		return d
+	}
+	return beta
+}
+
+// Attack runs the key-recovery attack on the public key: it BKZ-β reduces
+// the NTRU lattice and returns the index of the first row of the reduced
+// basis that is a rotation of the private key, or -1 if none is.
+func (k *NTRUKeyPair) Attack(ctx context.Context, beta int) (row int, err error) {
+	basis := k.Lattice()
+	reduced, err := bkzReduce(ctx, basis, beta, bkzOptions{})
+	if err != nil {
+		return -1, fmt.Errorf("BKZ-%d: %w", beta, err)
+	}
+	if err := checkVolumePreserved(basis, reduced); err != nil {
+		return -1, fmt.Errorf("BKZ-%d: %w", beta, err)
+	}
+	for i, v := range reduced {
+		if _, ok := k.Rotation(v); ok {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// ntruConfig holds the arguments of the ntru command.
+type ntruConfig struct {
+	Dims  []int
+	Q     int64
+	Betas []int
+	// AutoBeta attacks every degree with its NTRUParams.AttackBeta instead
+	// of Betas (-beta auto).
+	AutoBeta bool
+	Trials   int
+}
+
+// parseNTRUFlags builds an ntruConfig from the command line.
+func parseNTRUFlags(args []string) (ntruConfig, error) {
+	fs := flag.NewFlagSet("ntru", flag.ContinueOnError)
+	dims := fs.String("n", "41,53,61", "comma-separated ring degrees N")
+	q := fs.Int64("q", 127, "modulus")
+	betas := fs.String("beta", "2,10,20", "comma-separated BKZ block sizes, or auto for the least one expected to find the key at each degree")
+	trials := fs.Int("trials", 3, "key pairs per degree")
+	if err := fs.Parse(args); err != nil {
+		return ntruConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return ntruConfig{}, fmt.Errorf("usage: lattice-labs ntru [-n degrees] [-q q] [-beta betas] [-trials k]")
+	}
+	cfg := ntruConfig{Q: *q, Trials: *trials}
+	dimList, err := parseIntList(*dims)
+	if err != nil {
+		return cfg, fmt.Errorf("-n: %w", err)
+	}
+	for _, n := range dimList {
+		if err := (NTRUParams{N: int(n), Q: cfg.Q}).validate(); err != nil {
+			return cfg, err
+		}
+		cfg.Dims = append(cfg.Dims, int(n))
+	}
+	if *betas == "auto" {
+		cfg.AutoBeta = true
+	} else {
+		betaList, err := parseIntList(*betas)
+		if err != nil {
+			return cfg, fmt.Errorf("-beta: %w", err)
+		}
+		for _, beta := range betaList {
+			if err := bkzStep(int(beta)).validate(); err != nil {
+				return cfg, fmt.Errorf("-beta: %w", err)
+			}
+			cfg.Betas = append(cfg.Betas, int(beta))
+		}
+	}
+	if cfg.Trials < 1 {
+		return cfg, fmt.Errorf("-trials must be at least 1")
+	}
+	return cfg, nil
+}
+
+// runNTRU runs the NTRU key-recovery lab: for every degree, cfg.Trials key
+// pairs are generated, seeded by --seed, and attacked with every block
+// size, or with NTRUParams.AttackBeta with cfg.AutoBeta. One row per degree
+// and block size is written to w with how many keys were recovered, and the
+// row of the reduced basis the first rotation of the key appeared in on
+// average: the larger β, the more keys fall, and the sooner.
+func runNTRU(ctx context.Context, w io.Writer, cfg ntruConfig) error {
+	if dryRun != nil {
+		return fmt.Errorf("ntru reduces NTRU lattices and has no dry run")
+	}
+	fmt.Fprintf(w, "NTRU key recovery (q %d, ternary keys)\n\n", cfg.Q)
+	fmt.Fprintf(w, "%-6s | %-6s | %-10s | %s\n", "N", "Beta", "Recovered", "Mean row")
+	fmt.Fprintln(w, "--------------------------------------------")
+
+	for _, n := range cfg.Dims {
+		p := NTRUParams{N: n, Q: cfg.Q}
+		keys := make([]*NTRUKeyPair, cfg.Trials)
+		for trial := range keys {
+			key, err := p.GenerateKey(trialRand("ntru", int64(n), cfg.Q, int64(trial+1)))
+			if err != nil {
+				return fmt.Errorf("N %d trial %d: %w", n, trial+1, err)
+			}
+			keys[trial] = key
+		}
+		betas := cfg.Betas
+		if cfg.AutoBeta {
+			betas = []int{p.AttackBeta()}
+		}
+		for _, beta := range betas {
+			recovered, rows := 0, 0
+			for trial, key := range keys {
+				row, err := key.Attack(ctx, beta)
+				if ctx.Err() != nil {
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("N %d trial %d: %w", n, trial+1, err)
+				}
+				if row >= 0 {
+					recovered, rows = recovered+1, rows+row+1
+				}
+			}
+			mean := "-"
+			if recovered > 0 {
+				mean = fmt.Sprintf("%.1f", float64(rows)/float64(recovered))
+			}
+			fmt.Fprintf(w, "%-6d | %-6d | %-10s | %s\n", n, beta, fmt.Sprintf("%d/%d", recovered, cfg.Trials), mean)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// jsonFloat is a float64 that encodes non-finite values (a failed oracle call
+// yields an infinite relative error, a huge lattice an infinite float64
+// volume) as null instead of making encoding/json fail.
+type jsonFloat float64
+
+// MarshalJSON implements json.Marshaler.
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte("null"), nil
+	}
+	return strconv.AppendFloat(nil, v, 'g', -1, 64), nil
+}
+
+// jsonFloats converts values to jsonFloat.
+func jsonFloats(values []float64) []jsonFloat {
+	out := make([]jsonFloat, len(values))
+	for i, v := range values {
+		out[i] = jsonFloat(v)
+	}
+	return out
+}
+
+// runResults gathers everything produced by one invocation so that it can be
+// rendered by the structured output formats after the run.
+type runResults struct {
+	Metadata *runMetadata `json:"metadata,omitempty"`
+	Lab1     []lab1Result `json:"lab1,omitempty"`
+	Lab2     []lab2Result `json:"lab2,omitempty"`
+	Sweep    []sweepRow   `json:"sweep,omitempty"`
+}
+
+// resultWriters maps the names of structured output formats to their
+// renderers. The "text" format is not listed: it is the log the labs write
+// while they run.
+var resultWriters = map[string]func(io.Writer, *runResults) error{
+	"json":  writeJSON,
+	"html":  writeHTMLReport,
+	"latex": writeLaTeXTables,
+}
+
+// resultDirWriters maps the names of output formats that produce several
+// files to their renderers. Their output path names a directory, which is
+// created if needed.
+var resultDirWriters = map[string]func(dir string, res *runResults) error{
+	"csv":     writeCSVFiles,
+	"gnuplot": writeGnuplotFiles,
+	"plot":    writePlotFiles,
+}
+
+// resultPathWriters maps the names of output formats that manage their
+// destination path themselves, such as a database appended to by every run.
+var resultPathWriters = map[string]func(path string, res *runResults) error{
+	"sqlite": appendSQLite,
+}
+
+// validOutputFormat reports whether name is a known output format.
+func validOutputFormat(name string) bool {
+	return name == "" || name == "text" || resultWriters[name] != nil || resultDirWriters[name] != nil || resultPathWriters[name] != nil
+}
+
+// streamOutputFormat reports whether name is a known format that can be
+// written to a stream such as standard output.
+func streamOutputFormat(name string) bool {
+	return validOutputFormat(name) && resultDirWriters[name] == nil && resultPathWriters[name] == nil
+}
+
+// writeJSON renders the results as a single indented JSON document.
+func writeJSON(w io.Writer, res *runResults) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
+
+// stdout is where destinations without a path write. The TUI dashboard
+// replaces it so that the log scrolls above its status panel.
+var stdout io.Writer = os.Stdout
+
+// structuredSink is a destination for one structured output format.
+type structuredSink struct {
+	format string
+	w      io.Writer
+}
+
+// outputSinks are the opened destinations of a run: log receives the text
+// log of the labs, structured and paths the formats rendered once the run is
+// over.
+type outputSinks struct {
+	log        io.Writer
+	structured []structuredSink
+	paths      []outputSpec
+	files      []*os.File
+}
+
+// openOutputs opens every destination in specs. An empty path or "-" means
+// standard output; relative paths are resolved against --output-dir. In a
+// dry run only the destinations on standard output are opened; the others
+// are described instead.
+func openOutputs(specs []outputSpec) (*outputSinks, error) {
+	sinks := &outputSinks{}
+	var logs []io.Writer
+	for _, spec := range specs {
+		if !validOutputFormat(spec.Format) {
+			sinks.Close()
+			return nil, fmt.Errorf("unknown output format %q", spec.Format)
+		}
+		path, err := outputPath(spec.Path)
+		if err != nil {
+			sinks.Close()
+			return nil, err
+		}
+		spec.Path = path
+		if dryRun != nil && spec.Path != "" && spec.Path != "-" {
+			fmt.Fprintf(dryRun, "[dry-run] write %s output to %s\n", outputFormatName(spec.Format), spec.Path)
+			continue
+		}
+		if !streamOutputFormat(spec.Format) {
+			if spec.Path == "" || spec.Path == "-" {
+				sinks.Close()
+				return nil, fmt.Errorf("%s output needs a path", spec.Format)
+			}
+			sinks.paths = append(sinks.paths, spec)
+			continue
+		}
+
+		w := stdout
+		if spec.Path != "" && spec.Path != "-" {
+			f, err := createOutputFile(spec.Path)
+			if err != nil {
+				sinks.Close()
+				return nil, err
+			}
+			sinks.files = append(sinks.files, f)
+			w = f
+		}
+
+		if spec.Format == "" || spec.Format == "text" {
+			logs = append(logs, w)
+		} else {
+			sinks.structured = append(sinks.structured, structuredSink{format: spec.Format, w: w})
+		}
+	}
+
+	sinks.log = io.MultiWriter(logs...)
+	return sinks, nil
+}
+
+// createOutputFile creates the file at path, and the directories leading to
+// it if they are missing, as the directory outputs do.
+func createOutputFile(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// outputFormatName returns the display name of an output format.
+func outputFormatName(format string) string {
+	if format == "" {
+		return "text"
+	}
+	return format
+}
+
+// render stamps the results with the run metadata and writes them to every
+// structured destination.
+func (s *outputSinks) render(res *runResults) error {
+	res.Metadata = runInfo.finished()
+	for _, sink := range s.structured {
+		if err := resultWriters[sink.format](sink.w, res); err != nil {
+			return fmt.Errorf("writing %s output: %w", sink.format, err)
+		}
+	}
+	for _, spec := range s.paths {
+		write := resultPathWriters[spec.Format]
+		dir := filepath.Dir(spec.Path)
+		if dirWriter := resultDirWriters[spec.Format]; dirWriter != nil {
+			dir = spec.Path
+			write = dirWriter
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		if err := write(spec.Path, res); err != nil {
+			return fmt.Errorf("writing %s output: %w", spec.Format, err)
+		}
+	}
+	return nil
+}
+
+// Close closes all files opened for the outputs.
+func (s *outputSinks) Close() {
+	for _, f := range s.files {
+		f.Close()
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// pbkzImprovement is the factor by which the projection of a block's
+// shortest vector must be shorter than b*ₖ, in squared norm, for a tour to
+// insert it, as fplll's BKZ requires, so that rounding noise doesn't keep
+// the tours going: 100·‖πₖ(w)‖² < 99·‖b*ₖ‖².
+var pbkzImprovement = big.NewRat(99, 100)
+
+// parallelBKZ BKZ-reduces basis with block size beta in Go, using fplll
+// only as the LLL and SVP oracle, so that the SVP calls of one tour can run
+// concurrently: fplll's own tours go through the blocks one after another
+// in a single process. Every tour makes two passes over the basis, one
+// with the blocks [0, β), [β, 2β), … and one with the blocks shifted by
+// β/2, so that the block boundaries move. The blocks of a pass don't
+// overlap, and their projected bases πₖ(bₖ, …, b_end), taken with
+// ProjectOrthogonal from the same basis, are solved by fplll -a svp, up to
+// backend.Jobs at once. A block whose shortest vector is shorter than b*ₖ
+// (see pbkzImprovement) has it lifted to a lattice vector, which is
+// inserted before bₖ; after the pass fplll's LLL removes the linear
+// dependencies the insertions add as zero rows. The reduction stops after
+// a tour that inserts nothing, or at the tour and time limits of opts,
+// which like fplll's are checked after every tour; auto-abort and the GH
+// bound are fplll's and not supported. In a dry run the first LLL call and
+// one block's SVP call are planned.
+func parallelBKZ(ctx context.Context, basis Basis, beta int, opts bkzOptions) (Basis, error) {
+	if dryRun != nil {
+		planFplll(basis, backend.fplllArgs("lll")...)
+		planFplll(basis[:min(beta, len(basis))], svpArgs("")...)
+		return basis, nil
+	}
+	basis, err := lllReduce(ctx, basis)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	for tour := 1; ; tour++ {
+		inserted := 0
+		for _, offset := range []int{0, beta / 2} {
+			var n int
+			basis, n, err = pbkzPass(ctx, basis, pbkzBlocks(len(basis), beta, offset))
+			if err != nil {
+				return nil, err
+			}
+			inserted += n
+		}
+		slog.Debug("parallel BKZ tour", "beta", beta, "tour", tour, "insertions", inserted)
+		switch {
+		case inserted == 0:
+			return basis, nil
+		case opts.MaxLoops > 0 && tour >= opts.MaxLoops:
+			slog.Info("BKZ tour limit reached", "beta", beta, "tours", tour)
+			return basis, nil
+		case opts.MaxTime > 0 && time.Since(start).Seconds() >= opts.MaxTime:
+			slog.Info("BKZ time limit reached", "beta", beta, "tours", tour)
+			return basis, nil
+		}
+	}
+}
+
+// pbkzBlocks returns the blocks [start, end) of a pass over a basis of rank
+// n: [offset, offset+β), [offset+β, offset+2β), … cut off at n, and [0,
+// offset) before them. Blocks of fewer than 2 rows have nothing to reduce
+// and are left out.
+func pbkzBlocks(n, beta, offset int) [][2]int {
+	var blocks [][2]int
+	if offset >= 2 {
+		blocks = append(blocks, [2]int{0, min(offset, n)})
+	}
+	for start := offset; start < n; start += beta {
+		if end := min(start+beta, n); end-start >= 2 {
+			blocks = append(blocks, [2]int{start, end})
+		}
+	}
+	return blocks
+}
+
+// pbkzPass solves the SVP of every block of basis concurrently, inserts the
+// lifted vectors that improve their blocks and LLL-reduces the result back
+// to a basis. It returns the new basis and the number of insertions; if
+// there are none, basis is returned as it is.
+func pbkzPass(ctx context.Context, basis Basis, blocks [][2]int) (Basis, int, error) {
+	insert := make([][]*big.Int, len(blocks))
+	errs := make([]error, len(blocks))
+	slots := make(chan struct{}, max(backend.Jobs, 1))
+	var wg sync.WaitGroup
+	for i, block := range blocks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			insert[i], errs[i] = pbkzBlock(ctx, basis, block[0], block[1])
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, 0, fmt.Errorf("block [%d, %d): %w", blocks[i][0]+1, blocks[i][1], err)
+		}
+	}
+
+	rows := make(Basis, 0, len(basis)+len(blocks))
+	inserted := 0
+	next := 0
+	for i, block := range blocks {
+		if insert[i] == nil {
+			continue
+		}
+		rows = append(append(rows, basis[next:block[0]]...), insert[i])
+		next = block[0]
+		inserted++
+	}
+	if inserted == 0 {
+		return basis, 0, nil
+	}
+	rows = append(rows, basis[next:]...)
+	reduced, err := lllReduce(ctx, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make(Basis, 0, len(basis))
+	for _, row := range reduced {
+		if normSquared(row).Sign() != 0 {
+			result = append(result, row)
+		}
+	}
+	if len(result) != len(basis) {
+		return nil, 0, fmt.Errorf("LLL left %d non-zero rows after %d insertions into a basis of rank %d", len(result), inserted, len(basis))
+	}
+	return result, inserted, nil
+}
+
+// pbkzBlock finds a shortest vector of the projected block πₖ(bₖ, …,
+// b_end) of basis with fplll and returns its lift w = Σ xᵢbₖ₊ᵢ, the lattice
+// vector whose projection it is, if that improves on b*ₖ, or nil.
+func pbkzBlock(ctx context.Context, basis Basis, k, end int) ([]*big.Int, error) {
+	projected, _, err := basis[:end].ProjectOrthogonal(k)
+	if err != nil {
+		return nil, err
+	}
+	v, err := shortestVector(ctx, projected, "")
+	if err != nil {
+		return nil, err
+	}
+	// projected[0] is scale·b*ₖ and v is scale·πₖ(w), so the scale cancels.
+	norm := new(big.Rat).SetInt(normSquared(v))
+	bound := new(big.Rat).SetInt(normSquared(projected[0]))
+	if norm.Sign() == 0 || norm.Cmp(bound.Mul(bound, pbkzImprovement)) >= 0 {
+		return nil, nil
+	}
+	x, err := latticeCoordinates(projected, v)
+	if err != nil {
+		return nil, fmt.Errorf("fplll's shortest vector: %w", err)
+	}
+	w := make([]*big.Int, len(basis[k]))
+	for j := range w {
+		w[j] = new(big.Int)
+	}
+	var t big.Int
+	for i, c := range x {
+		if c.Sign() == 0 {
+			continue
+		}
+		for j, e := range basis[k+i] {
+			w[j].Add(w[j], t.Mul(c, e))
+		}
+	}
+	return w, nil
+}
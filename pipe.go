@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// pipeConfig holds the arguments of the reduce, svp, convert and profile
+// commands, which read a basis and write their result to standard output so
+// that they compose with shell pipelines and other lattice software.
+type pipeConfig struct {
+	Input     string // basis file, or "-" for standard input
+	From, To  string // matrix formats of the input and the output; see basisReaders
+	Reduction []reductionStep
+	// Stream is the profile command's auto, on or off: whether to stream
+	// the basis through streamGSO instead of loading it.
+	Stream string
+	// CSV makes the profile command write a CSV table with a header, see
+	// Profile.ExportCSV, instead of one number per line.
+	CSV bool
+	// SVPMethod is the svp command's fplll method; see svpMethods. A
+	// non-zero ApproxFactor makes it use approxSVP instead, a non-zero
+	// Radius boundedSVP.
+	SVPMethod    string
+	ApproxFactor float64
+	Radius       float64
+	// Gram says that the input of reduce and profile is a Gram matrix
+	// rather than a basis, and makes convert write the Gram matrix of its
+	// input basis.
+	Gram bool
+	// Outputs are the matrices the reduce command prints, in order: some
+	// of reductionOutputs.
+	Outputs string
+	// Solver is what the reduce and svp commands run: fplll, or sage to
+	// cross-validate fplll's results with SageMath (see sageReduce).
+	Solver string
+	// Project makes the convert command write the projection of its input
+	// basis orthogonally to the first Project rows; see
+	// Basis.ProjectOrthogonal.
+	Project int
+	// Verify makes the reduce command check that the basis it prints is
+	// reduced as its algorithm claims; see reductionStep.checkReduced.
+	Verify bool
+}
+
+// parsePipeFlags builds a pipeConfig from the command line of the reduce
+// (withReduction), svp, count, convert, profile or health command.
+func parsePipeFlags(name string, args []string, withReduction bool) (pipeConfig, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	from := fs.String("from", "auto", "format of the input basis: auto or one of "+basisFormatNames())
+	// The profile, count and health commands write numbers, not a matrix.
+	to, stream := "fplll", "off"
+	var csv bool
+	switch name {
+	case "profile":
+		fs.StringVar(&stream, "stream", "auto", fmt.Sprintf("stream the basis file row by row: auto (from rank %d), on or off", streamProfileRank))
+		fs.BoolVar(&csv, "csv", false, "write a CSV table (index,log2_norm) with a header instead of one number per line")
+	case "count", "health":
+	default:
+		fs.StringVar(&to, "to", "fplll", "format of the output: one of "+basisFormatNames())
+	}
+	var svpMethod string
+	solver := "fplll"
+	if name == "reduce" || name == "svp" {
+		fs.StringVar(&solver, "backend", "fplll", "solver to run: fplll or sage (SageMath, to cross-validate fplll)")
+	}
+	var approx, radius float64
+	var gram bool
+	switch name {
+	case "reduce", "profile":
+		fs.BoolVar(&gram, "gram", false, "the input is a Gram matrix instead of a basis (reduce: LLL only)")
+	case "convert":
+		fs.BoolVar(&gram, "gram", false, "write the Gram matrix of the basis")
+	}
+	var project int
+	if name == "convert" {
+		fs.IntVar(&project, "project", 0, "project the basis orthogonally to its first k rows, scaled to be integral (-v logs the scale)")
+	}
+	if name == "count" {
+		fs.Float64Var(&radius, "radius", 0, "count the non-zero vectors of norm at most this radius")
+	}
+	if name == "svp" {
+		fs.StringVar(&svpMethod, "m", "", "fplll SVP method: "+strings.Join(svpMethods, ", ")+" (empty lets fplll choose)")
+		fs.Float64Var(&approx, "approx", 0, "find a vector within this multiple of the Gaussian Heuristic by progressive BKZ instead of solving SVP (0 solves it exactly)")
+		fs.Float64Var(&radius, "radius", 0, fmt.Sprintf("find the shortest vector shorter than this radius by enumeration, or exit with status %d if there is none", exitNoVector))
+	}
+	var algo, outputs *string
+	var verify *bool
+	var beta, bestOf *int
+	var bkz *bkzOptions
+	var lll *lllOptions
+	if withReduction {
+		outputs = fs.String("of", "b", "matrices to print, like fplll's -of: b (reduced basis), u (transformation U with U·B = B'), v (U⁻¹), e.g. bu")
+		algo = fs.String("a", "bkz", "reduction algorithm: "+reductionAlgos)
+		beta = fs.Int("b", 20, "block size of BKZ and slide reduction")
+		bkz = bkzFlags(fs)
+		lll = lllFlags(fs)
+		bestOf = bestOfFlag(fs)
+		verify = fs.Bool("verify", false, fmt.Sprintf("check exactly that the printed basis is reduced as -a claims (lll, size, bkz or hkz), else exit with status %d", exitAssertion))
+	}
+	if err := fs.Parse(args); err != nil {
+		return pipeConfig{}, err
+	}
+	if fs.NArg() > 1 {
+		if withReduction {
+			return pipeConfig{}, fmt.Errorf("usage: lattice-labs reduce [-from fmt] [-to fmt] [-backend fplll|sage] [-a lll|flatter|size|bkz|hkz|sld] [-b beta] [-bkz... options] [-delta d] [-eta e] [-best-of k] [-verify] [-gram] [-of b|u|v...] [file|-]")
+		}
+		if name == "profile" {
+			return pipeConfig{}, fmt.Errorf("usage: lattice-labs profile [-from fmt] [-stream auto|on|off] [-gram] [-csv] [file|-]")
+		}
+		if name == "health" {
+			return pipeConfig{}, fmt.Errorf("usage: lattice-labs health [-from fmt] [file|-]")
+		}
+		if name == "count" {
+			return pipeConfig{}, fmt.Errorf("usage: lattice-labs count -radius r [-from fmt] [file|-]")
+		}
+		if name == "svp" {
+			return pipeConfig{}, fmt.Errorf("usage: lattice-labs svp [-from fmt] [-to fmt] [-backend fplll|sage] [-m proved|heuristic|fast | -approx factor | -radius r] [file|-]")
+		}
+		if name == "convert" {
+			return pipeConfig{}, fmt.Errorf("usage: lattice-labs convert [-from fmt] [-to fmt] [-project k] [-gram] [file|-]")
+		}
+		return pipeConfig{}, fmt.Errorf("usage: lattice-labs %s [-from fmt] [-to fmt] [file|-]", name)
+	}
+	if *from != "auto" && basisReaders[*from] == nil {
+		return pipeConfig{}, fmt.Errorf("unknown basis format %q (want auto, %s)", *from, basisFormatNames())
+	}
+	if basisWriters[to] == nil {
+		return pipeConfig{}, fmt.Errorf("unknown basis format %q (want %s)", to, basisFormatNames())
+	}
+	if stream != "auto" && stream != "on" && stream != "off" {
+		return pipeConfig{}, fmt.Errorf("-stream must be auto, on or off, got %q", stream)
+	}
+	if err := validateSVPMethod(svpMethod); err != nil {
+		return pipeConfig{}, err
+	}
+	if err := validateApproxFactor(approx); err != nil {
+		return pipeConfig{}, fmt.Errorf("-approx: %w", err)
+	}
+	if radius < 0 || math.IsNaN(radius) || math.IsInf(radius, 0) {
+		return pipeConfig{}, fmt.Errorf("-radius must be positive, got %g", radius)
+	}
+	if approx != 0 && svpMethod != "" || radius != 0 && svpMethod != "" {
+		return pipeConfig{}, fmt.Errorf("-m applies to unbounded exact SVP only, not with -approx or -radius")
+	}
+	if approx != 0 && radius != 0 {
+		return pipeConfig{}, fmt.Errorf("-approx and -radius exclude each other")
+	}
+	if project < 0 {
+		return pipeConfig{}, fmt.Errorf("-project must not be negative, got %d", project)
+	}
+	if name == "count" && radius == 0 {
+		return pipeConfig{}, fmt.Errorf("count needs a -radius")
+	}
+	if solver != "fplll" && solver != "sage" {
+		return pipeConfig{}, fmt.Errorf("-backend must be fplll or sage, got %q", solver)
+	}
+	if solver == "sage" && (svpMethod != "" || approx != 0 || radius != 0) {
+		return pipeConfig{}, fmt.Errorf("-backend sage solves exact SVP only, not with -m, -approx or -radius")
+	}
+
+	cfg := pipeConfig{Input: "-", From: *from, To: to, Stream: stream, CSV: csv, SVPMethod: svpMethod, ApproxFactor: approx, Radius: radius, Gram: gram, Solver: solver, Project: project}
+	if fs.NArg() == 1 {
+		cfg.Input = fs.Arg(0)
+	}
+	if withReduction {
+		step := reductionStep{Algo: strings.ToLower(*algo), BestOf: *bestOf, bkzOptions: *bkz, lllOptions: *lll}
+		if step.blockwise() {
+			step.Beta = *beta
+		}
+		if step.algo() == "enum" {
+			return pipeConfig{}, fmt.Errorf("an enum step leaves the basis as it is; use the svp command")
+		}
+		if err := step.validate(); err != nil {
+			return pipeConfig{}, err
+		}
+		if gram && step.algo() != "lll" {
+			return pipeConfig{}, fmt.Errorf("-gram needs -a lll, the only reduction of fplll on Gram matrices")
+		}
+		if err := checkReductionOutputs(*outputs); err != nil {
+			return pipeConfig{}, err
+		}
+		if gram && *outputs != "b" {
+			return pipeConfig{}, fmt.Errorf("-of applies to bases, not with -gram")
+		}
+		if step.BestOf > 1 && (gram || *outputs != "b") {
+			return pipeConfig{}, fmt.Errorf("-best-of rerandomizes bases, not with -gram or -of")
+		}
+		if (step.algo() == "flatter" || step.algo() == "size" || step.algo() == "pbkz") && (gram || *outputs != "b") {
+			return pipeConfig{}, fmt.Errorf("-a %s reduces bases and prints no transformation, not with -gram or -of", step.algo())
+		}
+		if solver == "sage" {
+			if gram || *outputs != "b" {
+				return pipeConfig{}, fmt.Errorf("-backend sage reduces bases, not with -gram or -of")
+			}
+			if _, err := sageReduction(step); err != nil {
+				return pipeConfig{}, err
+			}
+		}
+		if *verify && !step.checkableReduction() {
+			return pipeConfig{}, fmt.Errorf("-verify checks lll, size, bkz and hkz reductions, not %s", step.algo())
+		}
+		if *verify && (gram || *outputs != "b") {
+			return pipeConfig{}, fmt.Errorf("-verify checks the reduced basis, not with -gram or -of")
+		}
+		if step.SlopeTolerance > 0 && *outputs != "b" {
+			return pipeConfig{}, fmt.Errorf("-slope-tolerance splits the reduction into tours, not with -of")
+		}
+		cfg.Reduction, cfg.Outputs, cfg.Verify = []reductionStep{step}, *outputs, *verify
+	}
+	return cfg, nil
+}
+
+// loadPipeBasis reads the basis named by input, "-" being standard input,
+// in the given format ("auto" to detect it).
+func loadPipeBasis(input, format string) (Basis, error) {
+	if input == "-" {
+		basis, err := readBasis(os.Stdin, format)
+		if err != nil {
+			return nil, fmt.Errorf("reading basis from standard input: %w", err)
+		}
+		return basis, nil
+	}
+	f, err := os.Open(input)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	basis, err := readBasis(f, format)
+	if err != nil {
+		return nil, fmt.Errorf("reading basis from %s: %w", input, err)
+	}
+	return basis, nil
+}
+
+// runReducePipe reduces the input basis and writes the reduced basis to w,
+// or with cfg.Gram the input Gram matrix and the reduced Gram matrix. Other
+// cfg.Outputs go to runTransformPipe.
+func runReducePipe(ctx context.Context, w io.Writer, cfg pipeConfig) error {
+	basis, err := loadPipeBasis(cfg.Input, cfg.From)
+	if err != nil {
+		return err
+	}
+	if cfg.Outputs != "b" {
+		return runTransformPipe(ctx, w, cfg, basis)
+	}
+	var reduced Basis
+	command := reductionCommands(cfg.Reduction)
+	if cfg.Gram {
+		if err := checkGram(basis); err != nil {
+			return err
+		}
+		reduced, err = gramLLL(ctx, basis, cfg.Reduction[0].lllOptions)
+		command += " " + strings.Join(gramInputArgs, " ")
+	} else if cfg.Solver == "sage" {
+		reduced, err = sageReduce(ctx, basis, cfg.Reduction[0])
+		command = "sage"
+	} else {
+		reduced, err = applyReduction(ctx, basis, cfg.Reduction)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	if dryRun != nil {
+		return nil
+	}
+	if err := reduced.WriteFormat(w, cfg.To); err != nil || !cfg.Verify {
+		return err
+	}
+	// The basis is written first so that a failed check can be examined.
+	if err := cfg.Reduction[0].checkReduced(ctx, reduced); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !errors.Is(err, errNotReduced) {
+			return err
+		}
+		return &assertionError{Kind: "reduction check failed", Violations: []string{fmt.Sprintf("%s: %v", command, err)}}
+	}
+	return nil
+}
+
+// runTransformPipe reduces basis keeping track of the transformation and
+// writes the matrices of cfg.Outputs to w in that order, separated by blank
+// lines.
+func runTransformPipe(ctx context.Context, w io.Writer, cfg pipeConfig, basis Basis) error {
+	t, err := applyReductionTransform(ctx, basis, cfg.Reduction, strings.Contains(cfg.Outputs, "v"))
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", reductionCommands(cfg.Reduction), err)
+	}
+	if dryRun != nil {
+		return nil
+	}
+	matrices := map[rune]Basis{'b': t.Reduced, 'u': t.U, 'v': t.Inverse}
+	for i, c := range cfg.Outputs {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if err := basisWriters[cfg.To](w, matrices[c]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runSVPPipe finds a shortest non-zero vector of the input lattice, with an
+// approximation factor one within that multiple of the Gaussian Heuristic,
+// or with a radius the shortest one below it, and writes it to w: as fplll
+// prints it in fplll format, as a one-row matrix otherwise. A radius query
+// without a vector returns errNoVectorInRadius.
+func runSVPPipe(ctx context.Context, w io.Writer, cfg pipeConfig) error {
+	basis, err := loadPipeBasis(cfg.Input, cfg.From)
+	if err != nil {
+		return err
+	}
+	var vector []*big.Int
+	switch {
+	case cfg.Radius > 0:
+		_, vector, err = svpOracle(ctx, basis, cfg.Radius, "")
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("bounded SVP: %w", err)
+		}
+	case cfg.ApproxFactor > 0:
+		gh := gaussianHeuristic(basis.LogVolume(), len(basis))
+		_, vector, _, err = approxSVP(ctx, basis, gh, cfg.ApproxFactor)
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("approximate SVP: %w", err)
+		}
+	case cfg.Solver == "sage":
+		vector, err = sageShortestVector(ctx, basis)
+		if err == nil && dryRun == nil {
+			err = verifyLatticeVector(basis, vector)
+		}
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("sage: %w", err)
+		}
+	default:
+		if dryRun != nil {
+			planFplll(basis, svpArgs(cfg.SVPMethod)...)
+			return nil
+		}
+		vector, err = shortestVector(ctx, basis, cfg.SVPMethod)
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("%s: %w", svpCommand(cfg.SVPMethod), err)
+		}
+	}
+	if ctx.Err() != nil || dryRun != nil {
+		return nil
+	}
+	if cfg.To != "fplll" {
+		return basisWriters[cfg.To](w, Basis{vector})
+	}
+	return writeVector(w, vector)
+}
+
+// runCountPipe writes the number of non-zero vectors of the input lattice
+// of norm at most cfg.Radius to w.
+func runCountPipe(ctx context.Context, w io.Writer, cfg pipeConfig) error {
+	basis, err := loadPipeBasis(cfg.Input, cfg.From)
+	if err != nil {
+		return err
+	}
+	count, err := countVectors(ctx, basis, cfg.Radius)
+	if ctx.Err() != nil || dryRun != nil {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("counting vectors: %w", err)
+	}
+	_, err = fmt.Fprintln(w, count)
+	return err
+}
+
+// runConvertPipe rewrites the input basis, or with cfg.Gram its Gram
+// matrix, in another matrix format, projected first with cfg.Project.
+func runConvertPipe(w io.Writer, cfg pipeConfig) error {
+	basis, err := loadPipeBasis(cfg.Input, cfg.From)
+	if err != nil {
+		return err
+	}
+	if cfg.Project > 0 {
+		projected, scale, err := basis.ProjectOrthogonal(cfg.Project)
+		if err != nil {
+			return err
+		}
+		slog.Info("projected the basis", "k", cfg.Project, "rank", len(projected), "scale", scale, "log2_scale", log2Int(scale))
+		basis = projected
+	}
+	if cfg.Gram {
+		basis = basis.Gram()
+	}
+	return basis.WriteFormat(w, cfg.To)
+}
+
+// runProfilePipe writes the Gram-Schmidt profile of the input basis to w,
+// one log2 ‖b*ᵢ‖ per line, or as a CSV table with cfg.CSV. Bases of
+// streamProfileRank rows or more, or any with -stream on, are streamed
+// through streamGSO from the memory-mapped file instead of being loaded;
+// the others get computeGramSchmidtProfile.
+func runProfilePipe(w io.Writer, cfg pipeConfig) error {
+	profile, err := pipeProfile(cfg)
+	if err != nil {
+		return err
+	}
+	if cfg.CSV {
+		return profile.ExportCSV(w)
+	}
+	bw := bufio.NewWriter(w)
+	for _, v := range profile {
+		fmt.Fprintln(bw, formatCSVFloat(v))
+	}
+	return bw.Flush()
+}
+
+// pipeProfile computes the profile for runProfilePipe.
+func pipeProfile(cfg pipeConfig) (Profile, error) {
+	source := "standard input"
+	var data []byte
+	if cfg.Input == "-" {
+		var err error
+		if data, err = io.ReadAll(os.Stdin); err != nil {
+			return nil, fmt.Errorf("reading basis from %s: %w", source, err)
+		}
+	} else {
+		source = cfg.Input
+		mapped, unmap, err := mapFile(cfg.Input)
+		if err != nil {
+			return nil, err
+		}
+		defer unmap()
+		data = mapped
+	}
+
+	format := cfg.From
+	if format == "auto" {
+		// Only the start of the file tells the format; detectBasisFormat
+		// would copy all of it.
+		format = detectBasisFormat(data[:min(len(data), 4096)])
+	}
+	if cfg.Gram {
+		g, err := readBasis(bytes.NewReader(data), format)
+		if err != nil {
+			return nil, fmt.Errorf("reading Gram matrix from %s: %w", source, err)
+		}
+		if err := checkGram(g); err != nil {
+			return nil, err
+		}
+		return gramProfile(g), nil
+	}
+	rows := countBasisRows(data, format)
+	if cfg.Stream == "off" || cfg.Stream == "auto" && rows < streamProfileRank {
+		basis, err := readBasis(bytes.NewReader(data), format)
+		if err != nil {
+			return nil, fmt.Errorf("reading basis from %s: %w", source, err)
+		}
+		return basis.Profile(), nil
+	}
+
+	slog.Debug("streaming the Gram-Schmidt profile", "input", source, "format", format, "rank", rows)
+	profile, unstable, err := streamBasisProfile(data, format, rows)
+	if err != nil {
+		return nil, fmt.Errorf("reading basis from %s: %w", source, err)
+	}
+	if unstable != "" {
+		slog.Warn("streamed Gram-Schmidt profile is numerically unstable; -stream off recomputes it with big.Float",
+			"rank", len(profile), "reason", unstable)
+	}
+	if dependent := countVanishing(profile); dependent > 0 {
+		slog.Warn("rows of the basis depend linearly on earlier ones; their profile entries are -50",
+			"rank", len(profile), "dependent", dependent)
+	}
+	return profile, nil
+}
+
+// writeVector writes a vector to w in fplll format, as printed by fplll -a svp.
+func writeVector(w io.Writer, vector []*big.Int) error {
+	_, err := io.WriteString(w, formatVector(vector))
+	return err
+}
+
+// formatVector formats a vector as a line in fplll format.
+func formatVector(vector []*big.Int) string {
+	return "[" + joinRow(vector, " ") + "]\n"
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// fplllNames returns the names the fplll executable has on this platform,
+// preferred first. exec.LookPath adds the extensions of %PATHEXT% on
+// Windows, but MSYS2 and Cygwin builds may also be invoked by full name.
+func fplllNames() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"fplll.exe", "fplll"}
+	}
+	return []string{"fplll"}
+}
+
+// fplllInstallDirs returns the directories package managers install fplll
+// into on this platform that are often missing from PATH, e.g. when the
+// program is started from an IDE rather than a login shell.
+func fplllInstallDirs() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"/opt/homebrew/bin", "/usr/local/bin", "/opt/local/bin"}
+	case "windows":
+		return []string{`C:\msys64\ucrt64\bin`, `C:\msys64\mingw64\bin`, `C:\cygwin64\bin`}
+	}
+	return []string{"/usr/local/bin"}
+}
+
+// sharedLibraryPatterns returns glob patterns of the shared library lib<name>
+// on this platform and the directories it is searched in: those of the
+// loader's search path variable followed by the usual install locations.
+func sharedLibraryPatterns(name string) (names, dirs []string) {
+	switch runtime.GOOS {
+	case "darwin":
+		names = []string{"lib" + name + ".dylib", "lib" + name + ".*.dylib"}
+		dirs = append(filepath.SplitList(os.Getenv("DYLD_LIBRARY_PATH")), "/opt/homebrew/lib", "/usr/local/lib", "/opt/local/lib")
+	case "windows":
+		names = []string{"lib" + name + ".dll", "lib" + name + "-*.dll"}
+		dirs = append(filepath.SplitList(os.Getenv("PATH")), fplllInstallDirs()...)
+	default:
+		names = []string{"lib" + name + ".so", "lib" + name + ".so.*"}
+		dirs = append(filepath.SplitList(os.Getenv("LD_LIBRARY_PATH")), "/usr/local/lib", "/usr/local/lib64", "/usr/lib", "/usr/lib64", "/usr/lib/*-linux-gnu")
+	}
+	return names, dirs
+}
+
+// findSharedLibrary returns the path of the first shared library lib<name>
+// found as described for sharedLibraryPatterns, or "".
+func findSharedLibrary(name string) string {
+	names, dirs := sharedLibraryPatterns(name)
+	for _, dir := range dirs {
+		for _, name := range names {
+			if matches, _ := filepath.Glob(filepath.Join(dir, name)); len(matches) > 0 {
+				return matches[0]
+			}
+		}
+	}
+	return ""
+}
+
+// expandHome replaces a leading ~ in path with the home directory, since
+// paths from the config file and the environment don't pass through a
+// shell.
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// resolveFplll returns the absolute path of the fplll executable named by
+// binary. A name containing a path separator (/ on every platform, also \
+// on Windows) is taken as a path, relative to the working directory unless
+// it is absolute; on Windows ".exe" is added if the path without it doesn't
+// exist. Any other name is looked up in PATH, and the default name "fplll"
+// also under its other platform names and in fplllInstallDirs.
+func resolveFplll(binary string) (string, error) {
+	binary = expandHome(binary)
+	if strings.ContainsRune(binary, '/') || strings.ContainsRune(binary, filepath.Separator) {
+		path, err := filepath.Abs(filepath.FromSlash(binary))
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) && runtime.GOOS == "windows" && filepath.Ext(path) == "" {
+			path += ".exe"
+		}
+		return exec.LookPath(path)
+	}
+	names := []string{binary}
+	if binary == defaultBackend().Binary {
+		names = fplllNames()
+	}
+	for _, name := range names {
+		if path, err := exec.LookPath(name); err == nil {
+			return filepath.Abs(path)
+		}
+	}
+	if binary == defaultBackend().Binary {
+		for _, dir := range fplllInstallDirs() {
+			for _, name := range names {
+				if path, err := exec.LookPath(filepath.Join(dir, name)); err == nil {
+					return path, nil
+				}
+			}
+		}
+	}
+	return "", &exec.Error{Name: binary, Err: exec.ErrNotFound}
+}
+
+// BackendAvailable reports whether the configured backend can be used: the
+// fplll executable is found as described for resolveFplll and answers
+// "fplll --version". It starts fplll once and doesn't change the
+// configuration, so it can be called before a run to decide whether labs
+// needing fplll can be scheduled at all.
+func BackendAvailable() bool {
+	path, err := resolveFplll(backend.Binary)
+	if err != nil {
+		return false
+	}
+	_, err = detectFplllVersion(path)
+	return err == nil
+}
+
+// useResolvedFplll replaces backend.Binary by the absolute path of the
+// executable it names, so that every call of the run uses the same one and
+// the path is logged. If the executable can't be found, the name is kept
+// and the first fplll call reports the failure.
+func useResolvedFplll() {
+	path, err := resolveFplll(backend.Binary)
+	if err != nil {
+		slog.Debug("fplll not found", "fplll", backend.Binary, "err", err)
+		return
+	}
+	slog.Debug("using fplll", "path", path)
+	backend.Binary = path
+}
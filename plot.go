@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"path/filepath"
+	"sort"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// plotExtensions are the image formats every plot is saved in.
+var plotExtensions = []string{"png", "svg"}
+
+// writePlotFiles renders the results into dir with gonum/plot: one profile
+// plot with its fitted GSA line per Lab 2 run, one λ1-versus-GH scatter plot
+// per Lab 1 run and, for sweeps, the profile slope against β for every
+// (n, q) pair next to the slope the GSA predicts.
+func writePlotFiles(dir string, res *runResults) error {
+	for run, lab := range res.Lab2 {
+		if len(lab.Profile) < 2 {
+			// Interrupted, failed or timed out: there is nothing to plot.
+			continue
+		}
+		p, err := profilePlot(lab)
+		if err != nil {
+			return err
+		}
+		if err := savePlot(p, dir, fmt.Sprintf("profile_%d", run)); err != nil {
+			return err
+		}
+	}
+
+	for run, lab := range res.Lab1 {
+		p, err := lambda1Plot(lab)
+		if err != nil {
+			return err
+		}
+		if err := savePlot(p, dir, fmt.Sprintf("lambda1_vs_gh_%d", run)); err != nil {
+			return err
+		}
+	}
+
+	if len(res.Sweep) > 0 {
+		p, err := slopeVsBetaPlot(res.Sweep)
+		if err != nil {
+			return err
+		}
+		if err := savePlot(p, dir, "slope_vs_beta"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// savePlot saves p as dir/name.<ext> for every plot extension.
+func savePlot(p *plot.Plot, dir, name string) error {
+	for _, ext := range plotExtensions {
+		if err := p.Save(8*vg.Inch, 5*vg.Inch, filepath.Join(dir, name+"."+ext)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// profilePlot draws a Lab 2 profile with its least-squares GSA line.
+func profilePlot(lab lab2Result) (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = fmt.Sprintf("Basis profile (rank %d, %s)", lab.Rank, latexReduction(lab.Reduction))
+	p.X.Label.Text = "index i"
+	p.Y.Label.Text = "log2 ||b_i*||"
+	p.Add(plotter.NewGrid())
+
+	pts := make(plotter.XYs, len(lab.Profile))
+	for i, v := range lab.Profile {
+		pts[i].X = float64(i)
+		pts[i].Y = v
+	}
+	line, points, err := plotter.NewLinePoints(pts)
+	if err != nil {
+		return nil, err
+	}
+	line.Color = color.RGBA{R: 31, G: 119, B: 180, A: 255}
+	points.Color = line.Color
+	p.Add(line, points)
+	p.Legend.Add("profile", line, points)
+
+	slope, intercept, r2 := lab.Profile.Fit()
+	fit := plotter.NewFunction(func(x float64) float64 { return slope*x + intercept })
+	fit.Color = color.RGBA{R: 214, G: 39, B: 40, A: 255}
+	fit.Dashes = []vg.Length{vg.Points(6), vg.Points(4)}
+	fit.XMin, fit.XMax = 0, float64(len(lab.Profile)-1)
+	p.Add(fit)
+	p.Legend.Add(fmt.Sprintf("fitted GSA line (slope %.4f, R^2 %.3f)", slope, r2), fit)
+	p.Legend.Top = true
+	return p, nil
+}
+
+// lambda1Plot draws the measured λ1 of every Lab 1 instance against its GH
+// prediction; points on the diagonal match the heuristic exactly.
+func lambda1Plot(lab lab1Result) (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = fmt.Sprintf("SVP norm vs. Gaussian Heuristic (q = %s)", lab.Q)
+	p.X.Label.Text = "GH prediction"
+	p.Y.Label.Text = "lambda_1"
+	p.Add(plotter.NewGrid())
+
+	var pts plotter.XYs
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, row := range lab.Rows {
+		gh, l1 := float64(row.GH), float64(row.Lambda1)
+		if math.IsNaN(gh) || math.IsInf(gh, 0) || math.IsNaN(l1) || math.IsInf(l1, 0) || l1 == 0 {
+			continue
+		}
+		pts = append(pts, plotter.XY{X: gh, Y: l1})
+		lo = math.Min(lo, math.Min(gh, l1))
+		hi = math.Max(hi, math.Max(gh, l1))
+	}
+	if len(pts) == 0 {
+		return p, nil
+	}
+
+	scatter, err := plotter.NewScatter(pts)
+	if err != nil {
+		return nil, err
+	}
+	scatter.GlyphStyle.Shape = draw.CircleGlyph{}
+	scatter.GlyphStyle.Color = color.RGBA{R: 31, G: 119, B: 180, A: 255}
+	p.Add(scatter)
+	p.Legend.Add("instances", scatter)
+
+	diagonal := plotter.NewFunction(func(x float64) float64 { return x })
+	diagonal.XMin, diagonal.XMax = lo, hi
+	diagonal.Dashes = []vg.Length{vg.Points(6), vg.Points(4)}
+	p.Add(diagonal)
+	p.Legend.Add("lambda_1 = GH", diagonal)
+	p.Legend.Top = true
+	p.Legend.Left = true
+	return p, nil
+}
+
+// slopeVsBetaPlot draws, for every (n, q) pair of a sweep, the fitted
+// profile slope as a function of β, plus the slope predicted by the GSA.
+func slopeVsBetaPlot(rows []sweepRow) (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = "Profile slope vs. block size"
+	p.X.Label.Text = "beta"
+	p.Y.Label.Text = "slope of log2 profile"
+	p.Add(plotter.NewGrid())
+
+	type seriesKey struct {
+		n int
+		q int64
+	}
+	series := make(map[seriesKey]plotter.XYs)
+	var keys []seriesKey
+	minBeta, maxBeta := math.Inf(1), math.Inf(-1)
+	for _, row := range rows {
+		key := seriesKey{row.N, row.Q}
+		if _, ok := series[key]; !ok {
+			keys = append(keys, key)
+		}
+		series[key] = append(series[key], plotter.XY{X: float64(row.Beta), Y: row.Slope})
+		minBeta = math.Min(minBeta, float64(row.Beta))
+		maxBeta = math.Max(maxBeta, float64(row.Beta))
+	}
+
+	for i, key := range keys {
+		pts := series[key]
+		sort.Slice(pts, func(a, b int) bool { return pts[a].X < pts[b].X })
+		line, points, err := plotter.NewLinePoints(pts)
+		if err != nil {
+			return nil, err
+		}
+		c := plotColor(i)
+		line.Color, points.Color = c, c
+		p.Add(line, points)
+		p.Legend.Add(fmt.Sprintf("n=%d, q=%d", key.n, key.q), line, points)
+	}
+
+	if minBeta >= 2 && maxBeta > minBeta {
+		expected := plotter.NewFunction(func(beta float64) float64 { return expectedGSASlope(int(math.Round(beta))) })
+		expected.XMin, expected.XMax = minBeta, maxBeta
+		expected.Dashes = []vg.Length{vg.Points(6), vg.Points(4)}
+		p.Add(expected)
+		p.Legend.Add("GSA prediction", expected)
+	}
+	p.Legend.Top = true
+	return p, nil
+}
+
+// plotColor returns the i-th color of a small qualitative palette.
+func plotColor(i int) color.Color {
+	palette := []color.RGBA{
+		{R: 31, G: 119, B: 180, A: 255},
+		{R: 255, G: 127, B: 14, A: 255},
+		{R: 44, G: 160, B: 44, A: 255},
+		{R: 214, G: 39, B: 40, A: 255},
+		{R: 148, G: 103, B: 189, A: 255},
+		{R: 140, G: 86, B: 75, A: 255},
+	}
+	return palette[i%len(palette)]
+}
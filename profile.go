@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Profile is the profile of a basis, log2 ‖b*ᵢ‖ for every Gram-Schmidt
+// vector in order. Lab 2, the sweep, the pipeline reports and the backend
+// comparisons all read the quality of a reduction off it with these
+// methods, so a simulated profile can be compared the same way.
+type Profile []float64
+
+// LogVolume returns log2 of the volume of the lattice, the sum of the
+// profile, which stays finite where the float64 determinant would overflow.
+func (p Profile) LogVolume() float64 {
+	sum := 0.0
+	for _, v := range p {
+		sum += v
+	}
+	return sum
+}
+
+// Fit fits a least-squares line through the profile, i.e. the points
+// (i, p[i]). Under the Geometric Series Assumption the profile of a reduced
+// basis is close to such a line, so the slope summarises the quality of the
+// reduction and r2 (the coefficient of determination) its linearity.
+func (p Profile) Fit() (slope, intercept, r2 float64) {
+	n := float64(len(p))
+	if len(p) < 2 {
+		return 0, 0, 0
+	}
+
+	var sumX, sumY, sumXX, sumXY float64
+	for i, y := range p {
+		x := float64(i)
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+	}
+	slope = (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	intercept = (sumY - slope*sumX) / n
+
+	// Coefficient of determination of the fit
+	meanY := sumY / n
+	var ssRes, ssTot float64
+	for i, y := range p {
+		fit := slope*float64(i) + intercept
+		ssRes += (y - fit) * (y - fit)
+		ssTot += (y - meanY) * (y - meanY)
+	}
+	if ssTot > 0 {
+		r2 = 1 - ssRes/ssTot
+	}
+	return slope, intercept, r2
+}
+
+// Slope returns the slope of the line Fit fits through the profile.
+func (p Profile) Slope() float64 {
+	slope, _, _ := p.Fit()
+	return slope
+}
+
+// RHF returns the root Hermite factor δ0 = (‖b1‖ / vol(L)^(1/n))^(1/n) the
+// basis reaches, or 0 for an empty profile.
+func (p Profile) RHF() float64 {
+	if len(p) == 0 {
+		return 0
+	}
+	n := float64(len(p))
+	return math.Exp2((p[0] - p.LogVolume()/n) / n)
+}
+
+// OrthogonalityDefect returns log2 of the orthogonality defect
+// ∏‖bᵢ‖ / vol(L) of basis, whose profile p is: 0 for an orthogonal basis
+// and growing as its vectors lean onto each other. The profile fixes only
+// the volume, so the norms of the basis vectors are taken from basis.
+func (p Profile) OrthogonalityDefect(basis Basis) float64 {
+	logNorms := 0.0
+	for _, b := range basis {
+		logNorms += math.Log2(vectorNormSquared(b)) / 2
+	}
+	return logNorms - p.LogVolume()
+}
+
+// Dual returns the profile of the dual basis in reverse order, the basis
+// (B·Bᵀ)⁻¹·B with its rows reversed: its Gram-Schmidt vectors are those of
+// B inverted and in reverse order, so the profile is p reversed and
+// negated. The dual lattice has the inverse volume, and a GSA line keeps
+// its slope.
+func (p Profile) Dual() Profile {
+	n := len(p)
+	dual := make(Profile, n)
+	for i, v := range p {
+		dual[n-1-i] = -v
+	}
+	return dual
+}
+
+// GSALine returns the profile the Geometric Series Assumption predicts
+// after BKZ with block size beta for a basis of the same lattice:
+// SimulateGSAProfile with its rank and volume.
+func (p Profile) GSALine(beta int) Profile {
+	return SimulateGSAProfile(len(p), p.LogVolume(), beta)
+}
+
+// SimulateGSAProfile returns the idealized profile the Geometric Series
+// Assumption predicts after BKZ with block size beta for a lattice of rank
+// n and log2 volume logVol, without reducing anything: a line of slope
+// expectedGSASlope(beta) summing to logVol. Plots draw it next to measured
+// profiles, and estimates read the norms they need off it. beta must be at
+// least 2.
+func SimulateGSAProfile(n int, logVol float64, beta int) Profile {
+	return gsaProfile(n, logVol, expectedGSASlope(beta))
+}
+
+// gsaProfile returns the line of rank n, log2 volume logVol and the given
+// slope, which is centred on logVol/n at the middle index. The SIS lab
+// predicts with it from the slope it fits instead of one from β.
+func gsaProfile(n int, logVol, slope float64) Profile {
+	if n <= 0 {
+		return Profile{}
+	}
+	centre := float64(n-1) / 2
+	mean := logVol / float64(n)
+	line := make(Profile, n)
+	for i := range line {
+		line[i] = mean + slope*(float64(i)-centre)
+	}
+	return line
+}
+
+// ExportCSV writes the profile to w as CSV with a header row, one row per
+// index: index,log2_norm.
+func (p Profile) ExportCSV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "index,log2_norm")
+	for i, v := range p {
+		fmt.Fprintf(bw, "%d,%s\n", i, formatCSVFloat(v))
+	}
+	return bw.Flush()
+}
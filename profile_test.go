@@ -0,0 +1,169 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// approxEqual reports whether got is within tolerance of want.
+func approxEqual(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestProfileFit(t *testing.T) {
+	for _, tc := range []struct {
+		name                 string
+		profile              Profile
+		slope, intercept, r2 float64
+	}{
+		{"line", gsaProfile(8, 12, -0.25), -0.25, 1.5 + 0.25*3.5, 1},
+		{"flat", Profile{2, 2, 2, 2}, 0, 2, 0},
+		{"single", Profile{3}, 0, 0, 0},
+		{"empty", Profile{}, 0, 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			slope, intercept, r2 := tc.profile.Fit()
+			if !approxEqual(slope, tc.slope, 1e-12) || !approxEqual(intercept, tc.intercept, 1e-12) || !approxEqual(r2, tc.r2, 1e-12) {
+				t.Errorf("Fit() = %g, %g, %g, want %g, %g, %g", slope, intercept, r2, tc.slope, tc.intercept, tc.r2)
+			}
+			if got := tc.profile.Slope(); got != slope {
+				t.Errorf("Slope() = %g, want the slope of Fit, %g", got, slope)
+			}
+		})
+	}
+}
+
+// TestProfileRHF checks δ0 on exact GSA lines: a line of slope -2·log2(δ)
+// over n entries has ‖b1‖ = δ^(n-1) · vol^(1/n), so δ0 = δ^((n-1)/n).
+func TestProfileRHF(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		profile Profile
+		want    float64
+	}{
+		{"flat", Profile{3, 3, 3}, 1},
+		{"delta 1.01", gsaProfile(50, 100, -2*math.Log2(1.01)), math.Pow(1.01, 49.0/50)},
+		{"delta 1.02", gsaProfile(80, -40, -2*math.Log2(1.02)), math.Pow(1.02, 79.0/80)},
+		{"empty", Profile{}, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.profile.RHF(); !approxEqual(got, tc.want, 1e-12) {
+				t.Errorf("RHF() = %.15g, want %.15g", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProfileLogVolume(t *testing.T) {
+	if got := (Profile{1, -0.5, 2.25}).LogVolume(); got != 2.75 {
+		t.Errorf("LogVolume() = %g, want 2.75", got)
+	}
+	if got := gsaProfile(30, 45.5, -0.1).LogVolume(); !approxEqual(got, 45.5, 1e-12) {
+		t.Errorf("LogVolume() of a GSA line = %g, want 45.5", got)
+	}
+}
+
+// TestOrthogonalityDefect checks log2 of ∏‖bᵢ‖ / vol(L): 0 for orthogonal
+// bases, whatever their lengths, and log2 √2 for the sheared square.
+func TestOrthogonalityDefect(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		basis Basis
+		want  float64
+	}{
+		{"diagonal", basisOf([]int64{2, 0, 0}, []int64{0, 3, 0}, []int64{0, 0, 5}), 0},
+		{"orthogonal", basisOf([]int64{1, 1}, []int64{-2, 2}), 0},
+		{"sheared", basisOf([]int64{1, 0}, []int64{1, 1}), 0.5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := Profile(rationalProfile(intMatrixOf(tc.basis)))
+			if got := p.OrthogonalityDefect(tc.basis); !approxEqual(got, tc.want, 1e-12) {
+				t.Errorf("OrthogonalityDefect() = %g, want %g", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProfileDual(t *testing.T) {
+	p := gsaProfile(10, 20, -0.3)
+	dual := p.Dual()
+	for i := range p {
+		if dual[i] != -p[len(p)-1-i] {
+			t.Fatalf("Dual()[%d] = %g, want %g", i, dual[i], -p[len(p)-1-i])
+		}
+	}
+	if !approxEqual(dual.LogVolume(), -20, 1e-12) || !approxEqual(dual.Slope(), -0.3, 1e-12) {
+		t.Errorf("dual has log volume %g and slope %g, want -20 and -0.3", dual.LogVolume(), dual.Slope())
+	}
+}
+
+// TestGSALine checks that the predicted line keeps the rank and volume of
+// the profile and has the slope of the block size.
+func TestGSALine(t *testing.T) {
+	p := Profile{10, 9.5, 9, 7, 6.5, 6, 4, 3}
+	for _, beta := range []int{2, 20, 60} {
+		line := p.GSALine(beta)
+		switch {
+		case len(line) != len(p):
+			t.Errorf("beta %d: %d entries, want %d", beta, len(line), len(p))
+		case !approxEqual(line.LogVolume(), p.LogVolume(), 1e-12):
+			t.Errorf("beta %d: log volume %g, want %g", beta, line.LogVolume(), p.LogVolume())
+		case !approxEqual(line.Slope(), expectedGSASlope(beta), 1e-12):
+			t.Errorf("beta %d: slope %g, want %g", beta, line.Slope(), expectedGSASlope(beta))
+		}
+	}
+}
+
+func TestProfileExportCSV(t *testing.T) {
+	var b strings.Builder
+	if err := (Profile{1.5, -0.25, 3}).ExportCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := "index,log2_norm\n0,1.5\n1,-0.25\n2,3\n"
+	if b.String() != want {
+		t.Errorf("ExportCSV wrote %q, want %q", b.String(), want)
+	}
+}
+
+// TestSimulateGSAProfile checks that the simulated profile is a line of the
+// slope -2·log2 δ0(β) read off the δ0 table in use, also one loaded with
+// --delta-table, summing to logVol.
+func TestSimulateGSAProfile(t *testing.T) {
+	t.Cleanup(func() { deltas = defaultDeltaTable })
+	for _, table := range []struct {
+		name  string
+		table deltaTable
+	}{
+		{"default", defaultDeltaTable},
+		{"loaded", deltaTable{{2, 1.03}, {50, 1.015}, {100, 1.01}}},
+	} {
+		deltas = table.table
+		for _, tc := range []struct {
+			n      int
+			logVol float64
+			beta   int
+		}{
+			{2, 3, 2},
+			{40, 200, 20},
+			{100, -35.5, 50},
+			{180, 1234.5, 75},
+			{64, 0, 400},
+		} {
+			p := SimulateGSAProfile(tc.n, tc.logVol, tc.beta)
+			slope := -2 * math.Log2(deltas.At(tc.beta))
+			fit, _, r2 := p.Fit()
+			switch {
+			case len(p) != tc.n:
+				t.Errorf("%s table, n %d, β %d: %d entries", table.name, tc.n, tc.beta, len(p))
+			case !approxEqual(p.LogVolume(), tc.logVol, 1e-9):
+				t.Errorf("%s table, n %d, β %d: sums to %g, want %g", table.name, tc.n, tc.beta, p.LogVolume(), tc.logVol)
+			case !approxEqual(fit, slope, 1e-12) || !approxEqual(r2, 1, 1e-9):
+				t.Errorf("%s table, n %d, β %d: slope %g (r² %g), want %g = -2·log2 δ0(β)", table.name, tc.n, tc.beta, fit, r2, slope)
+			}
+		}
+	}
+	if p := SimulateGSAProfile(0, 10, 20); len(p) != 0 {
+		t.Errorf("rank 0 gives %d entries", len(p))
+	}
+}
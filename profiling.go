@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	_ "net/http/pprof" // registers the /debug/pprof/ handlers
+	"os"
+	"runtime/trace"
+)
+
+// startPprof serves the net/http/pprof handlers on addr (--pprof) for the
+// rest of the run, so that CPU and heap profiles of the Go side can be taken
+// while the labs run, e.g. with
+//
+//	go tool pprof http://localhost:6060/debug/pprof/profile?seconds=30
+func startPprof(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("--pprof: %w", err)
+	}
+	slog.Info("serving pprof", "url", "http://"+ln.Addr().String()+"/debug/pprof/")
+	go func() {
+		if err := http.Serve(ln, nil); err != nil {
+			slog.Warn("pprof server stopped", "err", err)
+		}
+	}()
+	return nil
+}
+
+// startTrace writes an execution trace of the run to path (--trace), for
+// go tool trace. fplll calls and Gram-Schmidt profiles show up as regions
+// within a task per lab or command, which separates the time spent waiting
+// for the solver from the time spent in Go-side linear algebra. The returned
+// function stops the trace and closes the file.
+func startTrace(path string) (stop func(), err error) {
+	path, err = outputPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("--trace: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("--trace: %w", err)
+	}
+	if err := trace.Start(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("--trace: %w", err)
+	}
+	return func() {
+		trace.Stop()
+		if err := f.Close(); err != nil {
+			slog.Error("writing execution trace", "path", path, "err", err)
+		}
+	}, nil
+}
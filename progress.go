@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// progressEvent is a snapshot of a running lab. The labs send one before and
+// after every expensive backend call so that long runs can show where they
+// are instead of waiting silently for fplll.
+type progressEvent struct {
+	Lab     string    // "Lab 1", "Lab 2" or "Sweep"
+	N       int       // dimension or rank of the current instance
+	Beta    int       // block size of the current instance, 0 if none
+	Q       string    // coefficient bound of the current instance
+	Trial   int       // trial of the current instance, from 1
+	Trials  int       // trials per instance
+	Done    int       // instances completed so far
+	Total   int       // instances planned for this lab
+	Backend string    // backend call in flight, e.g. "fplll -a svp"; empty when idle
+	Profile []float64 // latest profile, if the lab computes one
+	Seconds float64   // backend time of the instance just completed
+}
+
+// progressReporters receive every event of the run. They are registered by
+// main before any lab starts.
+var progressReporters []func(progressEvent)
+
+// reportProgress sends ev to every registered reporter.
+func reportProgress(ev progressEvent) {
+	for _, report := range progressReporters {
+		report(ev)
+	}
+}
+
+// sharedProgress is the progress of a lab whose instances run concurrently
+// (--jobs). It serializes their events, so that every reporter sees them one
+// at a time and each carries the lab-wide count of completed instances.
+type sharedProgress struct {
+	mu sync.Mutex
+	ev progressEvent
+}
+
+// set changes the event fields shared by the following events, e.g. the
+// parameters of the next combination of a sweep, without reporting.
+func (p *sharedProgress) set(update func(ev *progressEvent)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	update(&p.ev)
+}
+
+// running reports that a backend call starts on instance (n, trial).
+func (p *sharedProgress) running(n, trial int, backend string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ev.N, p.ev.Trial, p.ev.Backend = n, trial, backend
+	reportProgress(p.ev)
+}
+
+// completed reports that instance (n, trial) finished after seconds of
+// backend time, with its profile if the lab computes one.
+func (p *sharedProgress) completed(n, trial int, seconds float64, profile []float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ev.N, p.ev.Trial, p.ev.Backend, p.ev.Seconds = n, trial, "", seconds
+	if profile != nil {
+		p.ev.Profile = profile
+	}
+	p.ev.Done++
+	reportProgress(p.ev)
+}
+
+// progressPrinter writes one line per backend call and per completed
+// instance, with the estimated time left for the current lab.
+type progressPrinter struct {
+	w        io.Writer
+	start    time.Time
+	labStart time.Time
+	last     progressEvent
+}
+
+// newProgressPrinter returns a printer writing to w.
+func newProgressPrinter(w io.Writer) *progressPrinter {
+	now := time.Now()
+	return &progressPrinter{w: w, start: now, labStart: now}
+}
+
+// update prints ev if it starts a backend call or completes an instance.
+func (p *progressPrinter) update(ev progressEvent) {
+	now := time.Now()
+	if ev.Lab != p.last.Lab || ev.Done < p.last.Done {
+		p.labStart = now
+	}
+	defer func() { p.last = ev }()
+
+	instance := fmt.Sprintf("[%s] n=%d", ev.Lab, ev.N)
+	if ev.Beta > 0 {
+		instance += fmt.Sprintf(" beta=%d", ev.Beta)
+	}
+	instance += fmt.Sprintf(" q=%s trial %d/%d", ev.Q, ev.Trial, max(ev.Trials, 1))
+
+	switch {
+	case ev.Backend != "" && (ev.Backend != p.last.Backend || ev.N != p.last.N || ev.Trial != p.last.Trial):
+		fmt.Fprintf(p.w, "%s: running %s\n", instance, ev.Backend)
+	case ev.Done > p.last.Done || (ev.Lab != p.last.Lab && ev.Done > 0):
+		line := fmt.Sprintf("%s: done in %.2fs, %d/%d instances, elapsed %s", instance, ev.Seconds, ev.Done, ev.Total, formatDuration(now.Sub(p.start)))
+		if ev.Total > ev.Done {
+			perInstance := now.Sub(p.labStart) / time.Duration(ev.Done)
+			line += ", ETA " + formatDuration(perInstance*time.Duration(ev.Total-ev.Done))
+		}
+		fmt.Fprintln(p.w, line)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ProjectOrthogonal returns a basis of the projected lattice πₖ(L), the
+// rows bₖ₊₁, …, bₙ projected orthogonally to the first k rows: the
+// lattice BKZ works on in its blocks, whose Gram-Schmidt vectors are
+// b*ₖ₊₁, …, b*ₙ, and the one sieving in dimensions for free lifts from.
+// The projections are rational, so they are returned multiplied by scale,
+// the least positive integer making them integral; the profile of the
+// result is thus that of the last n-k Gram-Schmidt vectors shifted by
+// log2(scale). The projections are computed exactly; the rows of b must
+// be linearly independent.
+func (b Basis) ProjectOrthogonal(k int) (projected Basis, scale *big.Int, err error) {
+	n, cols := b.Dims()
+	if k < 0 || k >= n {
+		return nil, nil, fmt.Errorf("can't project a basis of rank %d orthogonally to its first %d rows (want 0 to %d)", n, k, n-1)
+	}
+	g := b.Gram()
+	head := make([][]*big.Int, k)
+	for i := range head {
+		head[i] = g[i][:k]
+	}
+	inv, err := invertRat(head)
+	if err != nil {
+		return nil, nil, fmt.Errorf("the first %d rows are linearly dependent: %w", k, err)
+	}
+	rows := make([][]*big.Rat, n-k)
+	var c, t big.Rat
+	for i := range rows {
+		row := b[k+i]
+		rows[i] = make([]*big.Rat, cols)
+		for j := range cols {
+			rows[i][j] = new(big.Rat).SetInt(row[j])
+		}
+		// The projection onto span(b₁, …, bₖ) is Σ cⱼbⱼ with
+		// c = G⁻¹·(<bⱼ, row>)ⱼ, G the Gram matrix of the first k rows.
+		for j := range k {
+			c.SetInt64(0)
+			for l := range k {
+				c.Add(&c, t.Mul(inv[j][l], t.SetInt(g[l][k+i])))
+			}
+			if c.Sign() == 0 {
+				continue
+			}
+			for col, v := range b[j] {
+				rows[i][col].Sub(rows[i][col], t.Mul(&c, t.SetInt(v)))
+			}
+		}
+	}
+	scale, projected = integralMultiple(rows)
+	return projected, scale, nil
+}
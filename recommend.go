@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+)
+
+// lprNoiseStdDev returns the standard deviation of the decryption noise of
+// LPR-style LWE encryption in dimension n with secret and errors of width
+// σ: the noise ⟨e, r⟩ - ⟨s, e₁⟩ + e₂ sums 2n products of two errors and one
+// more error, of variance 2nσ⁴ + σ².
+func lprNoiseStdDev(n int, sigma float64) float64 {
+	return math.Sqrt(2*float64(n)*math.Pow(sigma, 4) + sigma*sigma)
+}
+
+// lprModulus returns the least modulus q with which LPR-style decryption
+// in dimension n with width σ fails with probability at most 2^logFail,
+// the noise, taken as Gaussian, exceeding q/4: P(|X| > q/4) =
+// erfc(q/(4√2·std)).
+func lprModulus(n int, sigma, logFail float64) int64 {
+	return int64(math.Ceil(4 * math.Sqrt2 * lprNoiseStdDev(n, sigma) * erfcInverse(logFail)))
+}
+
+// erfcInverse returns the z with erfc(z) = 2^logP, by bisection: unlike
+// math.Erfcinv, which computes erfinv(1 - p), it stays exact for the tiny
+// probabilities of decryption failures. logP must be in [-1000, 0).
+func erfcInverse(logP float64) float64 {
+	lo, hi := 0.0, 27.0 // erfc(27) < 2^-1000
+	for range 100 {
+		mid := (lo + hi) / 2
+		if math.Log2(math.Erfc(mid)) > logP {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
+
+// lprLogFailure returns log2 of the probability that LPR-style decryption
+// with these parameters fails.
+func lprLogFailure(n int, q int64, sigma float64) float64 {
+	return math.Log2(math.Erfc(float64(q) / (4 * math.Sqrt2 * lprNoiseStdDev(n, sigma))))
+}
+
+// recommendConfig holds the arguments of the recommend command.
+type recommendConfig struct {
+	Bits float64
+	// LogFail is log2 of the largest acceptable decryption failure rate.
+	LogFail float64
+	Dims    []int
+	Sigmas  []float64
+	Model   costModel
+}
+
+// parseRecommendFlags builds a recommendConfig from the command line.
+func parseRecommendFlags(args []string) (recommendConfig, error) {
+	fs := flag.NewFlagSet("recommend", flag.ContinueOnError)
+	bits := fs.Float64("bits", 128, "target bit security")
+	fail := fs.Float64("fail", -64, "log2 of the largest acceptable decryption failure rate")
+	dims := fs.String("n", "256,384,512,640,768,1024", "comma-separated dimensions to search")
+	sigmas := fs.String("sigma", "1,1.5,2,3,4", "comma-separated widths of the secret and errors to search")
+	model := fs.String("model", "classical", "core-SVP cost model: one of "+costModelNames())
+	if err := fs.Parse(args); err != nil {
+		return recommendConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return recommendConfig{}, fmt.Errorf("usage: lattice-labs recommend [-bits b] [-fail log2] [-n dims] [-sigma widths] [-model model]")
+	}
+	cfg := recommendConfig{Bits: *bits, LogFail: *fail}
+	if !(cfg.Bits > 0) || cfg.Bits > 1024 {
+		return cfg, fmt.Errorf("-bits must be in (0, 1024], got %g", cfg.Bits)
+	}
+	// erfcInverse underflows below 2^-1000.
+	if !(cfg.LogFail < 0) || cfg.LogFail < -1000 {
+		return cfg, fmt.Errorf("-fail must be in [-1000, 0), got %g", cfg.LogFail)
+	}
+	dimList, err := parseIntList(*dims)
+	if err != nil {
+		return cfg, fmt.Errorf("-n: %w", err)
+	}
+	for _, n := range dimList {
+		if n < 2 {
+			return cfg, fmt.Errorf("-n: dimension must be at least 2, got %d", n)
+		}
+		cfg.Dims = append(cfg.Dims, int(n))
+	}
+	if cfg.Sigmas, err = parseFloatList(*sigmas); err != nil {
+		return cfg, fmt.Errorf("-sigma: %w", err)
+	}
+	for _, s := range cfg.Sigmas {
+		if !(s > 0) {
+			return cfg, fmt.Errorf("-sigma: widths must be positive, got %g", s)
+		}
+	}
+	if cfg.Model, err = lookupCostModel(*model); err != nil {
+		return cfg, fmt.Errorf("-model: %w", err)
+	}
+	return cfg, nil
+}
+
+// runRecommend inverts the LWE estimate for toy LPR-style encryption: for
+// every dimension and width of cfg, it takes the least modulus that keeps
+// the decryption failure rate within cfg.LogFail (lprModulus), which is the
+// most secure, and estimates the primal and dual attacks with n samples,
+// those of the public key. One row per candidate is written to w, and then
+// the recommendation: the candidate of the least dimension, then modulus,
+// whose cheapest attack costs at least cfg.Bits.
+func runRecommend(w io.Writer, cfg recommendConfig) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "LPR-style LWE parameters for %g bits (%s model), failure rate ≤ 2^%g\n\n", cfg.Bits, cfg.Model.Name, cfg.LogFail)
+	fmt.Fprintf(bw, "%-6s | %-10s | %-6s | %-8s | %-8s | %-8s | %-9s | %s\n", "n", "q", "σ", "Primal β", "Dual β", "Bits", "log2 fail", "Meets")
+	fmt.Fprintln(bw, "------------------------------------------------------------------------------")
+
+	var best *lweCandidate
+	for _, n := range cfg.Dims {
+		for _, sigma := range cfg.Sigmas {
+			q := lprModulus(n, sigma, cfg.LogFail)
+			if q < 2 || q >= maxLWEModulus {
+				fmt.Fprintf(bw, "%-6d | %-10s | %-6g | %-8s | %-8s | %-8s | %-9s | %s\n", n, "-", sigma, "-", "-", "-", "-", "q out of range")
+				continue
+			}
+			c := newLWECandidate(n, q, sigma, cfg.Model)
+			meets := c.bits >= cfg.Bits
+			primalBeta := "-"
+			if c.primalOK {
+				primalBeta = fmt.Sprint(c.primal.Beta)
+			}
+			fmt.Fprintf(bw, "%-6d | %-10d | %-6g | %-8s | %-8d | %-8.1f | %-9.1f | %s\n", n, q, sigma, primalBeta, c.dual.Beta, c.bits, lprLogFailure(n, q, sigma), yesNo(meets))
+			if meets && (best == nil || n < best.n || n == best.n && q < best.q) {
+				best = &c
+			}
+		}
+	}
+	if best == nil {
+		fmt.Fprintf(bw, "\nNo parameters searched reach %g bits; try larger dimensions.\n", cfg.Bits)
+	} else {
+		fmt.Fprintf(bw, "\nRecommended: n %d, q %d, σ %g (%.1f bits)\n", best.n, best.q, best.sigma, best.bits)
+	}
+	return bw.Flush()
+}
+
+// lweCandidate is a parameter set runRecommend considers, with the attacks
+// estimated on it.
+type lweCandidate struct {
+	n        int
+	q        int64
+	sigma    float64
+	primal   lweEstimate
+	primalOK bool
+	dual     lweEstimate
+	// bits is the cost of the cheaper attack.
+	bits float64
+}
+
+// newLWECandidate estimates the attacks on LWE with n samples in
+// dimension n, modulus q and width σ.
+func newLWECandidate(n int, q int64, sigma float64, model costModel) lweCandidate {
+	c := lweCandidate{n: n, q: q, sigma: sigma}
+	c.primal, c.primalOK = estimatePrimal(n, n, q, sigma, model)
+	c.dual = estimateDual(n, n, q, sigma, model)
+	c.bits = c.dual.Bits
+	if c.primalOK {
+		c.bits = min(c.bits, c.primal.Bits)
+	}
+	return c
+}
+
+// yesNo returns "yes" or "no".
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// errNotReduced is wrapped by the errors of checkLLLReduced and
+// checkBKZReduced for a basis that fails the definition they check.
+var errNotReduced = errors.New("the basis is not reduced")
+
+// exactGSO is the Gram-Schmidt orthogonalization of a basis in exact
+// rational arithmetic, kept as the integers of independentGSO.
+type exactGSO struct {
+	d      []*big.Int
+	lambda [][]*big.Int
+}
+
+// newExactGSO orthogonalizes basis exactly; its rows must be linearly
+// independent.
+func newExactGSO(basis Basis) (exactGSO, error) {
+	d, lambda, err := independentGSO(basis.Gram())
+	return exactGSO{d: d, lambda: lambda}, err
+}
+
+// mu returns μᵢⱼ = <bᵢ, b*ⱼ> / ‖b*ⱼ‖² for j < i.
+func (g exactGSO) mu(i, j int) *big.Rat {
+	return new(big.Rat).SetFrac(g.lambda[i][j], g.d[j+1])
+}
+
+// r returns ‖b*ᵢ‖².
+func (g exactGSO) r(i int) *big.Rat {
+	return new(big.Rat).SetFrac(g.d[i+1], g.d[i])
+}
+
+// float returns the orthogonalization rounded to float64 for enumeration.
+// Unlike newGSOFloat's, every entry is the exact value correctly rounded.
+func (g exactGSO) float() gsoFloat {
+	n := len(g.lambda)
+	f := gsoFloat{mu: make([][]float64, n), r: make([]float64, n)}
+	for i := range n {
+		f.r[i], _ = g.r(i).Float64()
+		f.mu[i] = make([]float64, i)
+		for j := range i {
+			f.mu[i][j], _ = g.mu(i, j).Float64()
+		}
+	}
+	return f
+}
+
+// checkLLLReduced checks that basis is LLL-reduced with the parameters of
+// opts, zero ones taking fplll's defaults: that every |μᵢⱼ| ≤ η and that
+// every pair of neighbours satisfies the Lovász condition
+// δ‖b*ₖ₋₁‖² ≤ ‖b*ₖ‖² + μ²ₖ,ₖ₋₁‖b*ₖ₋₁‖². The check is exact, on the rational
+// Gram-Schmidt data and the float64 values of δ and η, so a backend that
+// reports an LLL-reduced basis can be held to it; the error names the first
+// condition violated and wraps errNotReduced.
+func checkLLLReduced(basis Basis, opts lllOptions) error {
+	gso, err := newExactGSO(basis)
+	if err != nil {
+		return err
+	}
+	return gso.checkLLL(opts)
+}
+
+// checkSize checks that every |μᵢⱼ| ≤ eta.
+func (g exactGSO) checkSize(eta float64) error {
+	ratEta, abs := new(big.Rat).SetFloat64(eta), new(big.Rat)
+	for k := 1; k < len(g.lambda); k++ {
+		for j := range k {
+			if mu := g.mu(k, j); abs.Abs(mu).Cmp(ratEta) > 0 {
+				f, _ := mu.Float64()
+				return fmt.Errorf("%w: |μ(%d,%d)| = %.4g exceeds η = %g", errNotReduced, k+1, j+1, f, eta)
+			}
+		}
+	}
+	return nil
+}
+
+// checkLLL is checkLLLReduced on the orthogonalization.
+func (g exactGSO) checkLLL(opts lllOptions) error {
+	delta, eta := opts.Delta, opts.Eta
+	if delta == 0 {
+		delta = defaultLLLDelta
+	}
+	if eta == 0 {
+		eta = defaultLLLEta
+	}
+	if err := g.checkSize(eta); err != nil {
+		return err
+	}
+	ratDelta, lhs, rhs := new(big.Rat).SetFloat64(delta), new(big.Rat), new(big.Rat)
+	for k := 1; k < len(g.lambda); k++ {
+		prev, mu := g.r(k-1), g.mu(k, k-1)
+		lhs.Mul(ratDelta, prev)
+		rhs.Mul(mu, mu)
+		rhs.Add(g.r(k), rhs.Mul(rhs, prev))
+		if lhs.Cmp(rhs) > 0 {
+			return fmt.Errorf("%w: rows %d and %d violate the Lovász condition with δ = %g", errNotReduced, k, k+1, delta)
+		}
+	}
+	return nil
+}
+
+// errShorterInBlock stops the enumeration of checkBKZReduced at the first
+// projected vector shorter than the block allows.
+var errShorterInBlock = errors.New("shorter projected vector")
+
+// checkBKZReduced checks that basis is BKZ-reduced with block size beta as
+// fplll's BKZ with Lovász factor delta (0 for fplll's default) leaves it:
+// LLL-reduced with δ = delta and fplll's η, and with every b*ₖ a shortest
+// vector of its projected block L[k, min(k+β, n)) up to the factor δ, i.e.
+// δ‖b*ₖ‖² ≤ λ₁(L[k, k+β))². Each block is enumerated on the exact
+// Gram-Schmidt data rounded to float64 and a shorter projected vector is
+// confirmed exactly, so the cost is that of one enumeration of rank β per
+// index, which limits the check to the block sizes BKZ itself can handle.
+// beta = rank checks HKZ reduction. The error names the first index
+// violated and wraps errNotReduced.
+func checkBKZReduced(ctx context.Context, basis Basis, beta int, delta float64) error {
+	gso, err := newExactGSO(basis)
+	if err != nil {
+		return err
+	}
+	if err := gso.checkLLL(lllOptions{Delta: delta}); err != nil {
+		return err
+	}
+	if delta == 0 {
+		delta = defaultLLLDelta
+	}
+	ratDelta := new(big.Rat).SetFloat64(delta)
+	f := gso.float()
+	n := len(basis)
+	for k := 0; k+1 < n; k++ {
+		end := min(k+beta, n)
+		block := gsoFloat{mu: make([][]float64, end-k), r: f.r[k:end]}
+		for i := range block.mu {
+			block.mu[i] = f.mu[k+i][k : k+i]
+		}
+		bound := new(big.Rat).Mul(ratDelta, gso.r(k))
+		fb, _ := bound.Float64()
+		var shorter []int64
+		e := &enumerator{ctx: ctx, gso: block, x: make([]float64, end-k), bound: fb}
+		e.visit = func(x []float64) {
+			if gso.projectedNorm(k, x).Cmp(bound) < 0 {
+				shorter = make([]int64, len(x))
+				for i, xi := range x {
+					shorter[i] = int64(xi)
+				}
+				e.err = errShorterInBlock
+			}
+		}
+		if err := e.run(); err != nil && !errors.Is(err, errShorterInBlock) {
+			return err
+		}
+		if shorter != nil {
+			return fmt.Errorf("%w: block %d..%d of BKZ-%d holds a projected vector (coefficients %v) shorter than δ·‖b*%d‖² with δ = %g",
+				errNotReduced, k+1, end, beta, shorter, k+1, delta)
+		}
+	}
+	return nil
+}
+
+// projectedNorm returns the exact squared norm of the projection of
+// Σ xᵢ b_{k+i} orthogonally to the rows before row k.
+func (g exactGSO) projectedNorm(k int, x []float64) *big.Rat {
+	norm, c, t := new(big.Rat), new(big.Rat), new(big.Rat)
+	for l := len(x) - 1; l >= 0; l-- {
+		// The coordinate along b*_{k+l}: x_l + Σ_{i>l} x_i μ_{k+i,k+l}.
+		c.SetInt64(int64(x[l]))
+		for i := l + 1; i < len(x); i++ {
+			if x[i] != 0 {
+				c.Add(c, t.Mul(t.SetInt64(int64(x[i])), g.mu(k+i, k+l)))
+			}
+		}
+		norm.Add(norm, t.Mul(t.Mul(c, c), g.r(k+l)))
+	}
+	return norm
+}
+
+// checkReduced checks that basis, the output of the step, is reduced as
+// the step's algorithm defines it: LLL-reduced with its parameters, size-
+// reduced with η = 1/2 (as exactly as sizeReduce makes it), BKZ-reduced with
+// its block size or HKZ-reduced. Flatter and slide reduction guarantee
+// weaker or other properties and can't be checked.
+func (s reductionStep) checkReduced(ctx context.Context, basis Basis) error {
+	switch s.algo() {
+	case "lll":
+		return checkLLLReduced(basis, s.lllOptions)
+	case "size":
+		gso, err := newExactGSO(basis)
+		if err != nil {
+			return err
+		}
+		return gso.checkSize(0.5)
+	case "bkz":
+		return checkBKZReduced(ctx, basis, s.Beta, 0)
+	case "hkz":
+		return checkBKZReduced(ctx, basis, len(basis), 0)
+	}
+	return fmt.Errorf("%s has no reduction condition to check", s)
+}
+
+// checkableReduction reports whether checkReduced can check the output of
+// the step.
+func (s reductionStep) checkableReduction() bool {
+	switch s.algo() {
+	case "lll", "size", "bkz", "hkz":
+		return true
+	}
+	return false
+}
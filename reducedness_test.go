@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// lllNotBKZ is LLL-reduced with fplll's δ = 0.99 and η = 0.51 but not
+// BKZ-3-reduced: ‖b₁‖² = 42 and 0.99·42 exceeds λ₁² = 41, the squared norm
+// of b₁ + b₂ + b₃.
+var lllNotBKZ = basisOf([]int64{-1, 5, -4}, []int64{6, -3, -1}, []int64{-4, -3, -4})
+
+func TestCheckLLLReduced(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		basis   Basis
+		opts    lllOptions
+		reduced bool
+	}{
+		{"identity", basisOf([]int64{1, 0, 0}, []int64{0, 1, 0}, []int64{0, 0, 1}), lllOptions{}, true},
+		{"half", basisOf([]int64{2, 0}, []int64{1, 2}), lllOptions{}, true},
+		{"not BKZ", lllNotBKZ, lllOptions{}, true},
+		{"size", basisOf([]int64{1, 0}, []int64{3, 1}), lllOptions{}, false},
+		{"eta", basisOf([]int64{2, 0}, []int64{1, 2}), lllOptions{Eta: 0.4}, false},
+		{"lovász", basisOf([]int64{4, 0}, []int64{0, 3}), lllOptions{}, false},
+		{"small delta", basisOf([]int64{4, 0}, []int64{0, 3}), lllOptions{Delta: 0.5}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkLLLReduced(tc.basis, tc.opts)
+			switch {
+			case tc.reduced && err != nil:
+				t.Errorf("reduced basis rejected: %v", err)
+			case !tc.reduced && !errors.Is(err, errNotReduced):
+				t.Errorf("checkLLLReduced() = %v, want an error wrapping errNotReduced", err)
+			}
+		})
+	}
+}
+
+func TestCheckBKZReduced(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name    string
+		basis   Basis
+		beta    int
+		reduced bool
+	}{
+		{"identity", basisOf([]int64{1, 0, 0, 0}, []int64{0, 1, 0, 0}, []int64{0, 0, 1, 0}, []int64{0, 0, 0, 1}), 4, true},
+		{"BKZ-2", lllNotBKZ, 2, true},
+		{"BKZ-3", lllNotBKZ, 3, false},
+		{"not LLL", basisOf([]int64{4, 0}, []int64{0, 3}), 2, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkBKZReduced(ctx, tc.basis, tc.beta, 0)
+			switch {
+			case tc.reduced && err != nil:
+				t.Errorf("reduced basis rejected: %v", err)
+			case !tc.reduced && !errors.Is(err, errNotReduced):
+				t.Errorf("checkBKZReduced() = %v, want an error wrapping errNotReduced", err)
+			}
+		})
+	}
+}
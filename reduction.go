@@ -0,0 +1,531 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reductionStep is one stage of a reduction pipeline, e.g. an LLL pass
+// followed by BKZ with a given block size. The algorithms are those of
+// fplll -a: lll, bkz, hkz and sld (slide reduction, also accepted as
+// "slide"), and enum, which finds a shortest vector of the basis with
+// fplll -a svp and leaves the basis as it is; pbkz is BKZ with tours run
+// in Go (see parallelBKZ).
+type reductionStep struct {
+	Algo string `json:"algo" yaml:"algo"`
+	Beta int    `json:"beta,omitempty" yaml:"beta,omitempty"`
+	// BestOf, if above 1, reduces that many rerandomized copies of the
+	// basis and keeps the best result; see bestOfReduce.
+	BestOf int `json:"best_of,omitempty" yaml:"best_of,omitempty"`
+	// The BKZ options apply to BKZ and slide reduction steps.
+	bkzOptions `yaml:",inline"`
+	// The LLL options apply to LLL steps.
+	lllOptions `yaml:",inline"`
+}
+
+// lllOptions are the parameters of LLL reduction: the Lovász factor δ and
+// the size-reduction bound η of fplll's -d and -e. A basis is (δ, η)-LLL
+// reduced if |mu_ij| <= η and δ‖b*_i‖² <= ‖b*_{i+1}‖² + mu²‖b*_i‖²; larger δ
+// gives a better basis for more work. Zero values keep fplll's defaults,
+// δ = 0.99 and η = 0.51.
+type lllOptions struct {
+	Delta float64 `json:"delta,omitempty" yaml:"delta,omitempty"`
+	Eta   float64 `json:"eta,omitempty" yaml:"eta,omitempty"`
+}
+
+// Defaults of fplll's LLL, which the options fall back to.
+const (
+	defaultLLLDelta = 0.99
+	defaultLLLEta   = 0.51
+)
+
+// validate reports whether the options are in the range fplll accepts:
+// 1/4 < δ <= 1 and 1/2 <= η < √δ.
+func (o lllOptions) validate() error {
+	delta, eta := o.Delta, o.Eta
+	if delta == 0 {
+		delta = defaultLLLDelta
+	}
+	if eta == 0 {
+		eta = defaultLLLEta
+	}
+	switch {
+	case !(delta > 0.25 && delta <= 1):
+		return fmt.Errorf("LLL delta must be in (0.25, 1], got %g", delta)
+	case !(eta >= 0.5 && eta*eta < delta):
+		return fmt.Errorf("LLL eta must be in [0.5, sqrt(delta)) = [0.5, %.4g), got %g", math.Sqrt(delta), eta)
+	}
+	return nil
+}
+
+// args returns the fplll arguments setting the options.
+func (o lllOptions) args() []string {
+	var args []string
+	if o.Delta > 0 {
+		args = append(args, "-d", strconv.FormatFloat(o.Delta, 'g', -1, 64))
+	}
+	if o.Eta > 0 {
+		args = append(args, "-e", strconv.FormatFloat(o.Eta, 'g', -1, 64))
+	}
+	return args
+}
+
+// String describes the options that are set, e.g. "δ = 0.75", or returns "".
+func (o lllOptions) String() string {
+	var parts []string
+	if o.Delta > 0 {
+		parts = append(parts, fmt.Sprintf("δ = %g", o.Delta))
+	}
+	if o.Eta > 0 {
+		parts = append(parts, fmt.Sprintf("η = %g", o.Eta))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// lllFlags defines the flags of the LLL options on fs.
+func lllFlags(fs *flag.FlagSet) *lllOptions {
+	var o lllOptions
+	fs.Float64Var(&o.Delta, "delta", 0, fmt.Sprintf("Lovász factor δ of LLL, fplll's -d (0 keeps fplll's default %g)", defaultLLLDelta))
+	fs.Float64Var(&o.Eta, "eta", 0, fmt.Sprintf("size-reduction bound η of LLL, fplll's -e (0 keeps fplll's default %g)", defaultLLLEta))
+	return &o
+}
+
+// bkzOptions are fplll's BKZ 2.0 controls. They bound the tours of BKZ and
+// slide reduction, which at block sizes from about 30 otherwise go on long
+// after the basis stops improving. Unlike --timeout, which kills fplll, a
+// reduction stopped by them still returns its basis. Zero values keep
+// fplll's defaults.
+type bkzOptions struct {
+	// AutoAbort stops once a tour no longer improves the slope of the
+	// profile noticeably (-bkzautoabort).
+	AutoAbort bool `json:"auto_abort,omitempty" yaml:"auto_abort,omitempty"`
+	// MaxLoops limits the number of tours (-bkzmaxloops).
+	MaxLoops int `json:"max_loops,omitempty" yaml:"max_loops,omitempty"`
+	// MaxTime limits the reduction to about this many seconds; fplll checks
+	// it after every tour (-bkzmaxtime).
+	MaxTime float64 `json:"max_time,omitempty" yaml:"max_time,omitempty"`
+	// GHBound bounds the enumeration radius by this multiple of the
+	// Gaussian heuristic of each block (-bkzghbound).
+	GHBound float64 `json:"gh_bound,omitempty" yaml:"gh_bound,omitempty"`
+	// SlopeTolerance, which is not an fplll option, runs the reduction
+	// tour by tour and stops once a tour raises the slope of the fitted
+	// GSA line by less than this; see convergedReduce.
+	SlopeTolerance float64 `json:"slope_tolerance,omitempty" yaml:"slope_tolerance,omitempty"`
+}
+
+// validate reports whether the options are in range.
+func (o bkzOptions) validate() error {
+	switch {
+	case o.MaxLoops < 0:
+		return fmt.Errorf("BKZ tour limit must not be negative, got %d", o.MaxLoops)
+	case o.MaxTime < 0:
+		return fmt.Errorf("BKZ time limit must not be negative, got %g", o.MaxTime)
+	case o.GHBound < 0:
+		return fmt.Errorf("BKZ GH bound must not be negative, got %g", o.GHBound)
+	case o.SlopeTolerance < 0:
+		return fmt.Errorf("slope tolerance must not be negative, got %g", o.SlopeTolerance)
+	}
+	return nil
+}
+
+// args returns the fplll arguments setting the options; SlopeTolerance has
+// none.
+func (o bkzOptions) args() []string {
+	var args []string
+	if o.AutoAbort {
+		args = append(args, "-bkzautoabort")
+	}
+	if o.MaxLoops > 0 {
+		args = append(args, "-bkzmaxloops", strconv.Itoa(o.MaxLoops))
+	}
+	if o.MaxTime > 0 {
+		args = append(args, "-bkzmaxtime", strconv.FormatFloat(o.MaxTime, 'g', -1, 64))
+	}
+	if o.GHBound > 0 {
+		args = append(args, "-bkzghbound", strconv.FormatFloat(o.GHBound, 'g', -1, 64))
+	}
+	return args
+}
+
+// String describes the options that are set, e.g. "auto-abort, at most 8
+// tours", or returns "".
+func (o bkzOptions) String() string {
+	var parts []string
+	if o.AutoAbort {
+		parts = append(parts, "auto-abort")
+	}
+	if o.MaxLoops > 0 {
+		parts = append(parts, fmt.Sprintf("at most %d tours", o.MaxLoops))
+	}
+	if o.MaxTime > 0 {
+		parts = append(parts, fmt.Sprintf("at most %gs", o.MaxTime))
+	}
+	if o.GHBound > 0 {
+		parts = append(parts, fmt.Sprintf("enumeration radius %g·GH", o.GHBound))
+	}
+	if o.SlopeTolerance > 0 {
+		parts = append(parts, fmt.Sprintf("until a tour raises the slope by less than %g", o.SlopeTolerance))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// bestOfFlag defines the -best-of flag of a reduction step on fs.
+func bestOfFlag(fs *flag.FlagSet) *int {
+	return fs.Int("best-of", 0, "reduce this many rerandomized copies of the basis and keep the one with the shortest first vector (0 or 1 reduces the basis once)")
+}
+
+// bkzFlags defines the flags of the BKZ options on fs, named as fplll's
+// where fplll has them.
+func bkzFlags(fs *flag.FlagSet) *bkzOptions {
+	var o bkzOptions
+	fs.BoolVar(&o.AutoAbort, "bkzautoabort", false, "stop BKZ once a tour no longer improves the basis noticeably")
+	fs.IntVar(&o.MaxLoops, "bkzmaxloops", 0, "stop BKZ after this many tours (0 means no limit)")
+	fs.Float64Var(&o.MaxTime, "bkzmaxtime", 0, "stop BKZ after the tour that exceeds this many seconds (0 means no limit)")
+	fs.Float64Var(&o.GHBound, "bkzghbound", 0, "bound the enumeration radius by this multiple of the Gaussian heuristic (0 keeps fplll's default)")
+	fs.Float64Var(&o.SlopeTolerance, "slope-tolerance", 0, "run BKZ tour by tour and stop once a tour raises the GSA slope by less than this (0 leaves the tours to fplll)")
+	return &o
+}
+
+// reductionAlgos lists the algorithms a step can name, for usage messages.
+const reductionAlgos = "lll, flatter, size, bkz, pbkz, hkz, sld or enum"
+
+// algo returns the fplll name of the step's algorithm in lower case.
+func (s reductionStep) algo() string {
+	algo := strings.ToLower(s.Algo)
+	if algo == "slide" {
+		return "sld"
+	}
+	return algo
+}
+
+// blockwise reports whether the step's algorithm takes a block size.
+func (s reductionStep) blockwise() bool {
+	return s.algo() == "bkz" || s.algo() == "pbkz" || s.algo() == "sld"
+}
+
+// validate reports whether the step names a supported algorithm with sane
+// parameters.
+func (s reductionStep) validate() error {
+	switch {
+	case s.algo() == "flatter" && s.Eta != 0:
+		return fmt.Errorf("flatter step takes no eta")
+	case s.algo() != "lll" && s.algo() != "flatter" && s.lllOptions != (lllOptions{}):
+		return fmt.Errorf("%s step takes no LLL options", s.algo())
+	}
+	if s.BestOf < 0 {
+		return fmt.Errorf("best of %d copies: the number must not be negative", s.BestOf)
+	}
+	if s.BestOf > 1 && s.algo() == "enum" {
+		return fmt.Errorf("enum step can't be run best of %d copies; it finds a shortest vector already", s.BestOf)
+	}
+	switch s.algo() {
+	case "lll", "flatter", "size", "hkz", "enum":
+		if s.bkzOptions != (bkzOptions{}) {
+			return fmt.Errorf("%s step takes no BKZ options", s.algo())
+		}
+		return s.lllOptions.validate()
+	case "bkz", "pbkz", "sld":
+		if s.Beta < 2 {
+			return fmt.Errorf("%s step needs a block size beta >= 2, got %d", s.algo(), s.Beta)
+		}
+		if s.algo() == "pbkz" && (s.AutoAbort || s.GHBound != 0) {
+			return fmt.Errorf("pbkz step takes no auto-abort or GH bound; they are fplll's")
+		}
+		return s.bkzOptions.validate()
+	default:
+		return fmt.Errorf("unknown reduction algorithm %q (want %s)", s.Algo, reductionAlgos)
+	}
+}
+
+// String returns a human-readable description of the step.
+func (s reductionStep) String() string {
+	options := ""
+	if o := s.bkzOptions.String() + s.lllOptions.String(); o != "" {
+		options = " (" + o + ")"
+	}
+	if s.BestOf > 1 {
+		options += fmt.Sprintf(", best of %d rerandomized copies", s.BestOf)
+	}
+	switch s.algo() {
+	case "bkz":
+		return fmt.Sprintf("BKZ reduction with block size beta = %d%s", s.Beta, options)
+	case "pbkz":
+		return fmt.Sprintf("BKZ reduction with parallel block SVP, block size beta = %d%s", s.Beta, options)
+	case "sld":
+		return fmt.Sprintf("slide reduction with block size beta = %d%s", s.Beta, options)
+	case "hkz":
+		return "HKZ reduction" + options
+	case "lll":
+		return "LLL reduction" + options
+	case "flatter":
+		return "flatter reduction" + options
+	case "size":
+		return "size reduction" + options
+	case "enum":
+		return "enumeration of a shortest vector"
+	default:
+		return s.Algo
+	}
+}
+
+// args returns the fplll arguments of the step after -a and the algorithm.
+func (s reductionStep) args() []string {
+	if s.blockwise() {
+		return append([]string{"-b", strconv.Itoa(s.Beta)}, s.bkzOptions.args()...)
+	}
+	return s.lllOptions.args()
+}
+
+// command returns the fplll (or flatter) invocation that performs the step.
+func (s reductionStep) command() string {
+	switch s.algo() {
+	case "enum":
+		return svpCommand("")
+	case "flatter":
+		return strings.Join(append([]string{"flatter"}, flatterArgs(s.lllOptions)...), " ")
+	case "size":
+		return "native size reduction"
+	case "pbkz":
+		return fmt.Sprintf("native BKZ tours with block size %d over fplll -a lll and -a svp", s.Beta)
+	}
+	return strings.Join(append([]string{"fplll", "-a", s.algo()}, s.args()...), " ")
+}
+
+// bkzStep returns a BKZ step with block size beta and fplll's defaults
+// otherwise.
+func bkzStep(beta int) reductionStep { return reductionStep{Algo: "bkz", Beta: beta} }
+
+// reductionPipeline chains reduction steps on a shared pipelineState: each
+// step works on the basis the one before left, and an enum step records
+// the shortest vector for the steps and the caller after it.
+type reductionPipeline struct {
+	Steps []reductionStep
+	// Report, if set, is called with the report of every step as soon as
+	// it finishes; the reports are also kept in the state.
+	Report func(stepReport)
+	// MaxTime, if positive, is the time budget of the pipeline (--max-time).
+	// BKZ and slide steps are told the time left with -bkzmaxtime, so that
+	// fplll returns its basis after the tour that passes the deadline;
+	// other steps are stopped at the deadline and their work is lost. The
+	// pipeline then ends with the basis reduced so far.
+	MaxTime time.Duration
+	// CheckVolume makes every step but enum check with checkVolumePreserved
+	// that the basis it returns spans a lattice of the input's volume. A
+	// change fails the step and is recorded for volumeChangeError, which
+	// fails the run. The labs set it; the exact determinants cost too much
+	// for the large bases of the reduce command.
+	CheckVolume bool
+}
+
+// pipelineState is what the steps of a pipeline share: the current basis,
+// the shortest vector found by the last enum step, if any, and the reports
+// of the steps so far. Stopped is set if the pipeline ran out of its time
+// budget, in which case Basis is the best it reached.
+type pipelineState struct {
+	Basis   Basis
+	Vector  []*big.Int
+	Reports []stepReport
+	Stopped bool
+}
+
+// stepReport describes the basis after a step of a pipeline: how long the
+// step took, log2 ‖b_1‖, the slope of the fitted GSA line and the root
+// Hermite factor δ0 = (‖b_1‖ / vol^(1/n))^(1/n) the step reached, and for
+// an enum step log2 of the norm of the vector found.
+type stepReport struct {
+	Step        reductionStep `json:"step"`
+	Seconds     float64       `json:"seconds"`
+	Log2B1      float64       `json:"log2_b1"`
+	Slope       float64       `json:"slope"`
+	RootHermite float64       `json:"root_hermite"`
+	Log2Vector  float64       `json:"log2_vector,omitempty"`
+}
+
+// run runs the steps in order from basis. The error of the first failing
+// step is returned with the state before it; a step stopped by the time
+// budget is not an error.
+func (p reductionPipeline) run(ctx context.Context, basis Basis) (pipelineState, error) {
+	state := pipelineState{Basis: basis}
+	var volume *big.Int // det(B·Bᵀ) of the input, once a step needs it
+	var deadline time.Time
+	if p.MaxTime > 0 {
+		deadline = time.Now().Add(p.MaxTime)
+	}
+	for i, step := range p.Steps {
+		start := time.Now()
+		stepCtx := ctx
+		if !deadline.IsZero() {
+			left := time.Until(deadline)
+			if left <= 0 {
+				slog.Info("time budget used up, skipping the remaining steps", "max_time", p.MaxTime, "skipped", len(p.Steps)-i)
+				state.Stopped = true
+				return state, nil
+			}
+			if step.blockwise() {
+				step = step.withTimeLeft(left)
+			} else {
+				var cancel context.CancelFunc
+				stepCtx, cancel = context.WithDeadline(ctx, deadline)
+				defer cancel()
+			}
+		}
+		next, vector, err := runStep(stepCtx, state.Basis, step)
+		if err != nil && ctx.Err() == nil && stepCtx.Err() != nil {
+			slog.Info("time budget used up, stopped a step", "max_time", p.MaxTime, "step", step.String())
+			state.Stopped = true
+			return state, nil
+		}
+		if err != nil {
+			return state, err
+		}
+		if p.CheckVolume && dryRun == nil && step.algo() != "enum" {
+			if volume == nil {
+				volume = basis.SquaredVolume()
+			}
+			if err := checkSquaredVolume(volume, next); err != nil {
+				slog.Error("reduction changed the lattice volume", "step", step.String(), "rank", len(basis), "err", err)
+				recordVolumeChange(step, len(basis), err)
+				return state, err
+			}
+		}
+		state.Basis = next
+		if vector != nil {
+			state.Vector = vector
+		}
+		if p.Report == nil {
+			continue
+		}
+		r := newStepReport(step, state.Basis, time.Since(start))
+		if vector != nil {
+			r.Log2Vector = math.Log2(vectorNormSquared(vector)) / 2
+		}
+		state.Reports = append(state.Reports, r)
+		p.Report(r)
+	}
+	return state, nil
+}
+
+// withTimeLeft returns the BKZ or slide step with its -bkzmaxtime lowered
+// to the time left, rounded up to whole seconds.
+func (s reductionStep) withTimeLeft(left time.Duration) reductionStep {
+	seconds := math.Ceil(left.Seconds())
+	if s.MaxTime == 0 || seconds < s.MaxTime {
+		s.MaxTime = seconds
+	}
+	return s
+}
+
+// runStep runs one step on basis and returns the basis it leaves, and for
+// an enum step the vector it found.
+func runStep(ctx context.Context, basis Basis, step reductionStep) (Basis, []*big.Int, error) {
+	var err error
+	if step.BestOf > 1 {
+		basis, err = bestOfReduce(ctx, basis, step)
+		return basis, nil, err
+	}
+	if step.SlopeTolerance > 0 {
+		basis, err = convergedReduce(ctx, basis, step)
+		return basis, nil, err
+	}
+	switch step.algo() {
+	case "lll":
+		basis, err = fplllReduce(ctx, basis, "lll", step.lllOptions.args()...)
+	case "flatter":
+		basis, err = flatterReduce(ctx, basis, step.lllOptions)
+	case "size":
+		basis, err = sizeReduce(basis)
+	case "bkz", "pbkz", "sld":
+		if basis, err = flatterPreprocess(ctx, basis); err == nil {
+			basis, err = blockReduce(ctx, basis, step)
+		}
+	case "hkz":
+		basis, err = hkzReduce(ctx, basis)
+	case "enum":
+		if dryRun != nil {
+			planFplll(basis, svpArgs("")...)
+			return basis, nil, nil
+		}
+		vector, err := shortestVector(ctx, basis, "")
+		if err == nil {
+			err = verifyLatticeVector(basis, vector)
+		}
+		return basis, vector, err
+	default:
+		err = fmt.Errorf("unknown reduction algorithm %q", step.Algo)
+	}
+	return basis, nil, err
+}
+
+// blockReduce runs the BKZ or slide reduction of step on basis.
+func blockReduce(ctx context.Context, basis Basis, step reductionStep) (Basis, error) {
+	switch step.algo() {
+	case "sld":
+		return slideReduce(ctx, basis, step.Beta, step.bkzOptions)
+	case "pbkz":
+		return parallelBKZ(ctx, basis, step.Beta, step.bkzOptions)
+	}
+	return bkzReduce(ctx, basis, step.Beta, step.bkzOptions)
+}
+
+// newStepReport describes basis after step, which took elapsed.
+func newStepReport(step reductionStep, basis Basis, elapsed time.Duration) stepReport {
+	profile := basis.Profile()
+	r := stepReport{Step: step, Seconds: elapsed.Seconds()}
+	if len(profile) > 0 {
+		r.Log2B1 = profile[0]
+		r.Slope = profile.Slope()
+		r.RootHermite = profile.RHF()
+	}
+	return r
+}
+
+// writeStepReport writes the report of a pipeline step as one line.
+func writeStepReport(w io.Writer, r stepReport) {
+	fmt.Fprintf(w, "  after %s: %.3fs, log2 ‖b1‖ = %.3f, slope %.5f, δ0 = %.5f", r.Step, r.Seconds, r.Log2B1, r.Slope, r.RootHermite)
+	if r.Log2Vector != 0 {
+		fmt.Fprintf(w, ", log2 ‖v‖ = %.3f", r.Log2Vector)
+	}
+	fmt.Fprintln(w)
+}
+
+// applyReduction runs the steps of a pipeline in order, feeding the reduced
+// basis of one step into the next, and returns the final basis. An empty
+// pipeline returns the basis unchanged; the error of the first failing step
+// is returned. The pipeline has the time budget of the backend, and if it
+// runs out the basis reduced so far is returned.
+func applyReduction(ctx context.Context, basis Basis, steps []reductionStep) (Basis, error) {
+	state, err := reductionPipeline{Steps: steps, MaxTime: backend.MaxTime}.run(ctx, basis)
+	if err != nil {
+		return nil, err
+	}
+	return state.Basis, nil
+}
+
+// describeReduction joins the descriptions of all steps of a pipeline.
+func describeReduction(steps []reductionStep) string {
+	if len(steps) == 0 {
+		return "no reduction"
+	}
+	parts := make([]string, len(steps))
+	for i, step := range steps {
+		parts[i] = step.String()
+	}
+	return strings.Join(parts, ", then ")
+}
+
+// reductionCommands joins the fplll invocations of all steps of a pipeline.
+func reductionCommands(steps []reductionStep) string {
+	parts := make([]string, len(steps))
+	for i, step := range steps {
+		parts[i] = step.command()
+	}
+	return strings.Join(parts, ", then ")
+}
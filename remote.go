@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"runtime/trace"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// remoteMessage is a message of the worker protocol, sent as one JSON
+// object per line. A worker says hello with its fplll version and asks for
+// a job with next once for every call it can run at once; the coordinator
+// answers each next with a job, a fplll call, and may cancel it again; the
+// worker sends back its result and asks for the next job.
+type remoteMessage struct {
+	Type    string    `json:"type"` // hello, next, job, cancel or result
+	ID      uint64    `json:"id,omitempty"`
+	Version string    `json:"version,omitempty"` // hello: the worker's fplll --version line
+	Args    []string  `json:"args,omitempty"`    // job: fplll arguments
+	Basis   string    `json:"basis,omitempty"`   // job: input basis in fplll format
+	Output  string    `json:"output,omitempty"`  // result: fplll's standard output
+	Tours   []bkzTour `json:"tours,omitempty"`   // result: the tours fplll -v reported
+	// TourProfiles are the profiles of the result's GSO dump.
+	TourProfiles []tourProfile `json:"tour_profiles,omitempty"`
+	// Error, Failure and Stderr describe a failed call, Timeout one that
+	// hit the worker's own --timeout.
+	Error   string       `json:"error,omitempty"`
+	Failure fplllFailure `json:"failure,omitempty"`
+	Stderr  string       `json:"stderr,omitempty"`
+	Timeout bool         `json:"timeout,omitempty"`
+}
+
+// remoteConn is one end of a worker connection. send may be called from
+// several goroutines.
+type remoteConn struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	dec *json.Decoder
+	rw  io.ReadWriteCloser
+}
+
+func newRemoteConn(rw io.ReadWriteCloser) *remoteConn {
+	return &remoteConn{enc: json.NewEncoder(rw), dec: json.NewDecoder(rw), rw: rw}
+}
+
+func (c *remoteConn) send(m remoteMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enc.Encode(m)
+}
+
+func (c *remoteConn) receive() (remoteMessage, error) {
+	var m remoteMessage
+	err := c.dec.Decode(&m)
+	return m, err
+}
+
+// workers, if set, is the pool of remote workers every fplll call of the
+// run is sent to (--coordinator, --ssh-workers).
+var workers *workerPool
+
+// workerPool hands the fplll calls of the run out to remote workers, which
+// connect over TCP or are started over SSH and pull calls as they have room
+// for them. The calls of a worker that disconnects are handed out again.
+type workerPool struct {
+	jobs     chan *remoteJob
+	nextID   atomic.Uint64
+	listener net.Listener
+
+	mu    sync.Mutex
+	conns []*remoteConn
+	ssh   []*exec.Cmd
+}
+
+// remoteJob is an fplll call waiting for or running on a worker.
+type remoteJob struct {
+	ctx     context.Context
+	msg     remoteMessage
+	start   sync.Once
+	started chan struct{}      // closed once a worker took the job
+	done    chan remoteMessage // receives the result
+	ended   chan struct{}      // closed once the result arrived
+}
+
+// startWorkerPool listens for workers on addr, unless it is empty, and
+// starts a worker on every host in sshHosts.
+func startWorkerPool(addr string, sshHosts []string) (*workerPool, error) {
+	p := &workerPool{jobs: make(chan *remoteJob)}
+	if addr != "" {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("--coordinator: %w", err)
+		}
+		p.listener = ln
+		slog.Info("waiting for workers", "addr", ln.Addr().String())
+		go p.accept()
+	}
+	for _, host := range sshHosts {
+		if err := p.startSSH(host); err != nil {
+			p.Close()
+			return nil, fmt.Errorf("--ssh-workers: %s: %w", host, err)
+		}
+	}
+	return p, nil
+}
+
+// accept serves every worker connecting to the listener.
+func (p *workerPool) accept() {
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.serve(conn.RemoteAddr().String(), newRemoteConn(conn))
+	}
+}
+
+// sshPipe joins the standard input and output of an ssh process.
+type sshPipe struct {
+	io.Reader
+	io.WriteCloser
+}
+
+// startSSH runs "lattice-labs worker -stdio" on host with ssh and serves it
+// over the standard input and output of ssh; the worker's diagnostics go to
+// standard error.
+func (p *workerPool) startSSH(host string) error {
+	cmd := exec.Command("ssh", "-o", "BatchMode=yes", host, "lattice-labs", "worker", "-stdio")
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.ssh = append(p.ssh, cmd)
+	p.mu.Unlock()
+	go p.serve(host, newRemoteConn(sshPipe{stdout, stdin}))
+	return nil
+}
+
+// Close stops accepting workers and disconnects them, which ends the
+// workers started over SSH.
+func (p *workerPool) Close() {
+	if p.listener != nil {
+		p.listener.Close()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.conns {
+		c.rw.Close()
+	}
+	for _, cmd := range p.ssh {
+		cmd.Wait()
+	}
+}
+
+// serve hands jobs to the worker on c until it disconnects, and then hands
+// its unfinished jobs to the other workers.
+func (p *workerPool) serve(name string, c *remoteConn) {
+	hello, err := c.receive()
+	if err != nil || hello.Type != "hello" {
+		slog.Warn("worker failed to connect", "worker", name, "err", err)
+		c.rw.Close()
+		return
+	}
+	slog.Info("worker connected", "worker", name, "fplll", hello.Version)
+	if backend.Version.Major != 0 && hello.Version != backend.Version.Line {
+		slog.Warn("worker runs another fplll version; its output is parsed as the local version's", "worker", name,
+			"fplll", hello.Version, "local", backend.Version.Line)
+	}
+	p.mu.Lock()
+	p.conns = append(p.conns, c)
+	p.mu.Unlock()
+
+	var mu sync.Mutex
+	running := map[uint64]*remoteJob{}
+	gone := make(chan struct{})
+	defer func() {
+		close(gone)
+		c.rw.Close()
+		mu.Lock()
+		defer mu.Unlock()
+		for _, job := range running {
+			go p.submit(job)
+		}
+	}()
+	for {
+		m, err := c.receive()
+		if err != nil {
+			mu.Lock()
+			slog.Info("worker disconnected", "worker", name, "running", len(running), "err", err)
+			mu.Unlock()
+			return
+		}
+		switch m.Type {
+		case "next":
+			go func() {
+				var job *remoteJob
+				select {
+				case job = <-p.jobs:
+				case <-gone:
+					return
+				}
+				mu.Lock()
+				running[job.msg.ID] = job
+				mu.Unlock()
+				slog.Debug("sending fplll call to worker", "worker", name, "args", job.msg.Args)
+				if c.send(job.msg) != nil {
+					return // resubmitted once the connection is found closed
+				}
+				job.start.Do(func() { close(job.started) })
+				select {
+				case <-job.ctx.Done():
+					c.send(remoteMessage{Type: "cancel", ID: job.msg.ID})
+				case <-job.ended:
+				case <-gone:
+				}
+			}()
+		case "result":
+			mu.Lock()
+			job := running[m.ID]
+			delete(running, m.ID)
+			mu.Unlock()
+			if job == nil {
+				continue
+			}
+			select {
+			case job.done <- m:
+				close(job.ended)
+			default: // answered before, by a worker thought gone
+			}
+		}
+	}
+}
+
+// submit queues job for the next worker asking for one, unless the call is
+// cancelled first.
+func (p *workerPool) submit(job *remoteJob) {
+	select {
+	case p.jobs <- job:
+	case <-job.ctx.Done():
+	}
+}
+
+// runFplllRemote runs an fplll call on a worker of the pool as described for
+// runFplll, and parses its output as the local fplll's. b.Timeout counts
+// from when a worker takes the call, not while it waits for a free one.
+func (b backendConfig) runFplllRemote(ctx context.Context, basis Basis, args []string, parse func(io.Reader) error) error {
+	defer trace.StartRegion(ctx, "fplll").End()
+	var text bytes.Buffer
+	if err := writeBasis(&text, basis); err != nil {
+		return err
+	}
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	job := &remoteJob{
+		ctx:     jobCtx,
+		msg:     remoteMessage{Type: "job", ID: workers.nextID.Add(1), Args: args, Basis: text.String()},
+		started: make(chan struct{}),
+		done:    make(chan remoteMessage, 1),
+		ended:   make(chan struct{}),
+	}
+	go workers.submit(job)
+	var (
+		res      remoteMessage
+		started  = job.started
+		timeout  <-chan time.Time
+		timedOut bool
+	)
+wait:
+	for {
+		select {
+		case <-started:
+			started = nil
+			if b.Timeout > 0 {
+				timer := time.NewTimer(b.Timeout)
+				defer timer.Stop()
+				timeout = timer.C
+			}
+		case res = <-job.done:
+			break wait
+		case <-timeout:
+			timedOut = true
+			cancel()
+			break wait
+		case <-ctx.Done():
+			break wait
+		}
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case timedOut:
+		return fmt.Errorf("%w after %s", errTimeout, b.Timeout)
+	case res.Timeout:
+		return fmt.Errorf("%w on the worker: %s", errTimeout, res.Error)
+	case res.Error != "":
+		return &fplllError{Failure: res.Failure, Stderr: res.Stderr, Err: errors.New(res.Error)}
+	}
+	if err := parse(strings.NewReader(res.Output)); err != nil {
+		return b.badOutputError(err, res.Stderr)
+	}
+	tourLogOf(ctx).add(res.Tours)
+	tourLogOf(ctx).addProfiles(res.TourProfiles)
+	return nil
+}
+
+// workerConfig holds the arguments of the worker command.
+type workerConfig struct {
+	Addr  string // coordinator address, unless Stdio
+	Stdio bool   // talk to the coordinator on standard input and output
+	Jobs  int    // fplll calls run at once
+}
+
+// parseWorkerFlags builds a workerConfig from the worker command line.
+func parseWorkerFlags(args []string) (workerConfig, error) {
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	stdio := fs.Bool("stdio", false, "talk to the coordinator on standard input and output (as started by --ssh-workers)")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "fplll calls run at once")
+	if err := fs.Parse(args); err != nil {
+		return workerConfig{}, err
+	}
+	cfg := workerConfig{Stdio: *stdio, Jobs: *jobs}
+	if cfg.Stdio != (fs.NArg() == 0) || fs.NArg() > 1 {
+		return cfg, fmt.Errorf("usage: lattice-labs worker [-jobs n] host:port | -stdio")
+	}
+	cfg.Addr = fs.Arg(0)
+	if cfg.Jobs < 1 {
+		return cfg, fmt.Errorf("-jobs must be at least 1, got %d", cfg.Jobs)
+	}
+	return cfg, nil
+}
+
+// stdioConn is the standard input and output of the process as a
+// connection.
+type stdioConn struct{}
+
+func (stdioConn) Read(b []byte) (int, error)  { return os.Stdin.Read(b) }
+func (stdioConn) Write(b []byte) (int, error) { return os.Stdout.Write(b) }
+func (stdioConn) Close() error                { return os.Stdin.Close() }
+
+// runWorker serves fplll calls for a coordinator: it connects to it (or
+// talks on standard input and output), asks for up to cfg.Jobs calls at a
+// time, runs each with the local fplll and sends back its output. It
+// returns when the coordinator disconnects or ctx is cancelled.
+func runWorker(ctx context.Context, cfg workerConfig) error {
+	var rw io.ReadWriteCloser = stdioConn{}
+	if !cfg.Stdio {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", cfg.Addr)
+		if err != nil {
+			return err
+		}
+		rw = conn
+	}
+	c := newRemoteConn(rw)
+	stop := context.AfterFunc(ctx, func() { rw.Close() })
+	defer stop()
+
+	if err := c.send(remoteMessage{Type: "hello", Version: backend.Version.Line}); err != nil {
+		return err
+	}
+	for range cfg.Jobs {
+		if err := c.send(remoteMessage{Type: "next"}); err != nil {
+			return err
+		}
+	}
+	slog.Info("worker ready", "coordinator", cmp.Or(cfg.Addr, "stdio"), "jobs", cfg.Jobs, "fplll", backend.Version.String())
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		running = map[uint64]context.CancelFunc{}
+	)
+	defer wg.Wait()
+	for {
+		m, err := c.receive()
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) || ctx.Err() != nil {
+				slog.Info("coordinator disconnected, worker done")
+				return nil
+			}
+			return err
+		}
+		switch m.Type {
+		case "job":
+			jobCtx, cancel := context.WithCancel(ctx)
+			mu.Lock()
+			running[m.ID] = cancel
+			mu.Unlock()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res := runRemoteJob(jobCtx, m)
+				mu.Lock()
+				delete(running, m.ID)
+				mu.Unlock()
+				cancel()
+				c.send(res)
+				c.send(remoteMessage{Type: "next"})
+			}()
+		case "cancel":
+			mu.Lock()
+			if cancel := running[m.ID]; cancel != nil {
+				cancel()
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+// runRemoteJob runs the fplll call of a job with the local fplll and
+// returns the result message.
+func runRemoteJob(ctx context.Context, job remoteMessage) remoteMessage {
+	res := remoteMessage{Type: "result", ID: job.ID}
+	basis, err := scanBracketRows(strings.NewReader(job.Basis))
+	if err != nil {
+		res.Error, res.Failure = fmt.Sprintf("worker could not read the basis: %v", err), failureBadInput
+		return res
+	}
+	var out []byte
+	var tours tourLog
+	err = backend.runFplll(withTourLog(ctx, &tours), basis, job.Args, func(r io.Reader) (err error) {
+		out, err = io.ReadAll(r)
+		return err
+	})
+	res.Output, res.Tours, res.TourProfiles = string(out), tours.Tours(), tours.Profiles()
+	var fe *fplllError
+	switch {
+	case errors.Is(err, errTimeout):
+		res.Timeout, res.Error = true, err.Error()
+	case errors.As(err, &fe):
+		res.Error, res.Failure, res.Stderr = fe.Err.Error(), fe.Failure, fe.Stderr
+	case err != nil:
+		res.Error = err.Error()
+	}
+	return res
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log/slog"
+	"math/big"
+	"time"
+
+	mrand "math/rand/v2"
+)
+
+// retryable reports whether a failed fplll call may succeed when repeated.
+// Failures of the fplll process other than a missing executable, an
+// unreadable basis or output of an unexpected format are assumed to be
+// transient. Precision failures only
+// depend on the basis, so they are retried only if it is rerandomized.
+// Timeouts and cancellation are never retried.
+func (b backendConfig) retryable(err error) bool {
+	var fe *fplllError
+	if !errors.As(err, &fe) {
+		return false
+	}
+	switch fe.Failure {
+	case failureNotInstalled, failureBadInput, failureBadOutput:
+		return false
+	case failurePrecision:
+		return b.Rerandomize
+	}
+	return true
+}
+
+// runFplllWithRetries runs fplll like runFplll and repeats a call that
+// failed in a retryable way up to b.Retries times, waiting b.RetryBackoff
+// before the first retry and twice as long before each further one. With
+// b.Rerandomize every retry gets a new random basis of the same lattice, so
+// that fplll takes a different path through the reduction; its results
+// remain valid for the original basis. The error of the last attempt is
+// returned.
+func (b backendConfig) runFplllWithRetries(ctx context.Context, basis Basis, args []string, parse func(io.Reader) error) error {
+	delay := b.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := b.runFplll(ctx, basis, args, parse)
+		if err == nil || attempt > b.Retries || !b.retryable(err) {
+			return err
+		}
+		slog.Warn("fplll failed, retrying", "args", args, "rank", len(basis), "retry", attempt, "of", b.Retries, "delay", delay, "err", err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+		// A transformation fplll prints would be that of the rerandomized
+		// basis, so such calls are retried on the same one.
+		if b.Rerandomize && !isTransformOutput(args) {
+			fingerprint := basisFingerprint(basis)
+			rng := trialRand("rerandomize", fingerprint, int64(attempt))
+			switch {
+			case isCVP(args):
+				// The last row is the target, which must stay as it is.
+				basis = append(rerandomizeBasis(basis[:len(basis)-1], rng), basis[len(basis)-1])
+			case isGram(args):
+				basis = rerandomizeGram(basis, func() *mrand.Rand {
+					return trialRand("rerandomize", fingerprint, int64(attempt))
+				})
+			default:
+				basis = rerandomizeBasis(basis, rng)
+			}
+		}
+	}
+}
+
+// rerandomizeBasis returns another basis of the same lattice: the rows of
+// basis are shuffled and every row gets ±1 times a few random other rows
+// added, which amounts to multiplying by a random unimodular matrix.
+func rerandomizeBasis(basis Basis, rng *mrand.Rand) Basis {
+	n := len(basis)
+	out := basis.Clone()
+	if n < 2 {
+		return out
+	}
+	rng.Shuffle(n, func(i, j int) { out[i], out[j] = out[j], out[i] })
+	const mixes = 3 // rows added to each row
+	for i := range out {
+		for range mixes {
+			j := rng.IntN(n - 1)
+			if j >= i {
+				j++
+			}
+			add := (*big.Int).Add
+			if rng.IntN(2) == 0 {
+				add = (*big.Int).Sub
+			}
+			for k, v := range out[j] {
+				add(out[i][k], out[i][k], v)
+			}
+		}
+	}
+	return out
+}
+
+// basisFingerprint condenses a basis into a number for seeding its
+// rerandomization, so that a seeded run rerandomizes every basis the same
+// way regardless of the order of the calls.
+func basisFingerprint(basis Basis) int64 {
+	h := sha256.New()
+	for _, row := range basis {
+		for _, v := range row {
+			h.Write(v.Bytes())
+			h.Write([]byte{byte(v.Sign() + 1)})
+		}
+	}
+	return int64(binary.LittleEndian.Uint64(h.Sum(nil)))
+}
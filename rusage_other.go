@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// peakRSS reports that the peak memory of a process isn't known; resource
+// usage is only read on Unix.
+func peakRSS(state *os.ProcessState) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// peakRSS returns the peak resident memory in bytes of the exited process,
+// from its resource usage.
+func peakRSS(state *os.ProcessState) (uint64, bool) {
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || ru.Maxrss <= 0 {
+		return 0, false
+	}
+	// ru_maxrss is in bytes on macOS and in kilobytes elsewhere.
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return uint64(ru.Maxrss), true
+	}
+	return uint64(ru.Maxrss) * 1024, true
+}
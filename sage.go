@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"os/exec"
+	"strconv"
+)
+
+// defaultSage is the name SageMath is looked up by unless --sage names
+// another executable.
+const defaultSage = "sage"
+
+// sageInput is the start of every script sageScript generates: it reads the
+// basis from standard input, where it is written in fplll's format, into
+// the integer matrix B, and defines how results are printed, a row per
+// line in brackets as fplll prints them, since Sage's own vectors print as
+// tuples.
+const sageInput = `import sys, re
+B = matrix(ZZ, [[ZZ(x) for x in r.split()] for r in re.findall(r"\[([^\[\]]*)\]", sys.stdin.read())])
+def out(rows):
+    for r in rows:
+        print("[" + " ".join(str(x) for x in r) + "]")
+`
+
+// sageAvailable reports why Sage can't be run, or returns "".
+func sageAvailable() string {
+	if _, err := exec.LookPath(expandHome(backend.Sage)); err != nil {
+		return fmt.Sprintf("%s not found", backend.Sage)
+	}
+	return ""
+}
+
+// sageReduction returns the Sage expression reducing B like step: LLL with
+// its δ and η, BKZ with its block size, and HKZ as BKZ in the full rank.
+// Sage's LLL and BKZ call fplll's library, so the results should agree with
+// fplll's up to the choices the algorithms leave open; slide reduction,
+// flatter and the BKZ 2.0 controls have no counterpart and are refused.
+func sageReduction(step reductionStep) (string, error) {
+	if step.bkzOptions != (bkzOptions{}) || step.BestOf > 1 {
+		return "", fmt.Errorf("the sage backend supports no BKZ options or best-of steps")
+	}
+	switch step.algo() {
+	case "lll":
+		o := step.lllOptions
+		if o.Delta == 0 {
+			o.Delta = defaultLLLDelta
+		}
+		if o.Eta == 0 {
+			o.Eta = defaultLLLEta
+		}
+		return fmt.Sprintf("B.LLL(delta=%s, eta=%s)", strconv.FormatFloat(o.Delta, 'g', -1, 64), strconv.FormatFloat(o.Eta, 'g', -1, 64)), nil
+	case "bkz":
+		return fmt.Sprintf("B.BKZ(block_size=%d)", step.Beta), nil
+	case "hkz":
+		return "B.BKZ(block_size=B.nrows())", nil
+	default:
+		return "", fmt.Errorf("the sage backend has no %s reduction", step.algo())
+	}
+}
+
+// runSage runs the script after sageInput with sage -c on basis and returns
+// the rows it printed. In a dry run the call is only planned and no rows
+// are returned.
+func runSage(ctx context.Context, basis Basis, script string) (Basis, error) {
+	if dryRun != nil {
+		planCommand(backend.Sage, basis, "-c", strconv.Quote(script))
+		return nil, nil
+	}
+	out, err := runExternal(ctx, "sage", backend.Sage, []string{"-c", sageInput + script}, basis)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := scanBracketRows(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("sage's output: %w", err)
+	}
+	return rows, nil
+}
+
+// sageReduce reduces basis with SageMath as described for sageReduction.
+// In a dry run the basis is returned as it is.
+func sageReduce(ctx context.Context, basis Basis, step reductionStep) (Basis, error) {
+	expr, err := sageReduction(step)
+	if err != nil {
+		return nil, err
+	}
+	// Reductions of a full-rank basis have no zero rows to drop.
+	rows, err := runSage(ctx, basis, "out(("+expr+").rows())\n")
+	switch {
+	case err != nil:
+		return nil, err
+	case dryRun != nil:
+		return basis, nil
+	}
+	if err := checkReducedBasis(basis, rows); err != nil {
+		return nil, fmt.Errorf("sage's output: %w", err)
+	}
+	return rows, nil
+}
+
+// sageShortestVector returns a shortest non-zero vector of the lattice of
+// basis found by Sage's IntegerLattice, which enumerates with fplll's
+// library as well. In a dry run the first basis vector is returned.
+func sageShortestVector(ctx context.Context, basis Basis) ([]*big.Int, error) {
+	rows, err := runSage(ctx, basis, "out([IntegerLattice(B).shortest_vector()])\n")
+	switch {
+	case err != nil:
+		return nil, err
+	case dryRun != nil:
+		return basis[0], nil
+	case len(rows) != 1 || len(rows[0]) != len(basis[0]):
+		return nil, fmt.Errorf("sage's output: expected one vector with %d entries", len(basis[0]))
+	}
+	return rows[0], nil
+}
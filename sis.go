@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	mrand "math/rand/v2"
+	"slices"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// errSISAboveBound is returned, wrapped, for a vector of the kernel lattice
+// longer than the bound of the SIS instance.
+var errSISAboveBound = errors.New("the vector is longer than the SIS bound")
+
+// SISParams are the parameters of an SIS problem: find a non-zero x ∈ ℤ^M
+// with A·x = 0 mod Q and ‖x‖ ≤ Bound for a uniform N×M matrix A.
+type SISParams struct {
+	N, M  int
+	Q     int64
+	Bound float64
+}
+
+// validate checks that p describes SIS instances Sample can draw.
+func (p SISParams) validate() error {
+	switch {
+	case p.N < 1:
+		return fmt.Errorf("SIS dimension must be at least 1, got %d", p.N)
+	case p.M <= p.N:
+		return fmt.Errorf("SIS needs more columns than rows, got %d×%d", p.N, p.M)
+	case p.Q < 2 || p.Q >= maxLWEModulus:
+		return fmt.Errorf("SIS modulus must be in [2, 2^31), got %d", p.Q)
+	case !(p.Bound > 0) || math.IsInf(p.Bound, 0):
+		return fmt.Errorf("SIS bound must be positive, got %g", p.Bound)
+	}
+	return nil
+}
+
+// minkowskiSISBound returns √m·q^(n/m), the bound within which Minkowski's
+// theorem guarantees an SIS instance a solution: the kernel lattice has
+// rank m and volume at most qⁿ.
+func minkowskiSISBound(n, m int, q int64) float64 {
+	return math.Sqrt(float64(m)) * math.Pow(float64(q), float64(n)/float64(m))
+}
+
+// AttackBeta returns the block size Solve is run with for -beta auto: the
+// least one with which the GSA expects the first vector of the reduced
+// kernel lattice, of rank m and volume qⁿ, within the bound
+// (BetaForLength), or m, reducing the whole basis, if none is.
+func (p SISParams) AttackBeta() int {
+	beta, ok := BetaForLength(p.M, float64(p.N)*math.Log2(float64(p.Q)), p.Bound, p.M)
+	if !ok {
+		return p.M
+	}
+	return beta
+}
+
+// SISInstance is an SIS instance, a uniform matrix A mod q.
+type SISInstance struct {
+	SISParams
+	A [][]int64 // N×M, entries in [0, q)
+}
+
+// Sample draws an SIS instance with parameters p, which must be valid.
+func (p SISParams) Sample(rng *mrand.Rand) *SISInstance {
+	inst := &SISInstance{SISParams: p, A: make([][]int64, p.N)}
+	for i := range inst.A {
+		inst.A[i] = make([]int64, p.M)
+		for j := range inst.A[i] {
+			inst.A[i][j] = rng.Int64N(p.Q)
+		}
+	}
+	return inst
+}
+
+// KernelBasis returns a basis of the q-ary kernel lattice of the instance,
+// {x ∈ ℤ^M : A·x = 0 mod q}, whose short vectors are the solutions. A is
+// brought to reduced row echelon form mod q, with pivots invertible mod q,
+// as [I | R] up to the order of the columns; the basis is q·eₚ for the
+// pivot columns p, followed by the vectors eⱼ - R·eⱼ mod q of the free
+// columns j, of volume q^rank(A). A composite q whose factors leave a row
+// without an invertible pivot makes it fail.
+func (inst *SISInstance) KernelBasis() (Basis, error) {
+	q := inst.Q
+	a := make([][]int64, inst.N)
+	for i, row := range inst.A {
+		a[i] = slices.Clone(row)
+	}
+	pivots, err := rowReduceMod(a, q)
+	if err != nil {
+		return nil, fmt.Errorf("A: %w", err)
+	}
+	basis := make(Basis, 0, inst.M)
+	newRow := func() []*big.Int {
+		row := make([]*big.Int, inst.M)
+		for k := range row {
+			row[k] = new(big.Int)
+		}
+		return row
+	}
+	for _, p := range pivots {
+		row := newRow()
+		row[p].SetInt64(q)
+		basis = append(basis, row)
+	}
+	for j := range inst.M {
+		if slices.Contains(pivots, j) {
+			continue
+		}
+		row := newRow()
+		row[j].SetInt64(1)
+		for k, p := range pivots {
+			row[p].SetInt64((q - a[k][j]) % q)
+		}
+		basis = append(basis, row)
+	}
+	return basis, nil
+}
+
+// rowReduceMod brings a to reduced row echelon form mod q in place, with
+// pivots 1, and returns the pivot columns; the rows past them end up zero.
+// Each pivot must be invertible mod q, which only a composite q can
+// prevent: a row left non-zero without one is an error.
+func rowReduceMod(a [][]int64, q int64) (pivots []int, err error) {
+	r := 0
+	for c := 0; len(a) > 0 && c < len(a[0]) && r < len(a); c++ {
+		p := slices.IndexFunc(a[r:], func(row []int64) bool { _, ok := modInverse(row[c], q); return ok })
+		if p < 0 {
+			continue
+		}
+		a[r], a[r+p] = a[r+p], a[r]
+		inv, _ := modInverse(a[r][c], q)
+		for j := range a[r] {
+			a[r][j] = a[r][j] * inv % q
+		}
+		for i := range a {
+			if f := a[i][c]; i != r && f != 0 {
+				for j := range a[i] {
+					a[i][j] = ((a[i][j]-f*a[r][j])%q + q) % q
+				}
+			}
+		}
+		pivots = append(pivots, c)
+		r++
+	}
+	for _, row := range a[r:] {
+		if slices.ContainsFunc(row, func(x int64) bool { return x != 0 }) {
+			return nil, fmt.Errorf("no invertible pivot mod %d in row %d", q, r+1)
+		}
+	}
+	return pivots, nil
+}
+
+// modInverse returns the inverse of a mod q and whether there is one.
+func modInverse(a, q int64) (int64, bool) {
+	inv := new(big.Int).ModInverse(big.NewInt(a), big.NewInt(q))
+	if inv == nil {
+		return 0, false
+	}
+	return inv.Int64(), true
+}
+
+// Verify checks that x solves the instance: non-zero, A·x = 0 mod q and
+// ‖x‖ ≤ Bound. A vector that only exceeds the bound yields an error
+// wrapping errSISAboveBound.
+func (inst *SISInstance) Verify(x []*big.Int) error {
+	if len(x) != inst.M {
+		return fmt.Errorf("the vector has %d entries, want %d", len(x), inst.M)
+	}
+	if normSquared(x).Sign() == 0 {
+		return errors.New("the vector is zero")
+	}
+	q := big.NewInt(inst.Q)
+	var sum, t big.Int
+	for i, row := range inst.A {
+		sum.SetInt64(0)
+		for j, a := range row {
+			sum.Add(&sum, t.Mul(big.NewInt(a), x[j]))
+		}
+		if sum.Mod(&sum, q).Sign() != 0 {
+			return fmt.Errorf("row %d of A·x is %v mod %d, not 0", i+1, &sum, inst.Q)
+		}
+	}
+	if norm := math.Sqrt(vectorNormSquared(x)); norm > inst.Bound {
+		return fmt.Errorf("norm %.4g > %.4g: %w", norm, inst.Bound, errSISAboveBound)
+	}
+	return nil
+}
+
+// Solve runs the short-solution pipeline on the instance: it builds the
+// kernel lattice, BKZ-β reduces it, takes the shortest non-zero row of the
+// reduced basis and verifies it. The candidate and the reduced basis are
+// returned; if the candidate is merely too long the error wraps
+// errSISAboveBound.
+func (inst *SISInstance) Solve(ctx context.Context, beta int) (x []*big.Int, reduced Basis, err error) {
+	kernel, err := inst.KernelBasis()
+	if err != nil {
+		return nil, nil, err
+	}
+	reduced, err = bkzReduce(ctx, kernel, beta, bkzOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("BKZ-%d: %w", beta, err)
+	}
+	if err := checkVolumePreserved(kernel, reduced); err != nil {
+		return nil, nil, fmt.Errorf("BKZ-%d: %w", beta, err)
+	}
+	for _, row := range reduced {
+		if n := normSquared(row); n.Sign() != 0 && (x == nil || n.Cmp(normSquared(x)) < 0) {
+			x = row
+		}
+	}
+	if x == nil {
+		return nil, reduced, errors.New("the reduced basis has no non-zero row")
+	}
+	return x, reduced, inst.Verify(x)
+}
+
+// sisConfig holds the arguments of the sis command.
+type sisConfig struct {
+	Params SISParams
+	Betas  []int
+	// AutoBeta is set if Betas is the one block size BetaForLength picked
+	// for -beta auto.
+	AutoBeta bool
+	Trials   int
+}
+
+// parseSISFlags builds a sisConfig from the command line.
+func parseSISFlags(args []string) (sisConfig, error) {
+	fs := flag.NewFlagSet("sis", flag.ContinueOnError)
+	n := fs.Int("n", 16, "number of rows of A")
+	m := fs.Int("m", 48, "number of columns of A, the rank of the kernel lattice")
+	q := fs.Int64("q", 3329, "modulus")
+	bound := fs.Float64("bound", 0, "norm bound of the solutions (default √m·q^(n/m), Minkowski's bound)")
+	betas := fs.String("beta", "2,10,20", "comma-separated BKZ block sizes, or auto for the least one the GSA expects to reach the bound")
+	trials := fs.Int("trials", 3, "SIS instances per block size")
+	if err := fs.Parse(args); err != nil {
+		return sisConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return sisConfig{}, fmt.Errorf("usage: lattice-labs sis [-n n] [-m m] [-q q] [-bound b] [-beta betas] [-trials k]")
+	}
+	cfg := sisConfig{Params: SISParams{N: *n, M: *m, Q: *q, Bound: *bound}, Trials: *trials}
+	if cfg.Params.Bound == 0 {
+		cfg.Params.Bound = minkowskiSISBound(*n, *m, *q)
+	}
+	if err := cfg.Params.validate(); err != nil {
+		return cfg, err
+	}
+	if *betas == "auto" {
+		cfg.Betas, cfg.AutoBeta = []int{cfg.Params.AttackBeta()}, true
+	} else {
+		betaList, err := parseIntList(*betas)
+		if err != nil {
+			return cfg, fmt.Errorf("-beta: %w", err)
+		}
+		for _, beta := range betaList {
+			if err := bkzStep(int(beta)).validate(); err != nil {
+				return cfg, fmt.Errorf("-beta: %w", err)
+			}
+			cfg.Betas = append(cfg.Betas, int(beta))
+		}
+	}
+	if cfg.Trials < 1 {
+		return cfg, fmt.Errorf("-trials must be at least 1")
+	}
+	return cfg, nil
+}
+
+// runSIS runs the SIS lab: the same cfg.Trials instances are solved by
+// SISInstance.Solve with every block size, and for each the norm of the
+// solution found is set against the norm the reduced profile predicts for
+// the first basis vector under the geometric series assumption, vol^(1/m)
+// times 2^(-slope·(m-1)/2) for the slope of the profile. One row per block
+// size is written to w, with the share of instances solved within the
+// bound, then the correlation of the achieved and the predicted log norms
+// over all instances. Candidates that aren't in the kernel lattice are
+// returned as an *assertionError.
+func runSIS(ctx context.Context, w io.Writer, cfg sisConfig) error {
+	if dryRun != nil {
+		return fmt.Errorf("sis reduces kernel lattices and has no dry run")
+	}
+	p := cfg.Params
+	fmt.Fprintf(w, "SIS short solutions (n %d, m %d, q %d, bound %.4g)\n", p.N, p.M, p.Q, p.Bound)
+	if cfg.AutoBeta {
+		fmt.Fprintf(w, "Block size %d picked by the GSA for ‖b1‖ ≤ %.4g\n", cfg.Betas[0], p.Bound)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%-6s | %-10s | %-14s | %-14s | %s\n", "Beta", "Slope", "Predicted ‖x‖", "Achieved ‖x‖", "Solved")
+	fmt.Fprintln(w, "--------------------------------------------------------------------")
+
+	var violations []string
+	var predicted, achieved []float64
+	for _, beta := range cfg.Betas {
+		var slopes, pred, got []float64
+		solved := 0
+		for trial := 1; trial <= cfg.Trials; trial++ {
+			inst := p.Sample(trialRand("sis", int64(p.N), int64(p.M), p.Q, int64(trial)))
+			x, reduced, err := inst.Solve(ctx, beta)
+			if ctx.Err() != nil {
+				return nil
+			}
+			switch {
+			case err == nil:
+				solved++
+			case !errors.Is(err, errSISAboveBound):
+				violations = append(violations, fmt.Sprintf("beta %d trial %d: %v", beta, trial, err))
+				continue
+			}
+			profile := reduced.Profile()
+			slope := profile.Slope()
+			logPred := gsaProfile(p.M, reduced.LogVolume(), slope)[0]
+			logGot := math.Log2(vectorNormSquared(x)) / 2
+			slopes, pred, got = append(slopes, slope), append(pred, logPred), append(got, logGot)
+		}
+		predicted, achieved = append(predicted, pred...), append(achieved, got...)
+		if len(slopes) == 0 {
+			fmt.Fprintf(w, "%-6d | %-10s | %-14s | %-14s | %d/%d\n", beta, "-", "-", "-", solved, cfg.Trials)
+			continue
+		}
+		fmt.Fprintf(w, "%-6d | %-10.6f | %-14.4g | %-14.4g | %d/%d\n", beta, stat.Mean(slopes, nil), math.Exp2(stat.Mean(pred, nil)), math.Exp2(stat.Mean(got, nil)), solved, cfg.Trials)
+	}
+	// The correlation is undefined (NaN) if either side doesn't vary.
+	if c := stat.Correlation(predicted, achieved, nil); len(predicted) > 1 && !math.IsNaN(c) {
+		fmt.Fprintf(w, "\nCorrelation of achieved and predicted log2 ‖x‖: %.3f over %d instances\n", c, len(predicted))
+	}
+	if len(violations) > 0 {
+		return &assertionError{Kind: "SIS verification failure(s)", Violations: violations}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log/slog"
+	"math/big"
+)
+
+// sizeReduce returns basis size-reduced: from every bᵢ, integer multiples
+// of the bⱼ before it are subtracted, from j = i-1 down to 1, until all
+// Gram-Schmidt coefficients satisfy |μᵢⱼ| ≤ 1/2. This is the first half of
+// every LLL iteration, the swaps by the Lovász condition being the other.
+// On its own it leaves the Gram-Schmidt vectors, and so the profile, as
+// they are and only shortens the basis vectors, which makes it a cheap
+// preprocessing step for bases with huge entries and a way to watch what
+// LLL does between its swaps. The coefficients are kept as the integers of
+// independentGSO, so the result is exact; the rows must be linearly
+// independent.
+func sizeReduce(basis Basis) (Basis, error) {
+	d, lambda, err := independentGSO(basis.Gram())
+	if err != nil {
+		return nil, err
+	}
+	b := basis.Clone()
+	q, t, twice := new(big.Int), new(big.Int), new(big.Int)
+	ops := 0
+	for k := 1; k < len(b); k++ {
+		for l := k - 1; l >= 0; l-- {
+			// |μₖₗ| > 1/2, i.e. 2|λₖₗ| > dₗ₊₁: subtract round(μₖₗ)·bₗ.
+			if twice.Lsh(lambda[k][l], 1).CmpAbs(d[l+1]) <= 0 {
+				continue
+			}
+			q.Add(twice, d[l+1])
+			q.Div(q, t.Lsh(d[l+1], 1)) // ⌊μₖₗ + 1/2⌋
+			for j := range b[k] {
+				b[k][j].Sub(b[k][j], t.Mul(q, b[l][j]))
+			}
+			lambda[k][l].Sub(lambda[k][l], t.Mul(q, d[l+1]))
+			for i := range l {
+				lambda[k][i].Sub(lambda[k][i], t.Mul(q, lambda[l][i]))
+			}
+			ops++
+		}
+	}
+	slog.Debug("size reduction", "rank", len(b), "row_operations", ops)
+	return b, nil
+}
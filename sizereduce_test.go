@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+// ratGramSchmidt returns the Gram-Schmidt coefficients μᵢⱼ of b, computed
+// by the textbook recursion over the rationals, independently of the
+// integral Gram-Schmidt the code under test uses.
+func ratGramSchmidt(b Basis) [][]*big.Rat {
+	star := make([][]*big.Rat, len(b))
+	norms := make([]*big.Rat, len(b))
+	mu := make([][]*big.Rat, len(b))
+	var t big.Rat
+	for i, row := range b {
+		star[i] = make([]*big.Rat, len(row))
+		for k, v := range row {
+			star[i][k] = new(big.Rat).SetInt(v)
+		}
+		mu[i] = make([]*big.Rat, i)
+		for j := range i {
+			dot := new(big.Rat)
+			for k, v := range row {
+				dot.Add(dot, t.Mul(t.SetInt(v), star[j][k]))
+			}
+			mu[i][j] = dot.Quo(dot, norms[j])
+			for k := range star[i] {
+				star[i][k].Sub(star[i][k], t.Mul(mu[i][j], star[j][k]))
+			}
+		}
+		norms[i] = new(big.Rat)
+		for _, v := range star[i] {
+			norms[i].Add(norms[i], t.Mul(v, v))
+		}
+	}
+	return mu
+}
+
+// TestSizeReduce checks that every |μᵢⱼ| ≤ 1/2 after the step and that the
+// lattice is the same: the volume is unchanged and every original basis
+// vector is still a lattice vector of the result.
+func TestSizeReduce(t *testing.T) {
+	half := big.NewRat(1, 2)
+	for _, tc := range []struct {
+		name  string
+		basis Basis
+	}{
+		{"sheared", basisOf([]int64{1, 0, 0}, []int64{1234, 1, 0}, []int64{-987, 555, 1})},
+		{"halves", basisOf([]int64{2, 0}, []int64{3, 1})},
+		{"rectangular", basisOf([]int64{3, 1, 4, 1}, []int64{50, 92, 65, 35}, []int64{-89, 79, 32, 384})},
+		{"q-ary", qaryBasis(12, 6, 97, 1)},
+		{"reduced", basisOf([]int64{1, 0}, []int64{0, 1})},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			orig := tc.basis.Clone()
+			reduced, err := sizeReduce(tc.basis)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range tc.basis {
+				for j := range tc.basis[i] {
+					if tc.basis[i][j].Cmp(orig[i][j]) != 0 {
+						t.Fatalf("sizeReduce modified its argument at (%d, %d)", i+1, j+1)
+					}
+				}
+			}
+			for i, row := range ratGramSchmidt(reduced) {
+				for j, mu := range row {
+					if new(big.Rat).Abs(mu).Cmp(half) > 0 {
+						t.Errorf("|μ(%d,%d)| = %s exceeds 1/2", i+1, j+1, mu.RatString())
+					}
+				}
+			}
+			if got, want := reduced.SquaredVolume(), tc.basis.SquaredVolume(); got.Cmp(want) != 0 {
+				t.Errorf("squared volume %s, want %s", got, want)
+			}
+			for i, v := range tc.basis {
+				if _, err := latticeCoordinates(reduced, v); err != nil {
+					t.Errorf("row %d is no longer in the lattice: %v", i+1, err)
+				}
+			}
+		})
+	}
+	if _, err := sizeReduce(basisOf([]int64{1, 2}, []int64{2, 4})); err == nil {
+		t.Error("sizeReduce accepted linearly dependent rows")
+	}
+}
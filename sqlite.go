@@ -0,0 +1,203 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema creates the tables of a results database. Every rendered
+// result set adds one row to runs; the lab tables reference it, so results
+// can be compared across runs with plain SQL.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id            INTEGER PRIMARY KEY,
+	seed          TEXT, -- uint64, beyond the range of INTEGER
+	fplll_version TEXT,
+	strategy      TEXT,
+	strategy_sha256 TEXT,
+	go_version    TEXT,
+	hostname      TEXT,
+	command       TEXT,
+	started       TEXT,
+	finished      TEXT,
+	wall_seconds  REAL
+);
+CREATE TABLE IF NOT EXISTS lab1 (
+	run_id                 INTEGER NOT NULL REFERENCES runs(id),
+	lab_run                INTEGER NOT NULL,
+	q                      TEXT,
+	reduction              TEXT,
+	svp_method             TEXT,
+	approx_factor          REAL,
+	n                      INTEGER,
+	trial                  INTEGER,
+	volume                 REAL,
+	gh                     REAL,
+	lambda1                REAL,
+	relative_error_percent REAL,
+	oracle_seconds         REAL,
+	beta                   INTEGER,
+	status                 TEXT
+);
+CREATE TABLE IF NOT EXISTS lab2 (
+	run_id            INTEGER NOT NULL REFERENCES runs(id),
+	lab_run           INTEGER NOT NULL,
+	rank              INTEGER,
+	q                 TEXT,
+	reduction         TEXT,
+	profile           BLOB,
+	slope             REAL,
+	intercept         REAL,
+	r2                REAL,
+	reduction_seconds REAL,
+	seconds           REAL,
+	status            TEXT
+);
+CREATE TABLE IF NOT EXISTS sweep (
+	run_id    INTEGER NOT NULL REFERENCES runs(id),
+	n         INTEGER,
+	beta      INTEGER,
+	q         INTEGER,
+	gh        REAL,
+	b1        REAL,
+	delta0    REAL,
+	slope     REAL,
+	seconds   REAL,
+	timed_out INTEGER,
+	failed    INTEGER
+);
+`
+
+// sqliteMigrations add the columns introduced after the first schema to
+// databases created by earlier versions. Each fails harmlessly with a
+// duplicate column error once applied.
+var sqliteMigrations = []string{
+	`ALTER TABLE lab1 ADD COLUMN status TEXT`,
+	`ALTER TABLE lab2 ADD COLUMN status TEXT`,
+	`ALTER TABLE sweep ADD COLUMN timed_out INTEGER`,
+	`ALTER TABLE sweep ADD COLUMN failed INTEGER`,
+	`ALTER TABLE runs ADD COLUMN strategy TEXT`,
+	`ALTER TABLE runs ADD COLUMN strategy_sha256 TEXT`,
+	`ALTER TABLE lab1 ADD COLUMN svp_method TEXT`,
+	`ALTER TABLE lab1 ADD COLUMN approx_factor REAL`,
+	`ALTER TABLE lab1 ADD COLUMN beta INTEGER`,
+}
+
+// appendSQLite appends the results to the SQLite database at path, creating
+// the database and its tables if needed. Everything is written in a single
+// transaction, so an interrupted write leaves no partial run behind.
+// Profiles are stored as blobs of little-endian float64 values.
+func appendSQLite(path string, res *runResults) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return err
+	}
+	for _, migration := range sqliteMigrations {
+		if _, err := db.Exec(migration); err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+			return err
+		}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	meta := res.Metadata
+	if meta == nil {
+		meta = &runMetadata{}
+	}
+	inserted, err := tx.Exec(`INSERT INTO runs (seed, fplll_version, strategy, strategy_sha256, go_version, hostname, command, started, finished, wall_seconds)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		strconv.FormatUint(meta.Seed, 10), meta.FplllVersion, meta.Strategy, meta.StrategySHA256, meta.GoVersion, meta.Hostname, strings.Join(meta.Command, " "),
+		meta.Started.Format(time.RFC3339Nano), meta.Finished.Format(time.RFC3339Nano), meta.WallSeconds)
+	if err != nil {
+		return err
+	}
+	runID, err := inserted.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for labRun, lab := range res.Lab1 {
+		reduction, err := json.Marshal(lab.Reduction)
+		if err != nil {
+			return err
+		}
+		for _, row := range lab.Rows {
+			_, err := tx.Exec(`INSERT INTO lab1 (run_id, lab_run, q, reduction, svp_method, approx_factor, n, trial, volume, gh, lambda1, relative_error_percent, oracle_seconds, beta, status)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				runID, labRun, lab.Q, string(reduction), lab.SVPMethod, sqlNullable(lab.ApproxFactor), row.N, row.Trial,
+				sqlFloat(float64(row.Volume)), sqlFloat(float64(row.GH)), sqlFloat(float64(row.Lambda1)),
+				sqlFloat(float64(row.RelativeError)), row.OracleSeconds, sqlNullable(row.Beta), row.Status)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for labRun, lab := range res.Lab2 {
+		reduction, err := json.Marshal(lab.Reduction)
+		if err != nil {
+			return err
+		}
+		slope, intercept, r2 := lab.Profile.Fit()
+		_, err = tx.Exec(`INSERT INTO lab2 (run_id, lab_run, rank, q, reduction, profile, slope, intercept, r2, reduction_seconds, seconds, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, labRun, lab.Rank, lab.Q, string(reduction), profileBlob(lab.Profile),
+			slope, intercept, r2, lab.ReductionSeconds, lab.Seconds, lab.Status)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, row := range res.Sweep {
+		_, err := tx.Exec(`INSERT INTO sweep (run_id, n, beta, q, gh, b1, delta0, slope, seconds, timed_out, failed)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, row.N, row.Beta, row.Q, sqlFloat(row.GH), sqlFloat(row.B1), sqlFloat(row.Delta), sqlFloat(row.Slope), row.Seconds, row.TimedOut, row.Failed)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// sqlFloat stores non-finite values, e.g. the error of a failed oracle
+// call, as NULL.
+func sqlFloat(v float64) any {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return v
+}
+
+// sqlNullable stores the zero value of a setting that wasn't used, e.g. the
+// approximation factor of an exact run, as NULL.
+func sqlNullable[T int | float64](v T) any {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+// profileBlob encodes a profile as consecutive little-endian float64 values.
+func profileBlob(profile []float64) []byte {
+	b := make([]byte, 8*len(profile))
+	for i, v := range profile {
+		binary.LittleEndian.PutUint64(b[8*i:], math.Float64bits(v))
+	}
+	return b
+}
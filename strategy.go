@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// defaultStrategy is the --strategy value naming the default pruning
+// strategies that come with fplll, and defaultStrategyFile their file name.
+const (
+	defaultStrategy     = "default"
+	defaultStrategyFile = "default.json"
+)
+
+// fplllStrategyDirs returns the directories fplll installs its strategy
+// files into: share/fplll/strategies next to the bin directory of the fplll
+// executable, then the usual prefixes.
+func fplllStrategyDirs() []string {
+	var dirs []string
+	if path, err := resolveFplll(backend.Binary); err == nil {
+		dirs = append(dirs, filepath.Join(filepath.Dir(filepath.Dir(path)), "share", "fplll", "strategies"))
+	}
+	for _, prefix := range []string{"/usr/local", "/usr", "/opt/homebrew", "/opt/local"} {
+		dirs = append(dirs, filepath.Join(prefix, "share", "fplll", "strategies"))
+	}
+	return dirs
+}
+
+// strategyEntry is the part of an entry of an fplll strategy file that is
+// checked before a run: fplll reads a JSON array of one such object per
+// block size, with its preprocessing block sizes and pruning coefficients.
+type strategyEntry struct {
+	BlockSize *int `json:"block_size"`
+}
+
+// useStrategy resolves backend.Strategy (--strategy) to the strategy file
+// passed to fplll -s by BKZ and slide reduction and records its SHA-256 in
+// backend.StrategyDigest, so that results and cached outputs are tied to
+// the pruning parameters actually used. "default" is fplll's own
+// default.json; if it isn't found in fplllStrategyDirs the bare name is
+// passed on for fplll to resolve against its built-in strategy path, with
+// no digest.
+func useStrategy() error {
+	path := expandHome(backend.Strategy)
+	if backend.Strategy == defaultStrategy {
+		path = ""
+		for _, dir := range fplllStrategyDirs() {
+			if candidate := filepath.Join(dir, defaultStrategyFile); fileExists(candidate) {
+				path = candidate
+				break
+			}
+		}
+		if path == "" {
+			slog.Warn("fplll's default strategy file not found, leaving it to fplll", "searched", fplllStrategyDirs())
+			backend.Strategy = defaultStrategyFile
+			return nil
+		}
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var entries []strategyEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("%s is not an fplll strategy file: %w", path, err)
+	}
+	for i, e := range entries {
+		if e.BlockSize == nil {
+			return fmt.Errorf("%s is not an fplll strategy file: entry %d has no block_size", path, i+1)
+		}
+	}
+	sum := sha256.Sum256(data)
+	backend.Strategy, backend.StrategyDigest = path, hex.EncodeToString(sum[:])
+	slog.Debug("using pruning strategies", "path", path, "sha256", backend.StrategyDigest)
+	return nil
+}
+
+// fileExists reports whether path names an existing regular file.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
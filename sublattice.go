@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/rand"
+	"io"
+	"math/big"
+)
+
+// randomIndexHNF returns a random n×n matrix in Hermite normal form with
+// determinant index, drawing from src: upper triangular, each prime factor
+// of index (with multiplicity) multiplied into the diagonal entry of a
+// random row, and the entries above each diagonal entry uniform in
+// [0, entry). Its rows span a random sublattice of index index of ℤⁿ; the
+// sublattices aren't drawn uniformly among all of that index, which would
+// weight the diagonals by how many matrices each admits.
+func randomIndexHNF(src io.Reader, n int, index int64) [][]*big.Int {
+	h := identityMatrix(n)
+	for _, p := range primeFactors(index) {
+		i := randomBelow(src, int64(n))
+		h[i][i].Mul(h[i][i], big.NewInt(p))
+	}
+	for j := range n {
+		for i := range j {
+			r, _ := rand.Int(src, h[j][j])
+			h[i][j] = r
+		}
+	}
+	return h
+}
+
+// randomBelow returns a number uniform in [0, n) drawn from src.
+func randomBelow(src io.Reader, n int64) int {
+	r, _ := rand.Int(src, big.NewInt(n))
+	return int(r.Int64())
+}
+
+// primeFactors returns the prime factors of n > 0 in ascending order, with
+// multiplicity, by trial division.
+func primeFactors(n int64) []int64 {
+	var factors []int64
+	for p := int64(2); p*p <= n; p++ {
+		for n%p == 0 {
+			factors = append(factors, p)
+			n /= p
+		}
+	}
+	if n > 1 {
+		factors = append(factors, n)
+	}
+	return factors
+}
+
+// randomSublattice returns a basis of a random sublattice of index index of
+// the lattice of basis, H·B for a matrix H of randomIndexHNF; its volume is
+// index times that of the lattice.
+func randomSublattice(src io.Reader, basis Basis, index int64) Basis {
+	return Basis(mulMatrices(randomIndexHNF(src, len(basis), index), basis))
+}
+
+// randomSuperlattice returns a random superlattice of index index of the
+// lattice of basis, spanned by H⁻¹·B for a matrix H of randomIndexHNF, which
+// contains the lattice as a sublattice of that index, so that its volume is
+// that of the lattice divided by index. The superlattice is rational; the
+// basis returned spans it multiplied by scale, the least positive integer
+// that makes it integral, a divisor of index.
+func randomSuperlattice(src io.Reader, basis Basis, index int64) (super Basis, scale *big.Int) {
+	// H is triangular with a positive diagonal, so never singular.
+	inv, _ := invertRat(randomIndexHNF(src, len(basis), index))
+	scale, super = integralMultiple(mulRatInt(inv, basis))
+	return super, scale
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sweepConfig lists the values visited by a grid search. Every combination
+// of dimension, block size and coefficient range is run Trials times.
+type sweepConfig struct {
+	Dims   []int
+	Betas  []int
+	Qs     []int64
+	Trials int
+	// BKZ bounds the tours of every reduction.
+	BKZ bkzOptions
+	// Checkpoint, if set, is the file receiving every completed row. With
+	// Resume the rows already in it are reused instead of recomputed.
+	Checkpoint string
+	Resume     bool
+}
+
+// sweepRow is the aggregated result of one (n, β, q) combination.
+type sweepRow struct {
+	N       int     `json:"n"`
+	Beta    int     `json:"beta"`
+	Q       int64   `json:"q"`
+	GH      float64 `json:"gh"`     // Gaussian Heuristic prediction for λ1
+	B1      float64 `json:"b1"`     // norm of the first reduced basis vector
+	Delta   float64 `json:"delta0"` // root Hermite factor (‖b1‖ / vol^(1/n))^(1/n)
+	Slope   float64 `json:"slope"`  // slope of the fitted log2 profile
+	Seconds float64 `json:"seconds"`
+	// Tours and Log2Nodes are the mean number of tours and log2 of the mean
+	// enumeration nodes of the trials fplll reported tours for
+	// (--tour-stats).
+	Tours     float64 `json:"tours,omitempty"`
+	Log2Nodes float64 `json:"log2_nodes,omitempty"`
+	// TimedOut and Failed count the trials stopped by --timeout and those
+	// where fplll failed; both are left out of the averages.
+	TimedOut int `json:"timed_out,omitempty"`
+	Failed   int `json:"failed,omitempty"`
+}
+
+// parseIntList parses a comma-separated list of integers such as "30,40,50".
+func parseIntList(s string) ([]int64, error) {
+	var values []int64
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		v, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", field)
+		}
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("empty list %q", s)
+	}
+	return values, nil
+}
+
+// parseFloatList parses a comma-separated list of finite real numbers.
+func parseFloatList(s string) ([]float64, error) {
+	var values []float64
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("invalid number %q", field)
+		}
+		values = append(values, v)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("empty list %q", s)
+	}
+	return values, nil
+}
+
+// parseSweepFlags builds a sweepConfig from the sweep command line.
+func parseSweepFlags(args []string) (sweepConfig, error) {
+	fs := flag.NewFlagSet("sweep", flag.ContinueOnError)
+	dims := fs.String("n", "30,40,50", "comma-separated lattice dimensions")
+	betas := fs.String("beta", "10,20", "comma-separated BKZ block sizes")
+	qs := fs.String("q", "131", "comma-separated coefficient bounds q")
+	trials := fs.Int("trials", 1, "random instances per combination (results are averaged)")
+	checkpoint := fs.String("checkpoint", "", "append every completed combination to this JSON Lines file")
+	resume := fs.Bool("resume", false, "skip the combinations already completed in the -checkpoint file")
+	bkz := bkzFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return sweepConfig{}, err
+	}
+
+	cfg := sweepConfig{Trials: *trials, BKZ: *bkz, Checkpoint: *checkpoint, Resume: *resume}
+	if err := cfg.BKZ.validate(); err != nil {
+		return cfg, err
+	}
+	if cfg.Trials < 1 {
+		return cfg, fmt.Errorf("trials must be at least 1")
+	}
+	if cfg.Resume && cfg.Checkpoint == "" {
+		return cfg, fmt.Errorf("-resume needs a -checkpoint file")
+	}
+	nList, err := parseIntList(*dims)
+	if err != nil {
+		return cfg, fmt.Errorf("-n: %w", err)
+	}
+	betaList, err := parseIntList(*betas)
+	if err != nil {
+		return cfg, fmt.Errorf("-beta: %w", err)
+	}
+	if cfg.Qs, err = parseIntList(*qs); err != nil {
+		return cfg, fmt.Errorf("-q: %w", err)
+	}
+	for _, q := range cfg.Qs {
+		if q < 2 {
+			return cfg, fmt.Errorf("-q must be at least 2, got %d", q)
+		}
+	}
+	for _, n := range nList {
+		if n < 1 {
+			return cfg, fmt.Errorf("-n: rank must be positive, got %d", n)
+		}
+		cfg.Dims = append(cfg.Dims, int(n))
+	}
+	for _, b := range betaList {
+		if err := bkzStep(int(b)).validate(); err != nil {
+			return cfg, fmt.Errorf("-beta: %w", err)
+		}
+		cfg.Betas = append(cfg.Betas, int(b))
+	}
+	return cfg, nil
+}
+
+// sweepTrial holds the metrics of one trial of a sweep combination; ok is
+// false if the trial failed, timed out or was cancelled, and status tells
+// the first two apart.
+type sweepTrial struct {
+	gh, b1, delta, slope float64
+	tours                int
+	nodes                float64
+	ok                   bool
+	status               string
+}
+
+// runSweepCombination reduces trials random bases of rank n with block
+// size beta and averages the resulting metrics into one row. ok is false if
+// every trial failed or ctx was cancelled; the row then still counts the
+// trials that failed or timed out. Up to backend.Jobs trials run
+// concurrently, each on a basis drawn from its own trialSource; every trial
+// stores its metrics in its own slot and the slots are averaged in trial
+// order, so the row doesn't depend on scheduling. Progress is reported per
+// trial.
+func runSweepCombination(ctx context.Context, step reductionStep, n int, q int64, trials int, progress *sharedProgress) (row sweepRow, ok bool) {
+	start := time.Now()
+	row = sweepRow{N: n, Beta: step.Beta, Q: q}
+
+	results := make([]sweepTrial, trials)
+	slots := make(chan struct{}, max(backend.Jobs, 1))
+	var wg sync.WaitGroup
+	for trial := 1; trial <= trials; trial++ {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			results[trial-1] = runSweepTrial(ctx, step, n, q, trial, progress)
+		}()
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return row, false
+	}
+
+	succeeded, withTours := 0, 0
+	for _, t := range results {
+		switch t.status {
+		case statusTimeout:
+			row.TimedOut++
+		case statusFailed:
+			row.Failed++
+		}
+		if !t.ok {
+			continue
+		}
+		row.GH += t.gh
+		row.B1 += t.b1
+		row.Delta += t.delta
+		row.Slope += t.slope
+		succeeded++
+		if t.tours > 0 {
+			row.Tours += float64(t.tours)
+			row.Log2Nodes += t.nodes
+			withTours++
+		}
+	}
+	if succeeded == 0 {
+		return row, false
+	}
+
+	k := float64(succeeded)
+	row.GH /= k
+	row.B1 /= k
+	row.Delta /= k
+	row.Slope /= k
+	if withTours > 0 {
+		row.Tours /= float64(withTours)
+		if row.Log2Nodes > 0 {
+			row.Log2Nodes = math.Log2(row.Log2Nodes / float64(withTours))
+		}
+	}
+	row.Seconds = time.Since(start).Seconds()
+	return row, true
+}
+
+// runSweepTrial reduces the basis of trial (numbered from 1) of a sweep
+// combination and computes its metrics.
+func runSweepTrial(ctx context.Context, step reductionStep, n int, q int64, trial int, progress *sharedProgress) sweepTrial {
+	if ctx.Err() != nil {
+		return sweepTrial{}
+	}
+	progress.running(n, trial, step.command())
+	basis := genRandomBasisFrom(trialSource("sweep", int64(n), int64(step.Beta), q, int64(trial-1)), n, big.NewInt(q))
+	trialStart := time.Now()
+	var tours tourLog
+	pipeline := reductionPipeline{Steps: []reductionStep{step}, MaxTime: backend.MaxTime, CheckVolume: true}
+	state, err := pipeline.run(withTourLog(ctx, &tours), basis)
+	reduced := state.Basis
+	if ctx.Err() != nil {
+		return sweepTrial{}
+	}
+	if err != nil {
+		progress.completed(n, trial, time.Since(trialStart).Seconds(), nil)
+		return sweepTrial{status: failureStatus(err)}
+	}
+	archiveBasis(savedBasis{Lab: "Sweep", N: n, Q: strconv.FormatInt(q, 10), Trial: trial,
+		Reduction: []reductionStep{step}}, reduced, nil)
+	profile := reduced.Profile()
+	progress.completed(n, trial, time.Since(trialStart).Seconds(), profile)
+
+	count, nodes := tourTotals(tours.Tours())
+	return sweepTrial{
+		gh:    gaussianHeuristic(profile.LogVolume(), n),
+		b1:    math.Exp2(profile[0]),
+		delta: profile.RHF(),
+		slope: profile.Slope(),
+		tours: count,
+		nodes: nodes,
+		ok:    true,
+	}
+}
+
+// runSweep runs the full Cartesian product of the configured parameter lists
+// and prints one aggregated row per combination. Combinations with β > n are
+// skipped since the block would exceed the lattice. With a checkpoint file,
+// every completed row is saved as soon as it is done and, when resuming,
+// combinations found in the file are not run again. If ctx is cancelled the
+// sweep stops and returns the rows completed so far.
+func runSweep(ctx context.Context, w io.Writer, cfg sweepConfig) ([]sweepRow, error) {
+	var cp *sweepCheckpoint
+	if cfg.Checkpoint != "" {
+		if dryRun != nil {
+			fmt.Fprintf(dryRun, "[dry-run] append completed rows to %s\n", cfg.Checkpoint)
+		} else {
+			var err error
+			if cp, err = openCheckpoint(cfg.Checkpoint, cfg.Resume); err != nil {
+				return nil, fmt.Errorf("checkpoint: %w", err)
+			}
+			defer cp.Close()
+		}
+	}
+
+	total := len(cfg.Dims) * len(cfg.Betas) * len(cfg.Qs)
+	fmt.Fprintln(w, "--- Running grid search over (n, beta, q) ---")
+	fmt.Fprintf(w, "%d combinations, %d trial(s) each.\n", total, cfg.Trials)
+	if o := cfg.BKZ.String(); o != "" {
+		fmt.Fprintf(w, "BKZ options: %s.\n", o)
+	}
+	if cp != nil && cfg.Resume {
+		fmt.Fprintf(w, "Resuming from %s (%d completed combination(s) on record).\n", cfg.Checkpoint, len(cp.done))
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "%-4s | %-4s | %-8s | %-10s | %-10s | %-8s | %-8s\n", "n", "beta", "q", "GH", "||b1||", "delta0", "slope")
+	fmt.Fprintln(w, "-----------------------------------------------------------------------")
+
+	var rows []sweepRow
+	progress := &sharedProgress{ev: progressEvent{Lab: "Sweep", Trials: cfg.Trials}}
+	for _, n := range cfg.Dims {
+		for _, beta := range cfg.Betas {
+			if beta <= n {
+				progress.ev.Total += len(cfg.Qs) * cfg.Trials
+			}
+		}
+	}
+grid:
+	for _, n := range cfg.Dims {
+		for _, beta := range cfg.Betas {
+			for _, q := range cfg.Qs {
+				if beta > n {
+					fmt.Fprintf(w, "%-4d | %-4d | %-8d | skipped: beta exceeds n\n", n, beta, q)
+					continue
+				}
+
+				var row sweepRow
+				resumed, ok := false, false
+				if cp != nil {
+					row, resumed = cp.lookup(n, beta, q, cfg.Trials)
+				}
+				if resumed {
+					// Finished in an earlier run; it is no work for the ETA.
+					ok = true
+					progress.set(func(ev *progressEvent) { ev.Total -= cfg.Trials })
+				} else {
+					progress.set(func(ev *progressEvent) { ev.Beta, ev.Q = beta, strconv.FormatInt(q, 10) })
+					row, ok = runSweepCombination(ctx, reductionStep{Algo: "bkz", Beta: beta, bkzOptions: cfg.BKZ}, n, q, cfg.Trials, progress)
+					if ctx.Err() != nil {
+						// Interrupted: the completed rows are already checkpointed.
+						break grid
+					}
+				}
+				if !ok {
+					if row.TimedOut == cfg.Trials {
+						fmt.Fprintf(w, "%-4d | %-4d | %-8d | timed out\n", n, beta, q)
+					} else {
+						fmt.Fprintf(w, "%-4d | %-4d | %-8d | failed\n", n, beta, q)
+					}
+					continue
+				}
+				if cp != nil && !resumed {
+					if err := cp.record(row, cfg.Trials); err != nil {
+						return rows, fmt.Errorf("checkpoint: %w", err)
+					}
+				}
+				rows = append(rows, row)
+				fmt.Fprintf(w, "%-4d | %-4d | %-8d | %-10.2f | %-10.2f | %-8.5f | %-8.4f",
+					row.N, row.Beta, row.Q, row.GH, row.B1, row.Delta, row.Slope)
+				if row.Tours > 0 {
+					fmt.Fprintf(w, " | %.1f tours, 2^%.2f nodes", row.Tours, row.Log2Nodes)
+				}
+				if row.Failed > 0 {
+					fmt.Fprintf(w, " | %d of %d trials failed", row.Failed, cfg.Trials)
+				}
+				if row.TimedOut > 0 {
+					fmt.Fprintf(w, " | %d of %d trials timed out", row.TimedOut, cfg.Trials)
+				}
+				fmt.Fprintln(w)
+			}
+		}
+	}
+
+	if ctx.Err() != nil {
+		fmt.Fprintln(w, "\nSweep interrupted.")
+	} else {
+		fmt.Fprintln(w, "\nSweep finished.")
+	}
+	return rows, nil
+}
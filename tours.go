@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// bkzTour is what fplll -v prints at the end of every tour of BKZ or slide
+// reduction, e.g.
+//
+//	End of BKZ loop    3, time =     0.412s, r_0 = 1.3e+05, slope = -0.0412, log2(nodes) =  18.21
+//
+// Seconds and Log2Nodes are fplll's running totals since the reduction
+// started: CPU time and enumeration nodes visited.
+type bkzTour struct {
+	Algo      string  `json:"algo"` // bkz or sld
+	Tour      int     `json:"tour"` // fplll's loop counter, from 0
+	Seconds   float64 `json:"seconds"`
+	Log2B1    float64 `json:"log2_b1"` // log2 ‖b*_1‖ after the tour, from fplll's r_0 = ‖b*_1‖²
+	Slope     float64 `json:"slope"`
+	Log2Nodes float64 `json:"log2_nodes"`
+}
+
+// parseBKZTours returns the tours in fplll's verbose standard error; other
+// lines are skipped, as are fields of a tour line it doesn't know.
+func parseBKZTours(stderr string) []bkzTour {
+	var tours []bkzTour
+	sc := bufio.NewScanner(strings.NewReader(stderr))
+	for sc.Scan() {
+		if t, ok := parseBKZTour(sc.Text()); ok {
+			tours = append(tours, t)
+		}
+	}
+	return tours
+}
+
+// parseBKZTour parses one "End of BKZ loop" line.
+func parseBKZTour(line string) (bkzTour, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "End of ")
+	if !ok {
+		return bkzTour{}, false
+	}
+	algo, rest, ok := strings.Cut(rest, " loop ")
+	if !ok {
+		return bkzTour{}, false
+	}
+	fields := strings.Split(rest, ",")
+	tour, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+	if err != nil {
+		return bkzTour{}, false
+	}
+	t := bkzTour{Algo: strings.ToLower(algo), Tour: tour}
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "s"), 64)
+		if err != nil {
+			continue
+		}
+		switch {
+		case key == "time":
+			t.Seconds = v
+		case strings.HasPrefix(key, "r_"):
+			if v > 0 {
+				t.Log2B1 = math.Log2(v) / 2
+			}
+		case key == "slope":
+			t.Slope = v
+		case key == "log2(nodes)":
+			t.Log2Nodes = v
+		}
+	}
+	return t, true
+}
+
+// tourLog collects the tours of the fplll calls made with a context from
+// withTourLog, and the profiles of their GSO dumps. Calls answered from the
+// result cache report none.
+type tourLog struct {
+	mu       sync.Mutex
+	tours    []bkzTour
+	profiles []tourProfile
+}
+
+type tourLogKey struct{}
+
+// withTourLog returns a context whose fplll calls record their tours in l.
+func withTourLog(ctx context.Context, l *tourLog) context.Context {
+	return context.WithValue(ctx, tourLogKey{}, l)
+}
+
+// tourLogOf returns the tour log of ctx, or nil.
+func tourLogOf(ctx context.Context) *tourLog {
+	l, _ := ctx.Value(tourLogKey{}).(*tourLog)
+	return l
+}
+
+// add records tours; a nil log drops them.
+func (l *tourLog) add(tours []bkzTour) {
+	if l == nil || len(tours) == 0 {
+		return
+	}
+	l.mu.Lock()
+	l.tours = append(l.tours, tours...)
+	l.mu.Unlock()
+}
+
+// addProfiles records the profiles of a GSO dump; a nil log drops them.
+func (l *tourLog) addProfiles(profiles []tourProfile) {
+	if l == nil || len(profiles) == 0 {
+		return
+	}
+	l.mu.Lock()
+	l.profiles = append(l.profiles, profiles...)
+	l.mu.Unlock()
+}
+
+// Profiles returns the GSO dump profiles recorded so far.
+func (l *tourLog) Profiles() []tourProfile {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.profiles
+}
+
+// Tours returns the tours recorded so far.
+func (l *tourLog) Tours() []bkzTour {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tours
+}
+
+// tourTotals returns the number of tours and the enumeration nodes of all
+// reductions in tours, adding up the final running total of each.
+func tourTotals(tours []bkzTour) (count int, nodes float64) {
+	for i, t := range tours {
+		if i+1 == len(tours) || tours[i+1].Tour <= t.Tour {
+			nodes += math.Exp2(t.Log2Nodes)
+		}
+	}
+	return len(tours), nodes
+}
+
+// writeTours writes the total tours and enumeration nodes and then the tour
+// table, unless there are no tours.
+func writeTours(w io.Writer, tours []bkzTour) {
+	if len(tours) == 0 {
+		return
+	}
+	count, nodes := tourTotals(tours)
+	fmt.Fprintf(w, "%d tour(s), 2^%.2f enumeration nodes:\n", count, math.Log2(nodes))
+	writeTourTable(w, tours)
+}
+
+// writeTourTable writes one row per tour: fplll's CPU time, ‖b*_1‖, the
+// slope of the profile and the enumeration nodes so far.
+func writeTourTable(w io.Writer, tours []bkzTour) {
+	fmt.Fprintf(w, "%-4s | %-5s | %-9s | %-9s | %-9s | %s\n", "Algo", "Tour", "CPU time", "log2 ‖b1‖", "Slope", "log2 nodes")
+	fmt.Fprintln(w, "---------------------------------------------------------------")
+	for _, t := range tours {
+		fmt.Fprintf(w, "%-4s | %-5d | %-9s | %-9.3f | %-9.5f | %.2f\n",
+			t.Algo, t.Tour, strconv.FormatFloat(t.Seconds, 'f', 3, 64)+"s", t.Log2B1, t.Slope, t.Log2Nodes)
+	}
+}
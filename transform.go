@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// Matrices fplll prints for a reduction when asked with -of: the reduced
+// basis B' (b), the unimodular transformation U with U·B = B' (u) and its
+// inverse U⁻¹ (v). fplll names them with a k suffix (bk, uk, vk) for the
+// BKZ-type algorithms and prints them in this order.
+const reductionOutputs = "buv"
+
+// checkReductionOutputs reports whether of asks for some of reductionOutputs,
+// each at most once.
+func checkReductionOutputs(of string) error {
+	if of == "" {
+		return fmt.Errorf("-of must name at least one of b (basis), u (transformation) and v (inverse)")
+	}
+	for i, c := range of {
+		if !strings.ContainsRune(reductionOutputs, c) {
+			return fmt.Errorf("-of: unknown output %q (want b, u or v)", c)
+		}
+		if strings.ContainsRune(of[:i], c) {
+			return fmt.Errorf("-of: output %q given twice", c)
+		}
+	}
+	return nil
+}
+
+// isTransformOutput reports whether args ask fplll for a transformation
+// matrix, which only holds for the basis fplll was given.
+func isTransformOutput(args []string) bool {
+	i := slices.Index(args, "-of")
+	return i >= 0 && i+1 < len(args) && strings.ContainsAny(args[i+1], "uv")
+}
+
+// reductionTransform is the result of a reduction that kept track of its
+// unimodular transformation: U·B = Reduced for the input basis B, and
+// U·Inverse = I if the inverse was asked for.
+type reductionTransform struct {
+	Reduced    Basis
+	U, Inverse [][]*big.Int
+}
+
+// fplllReduceTransform runs the reduction step with fplll like fplllReduce,
+// but also has fplll print the transformation matrix, and with inverse its
+// inverse, and checks them exactly against the input and reduced bases. In
+// a dry run the basis is returned with the identity transformation.
+func fplllReduceTransform(ctx context.Context, basis Basis, step reductionStep, inverse bool) (reductionTransform, error) {
+	of := "bu"
+	if inverse {
+		of += "v"
+	}
+	algo := step.algo()
+	cmdArgs := backend.fplllArgs(algo, append(step.args(), "-of", fplllOutputNames(algo, of))...)
+	if dryRun != nil {
+		planFplll(basis, cmdArgs...)
+		t := reductionTransform{Reduced: basis, U: identityMatrix(len(basis))}
+		if inverse {
+			t.Inverse = t.U
+		}
+		return t, nil
+	}
+	n := len(basis)
+	var rows [][]*big.Int
+	err := backend.runFplllCached(ctx, basis, cmdArgs, func(r io.Reader) (err error) {
+		if rows, err = backend.Version.dialect().Matrix(r); err == nil && len(rows) != len(of)*n {
+			err = fmt.Errorf("%d rows, expected %d for %d matrices", len(rows), len(of)*n, len(of))
+		}
+		if err == nil {
+			err = checkReducedBasis(basis, rows[:n])
+		}
+		if err == nil {
+			err = checkMatrix(rows[n:], len(rows)-n, n)
+		}
+		return err
+	})
+	if err != nil {
+		logFplllError(algo, n, err)
+		return reductionTransform{}, err
+	}
+	t := reductionTransform{Reduced: rows[:n], U: rows[n : 2*n]}
+	if inverse {
+		t.Inverse = rows[2*n:]
+	}
+	if err := t.check(basis); err != nil {
+		return reductionTransform{}, fmt.Errorf("fplll's transformation: %w", err)
+	}
+	return t, nil
+}
+
+// fplllOutputNames returns the -of argument of fplll asking for the given
+// reductionOutputs of a reduction by algo.
+func fplllOutputNames(algo, of string) string {
+	if algo == "lll" {
+		return of
+	}
+	names := make([]string, 0, len(of))
+	for _, c := range of {
+		names = append(names, string(c)+"k")
+	}
+	return strings.Join(names, "")
+}
+
+// check verifies exactly that U·basis = Reduced and, if the inverse is
+// set, that U·Inverse = I, which also proves U unimodular.
+func (t reductionTransform) check(basis Basis) error {
+	if !slices.EqualFunc(mulMatrices(t.U, basis), t.Reduced, equalRows) {
+		return fmt.Errorf("U times the input basis is not the reduced basis")
+	}
+	if t.Inverse != nil && !slices.EqualFunc(mulMatrices(t.U, t.Inverse), identityMatrix(len(t.U)), equalRows) {
+		return fmt.Errorf("U times its inverse is not the identity")
+	}
+	return nil
+}
+
+// compose returns the transformation of the reduction t followed by next:
+// next.U·t.U, with the inverse t.Inverse·next.Inverse if both have one.
+func (t reductionTransform) compose(next reductionTransform) reductionTransform {
+	c := reductionTransform{Reduced: next.Reduced, U: mulMatrices(next.U, t.U)}
+	if t.Inverse != nil && next.Inverse != nil {
+		c.Inverse = mulMatrices(t.Inverse, next.Inverse)
+	}
+	return c
+}
+
+// applyReductionTransform runs the reduction steps of a pipeline like
+// applyReduction, composing the transformations of the steps, so that
+// U·basis is the reduced basis in the end. Enum steps are skipped.
+func applyReductionTransform(ctx context.Context, basis Basis, steps []reductionStep, inverse bool) (reductionTransform, error) {
+	t := reductionTransform{Reduced: basis, U: identityMatrix(len(basis))}
+	if inverse {
+		t.Inverse = t.U
+	}
+	for _, step := range steps {
+		if step.algo() == "enum" {
+			continue // leaves the basis as it is
+		}
+		next, err := fplllReduceTransform(ctx, t.Reduced, step, inverse)
+		if err != nil {
+			return reductionTransform{}, err
+		}
+		t = t.compose(next)
+	}
+	return t, nil
+}
+
+// equalRows reports whether two integer vectors are equal.
+func equalRows(a, b []*big.Int) bool {
+	return slices.EqualFunc(a, b, func(x, y *big.Int) bool { return x.Cmp(y) == 0 })
+}
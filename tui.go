@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dashboardRefresh is how often the panel is redrawn while nothing else
+// happens, so that the elapsed and estimated times keep moving.
+const dashboardRefresh = 500 * time.Millisecond
+
+// dashboard is a small terminal UI for long runs. It keeps a status panel
+// at the bottom of the terminal showing the current instance, the backend
+// call in flight, elapsed and estimated time and a sparkline of the latest
+// profile. Log output written through it is printed above the panel.
+type dashboard struct {
+	mu   sync.Mutex
+	out  io.Writer // receives the log, normally standard output
+	term io.Writer // receives the panel, normally standard error
+
+	start        time.Time
+	labStart     time.Time // start of the current lab, for the ETA
+	backendStart time.Time // start of the backend call in flight
+	ev           progressEvent
+	lines        int    // height of the panel currently on screen
+	partial      []byte // log output after the last newline
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// isTerminal reports whether f is attached to a character device.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// newDashboard starts a dashboard drawing its panel on term and passing the
+// log through to out. It must be closed to restore the terminal.
+func newDashboard(out, term io.Writer) *dashboard {
+	now := time.Now()
+	d := &dashboard{
+		out:      out,
+		term:     term,
+		start:    now,
+		labStart: now,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go d.tick()
+	return d
+}
+
+// tick redraws the panel periodically until the dashboard is closed.
+func (d *dashboard) tick() {
+	defer close(d.done)
+	t := time.NewTicker(dashboardRefresh)
+	defer t.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-t.C:
+			d.mu.Lock()
+			d.redraw()
+			d.mu.Unlock()
+		}
+	}
+}
+
+// Write implements io.Writer for the log. Complete lines are printed above
+// the panel; a trailing partial line is held back until it is finished.
+func (d *dashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.partial = append(d.partial, p...)
+	i := bytes.LastIndexByte(d.partial, '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	d.erase()
+	_, err := d.out.Write(d.partial[:i+1])
+	d.partial = append(d.partial[:0], d.partial[i+1:]...)
+	d.draw()
+	return len(p), err
+}
+
+// update records a progress event and redraws the panel.
+func (d *dashboard) update(ev progressEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if ev.Lab != d.ev.Lab || ev.Done < d.ev.Done {
+		d.labStart = now
+	}
+	if ev.Backend != d.ev.Backend {
+		d.backendStart = now
+	}
+	if ev.Profile == nil && ev.Lab == d.ev.Lab {
+		ev.Profile = d.ev.Profile
+	}
+	d.ev = ev
+	d.redraw()
+}
+
+// Close stops the dashboard, removes the panel and flushes the log.
+func (d *dashboard) Close() {
+	close(d.stop)
+	<-d.done
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.erase()
+	if len(d.partial) > 0 {
+		d.out.Write(d.partial)
+		d.partial = nil
+	}
+}
+
+// redraw replaces the panel on screen.
+func (d *dashboard) redraw() {
+	d.erase()
+	d.draw()
+}
+
+// erase moves the cursor to the first line of the panel and clears it.
+func (d *dashboard) erase() {
+	if d.lines > 0 {
+		fmt.Fprintf(d.term, "\x1b[%dA\r\x1b[J", d.lines)
+		d.lines = 0
+	}
+}
+
+// draw prints the panel at the cursor, which must be at the start of a line.
+func (d *dashboard) draw() {
+	ev := d.ev
+	now := time.Now()
+
+	var b strings.Builder
+	b.WriteString("── lattice-labs " + strings.Repeat("─", 40) + "\n")
+	if ev.Lab == "" {
+		b.WriteString(" starting...\n")
+	} else {
+		fmt.Fprintf(&b, " %-6s   n=%d", ev.Lab, ev.N)
+		if ev.Beta > 0 {
+			fmt.Fprintf(&b, "  beta=%d", ev.Beta)
+		}
+		fmt.Fprintf(&b, "  q=%s  trial %d/%d\n", ev.Q, ev.Trial, max(ev.Trials, 1))
+		fmt.Fprintf(&b, " progress %s %d/%d instances\n", progressBar(ev.Done, ev.Total, 30), ev.Done, ev.Total)
+		if ev.Backend != "" {
+			fmt.Fprintf(&b, " backend  %s (running %s)\n", ev.Backend, formatDuration(now.Sub(d.backendStart)))
+		} else {
+			b.WriteString(" backend  idle\n")
+		}
+	}
+	fmt.Fprintf(&b, " elapsed  %s", formatDuration(now.Sub(d.start)))
+	if ev.Done > 0 && ev.Total > ev.Done {
+		perInstance := now.Sub(d.labStart) / time.Duration(ev.Done)
+		fmt.Fprintf(&b, "   ETA %s", formatDuration(perInstance*time.Duration(ev.Total-ev.Done)))
+	}
+	b.WriteString("\n")
+	if len(ev.Profile) > 0 {
+		fmt.Fprintf(&b, " profile  %s\n", sparkline(ev.Profile))
+	}
+
+	panel := b.String()
+	d.term.Write([]byte(panel))
+	d.lines = strings.Count(panel, "\n")
+}
+
+// progressBar renders done out of total as a bar of the given width.
+func progressBar(done, total, width int) string {
+	filled := 0
+	if total > 0 {
+		filled = min(width, done*width/total)
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", width-filled) + "]"
+}
+
+// sparkline renders values as a row of block characters scaled between
+// their minimum and maximum.
+func sparkline(values []float64) string {
+	const blocks = "▁▂▃▄▅▆▇█"
+	levels := []rune(blocks)
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if hi > lo {
+			level = int(math.Round((v - lo) / (hi - lo) * float64(len(levels)-1)))
+		}
+		b.WriteRune(levels[level])
+	}
+	return b.String()
+}
+
+// formatDuration prints d rounded to whole seconds.
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
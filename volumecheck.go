@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// errVolumeChanged is returned by checkVolumePreserved.
+var errVolumeChanged = errors.New("the reduced basis spans a lattice of another volume")
+
+// checkVolumePreserved compares the exact squared volumes det(B·Bᵀ) of the
+// lattices of a basis and of its reduction and returns an error wrapping
+// errVolumeChanged if they differ. Reduction never changes the lattice, so
+// it catches a basis garbled on the way to or from an external tool at the
+// cost of one exact determinant per basis; a basis of another lattice of
+// the same volume passes (sameLattice decides that, at a higher cost).
+func checkVolumePreserved(before, after Basis) error {
+	return checkSquaredVolume(before.SquaredVolume(), after)
+}
+
+// checkSquaredVolume is checkVolumePreserved with the squared volume of the
+// input already computed.
+func checkSquaredVolume(want *big.Int, after Basis) error {
+	if got := after.SquaredVolume(); got.Cmp(want) != 0 {
+		return fmt.Errorf("%w: det(B·Bᵀ) differs from the input's (%d bits, the input's %d)", errVolumeChanged, got.BitLen(), want.BitLen())
+	}
+	return nil
+}
+
+// volumeChanges collects the volume changes the pipelines of the labs
+// found (see reductionPipeline.CheckVolume), so that the run fails once its
+// results are written.
+var volumeChanges struct {
+	sync.Mutex
+	found []string
+}
+
+// recordVolumeChange adds a volume change found after step on a basis of
+// the given rank.
+func recordVolumeChange(step reductionStep, rank int, err error) {
+	volumeChanges.Lock()
+	defer volumeChanges.Unlock()
+	volumeChanges.found = append(volumeChanges.found, fmt.Sprintf("%s on a basis of rank %d: %v", step, rank, err))
+}
+
+// volumeChangeError returns the volume changes recorded so far as an
+// *assertionError, or nil if there are none.
+func volumeChangeError() error {
+	volumeChanges.Lock()
+	defer volumeChanges.Unlock()
+	if len(volumeChanges.found) == 0 {
+		return nil
+	}
+	return &assertionError{Kind: "reduction(s) changed the lattice volume", Violations: volumeChanges.found}
+}